@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/config"
+	"github.com/xh63/netbird-events/pkg/dlq"
+	"github.com/xh63/netbird-events/pkg/processor"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+const dlqUsage = `Usage: eventsproc dlq [-config path] [-source name] <command> [flags]
+
+Commands:
+  list [-writer name] [-consumer id] [-limit n]   List dead letters, oldest first
+  inspect <id>                                     Print one dead letter as JSON
+  redrive [-to name] (-writer name | <id>...)      Send dead letters to a writer again
+                                                   and remove the delivered ones
+
+In multi-source mode, -source selects the instance whose dead letters are used.
+`
+
+// runDLQ implements the "eventsproc dlq" subcommands and returns the exit code.
+func runDLQ(args []string) int {
+	fs := flag.NewFlagSet("dlq", flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, dlqUsage) }
+	configFile := fs.String("config", "/etc/app/eventsproc/config.yaml", "Path to configuration file")
+	source := fs.String("source", "", "Instance name in multi-source mode")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		return 1
+	}
+	if len(cfg.Instances) > 0 {
+		inst, ok := cfg.Instance(*source)
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Error: -source must name one of the configured instances")
+			return 2
+		}
+		cfg = cfg.ForInstance(inst)
+	}
+	// Keep stdout for the command output; writers only report problems
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	store, closeStore, err := openDLQ(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening dead-letter queue: %v\n", err)
+		return 1
+	}
+	defer closeStore()
+
+	ctx := context.Background()
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		err = dlqList(ctx, store, cmdArgs, os.Stdout)
+	case "inspect":
+		err = dlqInspect(ctx, store, cmdArgs, os.Stdout)
+	case "redrive":
+		err = dlqRedrive(ctx, store, cmdArgs, os.Stdout, func(name string) (writer.EventWriter, error) {
+			return processor.BuildWriter(cfg, logger, name)
+		})
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// openDLQ opens the configured store, and the event database when the store
+// lives there.
+func openDLQ(cfg *config.Config, logger *slog.Logger) (dlq.Store, func(), error) {
+	var db *sql.DB
+	if cfg.DLQ.Type == dlq.TypeDatabase {
+		var err error
+		if db, err = config.OpenDatabase(cfg.CheckpointDatabase(), logger); err != nil {
+			return nil, nil, fmt.Errorf("failed to open database: %w", err)
+		}
+	}
+	store, err := processor.OpenDeadLetterStore(cfg, db)
+	if err != nil {
+		if db != nil {
+			_ = db.Close()
+		}
+		return nil, nil, err
+	}
+	return store, func() {
+		_ = store.Close()
+		if db != nil {
+			_ = db.Close()
+		}
+	}, nil
+}
+
+// dlqList prints the matching entries as a table.
+func dlqList(ctx context.Context, store dlq.Store, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	writerName := fs.String("writer", "", "Only entries rejected by this writer, e.g. loki or webhook:siem")
+	consumer := fs.String("consumer", "", "Only entries of this consumer_id")
+	limit := fs.Int("limit", 100, "Maximum number of entries (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	entries, err := store.List(ctx, dlq.ListOptions{ConsumerID: *consumer, Writer: *writerName, Limit: *limit})
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tFAILED_AT\tWRITER\tEVENT_ID\tACTIVITY\tREASON")
+	for _, e := range entries {
+		_, _ = fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			e.ID, e.FailedAt.Format(time.RFC3339), e.Writer, e.Event.ID, e.Event.ActivityCode, e.Reason)
+	}
+	return tw.Flush()
+}
+
+// dlqInspect prints one entry as indented JSON.
+func dlqInspect(ctx context.Context, store dlq.Store, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("inspect takes exactly one dead letter ID")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid dead letter ID %q", args[0])
+	}
+	e, err := store.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// dlqRedrive sends entries to their writer, or the -to writer, one event at a
+// time, and deletes the delivered ones. Entries that fail again stay in the
+// queue.
+func dlqRedrive(ctx context.Context, store dlq.Store, args []string, out io.Writer,
+	buildWriter func(name string) (writer.EventWriter, error)) error {
+	fs := flag.NewFlagSet("redrive", flag.ContinueOnError)
+	writerName := fs.String("writer", "", "Re-drive every entry rejected by this writer")
+	to := fs.String("to", "", "Send to this writer instead of the one that rejected the entries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var entries []dlq.Entry
+	switch {
+	case *writerName != "" && fs.NArg() > 0:
+		return errors.New("redrive takes either -writer or entry IDs, not both")
+	case *writerName != "":
+		var err error
+		if entries, err = store.List(ctx, dlq.ListOptions{Writer: *writerName}); err != nil {
+			return err
+		}
+	case fs.NArg() > 0:
+		for _, arg := range fs.Args() {
+			id, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid dead letter ID %q", arg)
+			}
+			e, err := store.Get(ctx, id)
+			if err != nil {
+				return fmt.Errorf("dead letter %d: %w", id, err)
+			}
+			entries = append(entries, *e)
+		}
+	default:
+		return errors.New("redrive needs -writer or entry IDs")
+	}
+
+	writers := make(map[string]writer.EventWriter)
+	defer func() {
+		for _, w := range writers {
+			_ = w.Close()
+		}
+	}()
+
+	var delivered []int64
+	failed := 0
+	for _, e := range entries {
+		target := e.Writer
+		if *to != "" {
+			target = *to
+		}
+		w, ok := writers[target]
+		if !ok {
+			var err error
+			if w, err = buildWriter(target); err != nil {
+				return fmt.Errorf("failed to create writer %s: %w", target, err)
+			}
+			writers[target] = w
+		}
+		if err := w.SendEvent(ctx, e.Event); err != nil {
+			failed++
+			_, _ = fmt.Fprintf(out, "%d: event %d failed again on %s: %v\n", e.ID, e.Event.ID, target, err)
+			continue
+		}
+		delivered = append(delivered, e.ID)
+	}
+
+	if err := store.Delete(ctx, delivered); err != nil {
+		return fmt.Errorf("events were delivered but their dead letters could not be removed: %w", err)
+	}
+	_, _ = fmt.Fprintf(out, "Re-drove %d of %d dead letters\n", len(delivered), len(entries))
+	if failed > 0 {
+		return fmt.Errorf("%d dead letters could not be delivered", failed)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xh63/netbird-events/pkg/dlq"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// failingWriter fails the event IDs in bad.
+type failingWriter struct {
+	bad  map[int64]bool
+	sent []int64
+}
+
+func (f *failingWriter) SendEvents(ctx context.Context, evts []events.Event) error {
+	for _, evt := range evts {
+		if err := f.SendEvent(ctx, evt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *failingWriter) Close() error { return nil }
+
+func (f *failingWriter) SendEvent(_ context.Context, evt events.Event) error {
+	if f.bad[evt.ID] {
+		return errors.New("still broken")
+	}
+	f.sent = append(f.sent, evt.ID)
+	return nil
+}
+
+func TestDLQRedrive(t *testing.T) {
+	ctx := context.Background()
+	store, err := dlq.NewFileStore(filepath.Join(t.TempDir(), "dlq.ndjson"))
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	if err := store.Add(ctx, []dlq.Entry{
+		{Writer: "loki", Event: events.Event{ID: 1}},
+		{Writer: "loki", Event: events.Event{ID: 2}},
+		{Writer: "webhook:siem", Event: events.Event{ID: 3}},
+	}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	built := map[string]*failingWriter{}
+	build := func(name string) (writer.EventWriter, error) {
+		w := &failingWriter{bad: map[int64]bool{2: true}}
+		built[name] = w
+		return w, nil
+	}
+
+	var out bytes.Buffer
+	err = dlqRedrive(ctx, store, []string{"-writer", "loki"}, &out, build)
+	if err == nil || !strings.Contains(out.String(), "Re-drove 1 of 2") {
+		t.Errorf("Expected one entry to fail again, got %v, output %q", err, out.String())
+	}
+	if len(built) != 1 || len(built["loki"].sent) != 1 || built["loki"].sent[0] != 1 {
+		t.Errorf("Expected event 1 to be sent to loki, got %+v", built)
+	}
+
+	left, _ := store.List(ctx, dlq.ListOptions{})
+	if len(left) != 2 || left[0].Event.ID != 2 || left[1].Event.ID != 3 {
+		t.Errorf("Expected the delivered entry to be removed, got %+v", left)
+	}
+
+	// Entry IDs with a different target
+	out.Reset()
+	if err := dlqRedrive(ctx, store, []string{"-to", "file", "3"}, &out, build); err != nil {
+		t.Fatalf("redrive failed: %v", err)
+	}
+	if built["file"] == nil || built["file"].sent[0] != 3 {
+		t.Errorf("Expected event 3 to be sent to the file writer, got %+v", built)
+	}
+
+	if err := dlqRedrive(ctx, store, nil, &out, build); err == nil {
+		t.Error("Expected error without -writer or IDs")
+	}
+}
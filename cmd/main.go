@@ -14,6 +14,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/xh63/netbird-events/pkg/config"
 	"github.com/xh63/netbird-events/pkg/election"
+	"github.com/xh63/netbird-events/pkg/format"
 	"github.com/xh63/netbird-events/pkg/metrics"
 	"github.com/xh63/netbird-events/pkg/processor"
 )
@@ -21,6 +22,11 @@ import (
 var version = "dev"
 
 func main() {
+	// Dead-letter queue maintenance: eventsproc dlq <list|inspect|redrive>
+	if len(os.Args) > 1 && os.Args[1] == "dlq" {
+		os.Exit(runDLQ(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	configFile := flag.String("config", "/etc/app/eventsproc/config.yaml", "Path to configuration file")
 	showVersion := flag.Bool("version", false, "Show version and exit")
@@ -32,6 +38,9 @@ func main() {
 		os.Exit(0)
 	}
 
+	// CEF/LEEF headers report the build version as the device version
+	format.DeviceVersion = version
+
 	// Load configuration
 	cfg, err := config.LoadConfig(*configFile)
 	if err != nil {
@@ -61,14 +70,6 @@ func main() {
 		}
 	}()
 
-	// Create processor
-	proc, err := processor.NewProcessor(cfg, logFactory)
-	if err != nil {
-		logger.Error("Failed to create processor", "error", err)
-		os.Exit(1)
-	}
-	defer func() { _ = proc.Close() }()
-
 	// Setup context with cancellation for the full application lifetime.
 	appCtx, appCancel := context.WithCancel(context.Background())
 	defer appCancel()
@@ -83,7 +84,41 @@ func main() {
 		appCancel()
 	}()
 
-	// Run the processor — directly (standalone) or via the Redis elector (HA mode).
+	// With secrets.refresh_interval, a configuration with changed secrets
+	// replaces the running one, re-opening every connection.
+	secretChanges := cfg.WatchSecrets(appCtx, logger)
+	for {
+		next, err := run(appCtx, cfg, logFactory, secretChanges)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error("Fatal error", "error", err)
+			os.Exit(1)
+		}
+		if next == nil {
+			break
+		}
+		logger.Info("Re-opening connections with the changed secrets")
+		cfg = next
+	}
+	logger.Info("Shutdown complete")
+}
+
+// run runs the processor — directly (standalone) or via the Redis elector
+// (HA mode) — until appCtx is cancelled, the processor fails, or a
+// configuration with changed secrets arrives, which it returns after
+// stopping the processor.
+func run(appCtx context.Context, cfg *config.Config, logFactory config.LogFactory, secretChanges <-chan *config.Config) (*config.Config, error) {
+	logger := logFactory.New("system")
+
+	// Create processor, one per instance in multi-source mode
+	proc, err := processor.New(cfg, logFactory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create processor: %w", err)
+	}
+	defer func() { _ = proc.Close() }()
+
+	runCtx, cancel := context.WithCancel(appCtx)
+	defer cancel()
+
 	// errChan is buffered so the goroutine never blocks on write.
 	errChan := make(chan error, 1)
 
@@ -99,17 +134,16 @@ func main() {
 			NodeID:        hostname,
 		}, clusterLogger)
 		if elErr != nil {
-			logger.Error("Failed to create leader elector", "error", elErr)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to create leader elector: %w", elErr)
 		}
+		defer func() { _ = el.Close() }()
 		logger.Info("Cluster mode enabled",
 			"node", hostname,
 			"lock_key", lockKey,
 			"lock_ttl_seconds", cfg.Cluster.LockTTL,
-			"redis_url", cfg.Cluster.RedisURL,
 		)
 		go func() {
-			errChan <- el.Run(appCtx, func(ctx context.Context) error {
+			errChan <- el.Run(runCtx, func(ctx context.Context) error {
 				metrics.IsLeader.Set(1)
 				defer metrics.IsLeader.Set(0)
 				return proc.Run(ctx)
@@ -118,14 +152,19 @@ func main() {
 	} else {
 		logger.Info("Standalone mode (cluster disabled)")
 		go func() {
-			errChan <- proc.Run(appCtx)
+			errChan <- proc.Run(runCtx)
 		}()
 	}
 
-	// Block until the processor (or elector) exits.
-	if err := <-errChan; err != nil && !errors.Is(err, context.Canceled) {
-		logger.Error("Fatal error", "error", err)
-		os.Exit(1)
+	// Block until the processor (or elector) exits or the secrets change.
+	select {
+	case err := <-errChan:
+		return nil, err
+	case next := <-secretChanges:
+		cancel()
+		if err := <-errChan; err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		return next, nil
 	}
-	logger.Info("Shutdown complete")
 }
@@ -3,15 +3,27 @@
 
 CREATE SCHEMA IF NOT EXISTS idp;
 
--- Single-checkpoint model (migration 003+)
--- consumer_id is the sole primary key; writer_type kept for legacy compatibility
+-- Per-writer checkpoints (migration 004+)
+-- Each writer of a consumer tracks its own progress
 CREATE TABLE IF NOT EXISTS idp.event_processing_checkpoint (
-    consumer_id VARCHAR(255) PRIMARY KEY,
-    writer_type VARCHAR(50) NOT NULL DEFAULT 'default',
+    consumer_id VARCHAR(255) NOT NULL,
+    writer_type VARCHAR(255) NOT NULL DEFAULT 'default',
     last_event_id BIGINT NOT NULL,
     last_event_timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
     total_events_processed BIGINT DEFAULT 0,
     processing_node VARCHAR(255),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_id, writer_type)
+);
+
+-- Dead letters (migration 005+), used with dlq.type "database"
+CREATE TABLE IF NOT EXISTS idp.event_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    consumer_id VARCHAR(255) NOT NULL,
+    writer_type VARCHAR(255) NOT NULL,
+    event_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    event JSONB NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -3,12 +3,24 @@
 -- Safe to run on an existing NetBird store.db (IF NOT EXISTS)
 
 CREATE TABLE IF NOT EXISTS event_processing_checkpoint (
-    consumer_id TEXT PRIMARY KEY,
+    consumer_id TEXT NOT NULL,
     writer_type TEXT NOT NULL DEFAULT 'default',
     last_event_id INTEGER NOT NULL DEFAULT 0,
     last_event_timestamp DATETIME,
     total_events_processed INTEGER NOT NULL DEFAULT 0,
     processing_node TEXT,
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_id, writer_type)
+);
+
+-- Dead letters, used with dlq.type "database"
+CREATE TABLE IF NOT EXISTS event_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    consumer_id TEXT NOT NULL,
+    writer_type TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    event TEXT NOT NULL,
+    failed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
@@ -0,0 +1,32 @@
+-- Migration 004: per-writer checkpoints (PostgreSQL)
+--
+-- Every writer keeps its own checkpoint, so a failing sink falls behind on
+-- its own instead of stalling the others. The primary key becomes
+-- (consumer_id, writer_type).
+--
+-- Existing single-checkpoint rows keep writer_type 'default'. On first start
+-- each writer copies that row, so no events are sent twice. Once every writer
+-- has saved a checkpoint of its own the 'default' row can be deleted.
+--
+-- Stop eventsproc on all nodes before applying.
+
+BEGIN;
+
+-- Migration 003 dropped writer_type on some installs; the lab schema kept it
+ALTER TABLE idp.event_processing_checkpoint
+    ADD COLUMN IF NOT EXISTS writer_type VARCHAR(255) DEFAULT 'default';
+
+ALTER TABLE idp.event_processing_checkpoint
+    ALTER COLUMN writer_type TYPE VARCHAR(255),
+    ALTER COLUMN writer_type SET DEFAULT 'default';
+
+UPDATE idp.event_processing_checkpoint
+    SET writer_type = 'default'
+    WHERE writer_type IS NULL OR writer_type = '';
+
+ALTER TABLE idp.event_processing_checkpoint
+    ALTER COLUMN writer_type SET NOT NULL,
+    DROP CONSTRAINT IF EXISTS event_processing_checkpoint_pkey,
+    ADD PRIMARY KEY (consumer_id, writer_type);
+
+COMMIT;
@@ -0,0 +1,19 @@
+-- Migration 004: per-writer checkpoints (MySQL/MariaDB)
+--
+-- MySQL support starts with per-writer checkpoints, so this creates the table
+-- in its current form, in the NetBird database next to the events table.
+-- The (consumer_id, writer_type) key is what ON DUPLICATE KEY UPDATE relies on.
+--
+--   mysql -h <mysql-host> -u netbird -p netbird < migrations/004_per_writer_checkpoints_mysql.sql
+
+CREATE TABLE IF NOT EXISTS event_processing_checkpoint (
+    consumer_id VARCHAR(255) NOT NULL,
+    writer_type VARCHAR(255) NOT NULL DEFAULT 'default',
+    last_event_id BIGINT NOT NULL DEFAULT 0,
+    last_event_timestamp DATETIME(6) NULL,
+    total_events_processed BIGINT NOT NULL DEFAULT 0,
+    processing_node VARCHAR(255) NULL,
+    updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (consumer_id, writer_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
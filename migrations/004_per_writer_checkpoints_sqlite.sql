@@ -0,0 +1,34 @@
+-- Migration 004: per-writer checkpoints (SQLite)
+--
+-- SQLite cannot change a primary key in place, so the table is rebuilt with
+-- the (consumer_id, writer_type) key. Existing rows keep writer_type
+-- 'default'; see 004_per_writer_checkpoints.sql for how they are used.
+--
+-- Stop eventsproc before applying:
+--   sqlite3 /var/lib/netbird/store.db < migrations/004_per_writer_checkpoints_sqlite.sql
+
+BEGIN;
+
+CREATE TABLE event_processing_checkpoint_new (
+    consumer_id TEXT NOT NULL,
+    writer_type TEXT NOT NULL DEFAULT 'default',
+    last_event_id INTEGER NOT NULL DEFAULT 0,
+    last_event_timestamp DATETIME,
+    total_events_processed INTEGER NOT NULL DEFAULT 0,
+    processing_node TEXT,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer_id, writer_type)
+);
+
+INSERT INTO event_processing_checkpoint_new
+    (consumer_id, writer_type, last_event_id, last_event_timestamp,
+     total_events_processed, processing_node, updated_at, created_at)
+SELECT consumer_id, COALESCE(NULLIF(writer_type, ''), 'default'), last_event_id, last_event_timestamp,
+       total_events_processed, processing_node, updated_at, created_at
+FROM event_processing_checkpoint;
+
+DROP TABLE event_processing_checkpoint;
+ALTER TABLE event_processing_checkpoint_new RENAME TO event_processing_checkpoint;
+
+COMMIT;
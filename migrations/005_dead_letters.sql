@@ -0,0 +1,20 @@
+-- Migration 005: dead-letter table (PostgreSQL)
+--
+-- Only needed with dlq.type "database". Events a writer permanently rejects
+-- are stored here with the writer name and the reason, and the rest of the
+-- batch is delivered. List, inspect and re-drive them with "eventsproc dlq".
+--
+-- Can be applied while eventsproc is running.
+
+CREATE TABLE IF NOT EXISTS idp.event_dead_letters (
+    id BIGSERIAL PRIMARY KEY,
+    consumer_id VARCHAR(255) NOT NULL,
+    writer_type VARCHAR(255) NOT NULL,
+    event_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    event JSONB NOT NULL,
+    failed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS event_dead_letters_writer_idx
+    ON idp.event_dead_letters (consumer_id, writer_type);
@@ -0,0 +1,15 @@
+-- Migration 005: dead-letter table (MySQL/MariaDB)
+--
+-- Only needed with dlq.type "database"; see 005_dead_letters.sql.
+--   mysql -h <mysql-host> -u netbird -p netbird < migrations/005_dead_letters_mysql.sql
+
+CREATE TABLE IF NOT EXISTS event_dead_letters (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    consumer_id VARCHAR(255) NOT NULL,
+    writer_type VARCHAR(255) NOT NULL,
+    event_id BIGINT NOT NULL,
+    reason TEXT NOT NULL,
+    event LONGTEXT NOT NULL,
+    failed_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    INDEX event_dead_letters_writer_idx (consumer_id, writer_type)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,17 @@
+-- Migration 005: dead-letter table (SQLite)
+--
+-- Only needed with dlq.type "database"; see 005_dead_letters.sql.
+--   sqlite3 /var/lib/netbird/store.db < migrations/005_dead_letters_sqlite.sql
+
+CREATE TABLE IF NOT EXISTS event_dead_letters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    consumer_id TEXT NOT NULL,
+    writer_type TEXT NOT NULL,
+    event_id INTEGER NOT NULL,
+    reason TEXT NOT NULL,
+    event TEXT NOT NULL,
+    failed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS event_dead_letters_writer_idx
+    ON event_dead_letters (consumer_id, writer_type);
@@ -0,0 +1,31 @@
+-- Migration 006: NOTIFY on new events (PostgreSQL)
+--
+-- Only needed with notify.enabled. An insert into the NetBird events table
+-- sends a NOTIFY on the configured channel, and eventsproc processes the new
+-- events at once instead of at the next poll.
+--
+-- eventsproc installs this itself at startup unless notify.install_trigger is
+-- false; apply it by hand when its database user may not create triggers.
+-- Replace netbird_events in the trigger name and argument when a different
+-- notify.channel is configured.
+--
+-- Can be applied while eventsproc and NetBird are running.
+
+BEGIN;
+
+CREATE OR REPLACE FUNCTION idp.eventsproc_notify() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify(TG_ARGV[0], '');
+    RETURN NULL;
+END;
+$$;
+
+-- One notification per INSERT statement; NetBird writes events one at a time
+DROP TRIGGER IF EXISTS eventsproc_notify_netbird_events ON events;
+CREATE TRIGGER eventsproc_notify_netbird_events
+    AFTER INSERT ON events
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION idp.eventsproc_notify('netbird_events');
+
+COMMIT;
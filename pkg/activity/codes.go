@@ -306,6 +306,17 @@ func (a Activity) Message() string {
 	return "UNKNOWN_ACTIVITY"
 }
 
+// FromCode returns the activity with the given string code, e.g. for events
+// read from the NetBird API, which reports codes instead of numbers.
+func FromCode(code string) (Activity, bool) {
+	for a, c := range activityMap {
+		if c.Code == code {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
 // RegisterActivityMap adds new codes to the activity map
 func RegisterActivityMap(codes map[Activity]Code) {
 	maps.Copy(activityMap, codes)
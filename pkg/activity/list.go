@@ -0,0 +1,12 @@
+package activity
+
+import (
+	"maps"
+	"slices"
+)
+
+// Activities returns every activity in the activity map, sorted by value.
+// Schema mappings (e.g. OCSF) use it to verify they cover every code.
+func Activities() []Activity {
+	return slices.Sorted(maps.Keys(activityMap))
+}
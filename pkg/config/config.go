@@ -1,14 +1,26 @@
 package config
 
 import (
+	"context"
 	"database/sql"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/go-sql-driver/mysql" // MySQL driver and DSN parsing
+	_ "github.com/lib/pq"            // PostgreSQL driver
 	"github.com/spf13/viper"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/secrets"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
 	"gopkg.in/yaml.v3"
 	_ "modernc.org/sqlite" // SQLite driver (pure Go, no CGO)
 )
@@ -36,8 +48,12 @@ type EmailEnrichmentConfig struct {
 
 	// NetbirdConfigPath is the filesystem path to NetBird's config.yaml.
 	// Option B: eventsproc reads server.store.encryptionKey from that file
-	// automatically. Takes priority over NetbirdEncryptionKey when both are set.
+	// automatically. Tried before NetbirdEncryptionKey when both are set.
 	NetbirdConfigPath string `mapstructure:"netbird_config_path"`
+
+	// Keyring lists further keys, tried in order after the ones above, so
+	// rows encrypted before a NetBird key rotation can still be decrypted.
+	Keyring []DecryptionKeyConfig `mapstructure:"keyring"`
 }
 
 // GetSource returns the email enrichment source
@@ -63,24 +79,120 @@ func (e *EmailEnrichmentConfig) IsEnabled() bool {
 	return e.Enabled
 }
 
-// GetDecryptionKey resolves the AES-256 key used to decrypt NetBird's encrypted
-// database columns. NetbirdConfigPath (Option B) takes priority over
-// NetbirdEncryptionKey (Option A). Returns nil if neither is configured,
-// meaning no decryption will be attempted.
-func (e *EmailEnrichmentConfig) GetDecryptionKey() ([]byte, error) {
+// GetDecryptionKeys resolves the keyring used to decrypt NetBird's encrypted
+// database columns, in the order the keys are tried: NetbirdConfigPath
+// (Option B), NetbirdEncryptionKey (Option A), then the Keyring entries.
+// Keys that cannot be loaded are left out and reported in the error. Returns
+// nil if no key is configured, meaning no decryption will be attempted.
+func (e *EmailEnrichmentConfig) GetDecryptionKeys() ([]events.DecryptionKey, error) {
+	var keys []events.DecryptionKey
+	var errs []error
 	// Option B: read key from NetBird's config.yaml (single source of truth)
 	if e.NetbirdConfigPath != "" {
-		return readEncryptionKeyFromNetbirdConfig(e.NetbirdConfigPath)
+		key, err := readEncryptionKeyFromNetbirdConfig(e.NetbirdConfigPath)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			keys = append(keys, events.DecryptionKey{ID: "netbird_config_path", Key: key})
+		}
 	}
 	// Option A: key supplied directly in eventsproc config
 	if e.NetbirdEncryptionKey != "" {
 		key, err := base64.StdEncoding.DecodeString(e.NetbirdEncryptionKey)
 		if err != nil {
-			return nil, fmt.Errorf("invalid netbird_encryption_key (must be base64): %w", err)
+			errs = append(errs, fmt.Errorf("invalid netbird_encryption_key (must be base64): %w", err))
+		} else {
+			keys = append(keys, events.DecryptionKey{ID: "netbird_encryption_key", Key: key})
+		}
+	}
+	for i, k := range e.Keyring {
+		key, err := k.load()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("keyring key %s: %w", k.keyID(i), err))
+			continue
+		}
+		keys = append(keys, events.DecryptionKey{ID: k.keyID(i), Key: key})
+	}
+	return keys, errors.Join(errs...)
+}
+
+// DecryptionKeyConfig is a key of the decryption keyring, e.g. the key
+// NetBird used before a rotation. Exactly one of Key, KeyFile, KeyEnv and
+// NetbirdConfigPath is set.
+type DecryptionKeyConfig struct {
+	// ID names the key in logs and metrics (default: keyring-<position>)
+	ID string `mapstructure:"id"`
+
+	// Key is the base64-encoded key
+	Key string `mapstructure:"key"`
+
+	// KeyFile is a file holding the base64-encoded key
+	KeyFile string `mapstructure:"key_file"`
+
+	// KeyEnv is an environment variable holding the base64-encoded key
+	KeyEnv string `mapstructure:"key_env"`
+
+	// NetbirdConfigPath is a NetBird config.yaml to read
+	// server.store.encryptionKey from
+	NetbirdConfigPath string `mapstructure:"netbird_config_path"`
+}
+
+// keyID returns the ID of the key at position i of the keyring.
+func (k DecryptionKeyConfig) keyID(i int) string {
+	if k.ID != "" {
+		return k.ID
+	}
+	return fmt.Sprintf("keyring-%d", i+1)
+}
+
+// load reads and decodes the key.
+func (k DecryptionKeyConfig) load() ([]byte, error) {
+	var encoded string
+	switch {
+	case k.NetbirdConfigPath != "":
+		return readEncryptionKeyFromNetbirdConfig(k.NetbirdConfigPath)
+	case k.KeyFile != "":
+		// #nosec G304 -- Path is provided by the administrator via config, not by an external user.
+		data, err := os.ReadFile(k.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		encoded = strings.TrimSpace(string(data))
+	case k.KeyEnv != "":
+		encoded = os.Getenv(k.KeyEnv)
+		if encoded == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", k.KeyEnv)
+		}
+	default:
+		encoded = k.Key
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key (must be base64): %w", err)
+	}
+	return key, nil
+}
+
+// validateKeyring checks that every keyring entry has one key and a unique ID.
+func validateKeyring(e EmailEnrichmentConfig) error {
+	seen := map[string]bool{"netbird_config_path": true, "netbird_encryption_key": true}
+	for i, k := range e.Keyring {
+		set := 0
+		for _, v := range []string{k.Key, k.KeyFile, k.KeyEnv, k.NetbirdConfigPath} {
+			if v != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			return fmt.Errorf("email_enrichment.keyring[%d] needs exactly one of key, key_file, key_env and netbird_config_path", i)
 		}
-		return key, nil
+		id := k.keyID(i)
+		if seen[id] {
+			return fmt.Errorf("email_enrichment.keyring[%d].id %q is not unique", i, id)
+		}
+		seen[id] = true
 	}
-	return nil, nil // no decryption configured
+	return nil
 }
 
 // netbirdConfigFile is the minimal subset of NetBird's config.yaml structure
@@ -135,18 +247,700 @@ type ClusterConfig struct {
 	LockRetryInterval int `mapstructure:"lock_retry_interval"`
 }
 
+// RetryConfig wraps a writer with retries and a circuit breaker. Every
+// writer section accepts it under "retry"; zero values use the defaults.
+type RetryConfig struct {
+	// Enabled turns retries and the circuit breaker on (default: false).
+	Enabled bool `mapstructure:"enabled"`
+
+	// MaxAttempts per batch, including the first one (default: 3).
+	MaxAttempts int `mapstructure:"max_attempts"`
+
+	// InitialBackoff is the delay in seconds before the first retry, doubled
+	// on every further retry up to MaxBackoff and jittered (defaults: 1 and 30).
+	InitialBackoff int `mapstructure:"initial_backoff"`
+	MaxBackoff     int `mapstructure:"max_backoff"`
+
+	// FailureThreshold opens the circuit breaker after this many consecutive
+	// failed batches (default: 5, -1 disables the breaker).
+	FailureThreshold int `mapstructure:"failure_threshold"`
+
+	// OpenTimeout is how many seconds the breaker stays open before a trial
+	// batch is sent (default: 60).
+	OpenTimeout int `mapstructure:"open_timeout"`
+}
+
+// RouteConfig selects the events a writer receives. Every writer section
+// accepts it under "route". An event is sent when it matches any include
+// rule (or there are none) and no exclude rule.
+type RouteConfig struct {
+	Include []RouteRule `mapstructure:"include"`
+	Exclude []RouteRule `mapstructure:"exclude"`
+}
+
+// RouteRule matches events. Every field that is set must match; within a
+// field any entry may match.
+type RouteRule struct {
+	// ActivityCodes are globs on the activity code, e.g. "user.*" or
+	// "setupkey.*". '*' and '?' also match '/' (see writer.ValidateGlob).
+	ActivityCodes []string `mapstructure:"activity_codes"`
+
+	// AccountIDs are exact NetBird account IDs.
+	AccountIDs []string `mapstructure:"account_ids"`
+
+	// Meta are "key=glob" matches on top-level meta fields, e.g. "os=linux*"
+	// or "ip=10.*".
+	// All of them must match.
+	Meta []string `mapstructure:"meta"`
+}
+
+// StdoutConfig holds configuration for the stdout (journal) writer.
+type StdoutConfig struct {
+	// Format of each line: "json" (default), "kv", "cef", "leef" or "ocsf".
+	Format string `mapstructure:"format"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// LokiConfig holds configuration for the native Loki push writer.
+// Only used when "loki" is listed in Config.Writers.
+type LokiConfig struct {
+	// URL is the Loki base URL, e.g. "https://loki.example.com:3100".
+	// "/loki/api/v1/push" is appended automatically.
+	URL string `mapstructure:"url"`
+
+	// TenantID is sent as the X-Scope-OrgID header (multi-tenant Loki only).
+	TenantID string `mapstructure:"tenant_id"`
+
+	// Username and Password enable HTTP basic auth (e.g. Grafana Cloud).
+	// Use EP_LOKI_PASSWORD in production rather than storing it in the file.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+
+	// Compression: "gzip" (default, JSON body), "snappy" (protobuf body) or "none".
+	Compression string `mapstructure:"compression"`
+
+	// Labels are extra static stream labels. service_name, platform and region
+	// are always added from the top-level config.
+	Labels map[string]string `mapstructure:"labels"`
+
+	// EventLabels lists event fields promoted to stream labels
+	// (default: account_id, activity_code). Supported: account_id, activity_code, activity, source.
+	EventLabels []string `mapstructure:"event_labels"`
+
+	// Format of each log line: "json" (default), "kv", "cef", "leef" or "ocsf".
+	Format string `mapstructure:"format"`
+
+	// Timeout is the per-request timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// SplunkConfig holds configuration for the Splunk HTTP Event Collector writer.
+// Only used when "splunk" is listed in Config.Writers.
+type SplunkConfig struct {
+	// URL is the HEC base URL, e.g. "https://splunk.example.com:8088".
+	URL string `mapstructure:"url"`
+
+	// Token is the HEC token. Use EP_SPLUNK_TOKEN in production.
+	Token string `mapstructure:"token"`
+
+	// Index, Source, SourceType and Host set the HEC event metadata.
+	// Host defaults to the machine hostname; empty Index uses the token default.
+	Index      string `mapstructure:"index"`
+	Source     string `mapstructure:"source"`
+	SourceType string `mapstructure:"sourcetype"`
+	Host       string `mapstructure:"host"`
+
+	// AckEnabled waits for indexer acknowledgement before a batch counts as sent,
+	// so the checkpoint never moves past events Splunk has not committed.
+	// Requires "Enable indexer acknowledgement" on the HEC token.
+	AckEnabled bool `mapstructure:"ack_enabled"`
+
+	// Channel is the X-Splunk-Request-Channel GUID (random when empty).
+	Channel string `mapstructure:"channel"`
+
+	// AckTimeout is how long to wait for acknowledgement in seconds (default: 60).
+	AckTimeout int `mapstructure:"ack_timeout"`
+
+	// AckPollInterval is the delay between ack queries in seconds (default: 1).
+	AckPollInterval int `mapstructure:"ack_poll_interval"`
+
+	// Format of the HEC event: "json" (default), "ocsf" and "ecs" are sent as an object,
+	// "kv", "cef" and "leef" as a string.
+	Format string `mapstructure:"format"`
+
+	// Timeout is the per-request timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// SyslogConfig holds configuration for the RFC 5424 syslog writer.
+// Only used when "syslog" is listed in Config.Writers.
+type SyslogConfig struct {
+	// Network is "udp", "tcp" (default) or "tls".
+	Network string `mapstructure:"network"`
+
+	// Address is the collector "host:port", e.g. "siem.example.com:6514".
+	Address string `mapstructure:"address"`
+
+	// Framing for tcp: "octet-counting" (default) or "non-transparent".
+	// tls always uses octet counting (RFC 5425).
+	Framing string `mapstructure:"framing"`
+
+	// Facility keyword (default: "local0").
+	Facility string `mapstructure:"facility"`
+
+	// Hostname and AppName fill the RFC 5424 header.
+	// Defaults: machine hostname and "eventsproc".
+	Hostname string `mapstructure:"hostname"`
+	AppName  string `mapstructure:"app_name"`
+
+	// SDID is the STRUCTURED-DATA element ID (default: "netbird@32473").
+	SDID string `mapstructure:"sd_id"`
+
+	// MessageFormat of the MSG part: "json" (default), "kv", "cef", "leef", "ocsf" or "ecs".
+	MessageFormat string `mapstructure:"message_format"`
+
+	// SeverityOverrides maps activity codes to severities, e.g. {"dashboard.login": "notice"}.
+	SeverityOverrides map[string]string `mapstructure:"severity_overrides"`
+
+	// Timeout is the dial/write timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates (network "tls").
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// ElasticConfig holds configuration for the Elasticsearch/OpenSearch bulk writer.
+// Only used when "elasticsearch" is listed in Config.Writers.
+type ElasticConfig struct {
+	// URL is the cluster base URL, e.g. "https://es.example.com:9200".
+	URL string `mapstructure:"url"`
+
+	// Index is the target index, alias or data stream (default: "netbird-events").
+	Index string `mapstructure:"index"`
+
+	// DataStream writes with op_type "create" as data streams require.
+	DataStream bool `mapstructure:"data_stream"`
+
+	// Username and Password enable basic auth. APIKey takes precedence.
+	// Use EP_ELASTICSEARCH_PASSWORD / EP_ELASTICSEARCH_API_KEY in production.
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	APIKey   string `mapstructure:"api_key"`
+
+	// Pipeline is an optional ingest pipeline.
+	Pipeline string `mapstructure:"pipeline"`
+
+	// Format of the documents: "ecs" (default), "json" or "ocsf".
+	Format string `mapstructure:"format"`
+
+	// TemplateName installs an index template with this name on first use (empty = skip).
+	TemplateName string `mapstructure:"template_name"`
+
+	// Timeout is the per-request timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// OTLPConfig holds configuration for the OpenTelemetry OTLP logs writer.
+// Only used when "otlp" is listed in Config.Writers.
+type OTLPConfig struct {
+	// Endpoint is the collector URL, e.g. "https://otel.example.com:4318"
+	// (http/protobuf, "/v1/logs" is appended) or "http://otel.example.com:4317" (grpc).
+	Endpoint string `mapstructure:"endpoint"`
+
+	// Protocol is "http/protobuf" (default) or "grpc".
+	Protocol string `mapstructure:"protocol"`
+
+	// Headers are sent with every export request, e.g. {"authorization": "Bearer ..."}.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Compression is "gzip" (default) or "none".
+	Compression string `mapstructure:"compression"`
+
+	// ResourceAttributes are added to (or override) the default resource:
+	// service.name, service.version, platform, region, consumer_id and host.name.
+	ResourceAttributes map[string]string `mapstructure:"resource_attributes"`
+
+	// MaxRetries is how often a retryable export failure is retried (default: 3).
+	MaxRetries int `mapstructure:"max_retries"`
+
+	// RetryBackoff is the initial retry delay in seconds, doubled per attempt (default: 1).
+	RetryBackoff int `mapstructure:"retry_backoff"`
+
+	// Timeout is the per-request timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// WriterGroupConfig combines writers into one delivery unit with a shared
+// checkpoint. The batch is sent to the members concurrently and counts as
+// delivered when the policy is met.
+type WriterGroupConfig struct {
+	// Name identifies the group; its checkpoint writer_type is "group:<name>".
+	Name string `mapstructure:"name"`
+
+	// Writers are the member writer names as used for checkpoints, e.g.
+	// "loki" or "webhook:siem". A writer can belong to one group only.
+	Writers []string `mapstructure:"writers"`
+
+	// Policy: "all" (default), "any", "quorum" or "best_effort"
+	// (failures are only reported in metrics and logs).
+	Policy string `mapstructure:"policy"`
+
+	// Quorum is how many members must succeed with policy "quorum"
+	// (default: a majority).
+	Quorum int `mapstructure:"quorum"`
+
+	// Timeout bounds each member's send in seconds (default: 0 = no limit).
+	Timeout int `mapstructure:"timeout"`
+}
+
+// WebhookConfig holds configuration for one webhook destination.
+// Used when "webhook" is listed in Config.Writers; every entry in
+// Config.Webhooks becomes its own writer.
+type WebhookConfig struct {
+	// Name identifies the destination in logs and names its checkpoint
+	// "webhook:<name>" (default: the URL host). Must be unique.
+	Name string `mapstructure:"name"`
+
+	// URL is the endpoint events are sent to.
+	URL string `mapstructure:"url"`
+
+	// Method is the HTTP method (default: POST).
+	Method string `mapstructure:"method"`
+
+	// Headers are sent with every request.
+	Headers map[string]string `mapstructure:"headers"`
+
+	// Mode is "batch" (default, one request per batch) or "event" (one request per event).
+	Mode string `mapstructure:"mode"`
+
+	// Template is a Go text/template for the request body (default: event JSON).
+	Template string `mapstructure:"template"`
+
+	// Secret enables HMAC-SHA256 request signing.
+	Secret string `mapstructure:"secret"`
+
+	// SignatureHeader and TimestampHeader name the signing headers
+	// (defaults: X-Signature-256 and X-Signature-Timestamp).
+	SignatureHeader string `mapstructure:"signature_header"`
+	TimestampHeader string `mapstructure:"timestamp_header"`
+
+	// Timeout is the per-request timeout in seconds for this destination (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// FileConfig holds configuration for the rotating NDJSON file writer.
+// Only used when "file" is listed in Config.Writers.
+type FileConfig struct {
+	// Dir is where segments are written (default: /var/lib/eventsproc/events).
+	// File names are "<consumer_id>-<first event ID>-<start time>.ndjson[.gz|.zst]".
+	Dir string `mapstructure:"dir"`
+
+	// Format of each line (default: json).
+	Format string `mapstructure:"format"`
+
+	// MaxSizeMB rotates the active segment at this size in MiB (default: 100).
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+
+	// RotateIntervalHours rotates the active segment after this many hours (default: 24).
+	RotateIntervalHours int `mapstructure:"rotate_interval_hours"`
+
+	// Compression of rotated segments: "gzip" (default), "zstd" or "none".
+	Compression string `mapstructure:"compression"`
+
+	// RetentionDays deletes rotated segments older than this (default: 30, 0 = keep forever).
+	RetentionDays int `mapstructure:"retention_days"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// WALConfig configures the on-disk write-ahead log between the database
+// reader and the writers. When enabled, fetched events are appended to the
+// log first and every writer drains it from its own checkpoint.
+type WALConfig struct {
+	// Enabled turns the write-ahead log on (default: false).
+	Enabled bool `mapstructure:"enabled"`
+
+	// Dir holds the segment files (default: /var/lib/eventsproc/wal).
+	// Each consumer needs its own directory.
+	Dir string `mapstructure:"dir"`
+
+	// SegmentSizeMB starts a new segment at this size in MiB (default: 16).
+	SegmentSizeMB int `mapstructure:"segment_size_mb"`
+
+	// MaxSizeMB stops fetching new events while the log holds this many MiB,
+	// until the slowest writer catches up (default: 1024).
+	MaxSizeMB int `mapstructure:"max_size_mb"`
+}
+
+// StreamingConfig configures reading events through a single database cursor
+// per processing cycle instead of one LIMIT query per batch. Events are still
+// sent to the writers in batches of batch_size.
+//
+// On PostgreSQL the cursor lives in a read-only transaction that stays open
+// until the backlog is sent, including every SendEvents call and retry
+// backoff in between. For that long it holds a connection and a snapshot
+// that keeps VACUUM from removing dead rows, so keep retry.max_attempts and
+// retry.max_backoff low for streamed writers, or leave streaming off where
+// long transactions matter. SQLite reads fetch_size rows per
+// query and closes each before sending, so checkpoints can be saved to the
+// same file.
+type StreamingConfig struct {
+	// Enabled turns streaming on (default: false).
+	Enabled bool `mapstructure:"enabled"`
+
+	// FetchSize is the number of rows read per round trip on PostgreSQL, per
+	// query on SQLite, and per email lookup with a separate enrichment source
+	// (default: 1000).
+	FetchSize int `mapstructure:"fetch_size"`
+
+	// CheckpointEvery saves a writer's checkpoint after this many delivered
+	// events, and when the stream ends (default: 10000).
+	CheckpointEvery int `mapstructure:"checkpoint_every"`
+}
+
+// NotifyConfig configures push mode on PostgreSQL: a trigger on the events
+// table NOTIFYs a channel on every insert and eventsproc LISTENs on it, so
+// new events are processed at once instead of at the next poll. The polling
+// ticker keeps running as a safety net.
+type NotifyConfig struct {
+	// Enabled turns push mode on (default: false). Requires database_driver
+	// postgres and a polling_interval.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Channel is the NOTIFY channel name (default: netbird_events).
+	Channel string `mapstructure:"channel"`
+
+	// InstallTrigger installs or updates the trigger of migration 006 at
+	// startup (default: true). Set to false when the database user may not
+	// create triggers and the migration is applied by hand.
+	InstallTrigger bool `mapstructure:"install_trigger"`
+
+	// MinReconnectInterval and MaxReconnectInterval bound the backoff, in
+	// seconds, between attempts to reconnect a dropped listener connection
+	// (defaults: 1 and 60).
+	MinReconnectInterval int `mapstructure:"min_reconnect_interval"`
+	MaxReconnectInterval int `mapstructure:"max_reconnect_interval"`
+}
+
+// DLQConfig configures the dead-letter queue for events a writer permanently
+// rejects. When enabled, those events are stored with the writer name and the
+// reason and the rest of the batch is delivered.
+type DLQConfig struct {
+	// Enabled turns the dead-letter queue on (default: false).
+	Enabled bool `mapstructure:"enabled"`
+
+	// Type selects the store: "file" (default) or "database", which uses the
+	// event_dead_letters table next to the checkpoints.
+	Type string `mapstructure:"type"`
+
+	// Path is the NDJSON file of the "file" store (default: /var/lib/eventsproc/dlq.ndjson).
+	Path string `mapstructure:"path"`
+}
+
+// KafkaConfig holds configuration for the Kafka producer writer.
+// Only used when "kafka" is listed in Config.Writers.
+type KafkaConfig struct {
+	// Brokers are the seed brokers, e.g. ["kafka-1:9092", "kafka-2:9092"].
+	Brokers []string `mapstructure:"brokers"`
+
+	// Topic receives all events (default: netbird-events).
+	Topic string `mapstructure:"topic"`
+
+	// KeyField is the event field used as record key and therefore partition:
+	// "account_id" (default), "activity_code", "initiator_id", "target_id", "event_id" or "none".
+	KeyField string `mapstructure:"key_field"`
+
+	// Format of each record value (default: json).
+	Format string `mapstructure:"format"`
+
+	// Compression: "snappy" (default), "gzip", "lz4", "zstd" or "none".
+	Compression string `mapstructure:"compression"`
+
+	// SASLMechanism enables SASL: "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512".
+	// Use EP_KAFKA_PASSWORD in production rather than storing it in the file.
+	SASLMechanism string `mapstructure:"sasl_mechanism"`
+	Username      string `mapstructure:"username"`
+	Password      string `mapstructure:"password"`
+
+	// TLSEnabled connects over TLS; implied when any tls option is set.
+	TLSEnabled bool `mapstructure:"tls_enabled"`
+
+	// Timeout is how long a batch may take to be acknowledged, in seconds (default: 30).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// RedisStreamConfig holds configuration for the Redis Streams writer.
+// Only used when "redis_stream" is listed in Config.Writers.
+type RedisStreamConfig struct {
+	// URL is the Redis address (redis:// URL or host:port).
+	// Defaults to cluster.redis_url, so HA deployments need no extra setting.
+	URL string `mapstructure:"url"`
+
+	// Stream is the stream key (default: netbird:events).
+	Stream string `mapstructure:"stream"`
+
+	// MaxLen trims the stream to about this many entries (default: 100000, -1 = never trim).
+	MaxLen int64 `mapstructure:"max_len"`
+
+	// ExactTrim uses "MAXLEN =" instead of the cheaper approximate "MAXLEN ~".
+	ExactTrim bool `mapstructure:"exact_trim"`
+
+	// Format of the entry data field (default: json).
+	Format string `mapstructure:"format"`
+
+	// Timeout is the per-batch timeout in seconds (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// NATSConfig holds configuration for the NATS JetStream writer.
+// Only used when "nats" is listed in Config.Writers.
+type NATSConfig struct {
+	// URL is the server URL, e.g. "nats://nats.example.com:4222" (comma-separated for several).
+	URL string `mapstructure:"url"`
+
+	// Subject events are published to (default: netbird.events). A stream must capture it.
+	Subject string `mapstructure:"subject"`
+
+	// Stream, when set, rejects publishes that would land in another stream.
+	Stream string `mapstructure:"stream"`
+
+	// Format of each message body (default: json).
+	Format string `mapstructure:"format"`
+
+	// CredsFile is a NATS .creds file; alternatively Token or Username/Password.
+	// Use EP_NATS_TOKEN / EP_NATS_PASSWORD in production.
+	CredsFile string `mapstructure:"creds_file"`
+	Token     string `mapstructure:"token"`
+	Username  string `mapstructure:"username"`
+	Password  string `mapstructure:"password"`
+
+	// Timeout in seconds for connecting and for the publish acks of a batch (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures CA verification and client certificates for mTLS.
+	TLS tlsutil.Config `mapstructure:"tls"`
+
+	// Retry enables retries with backoff and a circuit breaker for this writer.
+	Retry RetryConfig `mapstructure:"retry"`
+
+	// Route selects the events this writer receives (default: all events).
+	Route RouteConfig `mapstructure:"route"`
+}
+
+// DatabaseConfig locates one database. Driver selects which of the
+// connection settings is used, as with the top-level database settings.
+type DatabaseConfig struct {
+	// Driver is "postgres", "mysql" or "sqlite", or "api" for sources.events;
+	// empty means the source is not configured and the main database is used.
+	Driver string `mapstructure:"driver"`
+
+	PostgresURL string `mapstructure:"postgres_url"`
+	MySQLDSN    string `mapstructure:"mysql_dsn"`
+	SQLitePath  string `mapstructure:"sqlite_path"`
+
+	// ReadOnly opens a SQLite file read-only, e.g. a production store mounted
+	// read-only. PostgreSQL and MySQL sources are only read, so a user with
+	// SELECT privileges is enough. Not allowed for the checkpoint store.
+	ReadOnly bool `mapstructure:"read_only"`
+}
+
+// SourcesConfig splits the databases eventsproc reads from and writes to.
+// Each unset source falls back to the main database (database_driver and its
+// connection setting).
+type SourcesConfig struct {
+	// Events holds the NetBird events table, e.g. NetBird's separate events.db
+	Events DatabaseConfig `mapstructure:"events"`
+
+	// Enrichment holds the users (and okta_users or custom) tables email
+	// enrichment looks up
+	Enrichment DatabaseConfig `mapstructure:"enrichment"`
+
+	// Checkpoint holds event_processing_checkpoint, and event_dead_letters
+	// with dlq.type "database"
+	Checkpoint DatabaseConfig `mapstructure:"checkpoint"`
+}
+
+// NetBirdAPIConfig configures reading events from the NetBird Management
+// REST API (driver "api"), for NetBird Cloud and managed instances whose
+// database cannot be reached. The API resolves the initiator and target
+// emails itself, so email enrichment is not used.
+type NetBirdAPIConfig struct {
+	// URL of the Management API, e.g. https://api.netbird.io
+	URL string `mapstructure:"url"`
+
+	// Token is a personal access token of a user allowed to read events.
+	// Use EP_NETBIRD_API_TOKEN in production.
+	Token string `mapstructure:"token"`
+
+	// Timeout in seconds for each request (default: 30).
+	Timeout int `mapstructure:"timeout"`
+
+	// AccountID is set on every event, as the API does not return it.
+	AccountID string `mapstructure:"account_id"`
+
+	// CheckpointFile keeps the checkpoints when sources.checkpoint is not
+	// configured (default: /var/lib/eventsproc/checkpoints.json).
+	CheckpointFile string `mapstructure:"checkpoint_file"`
+}
+
+// InstanceConfig is one NetBird management server in multi-source mode. Its
+// settings replace the top-level settings of the same name for this instance.
+// Writers, batching and cluster mode are shared; the write-ahead log and the
+// dead-letter queue are configured once and kept apart per instance.
+type InstanceConfig struct {
+	// Name is set as the "source" of the instance's events and labels its
+	// metrics. It is appended to consumer_id, wal.dir and the file paths of
+	// the dead-letter queue and API checkpoints.
+	Name string `mapstructure:"name"`
+
+	// DatabaseDriver, PostgresURL, MySQLDSN and SQLitePath locate the
+	// instance's database as at the top level.
+	DatabaseDriver string `mapstructure:"database_driver"`
+	PostgresURL    string `mapstructure:"postgres_url"`
+	MySQLDSN       string `mapstructure:"mysql_dsn"`
+	SQLitePath     string `mapstructure:"sqlite_path"`
+
+	// Sources moves the instance's events, enrichment or checkpoint tables
+	Sources SourcesConfig `mapstructure:"sources"`
+
+	// NetBirdAPI configures the "api" driver; timeout defaults to the
+	// top-level one.
+	NetBirdAPI NetBirdAPIConfig `mapstructure:"netbird_api"`
+
+	// EmailEnrichmentSource replaces email_enrichment.source when set, e.g.
+	// "none" for an instance without user tables to enrich from.
+	EmailEnrichmentSource string `mapstructure:"email_enrichment_source"`
+
+	// NetbirdEncryptionKey, NetbirdConfigPath and Keyring give the instance's
+	// store encryption keys, as in email_enrichment. Keys are not inherited.
+	NetbirdEncryptionKey string                `mapstructure:"netbird_encryption_key"`
+	NetbirdConfigPath    string                `mapstructure:"netbird_config_path"`
+	Keyring              []DecryptionKeyConfig `mapstructure:"keyring"`
+}
+
+// GapDetectionConfig configures how event IDs missing below a writer's
+// checkpoint are handled. Reading resumes after the highest ID delivered, so
+// an event whose ID was allocated before but committed after a higher one
+// would otherwise be skipped.
+type GapDetectionConfig struct {
+	// Window is how long, in seconds, a missing ID is re-checked before it is
+	// reported as skipped (default: 300, 0 = disabled).
+	Window int `mapstructure:"window"`
+
+	// MaxPending caps the missing IDs tracked per writer; IDs beyond it are
+	// reported as skipped at once (default: 10000).
+	MaxPending int `mapstructure:"max_pending"`
+}
+
 // Config holds configuration for the events processor
 type Config struct {
-	// DatabaseDriver selects the backend: "postgres" (default) or "sqlite"
+	// DatabaseDriver selects the backend: "postgres" (default), "mysql", "sqlite"
+	// or "api" (see NetBirdAPI)
 	DatabaseDriver string `mapstructure:"database_driver"`
 
 	// Database connection string (required when DatabaseDriver = "postgres" or unset)
 	PostgresURL string `mapstructure:"postgres_url"`
 
+	// MySQLDSN is the go-sql-driver DSN of the NetBird MySQL/MariaDB store, e.g.
+	// "netbird:secret@tcp(db:3306)/netbird" (required when DatabaseDriver = "mysql").
+	// parseTime is always enabled.
+	MySQLDSN string `mapstructure:"mysql_dsn"`
+
 	// SQLitePath is the path to the NetBird SQLite store (default: /var/lib/netbird/store.db)
 	// Only used when DatabaseDriver = "sqlite"
 	SQLitePath string `mapstructure:"sqlite_path"`
 
+	// Sources moves the events, enrichment or checkpoint tables to other databases
+	Sources SourcesConfig `mapstructure:"sources"`
+
+	// GapDetection re-checks event IDs missing below a writer's checkpoint
+	GapDetection GapDetectionConfig `mapstructure:"gap_detection"`
+
+	// NetBirdAPI configures the "api" driver
+	NetBirdAPI NetBirdAPIConfig `mapstructure:"netbird_api"`
+
+	// Instances turns on multi-source mode: the events of every listed NetBird
+	// instance are processed concurrently, each from its own database
+	Instances []InstanceConfig `mapstructure:"instances"`
+
+	// Source is the instance name of a configuration returned by ForInstance
+	Source string `mapstructure:"-"`
+
 	// Platform (sandbox, preprod, prod)
 	Platform string `mapstructure:"platform"`
 
@@ -168,6 +962,9 @@ type Config struct {
 	// Polling interval in seconds (0 = run once and exit)
 	PollingInterval int `mapstructure:"polling_interval"`
 
+	// Push mode: wake on PostgreSQL NOTIFY instead of waiting for the next poll
+	Notify NotifyConfig `mapstructure:"notify"`
+
 	// Email enrichment configuration
 	EmailEnrichment EmailEnrichmentConfig `mapstructure:"email_enrichment"`
 
@@ -176,6 +973,64 @@ type Config struct {
 
 	// Cluster configuration for HA mode
 	Cluster ClusterConfig `mapstructure:"cluster"`
+
+	// Secrets configures the providers of secret references (see SecretSettings)
+	Secrets secrets.Config `mapstructure:"secrets"`
+
+	// Write-ahead log between the database reader and the writers
+	WAL WALConfig `mapstructure:"wal"`
+
+	// Streaming reads events through a database cursor
+	Streaming StreamingConfig `mapstructure:"streaming"`
+
+	// Dead-letter queue for permanently rejected events
+	DLQ DLQConfig `mapstructure:"dlq"`
+
+	// Writers lists the output sinks events are sent to (default: ["stdout"]).
+	// Supported: "stdout", "loki", "splunk", "syslog", "elasticsearch", "otlp", "webhook", "file", "kafka",
+	// "redis_stream", "nats".
+	Writers []string `mapstructure:"writers"`
+
+	// WriterGroups deliver to several writers under one checkpoint with a
+	// delivery policy; writers not in a group keep their own checkpoint.
+	WriterGroups []WriterGroupConfig `mapstructure:"writer_groups"`
+
+	// Stdout writer configuration
+	Stdout StdoutConfig `mapstructure:"stdout"`
+
+	// Loki configuration (only used when "loki" is listed in Writers)
+	Loki LokiConfig `mapstructure:"loki"`
+
+	// Splunk HEC configuration (only used when "splunk" is listed in Writers)
+	Splunk SplunkConfig `mapstructure:"splunk"`
+
+	// Syslog configuration (only used when "syslog" is listed in Writers)
+	Syslog SyslogConfig `mapstructure:"syslog"`
+
+	// Elasticsearch configuration (only used when "elasticsearch" is listed in Writers)
+	Elasticsearch ElasticConfig `mapstructure:"elasticsearch"`
+
+	// OTLP logs exporter configuration (only used when "otlp" is listed in Writers)
+	OTLP OTLPConfig `mapstructure:"otlp"`
+
+	// Webhook destinations (only used when "webhook" is listed in Writers)
+	Webhooks []WebhookConfig `mapstructure:"webhooks"`
+
+	// Local file writer configuration (only used when "file" is listed in Writers)
+	File FileConfig `mapstructure:"file"`
+
+	// Kafka producer configuration (only used when "kafka" is listed in Writers)
+	Kafka KafkaConfig `mapstructure:"kafka"`
+
+	// Redis Streams configuration (only used when "redis_stream" is listed in Writers)
+	RedisStream RedisStreamConfig `mapstructure:"redis_stream"`
+
+	// NATS JetStream configuration (only used when "nats" is listed in Writers)
+	NATS NATSConfig `mapstructure:"nats"`
+
+	// secretRefs maps the settings read from secret references to the
+	// references, for RefreshSecrets
+	secretRefs map[string]string
 }
 
 // LoadConfig loads configuration from file and environment variables
@@ -206,6 +1061,84 @@ func LoadConfig(configFile string) (*Config, error) {
 	v.SetDefault("cluster.lock_ttl", 15)
 	v.SetDefault("cluster.lock_retry_interval", 5)
 
+	// Secret provider defaults
+	v.SetDefault("secrets.refresh_interval", 0)
+	v.SetDefault("secrets.vault.timeout", 10)
+
+	// Write-ahead log defaults
+	v.SetDefault("wal.enabled", false)
+	v.SetDefault("wal.dir", "/var/lib/eventsproc/wal")
+	v.SetDefault("wal.segment_size_mb", 16)
+	v.SetDefault("wal.max_size_mb", 1024)
+	v.SetDefault("streaming.enabled", false)
+	v.SetDefault("streaming.fetch_size", 1000)
+	v.SetDefault("streaming.checkpoint_every", 10000)
+
+	// Gap detection defaults
+	v.SetDefault("gap_detection.window", 300)
+	v.SetDefault("gap_detection.max_pending", 10000)
+
+	// NetBird API defaults
+	v.SetDefault("netbird_api.timeout", 30)
+	v.SetDefault("netbird_api.checkpoint_file", "/var/lib/eventsproc/checkpoints.json")
+
+	// Push mode defaults
+	v.SetDefault("notify.enabled", false)
+	v.SetDefault("notify.channel", "netbird_events")
+	v.SetDefault("notify.install_trigger", true)
+	v.SetDefault("notify.min_reconnect_interval", 1)
+	v.SetDefault("notify.max_reconnect_interval", 60)
+
+	// Dead-letter queue defaults
+	v.SetDefault("dlq.enabled", false)
+	v.SetDefault("dlq.type", "file")
+	v.SetDefault("dlq.path", "/var/lib/eventsproc/dlq.ndjson")
+
+	// Writer defaults
+	v.SetDefault("writers", []string{"stdout"})
+	v.SetDefault("stdout.format", "json")
+	v.SetDefault("loki.compression", "gzip")
+	v.SetDefault("loki.event_labels", []string{"account_id", "activity_code"})
+	v.SetDefault("loki.timeout", 10)
+	v.SetDefault("splunk.source", "eventsproc")
+	v.SetDefault("splunk.sourcetype", "netbird:event")
+	v.SetDefault("splunk.ack_timeout", 60)
+	v.SetDefault("splunk.ack_poll_interval", 1)
+	v.SetDefault("splunk.timeout", 10)
+	v.SetDefault("syslog.network", "tcp")
+	v.SetDefault("syslog.framing", "octet-counting")
+	v.SetDefault("syslog.facility", "local0")
+	v.SetDefault("syslog.app_name", "eventsproc")
+	v.SetDefault("syslog.sd_id", "netbird@32473")
+	v.SetDefault("syslog.message_format", "json")
+	v.SetDefault("syslog.timeout", 10)
+	v.SetDefault("elasticsearch.index", "netbird-events")
+	v.SetDefault("elasticsearch.format", "ecs")
+	v.SetDefault("elasticsearch.timeout", 10)
+	v.SetDefault("otlp.protocol", "http/protobuf")
+	v.SetDefault("otlp.compression", "gzip")
+	v.SetDefault("otlp.max_retries", 3)
+	v.SetDefault("otlp.retry_backoff", 1)
+	v.SetDefault("otlp.timeout", 10)
+	v.SetDefault("file.dir", "/var/lib/eventsproc/events")
+	v.SetDefault("file.format", "json")
+	v.SetDefault("file.max_size_mb", 100)
+	v.SetDefault("file.rotate_interval_hours", 24)
+	v.SetDefault("file.compression", "gzip")
+	v.SetDefault("file.retention_days", 30)
+	v.SetDefault("kafka.topic", "netbird-events")
+	v.SetDefault("kafka.key_field", "account_id")
+	v.SetDefault("kafka.format", "json")
+	v.SetDefault("kafka.compression", "snappy")
+	v.SetDefault("kafka.timeout", 30)
+	v.SetDefault("redis_stream.stream", "netbird:events")
+	v.SetDefault("redis_stream.max_len", 100000)
+	v.SetDefault("redis_stream.format", "json")
+	v.SetDefault("redis_stream.timeout", 10)
+	v.SetDefault("nats.subject", "netbird.events")
+	v.SetDefault("nats.format", "json")
+	v.SetDefault("nats.timeout", 10)
+
 	// Load from config file if it exists
 	if configFile != "" {
 		v.SetConfigFile(configFile)
@@ -218,12 +1151,15 @@ func LoadConfig(configFile string) (*Config, error) {
 
 	// Environment variables override config file
 	v.SetEnvPrefix("EP") // Events Processor
+	// Nested keys map to underscores: loki.tls.ca_file -> EP_LOKI_TLS_CA_FILE
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
 	// Bind specific environment variables (errors are intentionally ignored as BindEnv only fails for empty keys)
 	_ = v.BindEnv("database_driver")
 	_ = v.BindEnv("sqlite_path")
 	_ = v.BindEnv("postgres_url")
+	_ = v.BindEnv("mysql_dsn")
 	_ = v.BindEnv("platform")
 	_ = v.BindEnv("region")
 	_ = v.BindEnv("consumer_id")
@@ -247,14 +1183,183 @@ func LoadConfig(configFile string) (*Config, error) {
 	_ = v.BindEnv("cluster.lock_ttl")
 	_ = v.BindEnv("cluster.lock_retry_interval")
 
+	// Secret provider environment variables
+	_ = v.BindEnv("secrets.refresh_interval")
+	_ = v.BindEnv("secrets.vault.address")
+	_ = v.BindEnv("secrets.vault.token")
+	_ = v.BindEnv("secrets.vault.namespace")
+	_ = v.BindEnv("secrets.vault.timeout")
+	_ = v.BindEnv("secrets.vault.tls.ca_file")
+
+	// Write-ahead log environment variables
+	_ = v.BindEnv("wal.enabled")
+	_ = v.BindEnv("wal.dir")
+	_ = v.BindEnv("wal.max_size_mb")
+	_ = v.BindEnv("streaming.enabled")
+	_ = v.BindEnv("streaming.fetch_size")
+	_ = v.BindEnv("streaming.checkpoint_every")
+
+	// Dead-letter queue environment variables
+	_ = v.BindEnv("sources.events.driver")
+	_ = v.BindEnv("sources.events.postgres_url")
+	_ = v.BindEnv("sources.events.mysql_dsn")
+	_ = v.BindEnv("sources.events.sqlite_path")
+	_ = v.BindEnv("sources.events.read_only")
+	_ = v.BindEnv("sources.enrichment.driver")
+	_ = v.BindEnv("sources.enrichment.postgres_url")
+	_ = v.BindEnv("sources.enrichment.mysql_dsn")
+	_ = v.BindEnv("sources.enrichment.sqlite_path")
+	_ = v.BindEnv("sources.enrichment.read_only")
+	_ = v.BindEnv("sources.checkpoint.driver")
+	_ = v.BindEnv("sources.checkpoint.postgres_url")
+	_ = v.BindEnv("sources.checkpoint.mysql_dsn")
+	_ = v.BindEnv("sources.checkpoint.sqlite_path")
+	_ = v.BindEnv("sources.checkpoint.read_only")
+	_ = v.BindEnv("gap_detection.window")
+	_ = v.BindEnv("gap_detection.max_pending")
+	_ = v.BindEnv("netbird_api.url")
+	_ = v.BindEnv("netbird_api.token")
+	_ = v.BindEnv("netbird_api.timeout")
+	_ = v.BindEnv("netbird_api.account_id")
+	_ = v.BindEnv("netbird_api.checkpoint_file")
+	_ = v.BindEnv("notify.enabled")
+	_ = v.BindEnv("notify.channel")
+	_ = v.BindEnv("notify.install_trigger")
+	_ = v.BindEnv("notify.min_reconnect_interval")
+	_ = v.BindEnv("notify.max_reconnect_interval")
+	_ = v.BindEnv("dlq.enabled")
+	_ = v.BindEnv("dlq.type")
+	_ = v.BindEnv("dlq.path")
+
+	// Writer environment variables
+	_ = v.BindEnv("writers")
+	_ = v.BindEnv("stdout.format")
+	_ = v.BindEnv("loki.url")
+	_ = v.BindEnv("loki.tenant_id")
+	_ = v.BindEnv("loki.username")
+	_ = v.BindEnv("loki.password")
+	_ = v.BindEnv("loki.compression")
+	_ = v.BindEnv("loki.format")
+	_ = v.BindEnv("loki.timeout")
+	_ = v.BindEnv("loki.tls.ca_file")
+	_ = v.BindEnv("loki.tls.cert_file")
+	_ = v.BindEnv("loki.tls.key_file")
+	_ = v.BindEnv("splunk.url")
+	_ = v.BindEnv("splunk.token")
+	_ = v.BindEnv("splunk.index")
+	_ = v.BindEnv("splunk.source")
+	_ = v.BindEnv("splunk.sourcetype")
+	_ = v.BindEnv("splunk.host")
+	_ = v.BindEnv("splunk.ack_enabled")
+	_ = v.BindEnv("splunk.format")
+	_ = v.BindEnv("splunk.tls.ca_file")
+	_ = v.BindEnv("splunk.tls.cert_file")
+	_ = v.BindEnv("splunk.tls.key_file")
+	_ = v.BindEnv("syslog.network")
+	_ = v.BindEnv("syslog.address")
+	_ = v.BindEnv("syslog.framing")
+	_ = v.BindEnv("syslog.facility")
+	_ = v.BindEnv("syslog.hostname")
+	_ = v.BindEnv("syslog.message_format")
+	_ = v.BindEnv("syslog.tls.ca_file")
+	_ = v.BindEnv("syslog.tls.cert_file")
+	_ = v.BindEnv("syslog.tls.key_file")
+	_ = v.BindEnv("elasticsearch.url")
+	_ = v.BindEnv("elasticsearch.index")
+	_ = v.BindEnv("elasticsearch.data_stream")
+	_ = v.BindEnv("elasticsearch.username")
+	_ = v.BindEnv("elasticsearch.password")
+	_ = v.BindEnv("elasticsearch.api_key")
+	_ = v.BindEnv("elasticsearch.pipeline")
+	_ = v.BindEnv("elasticsearch.format")
+	_ = v.BindEnv("elasticsearch.tls.ca_file")
+	_ = v.BindEnv("elasticsearch.tls.cert_file")
+	_ = v.BindEnv("elasticsearch.tls.key_file")
+	_ = v.BindEnv("otlp.endpoint")
+	_ = v.BindEnv("otlp.protocol")
+	_ = v.BindEnv("otlp.compression")
+	_ = v.BindEnv("otlp.tls.ca_file")
+	_ = v.BindEnv("otlp.tls.cert_file")
+	_ = v.BindEnv("otlp.tls.key_file")
+	_ = v.BindEnv("file.dir")
+	_ = v.BindEnv("file.compression")
+	_ = v.BindEnv("file.retention_days")
+	_ = v.BindEnv("kafka.brokers")
+	_ = v.BindEnv("kafka.topic")
+	_ = v.BindEnv("kafka.key_field")
+	_ = v.BindEnv("kafka.sasl_mechanism")
+	_ = v.BindEnv("kafka.username")
+	_ = v.BindEnv("kafka.password")
+	_ = v.BindEnv("kafka.tls_enabled")
+	_ = v.BindEnv("kafka.tls.ca_file")
+	_ = v.BindEnv("kafka.tls.cert_file")
+	_ = v.BindEnv("kafka.tls.key_file")
+	_ = v.BindEnv("redis_stream.url")
+	_ = v.BindEnv("redis_stream.stream")
+	_ = v.BindEnv("redis_stream.max_len")
+	_ = v.BindEnv("nats.url")
+	_ = v.BindEnv("nats.subject")
+	_ = v.BindEnv("nats.stream")
+	_ = v.BindEnv("nats.creds_file")
+	_ = v.BindEnv("nats.token")
+	_ = v.BindEnv("nats.username")
+	_ = v.BindEnv("nats.password")
+	_ = v.BindEnv("nats.tls.ca_file")
+	_ = v.BindEnv("nats.tls.cert_file")
+	_ = v.BindEnv("nats.tls.key_file")
+	_ = v.BindEnv("stdout.retry.enabled")
+	_ = v.BindEnv("loki.retry.enabled")
+	_ = v.BindEnv("splunk.retry.enabled")
+	_ = v.BindEnv("syslog.retry.enabled")
+	_ = v.BindEnv("elasticsearch.retry.enabled")
+	_ = v.BindEnv("otlp.retry.enabled")
+	_ = v.BindEnv("file.retry.enabled")
+	_ = v.BindEnv("kafka.retry.enabled")
+	_ = v.BindEnv("redis_stream.retry.enabled")
+	_ = v.BindEnv("nats.retry.enabled")
+
 	var config Config
 	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Read the secrets that settings refer to
+	if err := config.resolveSecrets(context.Background()); err != nil {
+		return nil, err
+	}
+	if config.Secrets.RefreshInterval < 0 {
+		return nil, fmt.Errorf("secrets.refresh_interval must not be negative")
+	}
+
 	// Validate required fields
-	if config.DatabaseDriver != "sqlite" && config.PostgresURL == "" {
-		return nil, fmt.Errorf("postgres_url is required when database_driver is not sqlite")
+	if err := validateInstances(&config); err != nil {
+		return nil, err
+	}
+	if config.GapDetection.Window < 0 || config.GapDetection.MaxPending < 0 {
+		return nil, fmt.Errorf("gap_detection.window and gap_detection.max_pending must not be negative")
+	}
+	if err := validateWriters(&config); err != nil {
+		return nil, err
+	}
+	if config.WAL.Enabled && config.WAL.Dir == "" {
+		return nil, fmt.Errorf("wal.dir is required when wal.enabled is true")
+	}
+	if config.Streaming.Enabled && (config.Streaming.FetchSize <= 0 || config.Streaming.CheckpointEvery <= 0) {
+		return nil, fmt.Errorf("streaming.fetch_size and streaming.checkpoint_every must be positive")
+	}
+	if err := validateNotify(&config); err != nil {
+		return nil, err
+	}
+	if config.DLQ.Enabled {
+		switch config.DLQ.Type {
+		case "file":
+			if config.DLQ.Path == "" {
+				return nil, fmt.Errorf("dlq.path is required when dlq.type is file")
+			}
+		case "database":
+		default:
+			return nil, fmt.Errorf("unsupported dlq.type %q (supported: file, database)", config.DLQ.Type)
+		}
 	}
 
 	// Auto-generate consumer_id if not provided
@@ -265,6 +1370,537 @@ func LoadConfig(configFile string) (*Config, error) {
 	return &config, nil
 }
 
+// instanceNamePattern matches instance names that are safe in file names and
+// consumer IDs.
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// validateInstances checks the databases of every instance in multi-source
+// mode, or the top-level ones otherwise.
+func validateInstances(config *Config) error {
+	if len(config.Instances) == 0 {
+		return validateDatabases(config)
+	}
+	seen := make(map[string]bool)
+	for i, inst := range config.Instances {
+		if !instanceNamePattern.MatchString(inst.Name) {
+			return fmt.Errorf("instances[%d].name %q is invalid: use lowercase letters, digits, '-' and '_'", i, inst.Name)
+		}
+		if seen[inst.Name] {
+			return fmt.Errorf("instances[%d].name %q is not unique", i, inst.Name)
+		}
+		seen[inst.Name] = true
+		if err := validateDatabases(config.ForInstance(inst)); err != nil {
+			return fmt.Errorf("instance %s: %w", inst.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateDatabases checks the database settings of config.
+func validateDatabases(config *Config) error {
+	if err := validateSources(config); err != nil {
+		return err
+	}
+	switch config.DatabaseDriver {
+	case "sqlite", "api":
+	case "mysql":
+		if !config.usesMainDatabase() {
+			break
+		}
+		if config.MySQLDSN == "" {
+			return fmt.Errorf("mysql_dsn is required when database_driver is mysql")
+		}
+		if _, err := mysql.ParseDSN(config.MySQLDSN); err != nil {
+			return fmt.Errorf("invalid mysql_dsn: %w", err)
+		}
+	default:
+		if config.PostgresURL == "" && config.usesMainDatabase() {
+			return fmt.Errorf("postgres_url is required when database_driver is not sqlite")
+		}
+	}
+	if err := validateNetBirdAPI(config); err != nil {
+		return err
+	}
+	if err := validateKeyring(config.EmailEnrichment); err != nil {
+		return err
+	}
+	if config.Notify.Enabled && config.EventsDatabase().Driver != "postgres" {
+		return fmt.Errorf("notify.enabled is only supported when the events database is postgres")
+	}
+	if config.DLQ.Enabled && config.DLQ.Type == "database" && config.CheckpointDatabase().Driver == "api" {
+		return fmt.Errorf("dlq.type database requires sources.checkpoint with the api driver")
+	}
+	return nil
+}
+
+// SecretSettings returns the settings that may be secret references
+// (file://, env://, credential:// or vault://), by name.
+func (c *Config) SecretSettings() map[string]*string {
+	settings := map[string]*string{
+		"postgres_url":      &c.PostgresURL,
+		"mysql_dsn":         &c.MySQLDSN,
+		"netbird_api.token": &c.NetBirdAPI.Token,
+		"cluster.redis_url": &c.Cluster.RedisURL,
+		"email_enrichment.netbird_encryption_key": &c.EmailEnrichment.NetbirdEncryptionKey,
+	}
+	addSourceSecrets(settings, "sources.", &c.Sources)
+	addKeyringSecrets(settings, "email_enrichment.keyring", c.EmailEnrichment.Keyring)
+	for i := range c.Instances {
+		inst := &c.Instances[i]
+		prefix := fmt.Sprintf("instances[%d].", i)
+		settings[prefix+"postgres_url"] = &inst.PostgresURL
+		settings[prefix+"mysql_dsn"] = &inst.MySQLDSN
+		settings[prefix+"netbird_api.token"] = &inst.NetBirdAPI.Token
+		settings[prefix+"netbird_encryption_key"] = &inst.NetbirdEncryptionKey
+		addSourceSecrets(settings, prefix+"sources.", &inst.Sources)
+		addKeyringSecrets(settings, prefix+"keyring", inst.Keyring)
+	}
+	return settings
+}
+
+// addSourceSecrets adds the connection settings of sources.
+func addSourceSecrets(settings map[string]*string, prefix string, sources *SourcesConfig) {
+	for name, d := range map[string]*DatabaseConfig{
+		"events":     &sources.Events,
+		"enrichment": &sources.Enrichment,
+		"checkpoint": &sources.Checkpoint,
+	} {
+		settings[prefix+name+".postgres_url"] = &d.PostgresURL
+		settings[prefix+name+".mysql_dsn"] = &d.MySQLDSN
+	}
+}
+
+// addKeyringSecrets adds the keys of a decryption keyring.
+func addKeyringSecrets(settings map[string]*string, prefix string, keyring []DecryptionKeyConfig) {
+	for i := range keyring {
+		settings[fmt.Sprintf("%s[%d].key", prefix, i)] = &keyring[i].Key
+	}
+}
+
+// resolveSecrets replaces the secret references among the SecretSettings
+// with the secrets and remembers them for RefreshSecrets.
+func (c *Config) resolveSecrets(ctx context.Context) error {
+	resolver, err := secrets.NewResolver(c.Secrets)
+	if err != nil {
+		return err
+	}
+	c.secretRefs = make(map[string]string)
+	for name, setting := range c.SecretSettings() {
+		if !secrets.IsReference(*setting) {
+			continue
+		}
+		ref := *setting
+		if *setting, err = resolver.Resolve(ctx, ref); err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		c.secretRefs[name] = ref
+	}
+	return nil
+}
+
+// RefreshSecrets resolves the secret references of c again. If a secret
+// changed, it returns a validated copy of c with the new secrets and the
+// names of the changed settings; otherwise c and no names.
+func (c *Config) RefreshSecrets(ctx context.Context) (*Config, []string, error) {
+	if len(c.secretRefs) == 0 {
+		return c, nil, nil
+	}
+	resolver, err := secrets.NewResolver(c.Secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	next := c.clone()
+	settings := next.SecretSettings()
+	var changed []string
+	for name, ref := range c.secretRefs {
+		value, err := resolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve %s: %w", name, err)
+		}
+		if *settings[name] != value {
+			*settings[name] = value
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return c, nil, nil
+	}
+	if err := validateInstances(next); err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(changed)
+	return next, changed, nil
+}
+
+// WatchSecrets calls RefreshSecrets every secrets.refresh_interval seconds
+// until ctx is cancelled and sends each configuration whose secrets changed.
+// Refresh errors are logged and the current secrets kept.
+func (c *Config) WatchSecrets(ctx context.Context, logger *slog.Logger) <-chan *Config {
+	changes := make(chan *Config)
+	if c.Secrets.RefreshInterval <= 0 || len(c.secretRefs) == 0 {
+		return changes
+	}
+	go func() {
+		ticker := time.NewTicker(time.Duration(c.Secrets.RefreshInterval) * time.Second)
+		defer ticker.Stop()
+		current := c
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			next, changed, err := current.RefreshSecrets(ctx)
+			if err != nil {
+				logger.Warn("Failed to refresh secrets, keeping the current ones", "error", err)
+				continue
+			}
+			if len(changed) == 0 {
+				continue
+			}
+			logger.Info("Secrets changed", "settings", changed)
+			select {
+			case changes <- next:
+				current = next
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return changes
+}
+
+// clone returns a copy of c that shares no slice a secret setting is in.
+func (c *Config) clone() *Config {
+	cfg := *c
+	cfg.EmailEnrichment.Keyring = slices.Clone(c.EmailEnrichment.Keyring)
+	cfg.Instances = slices.Clone(c.Instances)
+	for i := range cfg.Instances {
+		cfg.Instances[i].Keyring = slices.Clone(cfg.Instances[i].Keyring)
+	}
+	return &cfg
+}
+
+// ForInstance returns the configuration of one instance in multi-source mode:
+// a copy of c with the instance's database, API and enrichment settings, and
+// its own consumer ID, write-ahead log directory and file paths.
+func (c *Config) ForInstance(inst InstanceConfig) *Config {
+	cfg := *c
+	cfg.Instances = nil
+	cfg.Source = inst.Name
+	cfg.ConsumerID = c.ConsumerID + "-" + inst.Name
+
+	cfg.DatabaseDriver = inst.DatabaseDriver
+	cfg.PostgresURL = inst.PostgresURL
+	cfg.MySQLDSN = inst.MySQLDSN
+	cfg.SQLitePath = inst.SQLitePath
+	cfg.Sources = inst.Sources
+
+	cfg.NetBirdAPI = inst.NetBirdAPI
+	if cfg.NetBirdAPI.Timeout == 0 {
+		cfg.NetBirdAPI.Timeout = c.NetBirdAPI.Timeout
+	}
+	if cfg.NetBirdAPI.CheckpointFile == "" {
+		cfg.NetBirdAPI.CheckpointFile = instancePath(c.NetBirdAPI.CheckpointFile, inst.Name)
+	}
+
+	if inst.EmailEnrichmentSource != "" {
+		cfg.EmailEnrichment.Source = inst.EmailEnrichmentSource
+	}
+	cfg.EmailEnrichment.NetbirdEncryptionKey = inst.NetbirdEncryptionKey
+	cfg.EmailEnrichment.NetbirdConfigPath = inst.NetbirdConfigPath
+	cfg.EmailEnrichment.Keyring = inst.Keyring
+
+	cfg.WAL.Dir = filepath.Join(c.WAL.Dir, inst.Name)
+	cfg.DLQ.Path = instancePath(c.DLQ.Path, inst.Name)
+	return &cfg
+}
+
+// Instance returns the instance called name.
+func (c *Config) Instance(name string) (InstanceConfig, bool) {
+	for _, inst := range c.Instances {
+		if inst.Name == name {
+			return inst, true
+		}
+	}
+	return InstanceConfig{}, false
+}
+
+// instancePath inserts the instance name before the extension of path, e.g.
+// dlq.ndjson becomes dlq-emea.ndjson.
+func instancePath(file, name string) string {
+	if file == "" {
+		return ""
+	}
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + "-" + name + ext
+}
+
+// validateSources checks the databases configured under sources.
+func validateSources(config *Config) error {
+	for _, source := range []struct {
+		name string
+		db   DatabaseConfig
+	}{
+		{"sources.events", config.Sources.Events},
+		{"sources.enrichment", config.Sources.Enrichment},
+		{"sources.checkpoint", config.Sources.Checkpoint},
+	} {
+		if source.db.Driver == "" {
+			continue
+		}
+		if source.db.Driver == "api" && source.name != "sources.events" {
+			return fmt.Errorf("%s.driver api is not supported: only events are read from the NetBird API", source.name)
+		}
+		if err := validateDatabase(source.name, source.db); err != nil {
+			return err
+		}
+	}
+	if config.Sources.Checkpoint.ReadOnly {
+		return fmt.Errorf("sources.checkpoint.read_only is not allowed: checkpoints are written")
+	}
+	return nil
+}
+
+// validateDatabase checks that d has the connection setting of its driver.
+func validateDatabase(name string, d DatabaseConfig) error {
+	switch d.Driver {
+	case "postgres":
+		if d.PostgresURL == "" {
+			return fmt.Errorf("%s.postgres_url is required when %s.driver is postgres", name, name)
+		}
+	case "mysql":
+		if d.MySQLDSN == "" {
+			return fmt.Errorf("%s.mysql_dsn is required when %s.driver is mysql", name, name)
+		}
+		if _, err := mysql.ParseDSN(d.MySQLDSN); err != nil {
+			return fmt.Errorf("invalid %s.mysql_dsn: %w", name, err)
+		}
+	case "sqlite":
+		if d.SQLitePath == "" {
+			return fmt.Errorf("%s.sqlite_path is required when %s.driver is sqlite", name, name)
+		}
+	case "api":
+	default:
+		return fmt.Errorf("unsupported %s.driver %q (supported: postgres, mysql, sqlite, api)", name, d.Driver)
+	}
+	return nil
+}
+
+// validateNetBirdAPI checks the netbird_api settings when events are read
+// from the API.
+func validateNetBirdAPI(config *Config) error {
+	if config.DatabaseDriver == "api" && config.Sources.Events.Driver != "" && config.Sources.Events.Driver != "api" {
+		return fmt.Errorf("database_driver api cannot be combined with sources.events.driver %s", config.Sources.Events.Driver)
+	}
+	if config.EventsDatabase().Driver != "api" {
+		return nil
+	}
+	if config.NetBirdAPI.URL == "" || config.NetBirdAPI.Token == "" {
+		return fmt.Errorf("netbird_api.url and netbird_api.token are required with the api driver")
+	}
+	if config.NetBirdAPI.Timeout <= 0 {
+		return fmt.Errorf("netbird_api.timeout must be positive")
+	}
+	if config.CheckpointDatabase().Driver == "api" && config.NetBirdAPI.CheckpointFile == "" {
+		return fmt.Errorf("netbird_api.checkpoint_file is required when sources.checkpoint is not configured")
+	}
+	return nil
+}
+
+// MainDatabase returns the database of database_driver and its connection setting.
+func (c *Config) MainDatabase() DatabaseConfig {
+	driver := c.DatabaseDriver
+	if driver == "" {
+		driver = "postgres"
+	}
+	return DatabaseConfig{Driver: driver, PostgresURL: c.PostgresURL, MySQLDSN: c.MySQLDSN, SQLitePath: c.SQLitePath}
+}
+
+// EventsDatabase returns the database holding the events table.
+func (c *Config) EventsDatabase() DatabaseConfig {
+	return c.sourceOrMain(c.Sources.Events)
+}
+
+// EnrichmentDatabase returns the database holding the user tables.
+func (c *Config) EnrichmentDatabase() DatabaseConfig {
+	return c.sourceOrMain(c.Sources.Enrichment)
+}
+
+// CheckpointDatabase returns the database holding the checkpoint table.
+func (c *Config) CheckpointDatabase() DatabaseConfig {
+	return c.sourceOrMain(c.Sources.Checkpoint)
+}
+
+// SplitSources reports whether any source is configured under sources.
+func (c *Config) SplitSources() bool {
+	return c.Sources.Events.Driver != "" || c.Sources.Enrichment.Driver != "" || c.Sources.Checkpoint.Driver != ""
+}
+
+func (c *Config) sourceOrMain(d DatabaseConfig) DatabaseConfig {
+	if d.Driver != "" {
+		return d
+	}
+	return c.MainDatabase()
+}
+
+// usesMainDatabase reports whether any source falls back to the main database.
+func (c *Config) usesMainDatabase() bool {
+	return c.Sources.Events.Driver == "" || c.Sources.Checkpoint.Driver == "" ||
+		(c.Sources.Enrichment.Driver == "" && c.EmailEnrichment.GetSource() != "none")
+}
+
+// notifyChannelPattern matches channel names that need no quoting in LISTEN
+// and leave room for the "eventsproc_notify_" trigger name prefix.
+var notifyChannelPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,44}$`)
+
+// validateNotify checks the push mode settings.
+func validateNotify(config *Config) error {
+	if !config.Notify.Enabled {
+		return nil
+	}
+	if config.PollingInterval <= 0 {
+		return fmt.Errorf("notify.enabled requires a polling_interval; the poll remains the safety net")
+	}
+	if !notifyChannelPattern.MatchString(config.Notify.Channel) {
+		return fmt.Errorf("invalid notify.channel %q: use lowercase letters, digits and underscores, up to 45 characters", config.Notify.Channel)
+	}
+	if config.Notify.MinReconnectInterval <= 0 || config.Notify.MaxReconnectInterval < config.Notify.MinReconnectInterval {
+		return fmt.Errorf("notify reconnect intervals must be positive with min_reconnect_interval <= max_reconnect_interval")
+	}
+	return nil
+}
+
+// validateWriters checks that every configured writer is known and has the
+// settings it needs to start.
+func validateWriters(config *Config) error {
+	if len(config.Writers) == 0 {
+		return fmt.Errorf("at least one writer is required")
+	}
+	for _, name := range config.Writers {
+		switch name {
+		case "stdout", "file":
+		case "loki":
+			if config.Loki.URL == "" {
+				return fmt.Errorf("loki.url is required when the loki writer is enabled")
+			}
+		case "splunk":
+			if config.Splunk.URL == "" || config.Splunk.Token == "" {
+				return fmt.Errorf("splunk.url and splunk.token are required when the splunk writer is enabled")
+			}
+		case "syslog":
+			if config.Syslog.Address == "" {
+				return fmt.Errorf("syslog.address is required when the syslog writer is enabled")
+			}
+		case "elasticsearch":
+			if config.Elasticsearch.URL == "" {
+				return fmt.Errorf("elasticsearch.url is required when the elasticsearch writer is enabled")
+			}
+		case "otlp":
+			if config.OTLP.Endpoint == "" {
+				return fmt.Errorf("otlp.endpoint is required when the otlp writer is enabled")
+			}
+		case "webhook":
+			if len(config.Webhooks) == 0 {
+				return fmt.Errorf("at least one entry in webhooks is required when the webhook writer is enabled")
+			}
+			for i, wh := range config.Webhooks {
+				if wh.URL == "" {
+					return fmt.Errorf("webhooks[%d].url is required", i)
+				}
+			}
+		case "kafka":
+			if len(config.Kafka.Brokers) == 0 {
+				return fmt.Errorf("kafka.brokers is required when the kafka writer is enabled")
+			}
+		case "redis_stream":
+			if config.RedisStream.URL == "" && config.Cluster.RedisURL == "" {
+				return fmt.Errorf("redis_stream.url (or cluster.redis_url) is required when the redis_stream writer is enabled")
+			}
+		case "nats":
+			if config.NATS.URL == "" {
+				return fmt.Errorf("nats.url is required when the nats writer is enabled")
+			}
+		default:
+			return fmt.Errorf("unknown writer %q", name)
+		}
+	}
+
+	routes := map[string]RouteConfig{
+		"stdout":        config.Stdout.Route,
+		"loki":          config.Loki.Route,
+		"splunk":        config.Splunk.Route,
+		"syslog":        config.Syslog.Route,
+		"elasticsearch": config.Elasticsearch.Route,
+		"otlp":          config.OTLP.Route,
+		"file":          config.File.Route,
+		"kafka":         config.Kafka.Route,
+		"redis_stream":  config.RedisStream.Route,
+		"nats":          config.NATS.Route,
+	}
+	for _, wh := range config.Webhooks {
+		routes["webhooks."+wh.Name] = wh.Route
+	}
+	for name, rc := range routes {
+		if err := validateRoute(rc); err != nil {
+			return fmt.Errorf("%s.route: %w", name, err)
+		}
+	}
+
+	grouped := make(map[string]string)
+	groupNames := make(map[string]bool)
+	for i, g := range config.WriterGroups {
+		if g.Name == "" {
+			return fmt.Errorf("writer_groups[%d].name is required", i)
+		}
+		if groupNames[g.Name] {
+			return fmt.Errorf("writer group %q is configured more than once", g.Name)
+		}
+		groupNames[g.Name] = true
+		if len(g.Writers) == 0 {
+			return fmt.Errorf("writer group %q needs at least one writer", g.Name)
+		}
+		switch g.Policy {
+		case "", "all", "any", "best_effort":
+		case "quorum":
+			if g.Quorum > len(g.Writers) {
+				return fmt.Errorf("writer group %q: quorum %d exceeds its %d writers", g.Name, g.Quorum, len(g.Writers))
+			}
+		default:
+			return fmt.Errorf("writer group %q: unknown policy %q (supported: all, any, quorum, best_effort)", g.Name, g.Policy)
+		}
+		for _, member := range g.Writers {
+			if other, ok := grouped[member]; ok {
+				return fmt.Errorf("writer %q is in writer groups %q and %q; a writer can belong to one group only", member, other, g.Name)
+			}
+			grouped[member] = g.Name
+		}
+	}
+	return nil
+}
+
+// validateRoute checks the globs and meta matches of a route.
+func validateRoute(rc RouteConfig) error {
+	for _, rule := range append(slices.Clone(rc.Include), rc.Exclude...) {
+		for _, pattern := range rule.ActivityCodes {
+			if err := writer.ValidateGlob(pattern); err != nil {
+				return fmt.Errorf("invalid activity code pattern: %w", err)
+			}
+		}
+		for _, m := range rule.Meta {
+			key, pattern, ok := strings.Cut(m, "=")
+			if !ok || key == "" {
+				return fmt.Errorf("invalid meta match %q, expected key=value", m)
+			}
+			if err := writer.ValidateGlob(pattern); err != nil {
+				return fmt.Errorf("invalid meta pattern for %q: %w", key, err)
+			}
+		}
+	}
+	return nil
+}
+
 // LogFactory creates typed loggers from a shared handler.
 // Using an interface allows mocking in tests and decouples log creation from
 // consumption: any component calls factory.New("security"), factory.New("audit"),
@@ -320,6 +1956,50 @@ func GetDB(postgresURL string, logger *slog.Logger) (*sql.DB, error) {
 	return db, nil
 }
 
+// OpenDatabase opens d with the Get*DB function of its driver. A read-only
+// SQLite source is opened with mode=ro.
+func OpenDatabase(d DatabaseConfig, logger *slog.Logger) (*sql.DB, error) {
+	switch d.Driver {
+	case "api":
+		return nil, fmt.Errorf("the api driver has no database")
+	case "sqlite":
+		path := d.SQLitePath
+		if d.ReadOnly {
+			path = "file:" + path + "?mode=ro"
+		}
+		return GetSQLiteDB(path, logger)
+	case "mysql":
+		return GetMySQLDB(d.MySQLDSN, logger)
+	default:
+		return GetDB(d.PostgresURL, logger)
+	}
+}
+
+// GetMySQLDB opens a MySQL or MariaDB database from a go-sql-driver DSN.
+// parseTime is forced on so DATETIME columns scan into time.Time.
+func GetMySQLDB(dsn string, logger *slog.Logger) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mysql_dsn: %w", err)
+	}
+	cfg.ParseTime = true
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		logger.Error("Error opening MySQL database", "error", err)
+		return nil, err
+	}
+
+	if err = db.Ping(); err != nil {
+		logger.Error("Error pinging MySQL database", "addr", cfg.Addr, "database", cfg.DBName, "error", err)
+		_ = db.Close()
+		return nil, err
+	}
+
+	logger.Debug("Connected to MySQL database", "addr", cfg.Addr, "database", cfg.DBName)
+	return db, nil
+}
+
 // GetSQLiteDB opens a SQLite database at the given path
 func GetSQLiteDB(path string, logger *slog.Logger) (*sql.DB, error) {
 	db, err := sql.Open("sqlite", path)
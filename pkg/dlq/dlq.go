@@ -0,0 +1,77 @@
+// Package dlq stores dead letters: events a writer permanently rejected,
+// together with the writer name and the reason. Dead-lettering lets the rest
+// of the batch through, so one malformed event no longer blocks a sink
+// forever; operators list, inspect and re-drive the entries once the cause
+// is fixed.
+package dlq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// Supported values for the store type.
+const (
+	TypeFile     = "file"     // NDJSON file on local disk (default)
+	TypeDatabase = "database" // event_dead_letters table next to the checkpoints
+)
+
+// ErrNotFound is returned by Store.Get for an unknown entry ID.
+var ErrNotFound = errors.New("dead letter not found")
+
+// Entry is one dead-lettered event.
+type Entry struct {
+	// ID identifies the entry in the store; assigned by Store.Add.
+	ID int64 `json:"id"`
+
+	// ConsumerID and Writer name the consumer and the checkpoint writer_type
+	// (e.g. "loki" or "webhook:siem") that rejected the event.
+	ConsumerID string `json:"consumer_id"`
+	Writer     string `json:"writer"`
+
+	// Reason is the error the writer returned for the event.
+	Reason string `json:"reason"`
+
+	// FailedAt is when the event was dead-lettered.
+	FailedAt time.Time `json:"failed_at"`
+
+	// Event is the rejected event as read from the database.
+	Event events.Event `json:"event"`
+}
+
+// ListOptions filters Store.List. Empty fields match everything.
+type ListOptions struct {
+	ConsumerID string
+	Writer     string
+
+	// Limit caps the number of entries returned; 0 means no limit.
+	Limit int
+}
+
+// Store persists dead letters. Entries are returned in ID order, oldest first.
+type Store interface {
+	// Add stores the entries and assigns their IDs.
+	Add(ctx context.Context, entries []Entry) error
+
+	// List returns the entries matching opts.
+	List(ctx context.Context, opts ListOptions) ([]Entry, error)
+
+	// Get returns one entry, or ErrNotFound.
+	Get(ctx context.Context, id int64) (*Entry, error)
+
+	// Delete removes entries, e.g. after a successful re-drive. Unknown IDs
+	// are ignored.
+	Delete(ctx context.Context, ids []int64) error
+
+	// Close releases the store.
+	Close() error
+}
+
+// matches reports whether e passes the filters of opts.
+func (opts ListOptions) matches(e *Entry) bool {
+	return (opts.ConsumerID == "" || e.ConsumerID == opts.ConsumerID) &&
+		(opts.Writer == "" || e.Writer == opts.Writer)
+}
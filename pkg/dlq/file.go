@@ -0,0 +1,217 @@
+package dlq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+)
+
+// FileStore keeps dead letters in an NDJSON file, one entry per line. Every
+// operation holds an exclusive flock on "<path>.lock", so the running service
+// and the dlq CLI can use the same file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore creates a file store. The directory is created if missing;
+// the file itself is created by the first Add.
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("dlq path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create dlq dir: %w", err)
+	}
+	return &FileStore{path: path}, nil
+}
+
+// Add appends the entries, numbering them after the highest existing ID.
+func (s *FileStore) Add(_ context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	existing, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	var lastID int64
+	if n := len(existing); n > 0 {
+		lastID = existing[n-1].ID
+	}
+
+	var buf []byte
+	for i := range entries {
+		lastID++
+		entries[i].ID = lastID
+		line, err := json.Marshal(&entries[i])
+		if err != nil {
+			return fmt.Errorf("failed to encode dead letter for event %d: %w", entries[i].Event.ID, err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open dlq file: %w", err)
+	}
+	if _, err := f.Write(buf); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write dlq file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync dlq file: %w", err)
+	}
+	return f.Close()
+}
+
+// List returns the matching entries.
+func (s *FileStore) List(_ context.Context, opts ListOptions) ([]Entry, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	var result []Entry
+	for i := range all {
+		if !opts.matches(&all[i]) {
+			continue
+		}
+		result = append(result, all[i])
+		if opts.Limit > 0 && len(result) >= opts.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Get returns one entry.
+func (s *FileStore) Get(_ context.Context, id int64) (*Entry, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	for i := range all {
+		if all[i].ID == id {
+			return &all[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Delete rewrites the file without the given entries.
+func (s *FileStore) Delete(_ context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	kept := slices.DeleteFunc(all, func(e Entry) bool { return slices.Contains(ids, e.ID) })
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create dlq file: %w", err)
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for i := range kept {
+		if err := enc.Encode(&kept[i]); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to encode dead letter %d: %w", kept[i].ID, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write dlq file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync dlq file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write dlq file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace dlq file: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; the file is only open during an operation.
+func (s *FileStore) Close() error {
+	return nil
+}
+
+// readAll reads every entry. Callers hold the lock.
+func (s *FileStore) readAll() ([]Entry, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dlq file: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("invalid dlq entry at %s:%d: %w", s.path, line, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dlq file: %w", err)
+	}
+	return entries, nil
+}
+
+// lock takes the exclusive file lock and returns its release function.
+func (s *FileStore) lock() (func(), error) {
+	f, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dlq lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("failed to lock dlq file: %w", err)
+	}
+	return func() { _ = f.Close() }, nil
+}
@@ -0,0 +1,146 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func testEntries(ids ...int64) []Entry {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var entries []Entry
+	for _, id := range ids {
+		entries = append(entries, Entry{
+			ConsumerID: "eventsproc-prod-emea",
+			Writer:     "elasticsearch",
+			Reason:     "400 mapper_parsing_exception",
+			FailedAt:   ts,
+			Event:      events.Event{ID: id, Timestamp: ts, ActivityCode: "user.join", AccountID: "acc1", Meta: `{"ip":"10.0.0.1"}`},
+		})
+	}
+	return entries
+}
+
+func newTestFileStore(t *testing.T) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "dlq", "dlq.ndjson"))
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return s
+}
+
+func TestFileStore_AddListGetDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFileStore(t)
+
+	entries := testEntries(10, 11)
+	entries[1].Writer = "webhook:siem"
+	if err := s.Add(ctx, entries); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if entries[0].ID != 1 || entries[1].ID != 2 {
+		t.Errorf("Expected IDs 1 and 2, got %d and %d", entries[0].ID, entries[1].ID)
+	}
+
+	all, err := s.List(ctx, ListOptions{})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(all) != 2 || all[0].Event.ID != 10 || all[0].Event.Meta != `{"ip":"10.0.0.1"}` || !all[0].FailedAt.Equal(entries[0].FailedAt) {
+		t.Errorf("Expected both entries back unchanged, got %+v", all)
+	}
+
+	siem, err := s.List(ctx, ListOptions{Writer: "webhook:siem"})
+	if err != nil || len(siem) != 1 || siem[0].Event.ID != 11 {
+		t.Errorf("Expected the webhook entry only, got %+v (%v)", siem, err)
+	}
+
+	got, err := s.Get(ctx, 2)
+	if err != nil || got.Writer != "webhook:siem" {
+		t.Errorf("Expected entry 2, got %+v (%v)", got, err)
+	}
+	if _, err := s.Get(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := s.Delete(ctx, []int64{1}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	all, _ = s.List(ctx, ListOptions{})
+	if len(all) != 1 || all[0].ID != 2 {
+		t.Errorf("Expected only entry 2 left, got %+v", all)
+	}
+
+	// IDs keep increasing after a delete
+	more := testEntries(12)
+	if err := s.Add(ctx, more); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if more[0].ID != 3 {
+		t.Errorf("Expected ID 3, got %d", more[0].ID)
+	}
+}
+
+func TestFileStore_ListLimit(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFileStore(t)
+	if err := s.Add(ctx, testEntries(1, 2, 3)); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	got, err := s.List(ctx, ListOptions{Limit: 2})
+	if err != nil || len(got) != 2 || got[1].Event.ID != 2 {
+		t.Errorf("Expected the two oldest entries, got %+v (%v)", got, err)
+	}
+}
+
+func TestFileStore_EmptyStore(t *testing.T) {
+	s := newTestFileStore(t)
+
+	got, err := s.List(context.Background(), ListOptions{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("Expected no entries before the first Add, got %+v (%v)", got, err)
+	}
+	if _, err := NewFileStore(""); err == nil {
+		t.Error("Expected error for an empty path")
+	}
+}
+
+func TestFileStore_ConcurrentAdds(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFileStore(t)
+	// A second handle on the same file, as used by the dlq CLI
+	other := &FileStore{path: s.path}
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			store := s
+			if i%2 == 1 {
+				store = other
+			}
+			if err := store.Add(ctx, testEntries(int64(i))); err != nil {
+				t.Errorf("Add failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	all, err := s.List(ctx, ListOptions{})
+	if err != nil || len(all) != 10 {
+		t.Fatalf("Expected 10 entries, got %d (%v)", len(all), err)
+	}
+	for i, e := range all {
+		if e.ID != int64(i+1) {
+			t.Errorf("Expected unique sequential IDs, got %d at %d", e.ID, i)
+		}
+	}
+}
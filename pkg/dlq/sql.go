@@ -0,0 +1,179 @@
+package dlq
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SQLStore keeps dead letters in the event_dead_letters table (see
+// migrations/005_dead_letters.sql). Like the checkpoint table it lives in the
+// idp schema on PostgreSQL and in the NetBird store on SQLite and MySQL.
+type SQLStore struct {
+	db     *sql.DB
+	table  string
+	driver string
+}
+
+// NewSQLStore creates a store on db. driver is the configured database_driver.
+// The database is not contacted until the first operation.
+func NewSQLStore(db *sql.DB, driver string) *SQLStore {
+	switch driver {
+	case "sqlite", "mysql":
+		return &SQLStore{db: db, table: "event_dead_letters", driver: driver}
+	}
+	return &SQLStore{db: db, table: "idp.event_dead_letters", driver: "postgres"}
+}
+
+// Add inserts the entries in one transaction.
+func (s *SQLStore) Add(ctx context.Context, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin dlq transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `INSERT INTO ` + s.table + `
+		(consumer_id, writer_type, event_id, reason, event, failed_at)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	if s.driver != "mysql" {
+		query = s.rebind(query + `
+		RETURNING id`)
+	}
+	for i := range entries {
+		e := &entries[i]
+		payload, err := json.Marshal(e.Event)
+		if err != nil {
+			return fmt.Errorf("failed to encode dead letter for event %d: %w", e.Event.ID, err)
+		}
+		args := []any{e.ConsumerID, e.Writer, e.Event.ID, e.Reason, string(payload), e.FailedAt}
+		if s.driver == "mysql" {
+			// MySQL has no RETURNING
+			var res sql.Result
+			if res, err = tx.ExecContext(ctx, query, args...); err == nil {
+				e.ID, err = res.LastInsertId()
+			}
+		} else {
+			err = tx.QueryRowContext(ctx, query, args...).Scan(&e.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to insert dead letter for event %d: %w", e.Event.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dead letters: %w", err)
+	}
+	return nil
+}
+
+// List returns the matching entries.
+func (s *SQLStore) List(ctx context.Context, opts ListOptions) ([]Entry, error) {
+	query := `SELECT id, consumer_id, writer_type, reason, event, failed_at FROM ` + s.table
+	var where []string
+	var args []any
+	if opts.ConsumerID != "" {
+		where = append(where, "consumer_id = ?")
+		args = append(args, opts.ConsumerID)
+	}
+	if opts.Writer != "" {
+		where = append(where, "writer_type = ?")
+		args = append(args, opts.Writer)
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id"
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead letters: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var result []Entry
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	return result, nil
+}
+
+// Get returns one entry.
+func (s *SQLStore) Get(ctx context.Context, id int64) (*Entry, error) {
+	row := s.db.QueryRowContext(ctx, s.rebind(
+		`SELECT id, consumer_id, writer_type, reason, event, failed_at FROM `+s.table+` WHERE id = ?`), id)
+	e, err := scanEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return e, err
+}
+
+// Delete removes the given entries.
+func (s *SQLStore) Delete(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	if _, err := s.db.ExecContext(ctx, s.rebind(`DELETE FROM `+s.table+` WHERE id IN (`+placeholders+`)`), args...); err != nil {
+		return fmt.Errorf("failed to delete dead letters: %w", err)
+	}
+	return nil
+}
+
+// Close is a no-op; the database handle belongs to the caller.
+func (s *SQLStore) Close() error {
+	return nil
+}
+
+// rebind turns ? placeholders into $1, $2, ... for PostgreSQL.
+func (s *SQLStore) rebind(query string) string {
+	if s.driver != "postgres" {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// scanEntry scans one row of id, consumer_id, writer_type, reason, event, failed_at.
+func scanEntry(row interface{ Scan(...any) error }) (*Entry, error) {
+	var e Entry
+	var payload string
+	if err := row.Scan(&e.ID, &e.ConsumerID, &e.Writer, &e.Reason, &payload, &e.FailedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan dead letter: %w", err)
+	}
+	if err := json.Unmarshal([]byte(payload), &e.Event); err != nil {
+		return nil, fmt.Errorf("invalid event in dead letter %d: %w", e.ID, err)
+	}
+	return &e, nil
+}
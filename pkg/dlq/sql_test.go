@@ -0,0 +1,119 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var entryCols = []string{"id", "consumer_id", "writer_type", "reason", "event", "failed_at"}
+
+func TestSQLStore_Add(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	s := NewSQLStore(db, "postgres")
+
+	entries := testEntries(10, 11)
+	mock.ExpectBegin()
+	for i, e := range entries {
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO idp.event_dead_letters")).
+			WithArgs(e.ConsumerID, e.Writer, e.Event.ID, e.Reason, sqlmock.AnyArg(), e.FailedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(100 + i)))
+	}
+	mock.ExpectCommit()
+
+	if err := s.Add(context.Background(), entries); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if entries[0].ID != 100 || entries[1].ID != 101 {
+		t.Errorf("Expected IDs from the database, got %d and %d", entries[0].ID, entries[1].ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_AddMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	s := NewSQLStore(db, "mysql")
+
+	entries := testEntries(10)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO event_dead_letters")+`.*VALUES \(\?, \?, \?, \?, \?, \?\)$`).
+		WithArgs(entries[0].ConsumerID, entries[0].Writer, entries[0].Event.ID, entries[0].Reason, sqlmock.AnyArg(), entries[0].FailedAt).
+		WillReturnResult(sqlmock.NewResult(55, 1))
+	mock.ExpectCommit()
+
+	if err := s.Add(context.Background(), entries); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if entries[0].ID != 55 {
+		t.Errorf("Expected the last insert ID, got %d", entries[0].ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	s := NewSQLStore(db, "postgres")
+
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta("FROM idp.event_dead_letters WHERE writer_type = $1 ORDER BY id LIMIT $2")).
+		WithArgs("loki", 5).
+		WillReturnRows(sqlmock.NewRows(entryCols).
+			AddRow(int64(7), "eventsproc-prod-emea", "loki", "400 Bad Request", `{"id":42,"activity_code":"user.join"}`, ts))
+
+	got, err := s.List(context.Background(), ListOptions{Writer: "loki", Limit: 5})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != 7 || got[0].Event.ID != 42 || got[0].Event.ActivityCode != "user.join" || !got[0].FailedAt.Equal(ts) {
+		t.Errorf("Unexpected entries: %+v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSQLStore_GetAndDeleteSQLite(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	s := NewSQLStore(db, "sqlite")
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM event_dead_letters WHERE id = ?")).
+		WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows(entryCols))
+	if _, err := s.Get(context.Background(), 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM event_dead_letters WHERE id IN (?, ?)")).
+		WithArgs(int64(1), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	if err := s.Delete(context.Background(), []int64{1, 2}); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,376 @@
+package elastic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// maxReportedFailures caps how many rejected items are quoted in an error.
+const maxReportedFailures = 5
+
+// Config holds configuration for the Elasticsearch/OpenSearch bulk writer.
+type Config struct {
+	// URL is the cluster base URL, e.g. "https://es.example.com:9200".
+	URL string
+
+	// Index is the target index, alias or data stream name.
+	// Default: "netbird-events".
+	Index string
+
+	// DataStream writes with op_type "create", as data streams require.
+	// Re-sent events are then rejected with 409 by Elasticsearch, which the
+	// writer treats as already indexed.
+	DataStream bool
+
+	// Username and Password enable HTTP basic auth.
+	Username string
+	Password string
+
+	// APIKey is sent as "Authorization: ApiKey <key>" (takes precedence over basic auth).
+	APIKey string
+
+	// Pipeline is an optional ingest pipeline applied to every document.
+	Pipeline string
+
+	// Format selects the document schema: "ecs" (default), "json" or "ocsf".
+	Format string
+
+	// TemplateName, when set, installs a composable index template with that
+	// name before the first bulk request. It matches "<Index>*" and declares a
+	// data stream when DataStream is true.
+	TemplateName string
+
+	// Timeout bounds each HTTP request. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+}
+
+// Writer indexes events through the _bulk API. Each event's ID is used as the
+// document _id, so re-sending a batch after a partial failure never creates
+// duplicates.
+type Writer struct {
+	client       *http.Client
+	baseURL      string
+	index        string
+	dataStream   bool
+	username     string
+	password     string
+	apiKey       string
+	pipeline     string
+	templateName string
+	formatter    format.Formatter
+	logger       *slog.Logger
+
+	templateMu        sync.Mutex
+	templateInstalled bool
+}
+
+// NewWriter creates a bulk writer. It does not contact the cluster; the index
+// template (if any) is installed on the first SendEvents call.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("elasticsearch url is required")
+	}
+
+	formatName := cfg.Format
+	if formatName == "" {
+		formatName = format.NameECS
+	}
+	formatter, err := format.New(formatName)
+	if err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch format: %w", err)
+	}
+	if !format.IsJSON(formatter) {
+		return nil, fmt.Errorf("elasticsearch format must produce JSON documents (ecs, json or ocsf), got %q", formatName)
+	}
+
+	index := cfg.Index
+	if index == "" {
+		index = "netbird-events"
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch tls config: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	return &Writer{
+		client:       &http.Client{Timeout: timeout, Transport: transport},
+		baseURL:      strings.TrimRight(cfg.URL, "/"),
+		index:        index,
+		dataStream:   cfg.DataStream,
+		username:     cfg.Username,
+		password:     cfg.Password,
+		apiKey:       cfg.APIKey,
+		pipeline:     cfg.Pipeline,
+		templateName: cfg.TemplateName,
+		formatter:    formatter,
+		logger:       logger,
+	}, nil
+}
+
+// bulkAction is the action line preceding each document.
+type bulkAction struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// bulkResponse is the part of the _bulk response the writer inspects.
+type bulkResponse struct {
+	Errors bool                       `json:"errors"`
+	Items  []map[string]bulkItemState `json:"items"`
+}
+
+type bulkItemState struct {
+	ID     string `json:"_id"`
+	Status int    `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// SendEvents indexes a batch with a single _bulk request, each event's DedupID
+// being its document _id. It only succeeds when every document was indexed
+// (or, for data streams, already existed); otherwise the error names the
+// rejected event IDs. When every failure is
+// permanent (a 4xx such as a mapping error) or an event could not be
+// formatted, the error is a writer.RejectedError, so only those events are
+// dead-lettered; any other failure retries the whole batch.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	if err := w.ensureTemplate(ctx); err != nil {
+		return err
+	}
+
+	opType := "index"
+	if w.dataStream {
+		opType = "create"
+	}
+
+	var body bytes.Buffer
+	var sent []events.Event
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		doc, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		action, err := json.Marshal(map[string]bulkAction{
+			opType: {Index: w.index, ID: evt.DedupID()},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		body.Write(action)
+		body.WriteByte('\n')
+		body.WriteString(doc)
+		body.WriteByte('\n')
+		sent = append(sent, evt)
+	}
+	if len(sent) == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	endpoint := w.baseURL + "/_bulk"
+	if w.pipeline != "" {
+		endpoint += "?pipeline=" + url.QueryEscape(w.pipeline)
+	}
+	data, err := w.do(ctx, http.MethodPost, endpoint, "application/x-ndjson", body.Bytes())
+	if err != nil {
+		return fmt.Errorf("elasticsearch bulk request failed: %w", err)
+	}
+
+	var resp bulkResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("invalid elasticsearch bulk response: %w", err)
+	}
+	if len(resp.Items) != len(sent) {
+		return fmt.Errorf("elasticsearch bulk response has %d items, expected %d", len(resp.Items), len(sent))
+	}
+	if err := checkItems(resp.Items, sent); err != nil {
+		var rejectedErr *writer.RejectedError
+		if errors.As(err, &rejectedErr) {
+			rejectedErr.Rejected = append(rejected, rejectedErr.Rejected...)
+		}
+		return err
+	}
+
+	w.logger.Debug("Indexed events in Elasticsearch", "count", len(sent), "index", w.index)
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// checkItems returns an error describing every rejected bulk item; items[i]
+// is the result for sent[i]. A 409 on a create means the document already
+// exists, i.e. an earlier attempt succeeded, so it counts as indexed. When
+// every failure is permanent the error is a writer.RejectedError.
+func checkItems(items []map[string]bulkItemState, sent []events.Event) error {
+	var failures []string
+	var rejected []writer.Rejection
+	failed := 0
+	for i, item := range items {
+		for op, state := range item {
+			if state.Status >= 200 && state.Status < 300 {
+				continue
+			}
+			if op == "create" && state.Status == http.StatusConflict {
+				continue
+			}
+			failed++
+			reason := http.StatusText(state.Status)
+			if state.Error != nil {
+				reason = state.Error.Type + ": " + state.Error.Reason
+			}
+			if len(failures) < maxReportedFailures {
+				failures = append(failures, fmt.Sprintf("_id %s (%d %s)", state.ID, state.Status, reason))
+			}
+			if writer.IsPermanentStatus(state.Status) {
+				rejected = append(rejected, writer.Rejection{Event: sent[i], Reason: fmt.Sprintf("%d %s", state.Status, reason)})
+			}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("elasticsearch rejected %d of %d documents: %s", failed, len(items), strings.Join(failures, "; "))
+	if len(rejected) == failed {
+		return writer.Reject(err, rejected...)
+	}
+	return err
+}
+
+// SendEvent sends a single event to Elasticsearch
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close releases idle HTTP connections. Nothing is buffered, so there is nothing to flush.
+func (w *Writer) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// ensureTemplate installs the index template once. Failures are returned so
+// the batch is retried, and installation is attempted again next time.
+func (w *Writer) ensureTemplate(ctx context.Context) error {
+	if w.templateName == "" {
+		return nil
+	}
+	w.templateMu.Lock()
+	defer w.templateMu.Unlock()
+	if w.templateInstalled {
+		return nil
+	}
+
+	payload, err := json.Marshal(w.indexTemplate())
+	if err != nil {
+		return fmt.Errorf("failed to encode index template: %w", err)
+	}
+	if _, err := w.do(ctx, http.MethodPut, w.baseURL+"/_index_template/"+url.PathEscape(w.templateName), "application/json", payload); err != nil {
+		return fmt.Errorf("failed to install elasticsearch index template %s: %w", w.templateName, err)
+	}
+
+	w.logger.Info("Installed Elasticsearch index template", "template", w.templateName, "index_pattern", w.index+"*")
+	w.templateInstalled = true
+	return nil
+}
+
+// indexTemplate returns a composable index template for the target index.
+// Identifiers are mapped as keywords so they can be aggregated on.
+func (w *Writer) indexTemplate() map[string]any {
+	keyword := map[string]any{"type": "keyword"}
+	template := map[string]any{
+		"index_patterns": []string{w.index + "*"},
+		"priority":       200,
+		"template": map[string]any{
+			"mappings": map[string]any{
+				"dynamic": true,
+				"properties": map[string]any{
+					"@timestamp": map[string]any{"type": "date"},
+					"message":    map[string]any{"type": "match_only_text"},
+					"event": map[string]any{"properties": map[string]any{
+						"id": keyword, "action": keyword, "code": keyword, "category": keyword,
+						"type": keyword, "outcome": keyword, "dataset": keyword,
+					}},
+					"user": map[string]any{"properties": map[string]any{
+						"id": keyword, "email": keyword,
+						"target": map[string]any{"properties": map[string]any{"id": keyword, "email": keyword}},
+					}},
+					"organization": map[string]any{"properties": map[string]any{"id": keyword}},
+				},
+			},
+		},
+		"_meta": map[string]any{"managed_by": "eventsproc"},
+	}
+	if w.dataStream {
+		template["data_stream"] = map[string]any{}
+	}
+	return template
+}
+
+// do sends a request and returns the body of a 2xx response.
+func (w *Writer) do(ctx context.Context, method, endpoint, contentType string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "eventsproc")
+	switch {
+	case w.apiKey != "":
+		req.Header.Set("Authorization", "ApiKey "+w.apiKey)
+	case w.username != "":
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32*1024*1024))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elasticsearch response: %w", err)
+	}
+	if resp.StatusCode/100 != 2 {
+		msg := string(data)
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(msg))
+		if writer.IsPermanentStatus(resp.StatusCode) {
+			return nil, writer.Permanent(err)
+		}
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,309 @@
+package elastic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	return []events.Event{
+		{ID: 1, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorID: "user1"},
+		{ID: 2, Timestamp: ts.Add(time.Second), Activity: 2, ActivityCode: "user.join", AccountID: "acc1", InitiatorID: "user2"},
+	}
+}
+
+// fakeES is a minimal Elasticsearch stand-in recording bulk requests.
+type fakeES struct {
+	mu        sync.Mutex
+	auth      []string
+	paths     []string
+	actions   []map[string]map[string]string
+	docs      []map[string]any
+	templates map[string]map[string]any
+
+	// itemStatus returns the status for the n-th item (default 201)
+	itemStatus func(n int) (int, string)
+	status     int
+}
+
+func (f *fakeES) handler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	f.paths = append(f.paths, r.URL.RequestURI())
+
+	if strings.HasPrefix(r.URL.Path, "/_index_template/") {
+		var tmpl map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&tmpl)
+		if f.templates == nil {
+			f.templates = map[string]map[string]any{}
+		}
+		f.templates[strings.TrimPrefix(r.URL.Path, "/_index_template/")] = tmpl
+		_, _ = io.WriteString(w, `{"acknowledged":true}`)
+		return
+	}
+	if f.status != 0 {
+		w.WriteHeader(f.status)
+		_, _ = io.WriteString(w, `{"error":"boom"}`)
+		return
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	var items []map[string]any
+	for n := 0; scanner.Scan(); n++ {
+		var action map[string]map[string]string
+		_ = json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		var doc map[string]any
+		_ = json.Unmarshal(scanner.Bytes(), &doc)
+		f.actions = append(f.actions, action)
+		f.docs = append(f.docs, doc)
+
+		for op, meta := range action {
+			status, errType := http.StatusCreated, ""
+			if f.itemStatus != nil {
+				status, errType = f.itemStatus(len(f.docs) - 1)
+			}
+			state := map[string]any{"_id": meta["_id"], "status": status}
+			if errType != "" {
+				state["error"] = map[string]string{"type": errType, "reason": "rejected by test"}
+			}
+			items = append(items, map[string]any{op: state})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"errors": false, "items": items})
+}
+
+func newFakeES(t *testing.T, f *fakeES) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(f.handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing url")
+	}
+	if _, err := NewWriter(&Config{URL: "http://es:9200", Format: "cef"}, testLogger()); err == nil {
+		t.Error("Expected error for non-JSON format")
+	}
+}
+
+func TestSendEvents_IndexWithEventIDs(t *testing.T) {
+	es := &fakeES{}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Index: "netbird-audit", APIKey: "secret", Pipeline: "geoip"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if es.paths[0] != "/_bulk?pipeline=geoip" {
+		t.Errorf("Unexpected bulk path %s", es.paths[0])
+	}
+	if es.auth[0] != "ApiKey secret" {
+		t.Errorf("Expected ApiKey auth, got %q", es.auth[0])
+	}
+	if len(es.actions) != 2 {
+		t.Fatalf("Expected 2 bulk items, got %d", len(es.actions))
+	}
+	action, ok := es.actions[1]["index"]
+	if !ok || action["_id"] != "2" || action["_index"] != "netbird-audit" {
+		t.Errorf("Expected index action with event ID as _id, got %v", es.actions[1])
+	}
+	// ECS is the default document format
+	if es.docs[0]["@timestamp"] != "2026-01-28T10:30:00.000Z" {
+		t.Errorf("Expected ECS document, got %v", es.docs[0])
+	}
+}
+
+func TestSendEvents_DataStreamCreateConflictIsIndexed(t *testing.T) {
+	es := &fakeES{itemStatus: func(n int) (int, string) {
+		if n == 0 {
+			return http.StatusConflict, "version_conflict_engine_exception"
+		}
+		return http.StatusCreated, ""
+	}}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Index: "logs-netbird.audit-default", DataStream: true}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Expected a 409 on create to count as indexed, got %v", err)
+	}
+	if _, ok := es.actions[0]["create"]; !ok {
+		t.Errorf("Expected create op for data streams, got %v", es.actions[0])
+	}
+}
+
+func TestSendEvents_ItemErrorFailsBatch(t *testing.T) {
+	es := &fakeES{itemStatus: func(n int) (int, string) {
+		if n == 1 {
+			return http.StatusBadRequest, "mapper_parsing_exception"
+		}
+		return http.StatusCreated, ""
+	}}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error when an item is rejected")
+	}
+	if !strings.Contains(err.Error(), "1 of 2") || !strings.Contains(err.Error(), "_id 2") ||
+		!strings.Contains(err.Error(), "mapper_parsing_exception") {
+		t.Errorf("Expected error naming the rejected item, got %v", err)
+	}
+	var rejected *writer.RejectedError
+	if !errors.As(err, &rejected) || len(rejected.Rejected) != 1 || rejected.Rejected[0].Event.ID != 2 {
+		t.Errorf("Expected only event 2 to be rejected, got %v", err)
+	}
+}
+
+func TestSendEvents_IDsIncludeSource(t *testing.T) {
+	es := &fakeES{}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL, DataStream: true}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	// Two instances in multi-source mode with the same event ID
+	list := testEvents()[:1]
+	list = append(list, list[0])
+	list[0].Source, list[1].Source = "emea", "apac"
+	if err := w.SendEvents(context.Background(), list); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if len(es.actions) != 2 || es.actions[0]["create"]["_id"] != "emea:1" || es.actions[1]["create"]["_id"] != "apac:1" {
+		t.Errorf("Expected _id prefixed with the source, got %v", es.actions)
+	}
+}
+
+func TestSendEvents_TransientItemErrorIsNotARejection(t *testing.T) {
+	es := &fakeES{itemStatus: func(n int) (int, string) {
+		if n == 0 {
+			return http.StatusBadRequest, "mapper_parsing_exception"
+		}
+		return http.StatusTooManyRequests, "es_rejected_execution_exception"
+	}}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil || writer.IsPermanent(err) {
+		t.Errorf("Expected a retryable error while an item was throttled, got %v", err)
+	}
+}
+
+func TestSendEvents_IndexConflictIsAnError(t *testing.T) {
+	es := &fakeES{itemStatus: func(int) (int, string) { return http.StatusConflict, "version_conflict_engine_exception" }}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected 409 on an index op to fail")
+	}
+}
+
+func TestSendEvents_HTTPError(t *testing.T) {
+	es := &fakeES{status: http.StatusTooManyRequests}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Username: "elastic", Password: "pw"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvent(context.Background(), testEvents()[0]); err == nil {
+		t.Error("Expected error for 429 response")
+	}
+	if !strings.HasPrefix(es.auth[0], "Basic ") {
+		t.Errorf("Expected basic auth, got %q", es.auth[0])
+	}
+}
+
+func TestSendEvents_InstallsTemplateOnce(t *testing.T) {
+	es := &fakeES{}
+	srv := newFakeES(t, es)
+
+	w, err := NewWriter(&Config{
+		URL:          srv.URL,
+		Index:        "logs-netbird.audit-default",
+		DataStream:   true,
+		TemplateName: "netbird-audit",
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	for range 2 {
+		if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+			t.Fatalf("SendEvents failed: %v", err)
+		}
+	}
+
+	tmpl, ok := es.templates["netbird-audit"]
+	if !ok {
+		t.Fatal("Expected index template to be installed")
+	}
+	if _, ok := tmpl["data_stream"]; !ok {
+		t.Error("Expected data_stream in template")
+	}
+	patterns := tmpl["index_patterns"].([]any)
+	if patterns[0] != "logs-netbird.audit-default*" {
+		t.Errorf("Unexpected index patterns %v", patterns)
+	}
+	templateCalls := 0
+	for _, p := range es.paths {
+		if strings.HasPrefix(p, "/_index_template/") {
+			templateCalls++
+		}
+	}
+	if templateCalls != 1 {
+		t.Errorf("Expected template to be installed once, got %d calls", templateCalls)
+	}
+}
+
+func TestSendEvents_Empty(t *testing.T) {
+	w, err := NewWriter(&Config{URL: "http://127.0.0.1:1"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), nil); err != nil {
+		t.Errorf("Expected no request for an empty batch, got %v", err)
+	}
+}
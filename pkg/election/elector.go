@@ -16,6 +16,7 @@ import (
 // is the leader and runs the processor. If the leader crashes, the lock
 // expires after TTL and another node acquires it on its next poll.
 type Elector struct {
+	client        *redis.Client
 	locker        *redislock.Client
 	lockKey       string
 	ttl           time.Duration
@@ -62,6 +63,7 @@ func New(cfg *ElectorConfig, logger *slog.Logger) (*Elector, error) {
 	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := rc.Ping(pingCtx).Err(); err != nil {
+		_ = rc.Close()
 		return nil, fmt.Errorf("redis ping failed (%s): %w", cfg.RedisURL, err)
 	}
 
@@ -75,6 +77,7 @@ func New(cfg *ElectorConfig, logger *slog.Logger) (*Elector, error) {
 	}
 
 	return &Elector{
+		client:        rc,
 		locker:        redislock.New(rc),
 		lockKey:       cfg.LockKey,
 		ttl:           ttl,
@@ -84,6 +87,11 @@ func New(cfg *ElectorConfig, logger *slog.Logger) (*Elector, error) {
 	}, nil
 }
 
+// Close closes the Redis connection. Call it after Run has returned.
+func (e *Elector) Close() error {
+	return e.client.Close()
+}
+
 // Run manages the processor lifecycle based on Redis lock ownership.
 // It blocks until appCtx is cancelled or runFn returns a fatal error.
 //
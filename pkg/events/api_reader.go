@@ -0,0 +1,323 @@
+package events
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/activity"
+)
+
+// NetBird serves audit events at /api/events/audit; versions before the
+// network traffic events only at /api/events.
+const (
+	apiAuditEventsPath  = "/api/events/audit"
+	apiLegacyEventsPath = "/api/events"
+)
+
+// UnknownActivity is the Activity of API events whose activity_code is not in
+// the activity map. Their ActivityName and ActivityCode come from the API.
+const UnknownActivity = -1
+
+// APIReaderOptions configures an APIEventReader.
+type APIReaderOptions struct {
+	// URL is the NetBird Management URL, e.g. https://api.netbird.io
+	URL string
+
+	// Token is a personal access token of a user allowed to read events
+	Token string
+
+	// Timeout bounds each request (default: 30s)
+	Timeout time.Duration
+
+	// AccountID is set on every event, as the API does not report it
+	AccountID string
+
+	// CheckpointFile keeps the checkpoints (see FileCheckpointStore)
+	CheckpointFile string
+
+	// Client replaces the HTTP client, e.g. for custom TLS settings
+	Client *http.Client
+
+	// CacheFor is how long a fetched event list answers the queries that
+	// follow, so the writers, batches and gap rechecks of one poll share a
+	// request (default: 0, every query fetches)
+	CacheFor time.Duration
+}
+
+// APIEventReader implements ReaderInterface on the NetBird Management REST
+// API, for NetBird Cloud and managed instances whose database cannot be
+// reached. The API returns every event of the account in one response with
+// the initiator's email already resolved, so queries filter the list locally;
+// a fetched list is reused for APIReaderOptions.CacheFor. Checkpoints are kept
+// in a local file.
+type APIEventReader struct {
+	baseURL   string
+	token     string
+	accountID string
+	client    *http.Client
+	logger    *slog.Logger
+	*FileCheckpointStore
+
+	cacheFor time.Duration
+	now      func() time.Time // replaced in tests
+
+	// mu is held while fetching, so concurrent queries wait for one request
+	mu        sync.Mutex
+	endpoint  string  // events path, resolved on the first request
+	cached    []Event // last fetched list, sorted by ID
+	fetchedAt time.Time
+}
+
+// apiEvent is an event as returned by the API.
+type apiEvent struct {
+	ID             json.RawMessage `json:"id"` // a string in current versions
+	Timestamp      time.Time       `json:"timestamp"`
+	Activity       string          `json:"activity"`
+	ActivityCode   string          `json:"activity_code"`
+	InitiatorID    string          `json:"initiator_id"`
+	InitiatorName  string          `json:"initiator_name"`
+	InitiatorEmail string          `json:"initiator_email"`
+	TargetID       string          `json:"target_id"`
+	Meta           json.RawMessage `json:"meta"`
+}
+
+// NewAPIEventReader creates a reader for the API at opts.URL.
+func NewAPIEventReader(opts APIReaderOptions, logger *slog.Logger) (*APIEventReader, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid NetBird API URL %q", opts.URL)
+	}
+	if opts.Token == "" {
+		return nil, errors.New("a NetBird personal access token is required")
+	}
+	if opts.CheckpointFile == "" {
+		return nil, errors.New("a checkpoint file is required")
+	}
+	client := opts.Client
+	if client == nil {
+		timeout := opts.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+	return &APIEventReader{
+		baseURL:             strings.TrimSuffix(opts.URL, "/"),
+		token:               opts.Token,
+		accountID:           opts.AccountID,
+		client:              client,
+		logger:              logger,
+		cacheFor:            opts.CacheFor,
+		now:                 time.Now,
+		FileCheckpointStore: NewFileCheckpointStore(opts.CheckpointFile, logger),
+	}, nil
+}
+
+// GetEvents fetches the events and returns those matching opts.
+func (r *APIEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+	all, err := r.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := filterEvents(all, opts)
+	if opts.Limit == 0 {
+		opts.Limit = 1000
+	}
+	result = result[min(opts.Offset, len(result)):]
+	result = result[:min(opts.Limit, len(result))]
+	r.logger.Debug("Fetched events", "count", len(result))
+	return result, nil
+}
+
+// StreamEvents yields the events matching opts from a single fetched list.
+// The API has no cursor, so the response is decoded as a whole first.
+func (r *APIEventReader) StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		all, err := r.fetch(ctx)
+		if err != nil {
+			yield(Event{}, err)
+			return
+		}
+		result := filterEvents(all, opts)
+		if opts.Limit > 0 {
+			result = result[min(opts.Offset, len(result)):]
+			result = result[:min(opts.Limit, len(result))]
+		}
+		for _, evt := range result {
+			if !yield(evt, nil) {
+				return
+			}
+		}
+	}
+}
+
+// GetEventCount returns the number of events matching opts.
+func (r *APIEventReader) GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error) {
+	all, err := r.fetch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// Counted like on the databases: by account, time and activity
+	opts.MinEventID, opts.EventIDs = nil, nil
+	return int64(len(filterEvents(all, opts))), nil
+}
+
+// Close releases idle connections.
+func (r *APIEventReader) Close() error {
+	r.client.CloseIdleConnections()
+	return nil
+}
+
+// fetch returns every event, from the list fetched last when it is younger
+// than cacheFor or else from the API. The first request falls back to the
+// endpoint of older versions. The list is shared and must not be modified.
+func (r *APIEventReader) fetch(ctx context.Context) ([]Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cached != nil && r.now().Sub(r.fetchedAt) < r.cacheFor {
+		return r.cached, nil
+	}
+
+	resolved := r.endpoint != ""
+	path := r.endpoint
+	if !resolved {
+		path = apiAuditEventsPath
+	}
+
+	fetchedAt := r.now()
+	raw, status, err := r.get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusNotFound && !resolved {
+		path = apiLegacyEventsPath
+		if raw, status, err = r.get(ctx, path); err != nil {
+			return nil, err
+		}
+	}
+	if status != http.StatusOK {
+		return nil, apiStatusError(path, status, raw)
+	}
+	if !resolved {
+		r.logger.Info("Reading events from the NetBird API", "url", r.baseURL+path)
+		r.endpoint = path
+	}
+
+	all, err := r.decode(raw)
+	if err != nil {
+		return nil, err
+	}
+	r.logger.Debug("Fetched event list from the NetBird API", "count", len(all))
+	r.cached, r.fetchedAt = all, fetchedAt
+	return all, nil
+}
+
+// get requests path and returns the body and status.
+func (r *APIEventReader) get(ctx context.Context, path string) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.baseURL+path, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create API request: %w", err)
+	}
+	req.Header.Set("Authorization", "Token "+r.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query events from NetBird API: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read NetBird API response: %w", err)
+	}
+	return body, resp.StatusCode, nil
+}
+
+// apiStatusError describes a failed API request with the start of its body.
+func apiStatusError(path string, status int, body []byte) error {
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return fmt.Errorf("NetBird API %s returned status %d: %s", path, status, msg)
+}
+
+// decode maps the API response to events sorted by ID.
+func (r *APIEventReader) decode(raw []byte) ([]Event, error) {
+	var list []apiEvent
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("failed to decode NetBird API events: %w", err)
+	}
+
+	result := make([]Event, 0, len(list))
+	for _, e := range list {
+		id, err := strconv.ParseInt(strings.Trim(string(e.ID), `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event ID %s from NetBird API: %w", e.ID, err)
+		}
+		evt := Event{
+			ID:             id,
+			Timestamp:      e.Timestamp,
+			Activity:       UnknownActivity,
+			ActivityName:   e.Activity,
+			ActivityCode:   e.ActivityCode,
+			InitiatorID:    e.InitiatorID,
+			TargetID:       e.TargetID,
+			AccountID:      r.accountID,
+			InitiatorEmail: cmp.Or(e.InitiatorEmail, e.InitiatorName, e.InitiatorID),
+		}
+		if m := string(e.Meta); m != "" && m != "null" {
+			evt.Meta = m
+		}
+		// The API describes user targets in meta
+		var meta struct {
+			Email    string `json:"email"`
+			Username string `json:"username"`
+		}
+		_ = json.Unmarshal(e.Meta, &meta)
+		evt.TargetEmail = cmp.Or(meta.Email, meta.Username, e.TargetID)
+
+		if a, ok := activity.FromCode(e.ActivityCode); ok {
+			evt.Activity = int(a)
+			EnrichActivityInfo(&evt)
+		}
+		result = append(result, evt)
+	}
+	slices.SortFunc(result, func(a, b Event) int { return cmp.Compare(a.ID, b.ID) })
+	return result, nil
+}
+
+// filterEvents returns the events matching the filters of opts, ordered by
+// ID as requested. Limit and Offset are left to the caller.
+func filterEvents(all []Event, opts EventQueryOptions) []Event {
+	result := make([]Event, 0, len(all))
+	for _, evt := range all {
+		switch {
+		case opts.AccountID != "" && evt.AccountID != opts.AccountID,
+			opts.StartTime != nil && evt.Timestamp.Before(*opts.StartTime),
+			opts.EndTime != nil && evt.Timestamp.After(*opts.EndTime),
+			opts.Activity != nil && evt.Activity != *opts.Activity,
+			opts.MinEventID != nil && evt.ID <= *opts.MinEventID,
+			len(opts.EventIDs) > 0 && !slices.Contains(opts.EventIDs, evt.ID):
+			continue
+		}
+		result = append(result, evt)
+	}
+	if !opts.OrderAsc {
+		slices.Reverse(result)
+	}
+	return result
+}
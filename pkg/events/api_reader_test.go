@@ -0,0 +1,223 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// apiTestEvents is a /api/events response: ids as strings and numbers, a
+// user target described in meta and an activity code unknown to eventsproc.
+const apiTestEvents = `[
+  {"id": "12", "timestamp": "2026-01-28T10:02:00Z", "activity": "User joined", "activity_code": "user.join",
+   "initiator_id": "u2", "initiator_name": "Bob", "initiator_email": "", "target_id": "u2",
+   "meta": {"email": "bob@example.com", "username": "Bob"}},
+  {"id": 10, "timestamp": "2026-01-28T10:00:00Z", "activity": "Peer added", "activity_code": "peer.user.add",
+   "initiator_id": "u1", "initiator_name": "Alice", "initiator_email": "alice@example.com", "target_id": "p1",
+   "meta": {"fqdn": "peer1.netbird.cloud"}},
+  {"id": "11", "timestamp": "2026-01-28T10:01:00Z", "activity": "Something new", "activity_code": "something.new",
+   "initiator_id": "u1", "initiator_name": "Alice", "initiator_email": "alice@example.com", "target_id": "x1",
+   "meta": null}
+]`
+
+func newTestAPIReader(t *testing.T, handler http.HandlerFunc) *APIEventReader {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	reader, err := NewAPIEventReader(APIReaderOptions{
+		URL:            server.URL,
+		Token:          "nbp_test",
+		AccountID:      "acc1",
+		CheckpointFile: filepath.Join(t.TempDir(), "checkpoints.json"),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewAPIEventReader failed: %v", err)
+	}
+	return reader
+}
+
+func TestAPIEventReader_GetEvents(t *testing.T) {
+	var paths []string
+	reader := newTestAPIReader(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if got := r.Header.Get("Authorization"); got != "Token nbp_test" {
+			t.Errorf("Expected token authorization, got %q", got)
+		}
+		// An older Management server without the audit endpoint
+		if r.URL.Path != "/api/events" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, apiTestEvents)
+	})
+
+	minID := int64(10)
+	events, err := reader.GetEvents(context.Background(), EventQueryOptions{MinEventID: &minID, Limit: 10, OrderAsc: true})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != 11 || events[1].ID != 12 {
+		t.Fatalf("Expected events 11 and 12, got %+v", events)
+	}
+
+	unknown := events[0]
+	if unknown.Activity != UnknownActivity || unknown.ActivityCode != "something.new" || unknown.ActivityName != "Something new" {
+		t.Errorf("Expected the API's name and code for an unknown activity, got %+v", unknown)
+	}
+	if unknown.Meta != "" || unknown.TargetEmail != "x1" {
+		t.Errorf("Expected empty meta and the target ID, got meta %q target %q", unknown.Meta, unknown.TargetEmail)
+	}
+
+	joined := events[1]
+	if joined.Activity != 2 || joined.ActivityCode != "user.join" || joined.AccountID != "acc1" {
+		t.Errorf("Unexpected mapping: %+v", joined)
+	}
+	if joined.InitiatorEmail != "Bob" || joined.TargetEmail != "bob@example.com" {
+		t.Errorf("Expected initiator Bob and target bob@example.com, got %q and %q", joined.InitiatorEmail, joined.TargetEmail)
+	}
+
+	// The endpoint is resolved once
+	if _, err := reader.GetEvents(context.Background(), EventQueryOptions{}); err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if want := []string{"/api/events/audit", "/api/events", "/api/events"}; len(paths) != len(want) || paths[0] != want[0] || paths[2] != want[2] {
+		t.Errorf("Expected requests %v, got %v", want, paths)
+	}
+}
+
+func TestAPIEventReader_QueryOptions(t *testing.T) {
+	reader := newTestAPIReader(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, apiTestEvents)
+	})
+	ctx := context.Background()
+
+	events, err := reader.GetEvents(ctx, EventQueryOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != 12 || events[1].ID != 11 {
+		t.Errorf("Expected events 12 and 11 newest first, got %+v", events)
+	}
+
+	events, err = reader.GetEvents(ctx, EventQueryOptions{EventIDs: []int64{10, 12}, OrderAsc: true})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].InitiatorEmail != "alice@example.com" || events[0].TargetEmail != "p1" {
+		t.Errorf("Expected events 10 and 12, got %+v", events)
+	}
+
+	minID := int64(11)
+	var ids []int64
+	for evt, err := range reader.StreamEvents(ctx, EventQueryOptions{MinEventID: &minID, OrderAsc: true}) {
+		if err != nil {
+			t.Fatalf("StreamEvents failed: %v", err)
+		}
+		ids = append(ids, evt.ID)
+	}
+	if len(ids) != 1 || ids[0] != 12 {
+		t.Errorf("Expected event 12, got %v", ids)
+	}
+
+	count, err := reader.GetEventCount(ctx, EventQueryOptions{MinEventID: &minID})
+	if err != nil {
+		t.Fatalf("GetEventCount failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 events, got %d", count)
+	}
+}
+
+// TestAPIEventReader_CacheFor verifies that the queries of one poll share a
+// request and that the list is fetched again once it is older than CacheFor.
+func TestAPIEventReader_CacheFor(t *testing.T) {
+	requests := 0
+	reader := newTestAPIReader(t, func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		_, _ = io.WriteString(w, apiTestEvents)
+	})
+	clock := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	reader.now = func() time.Time { return clock }
+	reader.cacheFor = 30 * time.Second
+	ctx := context.Background()
+
+	minID := int64(10)
+	for _, opts := range []EventQueryOptions{
+		{Limit: 1, OrderAsc: true},
+		{MinEventID: &minID, Limit: 1, OrderAsc: true},
+		{EventIDs: []int64{11}},
+	} {
+		if _, err := reader.GetEvents(ctx, opts); err != nil {
+			t.Fatalf("GetEvents failed: %v", err)
+		}
+	}
+	if _, err := reader.GetEventCount(ctx, EventQueryOptions{}); err != nil {
+		t.Fatalf("GetEventCount failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected the queries to share 1 request, got %d", requests)
+	}
+
+	clock = clock.Add(30 * time.Second)
+	events, err := reader.GetEvents(ctx, EventQueryOptions{MinEventID: &minID, OrderAsc: true})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if requests != 2 || len(events) != 2 {
+		t.Errorf("Expected a new request for 2 events after CacheFor, got %d requests and %+v", requests, events)
+	}
+}
+
+func TestAPIEventReader_Errors(t *testing.T) {
+	reader := newTestAPIReader(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, `{"message":"token invalid","code":401}`, http.StatusUnauthorized)
+	})
+	if _, err := reader.GetEvents(context.Background(), EventQueryOptions{}); err == nil {
+		t.Error("Expected an error for an unauthorized request")
+	}
+
+	if _, err := NewAPIEventReader(APIReaderOptions{URL: "api.netbird.io", Token: "t", CheckpointFile: "c"}, slog.Default()); err == nil {
+		t.Error("Expected an error for a URL without scheme")
+	}
+}
+
+func TestAPIEventReader_FileCheckpoints(t *testing.T) {
+	reader := newTestAPIReader(t, func(w http.ResponseWriter, _ *http.Request) {
+		t.Error("Checkpoints must not query the API")
+	})
+	ctx := context.Background()
+
+	cp, err := reader.GetWriterCheckpoint(ctx, "eventsproc-test", "loki")
+	if err != nil || cp != nil {
+		t.Fatalf("Expected no checkpoint, got %+v, %v", cp, err)
+	}
+
+	for _, c := range []ProcessingCheckpoint{
+		{ConsumerID: "eventsproc-test", WriterType: "loki", LastEventID: 10, TotalEventsProcessed: 1},
+		{ConsumerID: "eventsproc-test", WriterType: "stdout", LastEventID: 11, TotalEventsProcessed: 2},
+		{ConsumerID: "eventsproc-test", WriterType: "loki", LastEventID: 12, TotalEventsProcessed: 3},
+	} {
+		if err := reader.SaveWriterCheckpoint(ctx, &c); err != nil {
+			t.Fatalf("SaveWriterCheckpoint failed: %v", err)
+		}
+	}
+
+	// A new store on the same file sees the saved checkpoints
+	store := NewFileCheckpointStore(reader.FileCheckpointStore.path, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cp, err = store.GetWriterCheckpoint(ctx, "eventsproc-test", "loki")
+	if err != nil {
+		t.Fatalf("GetWriterCheckpoint failed: %v", err)
+	}
+	if cp == nil || cp.LastEventID != 12 || cp.TotalEventsProcessed != 3 || cp.CreatedAt.IsZero() {
+		t.Errorf("Expected the updated loki checkpoint, got %+v", cp)
+	}
+	cp, err = store.GetWriterCheckpoint(ctx, "eventsproc-test", "stdout")
+	if err != nil || cp == nil || cp.LastEventID != 11 {
+		t.Errorf("Expected the stdout checkpoint, got %+v, %v", cp, err)
+	}
+}
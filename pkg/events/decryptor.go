@@ -4,9 +4,22 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/metrics"
 )
 
+// DecryptionKey is a raw AES-256 key of the keyring, with the ID that
+// identifies it in logs and metrics.
+type DecryptionKey struct {
+	ID  string
+	Key []byte
+}
+
 // NetbirdDecryptor decrypts AES-256-GCM encrypted fields from NetBird's database.
 // NetBird encrypts sensitive columns (email, name) before writing to PostgreSQL or SQLite.
 //
@@ -17,39 +30,150 @@ import (
 // to be updated. The symptom is base64-looking strings appearing in initiator_email /
 // target_email output despite decryption being enabled. Check NetBird release notes
 // for any changes to field encryption and update the wire format parsing accordingly.
+//
+// After a key rotation rows may be encrypted with different keys, so the
+// decryptor holds a keyring and tries its keys in order.
 type NetbirdDecryptor struct {
-	gcm cipher.AEAD
+	keys   []keyringEntry
+	logger *slog.Logger
+
+	mu         sync.Mutex
+	lastWarn   time.Time        // when undecryptable values were last logged
+	suppressed int              // undecryptable values not logged since
+	now        func() time.Time // replaced in tests
+}
+
+// undecryptableLogInterval limits the warning about undecryptable values,
+// which are all counted in metrics.UndecryptableValues.
+const undecryptableLogInterval = time.Minute
+
+// keyringEntry is a key of the keyring and whether it has been used yet.
+type keyringEntry struct {
+	id   string
+	gcm  cipher.AEAD
+	used sync.Once
 }
 
 // NewNetbirdDecryptor creates a decryptor from a raw 32-byte AES-256 key.
 func NewNetbirdDecryptor(key []byte) (*NetbirdDecryptor, error) {
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	return NewNetbirdKeyring([]DecryptionKey{{ID: "default", Key: key}}, slog.Default())
+}
+
+// NewNetbirdKeyring creates a decryptor that tries keys in the given order,
+// the current key first.
+func NewNetbirdKeyring(keys []DecryptionKey, logger *slog.Logger) (*NetbirdDecryptor, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no decryption keys")
 	}
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	d := &NetbirdDecryptor{keys: make([]keyringEntry, len(keys)), logger: logger, now: time.Now}
+	for i, key := range keys {
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create AES cipher for key %s: %w", key.ID, err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCM for key %s: %w", key.ID, err)
+		}
+		d.keys[i].id = key.ID
+		d.keys[i].gcm = gcm
 	}
-	return &NetbirdDecryptor{gcm: gcm}, nil
+	return d, nil
 }
 
 // Decrypt attempts to decrypt a base64-encoded AES-GCM ciphertext.
 // If the value is not valid base64, is too short, or decryption fails (e.g. the
 // field is not encrypted), the original value is returned unchanged — callers
 // are always safe to call this without knowing whether a value is encrypted.
+//
+// A value long enough to be ciphertext that no key opens is counted, and
+// logged at most once per undecryptableLogInterval, as it usually means a key
+// is missing from the keyring.
 func (d *NetbirdDecryptor) Decrypt(value string) string {
+	plaintext, _, ok := d.DecryptWithKey(value)
+	if !ok {
+		return value
+	}
+	return plaintext
+}
+
+// DecryptWithKey decrypts value like Decrypt and also returns the ID of the
+// key that opened it. ok is false if no key did.
+func (d *NetbirdDecryptor) DecryptWithKey(value string) (plaintext, keyID string, ok bool) {
 	data, err := base64.StdEncoding.DecodeString(value)
 	if err != nil {
-		return value // not base64 — plaintext field, return as-is
+		return "", "", false // not base64 — plaintext field
+	}
+	// All keys are AES-GCM with the same nonce and tag sizes
+	gcm := d.keys[0].gcm
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return "", "", false // too short to contain a nonce and tag
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	for i := range d.keys {
+		k := &d.keys[i]
+		opened, err := k.gcm.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			continue
+		}
+		metrics.DecryptedValues.WithLabelValues(k.id).Inc()
+		if i > 0 {
+			k.used.Do(func() {
+				d.logger.Info("Decrypted a value with an older key", "key_id", k.id, "position", i+1)
+			})
+		}
+		return string(opened), k.id, true
+	}
+
+	metrics.UndecryptableValues.Inc()
+	d.warnUndecryptable(len(value))
+	return "", "", false
+}
+
+// warnUndecryptable logs an undecryptable value unless one was logged less
+// than undecryptableLogInterval ago, with the number of values left unlogged
+// in between.
+func (d *NetbirdDecryptor) warnUndecryptable(length int) {
+	d.mu.Lock()
+	now := d.now()
+	if !d.lastWarn.IsZero() && now.Sub(d.lastWarn) < undecryptableLogInterval {
+		d.suppressed++
+		d.mu.Unlock()
+		return
+	}
+	suppressed := d.suppressed
+	d.lastWarn, d.suppressed = now, 0
+	d.mu.Unlock()
+
+	d.logger.Warn("Value looks encrypted but no key of the keyring opens it, was the NetBird key rotated?",
+		"keys", len(d.keys),
+		"length", length,
+		"suppressed", suppressed,
+	)
+}
+
+// newDecryptor creates the decryptor of the keys in conf. Keys that cannot be
+// loaded are logged and left out; it returns nil when no key is left.
+func newDecryptor(conf EmailEnrichmentConfig, logger *slog.Logger) *NetbirdDecryptor {
+	keys, err := conf.GetDecryptionKeys()
+	if err != nil {
+		logger.Warn("Failed to load NetBird decryption keys", "error", err)
 	}
-	if len(data) < d.gcm.NonceSize() {
-		return value // too short to contain a nonce
+	if len(keys) == 0 {
+		if err != nil {
+			logger.Warn("No NetBird decryption key loaded, email fields will not be decrypted")
+		}
+		return nil
 	}
-	nonce, ciphertext := data[:d.gcm.NonceSize()], data[d.gcm.NonceSize():]
-	plaintext, err := d.gcm.Open(nil, nonce, ciphertext, nil)
+	d, err := NewNetbirdKeyring(keys, logger)
 	if err != nil {
-		return value // decryption failed — value may not be encrypted
+		logger.Warn("Failed to initialise NetBird decryptor", "error", err)
+		return nil
+	}
+	ids := make([]string, len(keys))
+	for i, key := range keys {
+		ids[i] = key.ID
 	}
-	return string(plaintext)
+	logger.Info("NetBird AES-GCM decryptor initialised — email fields will be decrypted", "key_ids", ids)
+	return d
 }
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// encryptTestValue encrypts value with key in NetBird's wire format.
+func encryptTestValue(t *testing.T, key []byte, value string) string {
+	t.Helper()
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create GCM: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil))
+}
+
+func testKey(b byte) []byte {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = b
+	}
+	return key
+}
+
+func TestNetbirdKeyring_Decrypt(t *testing.T) {
+	current, previous, unknown := testKey(1), testKey(2), testKey(3)
+	d, err := NewNetbirdKeyring([]DecryptionKey{
+		{ID: "2026", Key: current},
+		{ID: "2025", Key: previous},
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewNetbirdKeyring failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		key   []byte
+		keyID string
+	}{
+		{current, "2026"},
+		{previous, "2025"},
+	} {
+		decrypted := testutil.ToFloat64(metrics.DecryptedValues.WithLabelValues(tt.keyID))
+		plaintext, keyID, ok := d.DecryptWithKey(encryptTestValue(t, tt.key, "alice@example.com"))
+		if !ok || plaintext != "alice@example.com" || keyID != tt.keyID {
+			t.Errorf("Expected alice@example.com decrypted by %s, got %q by %q (%v)", tt.keyID, plaintext, keyID, ok)
+		}
+		if got := testutil.ToFloat64(metrics.DecryptedValues.WithLabelValues(tt.keyID)) - decrypted; got != 1 {
+			t.Errorf("Expected 1 value counted for key %s, got %v", tt.keyID, got)
+		}
+	}
+
+	// Plaintext is returned as-is and not counted as undecryptable
+	undecryptable := testutil.ToFloat64(metrics.UndecryptableValues)
+	for _, plain := range []string{"alice@example.com", "user1", "Alice Smith"} {
+		if got := d.Decrypt(plain); got != plain {
+			t.Errorf("Expected %q unchanged, got %q", plain, got)
+		}
+	}
+	if got := testutil.ToFloat64(metrics.UndecryptableValues) - undecryptable; got != 0 {
+		t.Errorf("Expected no undecryptable values, got %v", got)
+	}
+
+	// Ciphertext of a key missing from the keyring is returned and counted
+	encrypted := encryptTestValue(t, unknown, "bob@example.com")
+	if got := d.Decrypt(encrypted); got != encrypted {
+		t.Errorf("Expected the ciphertext unchanged, got %q", got)
+	}
+	if got := testutil.ToFloat64(metrics.UndecryptableValues) - undecryptable; got != 1 {
+		t.Errorf("Expected 1 undecryptable value, got %v", got)
+	}
+}
+
+// TestNetbirdKeyring_UndecryptableLogged verifies that every undecryptable
+// value is counted but only one per interval is logged.
+func TestNetbirdKeyring_UndecryptableLogged(t *testing.T) {
+	var logs bytes.Buffer
+	d, err := NewNetbirdKeyring([]DecryptionKey{{ID: "2026", Key: testKey(1)}}, slog.New(slog.NewTextHandler(&logs, nil)))
+	if err != nil {
+		t.Fatalf("NewNetbirdKeyring failed: %v", err)
+	}
+	clock := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return clock }
+
+	encrypted := encryptTestValue(t, testKey(3), "bob@example.com")
+	undecryptable := testutil.ToFloat64(metrics.UndecryptableValues)
+	for range 3 {
+		d.Decrypt(encrypted)
+	}
+	if got := testutil.ToFloat64(metrics.UndecryptableValues) - undecryptable; got != 3 {
+		t.Errorf("Expected 3 undecryptable values, got %v", got)
+	}
+	if got := strings.Count(logs.String(), "no key of the keyring opens it"); got != 1 {
+		t.Errorf("Expected 1 warning within the interval, got %d:\n%s", got, logs.String())
+	}
+
+	clock = clock.Add(undecryptableLogInterval)
+	logs.Reset()
+	d.Decrypt(encrypted)
+	if !strings.Contains(logs.String(), "suppressed=2") {
+		t.Errorf("Expected a warning with 2 suppressed values after the interval, got:\n%s", logs.String())
+	}
+}
+
+func TestNetbirdKeyring_InvalidKey(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewNetbirdKeyring(nil, logger); err == nil {
+		t.Error("Expected an error for an empty keyring")
+	}
+	if _, err := NewNetbirdKeyring([]DecryptionKey{{ID: "short", Key: []byte("short")}}, logger); err == nil {
+		t.Error("Expected an error for a key of invalid length")
+	}
+}
@@ -0,0 +1,161 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// NoEnrichment is an EmailEnrichmentConfig with enrichment turned off, for a
+// reader whose database holds the events but not the user tables.
+var NoEnrichment EmailEnrichmentConfig = noEnrichment{}
+
+type noEnrichment struct{}
+
+func (noEnrichment) GetSource() string                           { return "none" }
+func (noEnrichment) GetCustomSchema() string                     { return "" }
+func (noEnrichment) GetCustomTable() string                      { return "" }
+func (noEnrichment) IsEnabled() bool                             { return false }
+func (noEnrichment) GetDecryptionKeys() ([]DecryptionKey, error) { return nil, nil }
+
+// userTable is a table email enrichment looks up, in lookup order.
+type userTable struct {
+	name    string
+	hasName bool // the name column is the fallback for a missing email
+}
+
+// UserEnricher sets InitiatorEmail and TargetEmail of events read from a
+// database without the user tables, e.g. NetBird's separate events store. It
+// resolves the same sources, in the same order, as the JOINs of the readers:
+// the first non-NULL email or name, else the user ID.
+type UserEnricher struct {
+	db        *sql.DB
+	driver    string
+	tables    []userTable
+	logger    *slog.Logger
+	decryptor *NetbirdDecryptor // nil if decryption is not configured
+}
+
+// NewUserEnricher creates an enricher on db. driver is the database's driver
+// ("postgres", "mysql" or "sqlite"). Like the SQLite reader, the okta_users
+// and custom sources are not supported on SQLite and fall back to none.
+func NewUserEnricher(db *sql.DB, driver string, logger *slog.Logger, conf EmailEnrichmentConfig) *UserEnricher {
+	e := &UserEnricher{db: db, driver: driver, logger: logger}
+
+	okta := userTable{name: "okta_users"}
+	users := userTable{name: "users", hasName: true}
+	switch source := conf.GetSource(); {
+	case source == "none":
+	case source == "netbird_users":
+		e.tables = []userTable{users}
+	case driver == "sqlite" && (source == "idp_okta_users" || source == "custom"):
+		logger.Warn("Email enrichment source not supported for SQLite, falling back to none", "source", source)
+	case driver == "sqlite":
+		e.tables = []userTable{users}
+	case source == "idp_okta_users":
+		e.tables = []userTable{okta}
+	case source == "custom":
+		e.tables = []userTable{{name: conf.GetCustomSchema() + "." + conf.GetCustomTable()}}
+	case source == "auto":
+		e.tables = []userTable{okta, users}
+	default:
+		logger.Warn("Unknown email enrichment source, defaulting to auto", "source", source)
+		e.tables = []userTable{okta, users}
+	}
+
+	e.decryptor = newDecryptor(conf, logger)
+	return e
+}
+
+// Enrich looks up the initiators and targets of the events and sets their
+// emails. Without lookup tables the user IDs are used, as with source none.
+func (e *UserEnricher) Enrich(ctx context.Context, eventList []Event) error {
+	var ids []string
+	for _, evt := range eventList {
+		for _, id := range []string{evt.InitiatorID, evt.TargetID} {
+			if id != "" && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	found := make(map[string]string)
+	if len(ids) > 0 {
+		for _, table := range e.tables {
+			if err := e.lookup(ctx, table, ids, found); err != nil {
+				return err
+			}
+		}
+	}
+
+	resolve := func(id string) string {
+		value, ok := found[id]
+		if !ok {
+			value = id
+		}
+		if e.decryptor != nil {
+			value = e.decryptor.Decrypt(value)
+		}
+		return value
+	}
+	for i := range eventList {
+		eventList[i].InitiatorEmail = resolve(eventList[i].InitiatorID)
+		eventList[i].TargetEmail = resolve(eventList[i].TargetID)
+	}
+	return nil
+}
+
+// lookup adds the email, or name, of the given users in table to found,
+// keeping the values earlier tables already found.
+func (e *UserEnricher) lookup(ctx context.Context, table userTable, ids []string, found map[string]string) error {
+	columns := "id, email, NULL"
+	if table.hasName {
+		columns = "id, email, name"
+	}
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		if e.driver == "postgres" {
+			placeholders[i] = "$" + strconv.Itoa(i+1)
+		}
+		args[i] = id
+	}
+	query := "SELECT " + columns + " FROM " + table.name + " WHERE id IN (" + strings.Join(placeholders, ", ") + ")"
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to look up users in %s: %w", table.name, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var id string
+		var email, name sql.NullString
+		if err := rows.Scan(&id, &email, &name); err != nil {
+			return fmt.Errorf("failed to scan user from %s: %w", table.name, err)
+		}
+		if _, ok := found[id]; ok {
+			continue
+		}
+		switch {
+		case email.Valid:
+			found[id] = email.String
+		case name.Valid:
+			found[id] = name.String
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating users from %s: %w", table.name, err)
+	}
+	return nil
+}
+
+// Close closes the enrichment database connection
+func (e *UserEnricher) Close() error {
+	return e.db.Close()
+}
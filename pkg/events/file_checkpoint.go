@@ -0,0 +1,178 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileCheckpointStore keeps checkpoints in a local JSON file, for readers
+// whose source cannot hold the event_processing_checkpoint table, such as the
+// NetBird API. The file is rewritten atomically on every save.
+type FileCheckpointStore struct {
+	path   string
+	logger *slog.Logger
+	mu     sync.Mutex
+}
+
+// fileCheckpoint is a checkpoint as stored in the file.
+type fileCheckpoint struct {
+	ConsumerID           string    `json:"consumer_id"`
+	WriterType           string    `json:"writer_type"`
+	LastEventID          int64     `json:"last_event_id"`
+	LastEventTimestamp   time.Time `json:"last_event_timestamp"`
+	TotalEventsProcessed int64     `json:"total_events_processed"`
+	ProcessingNode       string    `json:"processing_node"`
+	UpdatedAt            time.Time `json:"updated_at"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+// NewFileCheckpointStore creates a store on path. The file is created on the
+// first save; its directory must exist.
+func NewFileCheckpointStore(path string, logger *slog.Logger) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path, logger: logger}
+}
+
+// GetWriterCheckpoint returns the checkpoint of a consumer/writer, or nil if
+// there is none.
+func (s *FileCheckpointStore) GetWriterCheckpoint(_ context.Context, consumerID, writerType string) (*ProcessingCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(all, func(c fileCheckpoint) bool {
+		return c.ConsumerID == consumerID && c.WriterType == writerType
+	})
+	if i < 0 {
+		s.logger.Info("No checkpoint found for writer", "consumer_id", consumerID, "writer_type", writerType)
+		return nil, nil
+	}
+	c := all[i]
+	s.logger.Info("Loaded writer checkpoint",
+		"consumer_id", c.ConsumerID,
+		"writer_type", c.WriterType,
+		"last_event_id", c.LastEventID,
+	)
+	return &ProcessingCheckpoint{
+		ConsumerID:           c.ConsumerID,
+		WriterType:           c.WriterType,
+		LastEventID:          c.LastEventID,
+		LastEventTimestamp:   c.LastEventTimestamp,
+		TotalEventsProcessed: c.TotalEventsProcessed,
+		ProcessingNode:       c.ProcessingNode,
+		UpdatedAt:            c.UpdatedAt,
+		CreatedAt:            c.CreatedAt,
+	}, nil
+}
+
+// SaveWriterCheckpoint inserts or updates the checkpoint of a consumer/writer.
+func (s *FileCheckpointStore) SaveWriterCheckpoint(_ context.Context, checkpoint *ProcessingCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	c := fileCheckpoint{
+		ConsumerID:           checkpoint.ConsumerID,
+		WriterType:           checkpoint.WriterType,
+		LastEventID:          checkpoint.LastEventID,
+		LastEventTimestamp:   checkpoint.LastEventTimestamp,
+		TotalEventsProcessed: checkpoint.TotalEventsProcessed,
+		ProcessingNode:       checkpoint.ProcessingNode,
+		UpdatedAt:            now,
+		CreatedAt:            now,
+	}
+	if i := slices.IndexFunc(all, func(e fileCheckpoint) bool {
+		return e.ConsumerID == c.ConsumerID && e.WriterType == c.WriterType
+	}); i >= 0 {
+		c.CreatedAt = all[i].CreatedAt
+		all[i] = c
+	} else {
+		all = append(all, c)
+	}
+	if err := s.writeAll(all); err != nil {
+		return err
+	}
+
+	s.logger.Debug("Saved writer checkpoint",
+		"consumer_id", c.ConsumerID,
+		"writer_type", c.WriterType,
+		"last_event_id", c.LastEventID,
+	)
+	return nil
+}
+
+// GetCheckpoint returns the consumer's writer_type "default" checkpoint.
+func (s *FileCheckpointStore) GetCheckpoint(ctx context.Context, consumerID string) (*ProcessingCheckpoint, error) {
+	return s.GetWriterCheckpoint(ctx, consumerID, LegacyWriterType)
+}
+
+// SaveCheckpoint saves the consumer's writer_type "default" checkpoint.
+func (s *FileCheckpointStore) SaveCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
+	c := *checkpoint
+	c.WriterType = LegacyWriterType
+	return s.SaveWriterCheckpoint(ctx, &c)
+}
+
+// readAll reads every checkpoint. Callers hold mu.
+func (s *FileCheckpointStore) readAll() ([]fileCheckpoint, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var all []fileCheckpoint
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint file %s: %w", s.path, err)
+	}
+	return all, nil
+}
+
+// writeAll replaces the file with the given checkpoints. Callers hold mu.
+func (s *FileCheckpointStore) writeAll(all []fileCheckpoint) error {
+	data, err := json.MarshalIndent(all, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to sync checkpoint file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint file: %w", err)
+	}
+	return nil
+}
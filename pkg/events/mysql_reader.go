@@ -0,0 +1,248 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+)
+
+// MySQLEventReader implements ReaderInterface for reading events from NetBird's MySQL
+// or MariaDB store. MySQL differences from PostgreSQL:
+//   - Positional placeholders are ? instead of $1, $2, ...
+//   - No idp schema: the checkpoint table lives in the NetBird database
+//   - Upserts use ON DUPLICATE KEY UPDATE with VALUES(), which MariaDB also supports
+//   - NOW() has second precision; CURRENT_TIMESTAMP(6) keeps microseconds
+//
+// The email enrichment queries are the same as on PostgreSQL; a custom schema
+// is another database on the same server.
+type MySQLEventReader struct {
+	db                  *sql.DB
+	logger              *slog.Logger
+	emailEnrichmentConf EmailEnrichmentConfig
+	decryptor           *NetbirdDecryptor // nil if decryption is not configured
+}
+
+// NewMySQLEventReader creates a new MySQLEventReader. db must be opened with
+// parseTime=true (see config.GetMySQLDB).
+func NewMySQLEventReader(db *sql.DB, logger *slog.Logger, emailConf EmailEnrichmentConfig) ReaderInterface {
+	return &MySQLEventReader{
+		db:                  db,
+		logger:              logger,
+		emailEnrichmentConf: emailConf,
+		decryptor:           newDecryptor(emailConf, logger),
+	}
+}
+
+// eventsQuery builds the SELECT for opts without LIMIT and OFFSET.
+func (r *MySQLEventReader) eventsQuery(opts EventQueryOptions) (string, []any) {
+	query := emailEnrichmentQuery(r.emailEnrichmentConf, r.logger)
+	conditions := []string{}
+	args := []any{}
+
+	if opts.AccountID != "" {
+		conditions = append(conditions, "e.account_id = ?")
+		args = append(args, opts.AccountID)
+	}
+
+	if opts.StartTime != nil {
+		conditions = append(conditions, "e.timestamp >= ?")
+		args = append(args, *opts.StartTime)
+	}
+
+	if opts.EndTime != nil {
+		conditions = append(conditions, "e.timestamp <= ?")
+		args = append(args, *opts.EndTime)
+	}
+
+	if opts.Activity != nil {
+		conditions = append(conditions, "e.activity = ?")
+		args = append(args, *opts.Activity)
+	}
+
+	if opts.MinEventID != nil {
+		conditions = append(conditions, "e.id > ?")
+		args = append(args, *opts.MinEventID)
+	}
+
+	if len(opts.EventIDs) > 0 {
+		conditions = append(conditions, "e.id IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(opts.EventIDs)), ", ")+")")
+		for _, id := range opts.EventIDs {
+			args = append(args, id)
+		}
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if opts.OrderAsc {
+		query += " ORDER BY e.id ASC"
+	} else {
+		query += " ORDER BY e.id DESC"
+	}
+
+	return query, args
+}
+
+// GetEvents fetches events from MySQL using ? placeholders
+func (r *MySQLEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+	query, args := r.eventsQuery(opts)
+	if opts.Limit == 0 {
+		opts.Limit = 1000
+	}
+	query += " LIMIT ? OFFSET ?"
+	args = append(args, opts.Limit, opts.Offset)
+
+	r.logger.Debug("Executing MySQL query", "query", query, "args", args)
+
+	result, err := queryEvents(ctx, r.db, query, args, r.decryptor)
+	if err != nil {
+		return nil, err
+	}
+
+	r.logger.Info("Fetched events", "count", len(result))
+	return result, nil
+}
+
+// StreamEvents streams the events matching opts, FetchSize rows per query
+// (see streamChunks). go-sql-driver/mysql reads an open result set from the
+// connection as it is consumed, and the server drops a connection it could
+// not write to for net_write_timeout (60s by default), which a consumer
+// waiting out retries between rows easily exceeds.
+func (r *MySQLEventReader) StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error] {
+	return streamChunks(opts, func(opts EventQueryOptions, limit int) ([]Event, error) {
+		query, args := r.eventsQuery(opts)
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, opts.Offset)
+
+		r.logger.Debug("Streaming MySQL query", "query", query, "args", args)
+		return queryEvents(ctx, r.db, query, args, r.decryptor)
+	})
+}
+
+// GetEventCount returns the total count of events matching the query options
+func (r *MySQLEventReader) GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error) {
+	query := "SELECT COUNT(*) FROM events"
+	conditions := []string{}
+	args := []any{}
+
+	if opts.AccountID != "" {
+		conditions = append(conditions, "account_id = ?")
+		args = append(args, opts.AccountID)
+	}
+	if opts.StartTime != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, *opts.StartTime)
+	}
+	if opts.EndTime != nil {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, *opts.EndTime)
+	}
+	if opts.Activity != nil {
+		conditions = append(conditions, "activity = ?")
+		args = append(args, *opts.Activity)
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	return count, nil
+}
+
+// GetWriterCheckpoint retrieves the checkpoint for a consumer/writer pair
+func (r *MySQLEventReader) GetWriterCheckpoint(ctx context.Context, consumerID, writerType string) (*ProcessingCheckpoint, error) {
+	query := `
+		SELECT consumer_id, writer_type, last_event_id, last_event_timestamp,
+		       total_events_processed, COALESCE(processing_node, ''), updated_at, created_at
+		FROM event_processing_checkpoint
+		WHERE consumer_id = ? AND writer_type = ?
+	`
+
+	var checkpoint ProcessingCheckpoint
+	err := r.db.QueryRowContext(ctx, query, consumerID, writerType).Scan(
+		&checkpoint.ConsumerID,
+		&checkpoint.WriterType,
+		&checkpoint.LastEventID,
+		&checkpoint.LastEventTimestamp,
+		&checkpoint.TotalEventsProcessed,
+		&checkpoint.ProcessingNode,
+		&checkpoint.UpdatedAt,
+		&checkpoint.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		r.logger.Info("No checkpoint found for writer", "consumer_id", consumerID, "writer_type", writerType)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query writer checkpoint: %w", err)
+	}
+
+	r.logger.Info("Loaded writer checkpoint",
+		"consumer_id", checkpoint.ConsumerID,
+		"writer_type", checkpoint.WriterType,
+		"last_event_id", checkpoint.LastEventID,
+	)
+	return &checkpoint, nil
+}
+
+// SaveWriterCheckpoint saves or updates the checkpoint for a consumer/writer pair
+func (r *MySQLEventReader) SaveWriterCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
+	query := `
+		INSERT INTO event_processing_checkpoint
+		(consumer_id, writer_type, last_event_id, last_event_timestamp, total_events_processed, processing_node, updated_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP(6), CURRENT_TIMESTAMP(6))
+		ON DUPLICATE KEY UPDATE
+			last_event_id = VALUES(last_event_id),
+			last_event_timestamp = VALUES(last_event_timestamp),
+			total_events_processed = VALUES(total_events_processed),
+			processing_node = VALUES(processing_node),
+			updated_at = CURRENT_TIMESTAMP(6)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		checkpoint.ConsumerID,
+		checkpoint.WriterType,
+		checkpoint.LastEventID,
+		checkpoint.LastEventTimestamp,
+		checkpoint.TotalEventsProcessed,
+		checkpoint.ProcessingNode,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save writer checkpoint: %w", err)
+	}
+
+	r.logger.Debug("Saved writer checkpoint",
+		"consumer_id", checkpoint.ConsumerID,
+		"writer_type", checkpoint.WriterType,
+		"last_event_id", checkpoint.LastEventID,
+	)
+	return nil
+}
+
+// GetCheckpoint retrieves the checkpoint a consumer kept before per-writer
+// checkpoints. MySQL support starts with the per-writer schema, so this is the
+// row of writer_type "default".
+func (r *MySQLEventReader) GetCheckpoint(ctx context.Context, consumerID string) (*ProcessingCheckpoint, error) {
+	return r.GetWriterCheckpoint(ctx, consumerID, LegacyWriterType)
+}
+
+// SaveCheckpoint saves the consumer's writer_type "default" row; see GetCheckpoint.
+func (r *MySQLEventReader) SaveCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
+	c := *checkpoint
+	c.WriterType = LegacyWriterType
+	return r.SaveWriterCheckpoint(ctx, &c)
+}
+
+// Close closes the database connection
+func (r *MySQLEventReader) Close() error {
+	return r.db.Close()
+}
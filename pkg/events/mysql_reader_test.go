@@ -0,0 +1,148 @@
+package events
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// keyedEmailConfig is a mock email enrichment config with a decryption key.
+type keyedEmailConfig struct {
+	mockEmailEnrichmentConfig
+	key []byte
+}
+
+func (m *keyedEmailConfig) GetDecryptionKeys() ([]DecryptionKey, error) {
+	return []DecryptionKey{{ID: "test", Key: m.key}}, nil
+}
+
+func newMySQLTestReader(t *testing.T, conf EmailEnrichmentConfig) (ReaderInterface, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return NewMySQLEventReader(db, slog.New(slog.NewTextHandler(io.Discard, nil)), conf), mock
+}
+
+func TestMySQLGetEvents_Filters(t *testing.T) {
+	reader, mock := newMySQLTestReader(t, &mockEmailEnrichmentConfig{enabled: true, source: "netbird_users"})
+
+	now := time.Now()
+	minID := int64(41)
+	mock.ExpectQuery(regexp.QuoteMeta("LEFT JOIN users u1 ON e.initiator_id = u1.id")+
+		`.*`+regexp.QuoteMeta("WHERE e.account_id = ? AND e.id > ? ORDER BY e.id ASC LIMIT ? OFFSET ?")).
+		WithArgs("account1", minID, 100, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "activity", "initiator_id", "target_id", "account_id", "meta", "initiator_email", "target_email"}).
+			AddRow(int64(42), now, 1, "user1", nil, "account1", `{"os":"linux"}`, "alice@example.com", nil))
+
+	events, err := reader.GetEvents(context.Background(), EventQueryOptions{
+		AccountID:  "account1",
+		MinEventID: &minID,
+		OrderAsc:   true,
+		Limit:      100,
+	})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != 42 || events[0].InitiatorEmail != "alice@example.com" || events[0].TargetID != "" {
+		t.Errorf("Unexpected events: %+v", events)
+	}
+	if events[0].ActivityCode == "" {
+		t.Error("Expected activity info to be enriched")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestMySQLGetEvents_Decrypts(t *testing.T) {
+	key := make([]byte, 32)
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	nonce := make([]byte, gcm.NonceSize())
+	encrypted := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("bob@example.com"), nil))
+
+	reader, mock := newMySQLTestReader(t, &keyedEmailConfig{mockEmailEnrichmentConfig{enabled: true, source: "netbird_users"}, key})
+	mock.ExpectQuery("SELECT e.id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "activity", "initiator_id", "target_id", "account_id", "meta", "initiator_email", "target_email"}).
+			AddRow(int64(1), time.Now(), 1, "user1", "user2", "account1", nil, encrypted, "user2"))
+
+	events, err := reader.GetEvents(context.Background(), EventQueryOptions{})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if events[0].InitiatorEmail != "bob@example.com" || events[0].TargetEmail != "user2" {
+		t.Errorf("Expected the encrypted email to be decrypted, got %q and %q", events[0].InitiatorEmail, events[0].TargetEmail)
+	}
+}
+
+func TestMySQLGetEventCount(t *testing.T) {
+	reader, mock := newMySQLTestReader(t, newMockEmailConfig())
+
+	activity := 5
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM events WHERE activity = ?")).
+		WithArgs(activity).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(int64(7)))
+
+	count, err := reader.GetEventCount(context.Background(), EventQueryOptions{Activity: &activity})
+	if err != nil {
+		t.Fatalf("GetEventCount failed: %v", err)
+	}
+	if count != 7 {
+		t.Errorf("Expected 7, got %d", count)
+	}
+}
+
+func TestMySQLWriterCheckpoint(t *testing.T) {
+	reader, mock := newMySQLTestReader(t, newMockEmailConfig())
+	ctx := context.Background()
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM event_processing_checkpoint\n\t\tWHERE consumer_id = ? AND writer_type = ?")).
+		WithArgs("eventsproc-prod-emea", "loki").
+		WillReturnError(sql.ErrNoRows)
+	checkpoint, err := reader.GetWriterCheckpoint(ctx, "eventsproc-prod-emea", "loki")
+	if err != nil || checkpoint != nil {
+		t.Fatalf("Expected no checkpoint, got %+v, %v", checkpoint, err)
+	}
+
+	ts := time.Now()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO event_processing_checkpoint")+`(?s).*`+regexp.QuoteMeta("ON DUPLICATE KEY UPDATE")+
+		`.*`+regexp.QuoteMeta("last_event_id = VALUES(last_event_id)")).
+		WithArgs("eventsproc-prod-emea", "loki", int64(42), ts, int64(10), "node-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := reader.SaveWriterCheckpoint(ctx, &ProcessingCheckpoint{
+		ConsumerID:           "eventsproc-prod-emea",
+		WriterType:           "loki",
+		LastEventID:          42,
+		LastEventTimestamp:   ts,
+		TotalEventsProcessed: 10,
+		ProcessingNode:       "node-1",
+	}); err != nil {
+		t.Fatalf("SaveWriterCheckpoint failed: %v", err)
+	}
+
+	// The single-consumer checkpoint is the writer_type "default" row
+	mock.ExpectQuery("FROM event_processing_checkpoint").
+		WithArgs("eventsproc-prod-emea", LegacyWriterType).
+		WillReturnRows(sqlmock.NewRows([]string{"consumer_id", "writer_type", "last_event_id", "last_event_timestamp", "total_events_processed", "processing_node", "updated_at", "created_at"}).
+			AddRow("eventsproc-prod-emea", LegacyWriterType, int64(7), ts, int64(7), "", ts, ts))
+	checkpoint, err = reader.GetCheckpoint(ctx, "eventsproc-prod-emea")
+	if err != nil || checkpoint == nil || checkpoint.LastEventID != 7 {
+		t.Fatalf("Expected the default checkpoint, got %+v, %v", checkpoint, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// notifyPingInterval is how often an idle listener connection is checked, so
+// a connection that died without a reset is noticed and reconnected.
+const notifyPingInterval = 90 * time.Second
+
+// NotifyOptions configures PostgresEventReader.Listen.
+type NotifyOptions struct {
+	// ConnString opens the dedicated LISTEN connection, normally postgres_url.
+	ConnString string
+
+	// Channel is the NOTIFY channel the events trigger signals.
+	Channel string
+
+	// InstallTrigger installs or updates the trigger before listening.
+	InstallTrigger bool
+
+	// MinReconnectInterval and MaxReconnectInterval bound the backoff
+	// between attempts to reconnect a dropped connection.
+	MinReconnectInterval time.Duration
+	MaxReconnectInterval time.Duration
+
+	// Source labels the listener metric in multi-source mode.
+	Source string
+}
+
+// Listen installs the NOTIFY trigger when asked to and listens on the channel
+// on a dedicated connection. The returned channel receives a value whenever
+// events were inserted, coalesced while the receiver is busy, and after every
+// reconnect, as notifications sent while the connection was down are lost.
+// A dropped connection is re-established in the background. The channel is
+// closed once ctx is cancelled.
+func (er *PostgresEventReader) Listen(ctx context.Context, opts NotifyOptions) (<-chan struct{}, error) {
+	if opts.InstallTrigger {
+		if err := er.InstallNotifyTrigger(ctx, opts.Channel); err != nil {
+			return nil, err
+		}
+	}
+
+	listener := pq.NewListener(opts.ConnString, opts.MinReconnectInterval, opts.MaxReconnectInterval,
+		func(ev pq.ListenerEventType, err error) {
+			switch ev {
+			case pq.ListenerEventConnected:
+				metrics.NotifyListenerUp.WithLabelValues(opts.Source).Set(1)
+				er.logger.Info("Listening for new events", "channel", opts.Channel)
+			case pq.ListenerEventDisconnected:
+				metrics.NotifyListenerUp.WithLabelValues(opts.Source).Set(0)
+				if ctx.Err() == nil {
+					er.logger.Warn("Notify listener disconnected, reconnecting", "channel", opts.Channel, "error", err)
+				}
+			case pq.ListenerEventReconnected:
+				metrics.NotifyListenerUp.WithLabelValues(opts.Source).Set(1)
+				er.logger.Info("Notify listener reconnected", "channel", opts.Channel)
+			case pq.ListenerEventConnectionAttemptFailed:
+				er.logger.Warn("Notify listener failed to reconnect", "channel", opts.Channel, "error", err)
+			}
+		})
+	// Close unblocks Listen when ctx ends while it waits for a connection
+	stop := context.AfterFunc(ctx, func() { _ = listener.Close() })
+
+	if err := listener.Listen(opts.Channel); err != nil {
+		stop()
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to listen on channel %s: %w", opts.Channel, err)
+	}
+
+	wake := make(chan struct{}, 1)
+	go func() {
+		defer close(wake)
+		defer metrics.NotifyListenerUp.WithLabelValues(opts.Source).Set(0)
+
+		ping := time.NewTicker(notifyPingInterval)
+		defer ping.Stop()
+		for {
+			select {
+			case _, ok := <-listener.Notify:
+				if !ok {
+					return
+				}
+				// nil after a reconnect: events may have been missed
+				select {
+				case wake <- struct{}{}:
+				default:
+				}
+			case <-ping.C:
+				go func() { _ = listener.Ping() }()
+			}
+		}
+	}()
+	return wake, nil
+}
+
+// InstallNotifyTrigger creates or updates the trigger of migration
+// 006_events_notify.sql that NOTIFYs channel on every insert into events.
+// An existing trigger is left alone, so NetBird's inserts are never blocked
+// on the table lock CREATE TRIGGER takes.
+func (er *PostgresEventReader) InstallNotifyTrigger(ctx context.Context, channel string) error {
+	tx, err := er.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin notify trigger transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Serialise nodes starting together
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('eventsproc_notify'))`); err != nil {
+		return fmt.Errorf("failed to lock notify trigger installation: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE OR REPLACE FUNCTION idp.eventsproc_notify() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    PERFORM pg_notify(TG_ARGV[0], '');
+    RETURN NULL;
+END;
+$$`); err != nil {
+		return fmt.Errorf("failed to create notify function: %w", err)
+	}
+
+	name := "eventsproc_notify_" + channel
+	var exists bool
+	if err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgrelid = 'events'::regclass AND tgname = $1)`, name,
+	).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up notify trigger: %w", err)
+	}
+	if !exists {
+		// channel is validated as a plain identifier, so it needs no quoting
+		if _, err := tx.ExecContext(ctx, `CREATE TRIGGER `+name+`
+    AFTER INSERT ON events
+    FOR EACH STATEMENT
+    EXECUTE FUNCTION idp.eventsproc_notify('`+channel+`')`); err != nil {
+			return fmt.Errorf("failed to create notify trigger: %w", err)
+		}
+		er.logger.Info("Installed notify trigger on events", "trigger", name, "channel", channel)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit notify trigger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newNotifyTestReader(t *testing.T) (*PostgresEventReader, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewPostgresEventReader(db, logger, newMockEmailConfig()).(*PostgresEventReader), mock
+}
+
+func TestInstallNotifyTrigger_Creates(t *testing.T) {
+	reader, mock := newNotifyTestReader(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE OR REPLACE FUNCTION idp.eventsproc_notify").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS .* FROM pg_trigger").
+		WithArgs("eventsproc_notify_netbird_events").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`CREATE TRIGGER eventsproc_notify_netbird_events\s+AFTER INSERT ON events\s+FOR EACH STATEMENT\s+EXECUTE FUNCTION idp.eventsproc_notify\('netbird_events'\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := reader.InstallNotifyTrigger(context.Background(), "netbird_events"); err != nil {
+		t.Fatalf("InstallNotifyTrigger failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestInstallNotifyTrigger_KeepsExisting(t *testing.T) {
+	reader, mock := newNotifyTestReader(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE OR REPLACE FUNCTION idp.eventsproc_notify").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS .* FROM pg_trigger").
+		WithArgs("eventsproc_notify_netbird_events").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectCommit()
+
+	if err := reader.InstallNotifyTrigger(context.Background(), "netbird_events"); err != nil {
+		t.Fatalf("InstallNotifyTrigger failed: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
+
+func TestListen_InstallError(t *testing.T) {
+	reader, mock := newNotifyTestReader(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE OR REPLACE FUNCTION").WillReturnError(errors.New("permission denied for schema idp"))
+	mock.ExpectRollback()
+
+	_, err := reader.Listen(context.Background(), NotifyOptions{Channel: "netbird_events", InstallTrigger: true})
+	if err == nil {
+		t.Fatal("Expected error when the trigger cannot be installed")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unfulfilled expectations: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"iter"
 	"log/slog"
 	"strings"
 )
@@ -18,31 +19,25 @@ type PostgresEventReader struct {
 
 // NewPostgresEventReader creates a new PostgresEventReader
 func NewPostgresEventReader(db *sql.DB, logger *slog.Logger, emailConf EmailEnrichmentConfig) ReaderInterface {
-	r := &PostgresEventReader{
+	return &PostgresEventReader{
 		db:                  db,
 		logger:              logger,
 		emailEnrichmentConf: emailConf,
+		decryptor:           newDecryptor(emailConf, logger),
 	}
-	key, err := emailConf.GetDecryptionKey()
-	if err != nil {
-		logger.Warn("Failed to load NetBird decryption key, email fields will not be decrypted", "error", err)
-	} else if key != nil {
-		d, err := NewNetbirdDecryptor(key)
-		if err != nil {
-			logger.Warn("Failed to initialise NetBird decryptor", "error", err)
-		} else {
-			r.decryptor = d
-			logger.Info("NetBird AES-GCM decryptor initialised — email fields will be decrypted")
-		}
-	}
-	return r
 }
 
 // buildEmailEnrichmentQuery builds the appropriate SELECT query based on email enrichment configuration
 func (er *PostgresEventReader) buildEmailEnrichmentQuery() string {
+	return emailEnrichmentQuery(er.emailEnrichmentConf, er.logger)
+}
+
+// emailEnrichmentQuery builds the SELECT query for an email enrichment source.
+// The SQL is shared by PostgreSQL and MySQL, where a custom schema is a database.
+func emailEnrichmentQuery(conf EmailEnrichmentConfig, logger *slog.Logger) string {
 	baseQuery := `SELECT e.id, e.timestamp, e.activity, e.initiator_id, e.target_id, e.account_id, e.meta`
 
-	source := er.emailEnrichmentConf.GetSource()
+	source := conf.GetSource()
 
 	switch source {
 	case "idp_okta_users":
@@ -65,8 +60,8 @@ func (er *PostgresEventReader) buildEmailEnrichmentQuery() string {
 
 	case "custom":
 		// Use custom table
-		schema := er.emailEnrichmentConf.GetCustomSchema()
-		table := er.emailEnrichmentConf.GetCustomTable()
+		schema := conf.GetCustomSchema()
+		table := conf.GetCustomTable()
 		return baseQuery + fmt.Sprintf(`,
 		COALESCE(u1.email, e.initiator_id) as initiator_email,
 		COALESCE(u2.email, e.target_id) as target_email
@@ -104,7 +99,7 @@ func (er *PostgresEventReader) buildEmailEnrichmentQuery() string {
 
 	default:
 		// Default to auto
-		er.logger.Warn("Unknown email enrichment source, defaulting to auto", "source", source)
+		logger.Warn("Unknown email enrichment source, defaulting to auto", "source", source)
 		// Create temporary config with auto source to avoid infinite recursion
 		return baseQuery + `,
 		COALESCE(
@@ -127,8 +122,9 @@ func (er *PostgresEventReader) buildEmailEnrichmentQuery() string {
 	}
 }
 
-// GetEvents fetches events from the database based on query options
-func (er *PostgresEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+// eventsQuery builds the SELECT for opts without LIMIT and OFFSET. It returns
+// the query, its arguments and the position of the next placeholder.
+func (er *PostgresEventReader) eventsQuery(opts EventQueryOptions) (string, []any, int) {
 	// Build the query with appropriate email enrichment
 	query := er.buildEmailEnrichmentQuery()
 	conditions := []string{}
@@ -166,18 +162,35 @@ func (er *PostgresEventReader) GetEvents(ctx context.Context, opts EventQueryOpt
 		argPos++
 	}
 
+	if len(opts.EventIDs) > 0 {
+		placeholders := make([]string, len(opts.EventIDs))
+		for i, id := range opts.EventIDs {
+			placeholders[i] = fmt.Sprintf("$%d", argPos)
+			args = append(args, id)
+			argPos++
+		}
+		conditions = append(conditions, "e.id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
 	// Add WHERE clause if there are conditions
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	// Add ORDER BY
+	// Add ORDER BY: by ID, the key MinEventID resumes from
 	if opts.OrderAsc {
-		query += " ORDER BY e.timestamp ASC"
+		query += " ORDER BY e.id ASC"
 	} else {
-		query += " ORDER BY e.timestamp DESC"
+		query += " ORDER BY e.id DESC"
 	}
 
+	return query, args, argPos
+}
+
+// GetEvents fetches events from the database based on query options
+func (er *PostgresEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+	query, args, argPos := er.eventsQuery(opts)
+
 	// Add LIMIT and OFFSET
 	if opts.Limit == 0 {
 		opts.Limit = 1000 // default limit
@@ -197,55 +210,10 @@ func (er *PostgresEventReader) GetEvents(ctx context.Context, opts EventQueryOpt
 	// Parse results
 	events := []Event{}
 	for rows.Next() {
-		var event Event
-		var initiatorID, targetID, accountID, meta, initiatorEmail, targetEmail sql.NullString
-
-		err := rows.Scan(
-			&event.ID,
-			&event.Timestamp,
-			&event.Activity,
-			&initiatorID,
-			&targetID,
-			&accountID,
-			&meta,
-			&initiatorEmail,
-			&targetEmail,
-		)
+		event, err := scanEvent(rows, er.decryptor)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-
-		// Handle NULL values
-		if initiatorID.Valid {
-			event.InitiatorID = initiatorID.String
-		}
-		if targetID.Valid {
-			event.TargetID = targetID.String
+			return nil, err
 		}
-		if accountID.Valid {
-			event.AccountID = accountID.String
-		}
-		if meta.Valid {
-			event.Meta = meta.String
-		}
-		if initiatorEmail.Valid {
-			event.InitiatorEmail = initiatorEmail.String
-		}
-		if targetEmail.Valid {
-			event.TargetEmail = targetEmail.String
-		}
-
-		// Decrypt email fields if NetBird AES-GCM decryption is configured.
-		// NetBird encrypts name/email columns before writing to the database;
-		// without decryption, these fields contain base64 ciphertext blobs.
-		if er.decryptor != nil {
-			event.InitiatorEmail = er.decryptor.Decrypt(event.InitiatorEmail)
-			event.TargetEmail = er.decryptor.Decrypt(event.TargetEmail)
-		}
-
-		// Enrich with activity name and code
-		EnrichActivityInfo(&event)
-
 		events = append(events, event)
 	}
 
@@ -257,6 +225,54 @@ func (er *PostgresEventReader) GetEvents(ctx context.Context, opts EventQueryOpt
 	return events, nil
 }
 
+// streamCursor is the name of the cursor StreamEvents declares.
+const streamCursor = "eventsproc_events"
+
+// StreamEvents streams the events matching opts through a server-side cursor
+// in a read-only transaction, fetching FetchSize rows per round trip. A Limit
+// of 0 streams every matching event; Offset is only used with a Limit. The
+// transaction, and the snapshot it holds, lasts until the stream ends.
+func (er *PostgresEventReader) StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error] {
+	query, args, argPos := er.eventsQuery(opts)
+	if opts.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argPos, argPos+1)
+		args = append(args, opts.Limit, opts.Offset)
+	}
+	fetchSize := opts.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = defaultFetchSize
+	}
+
+	return func(yield func(Event, error) bool) {
+		tx, err := er.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			yield(Event{}, fmt.Errorf("failed to begin cursor transaction: %w", err))
+			return
+		}
+		// Ending the transaction closes the cursor
+		defer func() { _ = tx.Rollback() }()
+
+		er.logger.Debug("Declaring cursor", "query", query, "args", args, "fetch_size", fetchSize)
+		if _, err := tx.ExecContext(ctx, "DECLARE "+streamCursor+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+			yield(Event{}, fmt.Errorf("failed to declare cursor: %w", err))
+			return
+		}
+
+		fetch := fmt.Sprintf("FETCH FORWARD %d FROM %s", fetchSize, streamCursor)
+		for {
+			rows, err := tx.QueryContext(ctx, fetch)
+			if err != nil {
+				yield(Event{}, fmt.Errorf("failed to fetch from cursor: %w", err))
+				return
+			}
+			n, more := yieldRows(rows, er.decryptor, yield)
+			if !more || n < fetchSize {
+				return
+			}
+		}
+	}
+}
+
 // GetEventCount returns the total count of events matching the query options
 func (er *PostgresEventReader) GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error) {
 	query := "SELECT COUNT(*) FROM events"
@@ -302,7 +318,7 @@ func (er *PostgresEventReader) GetEventCount(ctx context.Context, opts EventQuer
 }
 
 // GetWriterCheckpoint retrieves the checkpoint for a specific consumer/writer combination
-// Deprecated: Use GetCheckpoint for single-checkpoint model (migration 003+)
+// Requires the (consumer_id, writer_type) primary key of migration 004
 func (er *PostgresEventReader) GetWriterCheckpoint(ctx context.Context, consumerID, writerType string) (*ProcessingCheckpoint, error) {
 	query := `
 		SELECT consumer_id, writer_type, last_event_id, last_event_timestamp,
@@ -349,7 +365,7 @@ func (er *PostgresEventReader) GetWriterCheckpoint(ctx context.Context, consumer
 }
 
 // SaveWriterCheckpoint saves or updates the checkpoint for a specific consumer/writer
-// Deprecated: Use SaveCheckpoint for single-checkpoint model (migration 003+)
+// Requires the (consumer_id, writer_type) primary key of migration 004
 func (er *PostgresEventReader) SaveWriterCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
 	query := `
 		INSERT INTO idp.event_processing_checkpoint
@@ -389,7 +405,7 @@ func (er *PostgresEventReader) SaveWriterCheckpoint(ctx context.Context, checkpo
 }
 
 // GetCheckpoint retrieves the checkpoint for a consumer (single checkpoint model)
-// This is used after migration 003 which removes per-writer checkpoints
+// Deprecated: Only valid on the migration 003 schema; use GetWriterCheckpoint
 func (er *PostgresEventReader) GetCheckpoint(ctx context.Context, consumerID string) (*ProcessingCheckpoint, error) {
 	query := `
 		SELECT consumer_id, last_event_id, last_event_timestamp,
@@ -433,7 +449,7 @@ func (er *PostgresEventReader) GetCheckpoint(ctx context.Context, consumerID str
 }
 
 // SaveCheckpoint saves or updates the checkpoint for a consumer (single checkpoint model)
-// This is used after migration 003 which removes per-writer checkpoints
+// Deprecated: Only valid on the migration 003 schema; use SaveWriterCheckpoint
 func (er *PostgresEventReader) SaveCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
 	query := `
 		INSERT INTO idp.event_processing_checkpoint
@@ -1,19 +1,32 @@
 package events
 
-import "context"
+import (
+	"context"
+	"iter"
+)
 
 // ReaderInterface defines the interface for reading events from the database
 type ReaderInterface interface {
 	// GetEvents fetches events from the database based on query options
 	GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error)
 
+	// StreamEvents yields the events matching opts, reading rows as they are
+	// consumed instead of collecting them in a slice: through a cursor on
+	// PostgreSQL, FetchSize rows per query on MySQL and SQLite. A Limit of 0
+	// streams every matching event. The stream stops at the first error,
+	// which is yielded with a zero Event; the query is closed once the
+	// consumer stops ranging over it.
+	StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error]
+
 	// GetEventCount returns the total count of events matching the query options
 	GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error)
 
-	// GetCheckpoint retrieves the last processing checkpoint for a consumer (deprecated: use GetWriterCheckpoint)
+	// GetCheckpoint retrieves the last processing checkpoint for a consumer
+	// (deprecated: requires the single-checkpoint schema of migration 003; use GetWriterCheckpoint)
 	GetCheckpoint(ctx context.Context, consumerID string) (*ProcessingCheckpoint, error)
 
-	// SaveCheckpoint saves or updates the processing checkpoint for a consumer (deprecated: use SaveWriterCheckpoint)
+	// SaveCheckpoint saves or updates the processing checkpoint for a consumer
+	// (deprecated: requires the single-checkpoint schema of migration 003; use SaveWriterCheckpoint)
 	SaveCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error
 
 	// GetWriterCheckpoint retrieves the checkpoint for a specific consumer/writer combination
@@ -32,7 +45,16 @@ type EmailEnrichmentConfig interface {
 	GetCustomSchema() string
 	GetCustomTable() string
 	IsEnabled() bool
-	// GetDecryptionKey returns the raw AES-256 key for decrypting NetBird's
-	// encrypted database columns, or nil if decryption is not configured.
-	GetDecryptionKey() ([]byte, error)
+	// GetDecryptionKeys returns the keyring for decrypting NetBird's
+	// encrypted database columns, current key first, or nil if decryption is
+	// not configured.
+	GetDecryptionKeys() ([]DecryptionKey, error)
+}
+
+// Listener is implemented by readers that signal newly inserted events, so
+// the processor need not wait for the next poll (PostgreSQL push mode).
+type Listener interface {
+	// Listen returns a channel that receives a value when new events may be
+	// available. It is closed once ctx is cancelled.
+	Listen(ctx context.Context, opts NotifyOptions) (<-chan struct{}, error)
 }
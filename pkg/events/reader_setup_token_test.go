@@ -38,7 +38,7 @@ func (m *mockEmailEnrichmentConfigSetupToken) IsEnabled() bool {
 	return m.enabled
 }
 
-func (m *mockEmailEnrichmentConfigSetupToken) GetDecryptionKey() ([]byte, error) {
+func (m *mockEmailEnrichmentConfigSetupToken) GetDecryptionKeys() ([]DecryptionKey, error) {
 	return nil, nil
 }
 
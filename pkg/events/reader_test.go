@@ -38,7 +38,7 @@ func (m *mockEmailEnrichmentConfig) IsEnabled() bool {
 	return m.enabled
 }
 
-func (m *mockEmailEnrichmentConfig) GetDecryptionKey() ([]byte, error) {
+func (m *mockEmailEnrichmentConfig) GetDecryptionKeys() ([]DecryptionKey, error) {
 	return nil, nil
 }
 
@@ -266,7 +266,7 @@ func TestGetEvents_WithMinEventID(t *testing.T) {
 		AddRow(int64(6), now, 1, "initiator1", "target1", "account_1", `{"key":"value"}`, "initiator1@example.com", "target1@example.com").
 		AddRow(int64(7), now, 2, "initiator2", "target2", "account_1", `{"key":"value2"}`, "initiator2@example.com", "target2@example.com")
 
-	mock.ExpectQuery("SELECT e.id, e.timestamp, e.activity, e.initiator_id, e.target_id, e.account_id, e.meta.*WHERE e.id > \\$1 ORDER BY e.timestamp ASC").
+	mock.ExpectQuery("SELECT e.id, e.timestamp, e.activity, e.initiator_id, e.target_id, e.account_id, e.meta.*WHERE e.id > \\$1 ORDER BY e.id ASC").
 		WithArgs(minEventID, 100, 0).
 		WillReturnRows(rows)
 
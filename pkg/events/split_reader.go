@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// SplitReader implements ReaderInterface on separate databases: events are
+// read from one, user emails looked up in another and checkpoints kept in a
+// third, e.g. NetBird's events.db, its main store, and a writable database
+// next to read-only production stores. Any of them may be the same database.
+type SplitReader struct {
+	events      ReaderInterface
+	enricher    *UserEnricher // nil when events already carry their emails
+	checkpoints ReaderInterface
+}
+
+// NewSplitReader combines the readers. events reads the events table;
+// when enricher is set it should not enrich itself (see NoEnrichment).
+// checkpoints serves the checkpoint methods.
+func NewSplitReader(events ReaderInterface, enricher *UserEnricher, checkpoints ReaderInterface) *SplitReader {
+	return &SplitReader{events: events, enricher: enricher, checkpoints: checkpoints}
+}
+
+// GetEvents reads events from the events database and enriches them.
+func (r *SplitReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+	result, err := r.events.GetEvents(ctx, opts)
+	if err != nil || r.enricher == nil || len(result) == 0 {
+		return result, err
+	}
+	if err := r.enricher.Enrich(ctx, result); err != nil {
+		return nil, fmt.Errorf("failed to enrich events: %w", err)
+	}
+	return result, nil
+}
+
+// StreamEvents streams events from the events database. With an enricher,
+// the stream is enriched in chunks of FetchSize events, one lookup per chunk.
+func (r *SplitReader) StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error] {
+	if r.enricher == nil {
+		return r.events.StreamEvents(ctx, opts)
+	}
+	chunkSize := opts.FetchSize
+	if chunkSize <= 0 {
+		chunkSize = defaultFetchSize
+	}
+
+	return func(yield func(Event, error) bool) {
+		chunk := make([]Event, 0, chunkSize)
+		flush := func() bool {
+			if err := r.enricher.Enrich(ctx, chunk); err != nil {
+				yield(Event{}, fmt.Errorf("failed to enrich events: %w", err))
+				return false
+			}
+			for _, evt := range chunk {
+				if !yield(evt, nil) {
+					return false
+				}
+			}
+			chunk = chunk[:0]
+			return true
+		}
+		for evt, err := range r.events.StreamEvents(ctx, opts) {
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+			chunk = append(chunk, evt)
+			if len(chunk) == chunkSize && !flush() {
+				return
+			}
+		}
+		if len(chunk) > 0 {
+			flush()
+		}
+	}
+}
+
+// GetEventCount counts events in the events database.
+func (r *SplitReader) GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error) {
+	return r.events.GetEventCount(ctx, opts)
+}
+
+// GetCheckpoint retrieves the single checkpoint for a consumer from the checkpoint database.
+func (r *SplitReader) GetCheckpoint(ctx context.Context, consumerID string) (*ProcessingCheckpoint, error) {
+	return r.checkpoints.GetCheckpoint(ctx, consumerID)
+}
+
+// SaveCheckpoint saves the single checkpoint for a consumer in the checkpoint database.
+func (r *SplitReader) SaveCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
+	return r.checkpoints.SaveCheckpoint(ctx, checkpoint)
+}
+
+// GetWriterCheckpoint retrieves a writer's checkpoint from the checkpoint database.
+func (r *SplitReader) GetWriterCheckpoint(ctx context.Context, consumerID, writerType string) (*ProcessingCheckpoint, error) {
+	return r.checkpoints.GetWriterCheckpoint(ctx, consumerID, writerType)
+}
+
+// SaveWriterCheckpoint saves a writer's checkpoint in the checkpoint database.
+func (r *SplitReader) SaveWriterCheckpoint(ctx context.Context, checkpoint *ProcessingCheckpoint) error {
+	return r.checkpoints.SaveWriterCheckpoint(ctx, checkpoint)
+}
+
+// Listen listens for new events on the events database when its reader
+// supports push mode.
+func (r *SplitReader) Listen(ctx context.Context, opts NotifyOptions) (<-chan struct{}, error) {
+	listener, ok := r.events.(Listener)
+	if !ok {
+		return nil, errors.New("the events database does not support push mode")
+	}
+	return listener.Listen(ctx, opts)
+}
+
+// Close closes every database. Closing a database shared by several
+// parts more than once is harmless.
+func (r *SplitReader) Close() error {
+	errs := []error{r.events.Close(), r.checkpoints.Close()}
+	if r.enricher != nil {
+		errs = append(errs, r.enricher.Close())
+	}
+	return errors.Join(errs...)
+}
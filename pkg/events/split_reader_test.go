@@ -0,0 +1,139 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newTestDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db, mock
+}
+
+func TestSplitReader_EnrichesFromOtherDatabase(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	eventsDB, eventsMock := newTestDB(t)
+	usersDB, usersMock := newTestDB(t)
+	checkpointDB, checkpointMock := newTestDB(t)
+
+	reader := NewSplitReader(
+		NewSQLiteEventReader(eventsDB, logger, NoEnrichment),
+		NewUserEnricher(usersDB, "postgres", logger, &mockEmailEnrichmentConfig{enabled: true, source: "auto"}),
+		NewPostgresEventReader(checkpointDB, logger, NoEnrichment),
+	)
+
+	now := time.Now()
+	// The events database is read without JOINs
+	eventsMock.ExpectQuery(`SELECT e.id.*FROM events e WHERE e.id > \? ORDER BY e.id ASC`).
+		WithArgs(int64(6), 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "activity", "initiator_id", "target_id", "account_id", "meta", "initiator_email", "target_email"}).
+			AddRow(int64(7), now, 1, "u1", "u2", "acc", "{}", "u1", "u2").
+			AddRow(int64(8), now, 1, "u1", "u3", "acc", "{}", "u1", "u3"))
+	// okta_users wins over users; users falls back to the name
+	usersMock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, NULL FROM okta_users WHERE id IN ($1, $2, $3)")).
+		WithArgs("u1", "u2", "u3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).AddRow("u1", "alice@okta.example", nil))
+	usersMock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, name FROM users WHERE id IN ($1, $2, $3)")).
+		WithArgs("u1", "u2", "u3").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).
+			AddRow("u1", "alice@netbird.example", nil).
+			AddRow("u2", nil, "Service Bob"))
+
+	minID := int64(6)
+	events, err := reader.GetEvents(context.Background(), EventQueryOptions{MinEventID: &minID, Limit: 2, OrderAsc: true})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].InitiatorEmail != "alice@okta.example" || events[0].TargetEmail != "Service Bob" || events[1].TargetEmail != "u3" {
+		t.Errorf("Unexpected enrichment: %+v", events)
+	}
+
+	// Checkpoints go to their own database
+	checkpointMock.ExpectExec("INSERT INTO idp.event_processing_checkpoint").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	if err := reader.SaveWriterCheckpoint(context.Background(), &ProcessingCheckpoint{ConsumerID: "c", WriterType: "loki", LastEventID: 8}); err != nil {
+		t.Fatalf("SaveWriterCheckpoint failed: %v", err)
+	}
+
+	for name, mock := range map[string]sqlmock.Sqlmock{"events": eventsMock, "users": usersMock, "checkpoint": checkpointMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet %s expectations: %v", name, err)
+		}
+	}
+}
+
+func TestSplitReader_EventIDs(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	eventsDB, eventsMock := newTestDB(t)
+	usersDB, usersMock := newTestDB(t)
+	checkpointDB, checkpointMock := newTestDB(t)
+
+	reader := NewSplitReader(
+		NewSQLiteEventReader(eventsDB, logger, NoEnrichment),
+		NewUserEnricher(usersDB, "postgres", logger, &mockEmailEnrichmentConfig{enabled: true, source: "netbird_users"}),
+		NewPostgresEventReader(checkpointDB, logger, NoEnrichment),
+	)
+
+	// Gap re-checks look up the missing IDs directly
+	eventsMock.ExpectQuery(`SELECT e.id.*FROM events e WHERE e.id IN \(\?, \?\) ORDER BY e.id ASC`).
+		WithArgs(int64(7), int64(9), 2, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "timestamp", "activity", "initiator_id", "target_id", "account_id", "meta", "initiator_email", "target_email"}).
+			AddRow(int64(9), time.Now(), 1, "u1", "u1", "acc", "{}", "u1", "u1"))
+	usersMock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, name FROM users WHERE id IN ($1)")).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).AddRow("u1", "alice@netbird.example", nil))
+
+	events, err := reader.GetEvents(context.Background(), EventQueryOptions{EventIDs: []int64{7, 9}, Limit: 2, OrderAsc: true})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if len(events) != 1 || events[0].ID != 9 {
+		t.Fatalf("Expected event 9, got %+v", events)
+	}
+	if events[0].InitiatorEmail != "alice@netbird.example" {
+		t.Errorf("Unexpected enrichment: %+v", events[0])
+	}
+
+	for name, mock := range map[string]sqlmock.Sqlmock{"events": eventsMock, "users": usersMock, "checkpoint": checkpointMock} {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet %s expectations: %v", name, err)
+		}
+	}
+}
+
+func TestUserEnricher_SQLiteSources(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, mock := newTestDB(t)
+
+	// okta_users does not exist in NetBird's SQLite store
+	enricher := NewUserEnricher(db, "sqlite", logger, &mockEmailEnrichmentConfig{enabled: true, source: "auto"})
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, email, name FROM users WHERE id IN (?)")).
+		WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name"}).AddRow("u1", "alice@example.com", "Alice"))
+
+	events := []Event{{ID: 1, InitiatorID: "u1"}}
+	if err := enricher.Enrich(context.Background(), events); err != nil {
+		t.Fatalf("Enrich failed: %v", err)
+	}
+	if events[0].InitiatorEmail != "alice@example.com" || events[0].TargetEmail != "" {
+		t.Errorf("Unexpected enrichment: %+v", events[0])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"iter"
 	"log/slog"
 	"strings"
 )
@@ -23,24 +24,12 @@ type SQLiteEventReader struct {
 
 // NewSQLiteEventReader creates a new SQLiteEventReader
 func NewSQLiteEventReader(db *sql.DB, logger *slog.Logger, emailConf EmailEnrichmentConfig) ReaderInterface {
-	r := &SQLiteEventReader{
+	return &SQLiteEventReader{
 		db:                  db,
 		logger:              logger,
 		emailEnrichmentConf: emailConf,
+		decryptor:           newDecryptor(emailConf, logger),
 	}
-	key, err := emailConf.GetDecryptionKey()
-	if err != nil {
-		logger.Warn("Failed to load NetBird decryption key, email fields will not be decrypted", "error", err)
-	} else if key != nil {
-		d, err := NewNetbirdDecryptor(key)
-		if err != nil {
-			logger.Warn("Failed to initialise NetBird decryptor", "error", err)
-		} else {
-			r.decryptor = d
-			logger.Info("NetBird AES-GCM decryptor initialised — email fields will be decrypted")
-		}
-	}
-	return r
 }
 
 // buildEmailEnrichmentQuery builds the SELECT query for SQLite (no schema prefixes)
@@ -95,8 +84,8 @@ func (r *SQLiteEventReader) buildEmailEnrichmentQuery() string {
 	}
 }
 
-// GetEvents fetches events from SQLite using ? placeholders
-func (r *SQLiteEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+// eventsQuery builds the SELECT for opts without LIMIT and OFFSET.
+func (r *SQLiteEventReader) eventsQuery(opts EventQueryOptions) (string, []any) {
 	query := r.buildEmailEnrichmentQuery()
 	conditions := []string{}
 	args := []any{}
@@ -126,16 +115,29 @@ func (r *SQLiteEventReader) GetEvents(ctx context.Context, opts EventQueryOption
 		args = append(args, *opts.MinEventID)
 	}
 
+	if len(opts.EventIDs) > 0 {
+		conditions = append(conditions, "e.id IN ("+strings.TrimSuffix(strings.Repeat("?, ", len(opts.EventIDs)), ", ")+")")
+		for _, id := range opts.EventIDs {
+			args = append(args, id)
+		}
+	}
+
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	if opts.OrderAsc {
-		query += " ORDER BY e.timestamp ASC"
+		query += " ORDER BY e.id ASC"
 	} else {
-		query += " ORDER BY e.timestamp DESC"
+		query += " ORDER BY e.id DESC"
 	}
 
+	return query, args
+}
+
+// GetEvents fetches events from SQLite using ? placeholders
+func (r *SQLiteEventReader) GetEvents(ctx context.Context, opts EventQueryOptions) ([]Event, error) {
+	query, args := r.eventsQuery(opts)
 	if opts.Limit == 0 {
 		opts.Limit = 1000
 	}
@@ -144,70 +146,30 @@ func (r *SQLiteEventReader) GetEvents(ctx context.Context, opts EventQueryOption
 
 	r.logger.Debug("Executing SQLite query", "query", query, "args", args)
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	result, err := queryEvents(ctx, r.db, query, args, r.decryptor)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query events: %w", err)
-	}
-	defer func() { _ = rows.Close() }()
-
-	result := []Event{}
-	for rows.Next() {
-		var event Event
-		var initiatorID, targetID, accountID, meta, initiatorEmail, targetEmail sql.NullString
-
-		if err := rows.Scan(
-			&event.ID,
-			&event.Timestamp,
-			&event.Activity,
-			&initiatorID,
-			&targetID,
-			&accountID,
-			&meta,
-			&initiatorEmail,
-			&targetEmail,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan event: %w", err)
-		}
-
-		if initiatorID.Valid {
-			event.InitiatorID = initiatorID.String
-		}
-		if targetID.Valid {
-			event.TargetID = targetID.String
-		}
-		if accountID.Valid {
-			event.AccountID = accountID.String
-		}
-		if meta.Valid {
-			event.Meta = meta.String
-		}
-		if initiatorEmail.Valid {
-			event.InitiatorEmail = initiatorEmail.String
-		}
-		if targetEmail.Valid {
-			event.TargetEmail = targetEmail.String
-		}
-
-		// Decrypt email fields if NetBird AES-GCM decryption is configured.
-		// NetBird encrypts name/email columns before writing to the database;
-		// without decryption, these fields contain base64 ciphertext blobs.
-		if r.decryptor != nil {
-			event.InitiatorEmail = r.decryptor.Decrypt(event.InitiatorEmail)
-			event.TargetEmail = r.decryptor.Decrypt(event.TargetEmail)
-		}
-
-		EnrichActivityInfo(&event)
-		result = append(result, event)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating rows: %w", err)
+		return nil, err
 	}
 
 	r.logger.Info("Fetched events", "count", len(result))
 	return result, nil
 }
 
+// StreamEvents streams the events matching opts, FetchSize rows per query
+// (see streamChunks). An open statement holds a shared lock on the database
+// file, and a checkpoint saved to the same file while the consumer sends the
+// events would fail with "database is locked".
+func (r *SQLiteEventReader) StreamEvents(ctx context.Context, opts EventQueryOptions) iter.Seq2[Event, error] {
+	return streamChunks(opts, func(opts EventQueryOptions, limit int) ([]Event, error) {
+		query, args := r.eventsQuery(opts)
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, limit, opts.Offset)
+
+		r.logger.Debug("Streaming SQLite query", "query", query, "args", args)
+		return queryEvents(ctx, r.db, query, args, r.decryptor)
+	})
+}
+
 // GetEventCount returns the total count of events matching the query options
 func (r *SQLiteEventReader) GetEventCount(ctx context.Context, opts EventQueryOptions) (int64, error) {
 	query := "SELECT COUNT(*) FROM events"
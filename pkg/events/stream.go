@@ -0,0 +1,146 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+)
+
+// defaultFetchSize is the number of rows per cursor round trip when
+// EventQueryOptions.FetchSize is not set.
+const defaultFetchSize = 1000
+
+// scanEvent scans one row of the events query: the event columns, then the
+// initiator and target emails. Emails are decrypted when decryptor is set.
+func scanEvent(rows *sql.Rows, decryptor *NetbirdDecryptor) (Event, error) {
+	var event Event
+	var initiatorID, targetID, accountID, meta, initiatorEmail, targetEmail sql.NullString
+
+	if err := rows.Scan(
+		&event.ID,
+		&event.Timestamp,
+		&event.Activity,
+		&initiatorID,
+		&targetID,
+		&accountID,
+		&meta,
+		&initiatorEmail,
+		&targetEmail,
+	); err != nil {
+		return Event{}, fmt.Errorf("failed to scan event: %w", err)
+	}
+
+	// Handle NULL values
+	event.InitiatorID = initiatorID.String
+	event.TargetID = targetID.String
+	event.AccountID = accountID.String
+	event.Meta = meta.String
+	event.InitiatorEmail = initiatorEmail.String
+	event.TargetEmail = targetEmail.String
+
+	// Decrypt email fields if NetBird AES-GCM decryption is configured.
+	// NetBird encrypts name/email columns before writing to the database;
+	// without decryption, these fields contain base64 ciphertext blobs.
+	if decryptor != nil {
+		event.InitiatorEmail = decryptor.Decrypt(event.InitiatorEmail)
+		event.TargetEmail = decryptor.Decrypt(event.TargetEmail)
+	}
+
+	// Enrich with activity name and code
+	EnrichActivityInfo(&event)
+	return event, nil
+}
+
+// yieldRows passes the events of rows to yield one by one and closes rows.
+// It returns the number of rows read and whether the consumer wants more;
+// a scan or iteration error is yielded and ends the stream.
+func yieldRows(rows *sql.Rows, decryptor *NetbirdDecryptor, yield func(Event, error) bool) (int, bool) {
+	defer func() { _ = rows.Close() }()
+	n := 0
+	for rows.Next() {
+		event, err := scanEvent(rows, decryptor)
+		if err != nil {
+			yield(Event{}, err)
+			return n, false
+		}
+		n++
+		if !yield(event, nil) {
+			return n, false
+		}
+	}
+	if err := rows.Err(); err != nil {
+		yield(Event{}, fmt.Errorf("error iterating rows: %w", err))
+		return n, false
+	}
+	return n, true
+}
+
+// queryEvents runs an events query and reads all its rows. The rows are
+// closed when it returns.
+func queryEvents(ctx context.Context, db *sql.DB, query string, args []any, decryptor *NetbirdDecryptor) ([]Event, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := []Event{}
+	for rows.Next() {
+		event, err := scanEvent(rows, decryptor)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return result, nil
+}
+
+// streamChunks streams the events matching opts, FetchSize events per call
+// of fetch, which reads a chunk with the given limit and closes its query
+// before the events are yielded. No query stays open while the consumer sends
+// the events and waits out retries. In ascending order the next chunk resumes
+// after the last event ID, otherwise at the next offset. A Limit of 0 streams
+// every matching event.
+func streamChunks(opts EventQueryOptions, fetch func(opts EventQueryOptions, limit int) ([]Event, error)) iter.Seq2[Event, error] {
+	fetchSize := opts.FetchSize
+	if fetchSize <= 0 {
+		fetchSize = defaultFetchSize
+	}
+
+	return func(yield func(Event, error) bool) {
+		remaining := opts.Limit
+		for {
+			limit := fetchSize
+			if opts.Limit > 0 && remaining < limit {
+				limit = remaining
+			}
+			chunk, err := fetch(opts, limit)
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+			for _, event := range chunk {
+				if !yield(event, nil) {
+					return
+				}
+			}
+
+			remaining -= len(chunk)
+			if len(chunk) < limit || (opts.Limit > 0 && remaining == 0) {
+				return
+			}
+			if opts.OrderAsc {
+				lastID := chunk[len(chunk)-1].ID
+				opts.MinEventID = &lastID
+				opts.Offset = 0
+			} else {
+				opts.Offset += len(chunk)
+			}
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The benchmarks compare reading a backfill of benchEvents events in
+// batch_size batches, one LIMIT query each, with a single stream consumed
+// in batches of the same size. They run against benchDriver, an in-memory
+// driver that generates the rows, so they measure the reader and
+// database/sql rather than a database:
+//
+//	go test ./pkg/events -run '^$' -bench ReadEvents -benchmem
+const (
+	benchEvents    = 100_000
+	benchBatchSize = 10_000
+)
+
+func init() {
+	sql.Register("eventsbench", benchDriver{})
+}
+
+func BenchmarkReadEvents(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	conf := &mockEmailEnrichmentConfig{enabled: true, source: "none"}
+	readers := []struct {
+		name string
+		new  func(*sql.DB) ReaderInterface
+	}{
+		{"postgres", func(db *sql.DB) ReaderInterface { return NewPostgresEventReader(db, logger, conf) }},
+		{"sqlite", func(db *sql.DB) ReaderInterface { return NewSQLiteEventReader(db, logger, conf) }},
+	}
+
+	for _, r := range readers {
+		db, err := sql.Open("eventsbench", strconv.Itoa(benchEvents))
+		if err != nil {
+			b.Fatal(err)
+		}
+		reader := r.new(db)
+
+		b.Run(r.name+"/batches", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				n := 0
+				var after int64
+				for {
+					batch, err := reader.GetEvents(context.Background(), EventQueryOptions{MinEventID: &after, Limit: benchBatchSize, OrderAsc: true})
+					if err != nil {
+						b.Fatal(err)
+					}
+					n += len(batch)
+					if len(batch) < benchBatchSize {
+						break
+					}
+					after = batch[len(batch)-1].ID
+				}
+				if n != benchEvents {
+					b.Fatalf("read %d events", n)
+				}
+			}
+			b.ReportMetric(float64(b.N*benchEvents)/b.Elapsed().Seconds(), "events/s")
+		})
+
+		b.Run(r.name+"/stream", func(b *testing.B) {
+			b.ReportAllocs()
+			batch := make([]Event, 0, benchBatchSize)
+			for b.Loop() {
+				n := 0
+				var after int64
+				for evt, err := range reader.StreamEvents(context.Background(), EventQueryOptions{MinEventID: &after, OrderAsc: true}) {
+					if err != nil {
+						b.Fatal(err)
+					}
+					batch = append(batch, evt)
+					if len(batch) == benchBatchSize {
+						n += len(batch)
+						batch = batch[:0]
+					}
+				}
+				n += len(batch)
+				batch = batch[:0]
+				if n != benchEvents {
+					b.Fatalf("read %d events", n)
+				}
+			}
+			b.ReportMetric(float64(b.N*benchEvents)/b.Elapsed().Seconds(), "events/s")
+		})
+
+		_ = db.Close()
+	}
+}
+
+// benchDriver serves an events table of n rows, n being the data source name.
+// It understands the queries of the readers: a SELECT with an optional
+// "e.id > ?" condition and LIMIT, and DECLARE and FETCH FORWARD for cursors.
+type benchDriver struct{}
+
+func (benchDriver) Open(name string) (driver.Conn, error) {
+	n, err := strconv.ParseInt(name, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &benchConn{total: n}, nil
+}
+
+type benchConn struct {
+	total  int64
+	cursor int64 // ID of the last row fetched from the cursor
+}
+
+func (c *benchConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("eventsbench: prepared statements are not supported")
+}
+func (c *benchConn) Close() error              { return nil }
+func (c *benchConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *benchConn) Commit() error             { return nil }
+func (c *benchConn) Rollback() error           { return nil }
+
+func (c *benchConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *benchConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if !strings.HasPrefix(query, "DECLARE") {
+		return nil, fmt.Errorf("eventsbench: unexpected exec %q", query)
+	}
+	c.cursor, _ = benchRange(query, args)
+	return driver.ResultNoRows, nil
+}
+
+func (c *benchConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if strings.HasPrefix(query, "FETCH FORWARD") {
+		var n int64
+		if _, err := fmt.Sscanf(query, "FETCH FORWARD %d", &n); err != nil {
+			return nil, err
+		}
+		from := c.cursor
+		c.cursor = min(c.cursor+n, c.total)
+		return &benchRows{next: from + 1, last: c.cursor}, nil
+	}
+	after, limit := benchRange(query, args)
+	last := c.total
+	if limit > 0 {
+		last = min(after+limit, c.total)
+	}
+	return &benchRows{next: after + 1, last: last}, nil
+}
+
+// benchRange returns the ID after which the query starts and its LIMIT, 0
+// without one.
+func benchRange(query string, args []driver.NamedValue) (after, limit int64) {
+	if strings.Contains(query, "e.id >") {
+		after = args[0].Value.(int64)
+	}
+	if strings.Contains(query, "LIMIT") {
+		limit = args[len(args)-2].Value.(int64)
+	}
+	return after, limit
+}
+
+var benchTimestamp = time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+
+type benchRows struct {
+	next, last int64
+}
+
+func (r *benchRows) Columns() []string { return streamCols }
+func (r *benchRows) Close() error      { return nil }
+
+func (r *benchRows) Next(dest []driver.Value) error {
+	if r.next > r.last {
+		return io.EOF
+	}
+	dest[0] = r.next
+	dest[1] = benchTimestamp
+	dest[2] = int64(1)
+	dest[3] = "d3f1c2a0-user"
+	dest[4] = "a9b8c7d6-peer"
+	dest[5] = "c0ffee00-account"
+	dest[6] = `{"fqdn":"peer1.netbird.cloud","ip":"100.64.0.1"}`
+	dest[7] = "alice@example.com"
+	dest[8] = "peer1.netbird.cloud"
+	r.next++
+	return nil
+}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var streamCols = []string{"id", "timestamp", "activity", "initiator_id", "target_id", "account_id", "meta", "initiator_email", "target_email"}
+
+func TestPostgresStreamEvents_Cursor(t *testing.T) {
+	db, mock := newTestDB(t)
+	reader := NewPostgresEventReader(db, slog.New(slog.NewTextHandler(io.Discard, nil)), &mockEmailEnrichmentConfig{enabled: true, source: "none"})
+
+	now := time.Now()
+	minID := int64(10)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DECLARE eventsproc_events NO SCROLL CURSOR FOR SELECT") +
+		".*" + regexp.QuoteMeta("WHERE e.id > $1 ORDER BY e.id ASC")).
+		WithArgs(minID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("FETCH FORWARD 2 FROM eventsproc_events")).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(11), now, 1, "u1", nil, "acc", "{}", "u1", nil).
+			AddRow(int64(12), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+	mock.ExpectQuery(regexp.QuoteMeta("FETCH FORWARD 2 FROM eventsproc_events")).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(13), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+	mock.ExpectRollback()
+
+	var ids []int64
+	for evt, err := range reader.StreamEvents(context.Background(), EventQueryOptions{MinEventID: &minID, OrderAsc: true, FetchSize: 2}) {
+		if err != nil {
+			t.Fatalf("StreamEvents failed: %v", err)
+		}
+		ids = append(ids, evt.ID)
+	}
+	if len(ids) != 3 || ids[0] != 11 || ids[2] != 13 {
+		t.Errorf("Expected events 11-13, got %v", ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresStreamEvents_StopEarly(t *testing.T) {
+	db, mock := newTestDB(t)
+	reader := NewPostgresEventReader(db, slog.New(slog.NewTextHandler(io.Discard, nil)), &mockEmailEnrichmentConfig{enabled: true, source: "none"})
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE eventsproc_events").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH FORWARD 1000 FROM eventsproc_events").
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(1), now, 1, "u1", nil, "acc", "{}", "u1", nil).
+			AddRow(int64(2), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+	mock.ExpectRollback()
+
+	count := 0
+	for _, err := range reader.StreamEvents(context.Background(), EventQueryOptions{OrderAsc: true}) {
+		if err != nil {
+			t.Fatalf("StreamEvents failed: %v", err)
+		}
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 event, got %d", count)
+	}
+	// Breaking out of the loop ends the transaction
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestSQLiteStreamEvents(t *testing.T) {
+	db, mock := newTestDB(t)
+	reader := NewSQLiteEventReader(db, slog.New(slog.NewTextHandler(io.Discard, nil)), &mockEmailEnrichmentConfig{enabled: true, source: "none"})
+
+	now := time.Now()
+	minID := int64(5)
+	// FetchSize rows per query, each resuming after the last ID read
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE e.id > ? ORDER BY e.id ASC LIMIT ? OFFSET ?")+"$").
+		WithArgs(minID, 2, 0).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(6), now, 1, "u1", nil, "acc", "{}", "u1", nil).
+			AddRow(int64(8), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE e.id > ? ORDER BY e.id ASC LIMIT ? OFFSET ?")+"$").
+		WithArgs(int64(8), 2, 0).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(9), now, 1, "u1", nil, "acc", "{}", "u1", nil).
+			RowError(0, errors.New("disk I/O error")))
+
+	var ids []int64
+	var errs []error
+	for evt, err := range reader.StreamEvents(context.Background(), EventQueryOptions{MinEventID: &minID, OrderAsc: true, FetchSize: 2}) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids = append(ids, evt.ID)
+	}
+	// A chunk is read completely before any of its events is yielded
+	if len(ids) != 2 || ids[1] != 8 {
+		t.Errorf("Expected events 6 and 8, got %v", ids)
+	}
+	if len(errs) != 1 {
+		t.Errorf("Expected the row error to end the stream, got %v", errs)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestMySQLStreamEvents(t *testing.T) {
+	reader, mock := newMySQLTestReader(t, &mockEmailEnrichmentConfig{enabled: true, source: "none"})
+
+	now := time.Now()
+	minID := int64(5)
+	// FetchSize rows per query, each resuming after the last ID read, until
+	// a short chunk
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE e.id > ? ORDER BY e.id ASC LIMIT ? OFFSET ?")+"$").
+		WithArgs(minID, 2, 0).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(6), now, 1, "u1", nil, "acc", "{}", "u1", nil).
+			AddRow(int64(8), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+	mock.ExpectQuery(regexp.QuoteMeta("WHERE e.id > ? ORDER BY e.id ASC LIMIT ? OFFSET ?")+"$").
+		WithArgs(int64(8), 2, 0).
+		WillReturnRows(sqlmock.NewRows(streamCols).
+			AddRow(int64(9), now, 1, "u1", nil, "acc", "{}", "u1", nil))
+
+	var ids []int64
+	for evt, err := range reader.StreamEvents(context.Background(), EventQueryOptions{MinEventID: &minID, OrderAsc: true, FetchSize: 2}) {
+		if err != nil {
+			t.Fatalf("StreamEvents failed: %v", err)
+		}
+		ids = append(ids, evt.ID)
+	}
+	if len(ids) != 3 || ids[0] != 6 || ids[2] != 9 {
+		t.Errorf("Expected events 6, 8 and 9, got %v", ids)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
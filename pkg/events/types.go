@@ -1,6 +1,7 @@
 package events
 
 import (
+	"strconv"
 	"time"
 )
 
@@ -17,11 +18,22 @@ type Event struct {
 	Meta           string    `json:"meta"` // JSON string containing additional metadata
 	InitiatorEmail string    `json:"initiator_email"`
 	TargetEmail    string    `json:"target_email"`
+	Source         string    `json:"source,omitempty"` // Instance name in multi-source mode
+}
+
+// DedupID identifies the event to sinks that drop duplicates: the event ID,
+// or "<source>:<id>" in multi-source mode, where instances reuse the same IDs.
+func (e Event) DedupID() string {
+	id := strconv.FormatInt(e.ID, 10)
+	if e.Source == "" {
+		return id
+	}
+	return e.Source + ":" + id
 }
 
 // EventQueryOptions defines parameters for querying events
 type EventQueryOptions struct {
-	// Limit the number of events to fetch (default: 1000)
+	// Limit the number of events to fetch (default: 1000, StreamEvents: no limit)
 	Limit int
 
 	// Offset for pagination
@@ -40,16 +52,28 @@ type EventQueryOptions struct {
 	// Filter by minimum event ID (for resuming from checkpoint)
 	MinEventID *int64
 
-	// Order by timestamp (default: DESC)
+	// Filter by event IDs, e.g. to re-check IDs missing from earlier batches
+	EventIDs []int64
+
+	// FetchSize is the number of rows StreamEvents reads per round trip on
+	// PostgreSQL cursors, and per query on MySQL and SQLite (default: 1000)
+	FetchSize int
+
+	// Order by event ID (default: DESC). Resuming with MinEventID is keyset
+	// pagination on the ID, so the order must not depend on the timestamp.
 	OrderAsc bool
 }
 
+// LegacyWriterType is the writer_type of checkpoints saved by the single
+// checkpoint model (migration 003). Per-writer checkpoints start from it.
+const LegacyWriterType = "default"
+
 // ProcessingCheckpoint tracks the last processed event for a consumer/writer combination
 type ProcessingCheckpoint struct {
 	// ConsumerID uniquely identifies the consumer group (e.g., "eventsproc-sandbox-apac")
 	ConsumerID string
 
-	// WriterType identifies the output writer (e.g., "journal", "loki", "splunk")
+	// WriterType identifies the output writer (e.g., "stdout", "loki", "webhook:siem")
 	// Each writer tracks its own checkpoint independently
 	WriterType string
 
@@ -0,0 +1,432 @@
+// Package file writes events to rotating local NDJSON files for air-gapped
+// hosts. Segments are named after the consumer and the first event ID they
+// contain, so they can be replayed in order later.
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Supported values for Config.Compression.
+const (
+	CompressionGzip = "gzip" // rotated segments become .ndjson.gz (default)
+	CompressionZstd = "zstd" // rotated segments become .ndjson.zst
+	CompressionNone = "none" // rotated segments stay .ndjson
+)
+
+// segmentExt is the extension of an uncompressed segment.
+const segmentExt = ".ndjson"
+
+// Config holds configuration for the file writer.
+type Config struct {
+	// Dir is the directory segments are written to. It is created if missing.
+	Dir string
+
+	// ConsumerID prefixes every file name, so several consumers can share Dir.
+	ConsumerID string
+
+	// Format of each line: "json" (default) or any other format.New name.
+	Format string
+
+	// MaxSize rotates the active segment once it reaches this many bytes.
+	// Default: 100 MiB.
+	MaxSize int64
+
+	// RotateInterval rotates the active segment once it is this old.
+	// Default: 24h. Negative disables time-based rotation.
+	RotateInterval time.Duration
+
+	// Compression of rotated segments: "gzip" (default), "zstd" or "none".
+	Compression string
+
+	// Retention deletes rotated segments older than this. 0 keeps them forever.
+	Retention time.Duration
+}
+
+// Writer appends events to the active segment and fsyncs before SendEvents
+// returns, so the checkpoint never gets ahead of what is on disk.
+//
+// Segment names are "<consumer_id>-<first event ID, 20 digits>-<UTC start time>.ndjson",
+// so a lexical sort orders them by event ID for replay.
+type Writer struct {
+	dir            string
+	prefix         string
+	maxSize        int64
+	rotateInterval time.Duration
+	compression    string
+	retention      time.Duration
+	formatter      format.Formatter
+	logger         *slog.Logger
+	segmentName    *regexp.Regexp
+
+	mu      sync.Mutex
+	active  *os.File
+	path    string
+	size    int64
+	opened  time.Time
+	nowFunc func() time.Time
+}
+
+// unsafeChars are replaced in the consumer ID so it is a valid file name.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// NewWriter creates a file writer. Segments left uncompressed by a previous
+// run (e.g. after a crash) are finalized, i.e. compressed, before returning.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("file dir is required")
+	}
+
+	compression := cfg.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	switch compression {
+	case CompressionGzip, CompressionZstd, CompressionNone:
+	default:
+		return nil, fmt.Errorf("unsupported file compression %q (use gzip, zstd or none)", compression)
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file format: %w", err)
+	}
+
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = 100 << 20
+	}
+	rotateInterval := cfg.RotateInterval
+	if rotateInterval == 0 {
+		rotateInterval = 24 * time.Hour
+	}
+
+	prefix := unsafeChars.ReplaceAllString(cfg.ConsumerID, "_")
+	if prefix == "" {
+		prefix = "eventsproc"
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create file dir: %w", err)
+	}
+
+	w := &Writer{
+		dir:            cfg.Dir,
+		prefix:         prefix,
+		maxSize:        maxSize,
+		rotateInterval: rotateInterval,
+		compression:    compression,
+		retention:      cfg.Retention,
+		formatter:      formatter,
+		logger:         logger,
+		segmentName:    regexp.MustCompile(`^` + regexp.QuoteMeta(prefix) + `-\d{20}-\d{8}T\d{6}Z\.ndjson`),
+		nowFunc:        time.Now,
+	}
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SendEvents appends a batch and fsyncs it. The active segment is rotated
+// before an event that would start past MaxSize or RotateInterval, so a new
+// segment always begins at an event boundary. Events that cannot be
+// formatted are skipped and returned in a writer.RejectedError once the rest
+// was written.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var buf bytes.Buffer
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		line, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+
+		if w.active != nil && w.dueForRotation(int64(buf.Len())) {
+			if err := w.flush(&buf); err != nil {
+				return err
+			}
+			if err := w.rotate(); err != nil {
+				return err
+			}
+		}
+		if w.active == nil {
+			if err := w.open(evt.ID); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := w.flush(&buf); err != nil {
+		return err
+	}
+	w.logger.Debug("Wrote events to file", "count", len(eventsList)-len(rejected), "path", w.path)
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent writes a single event
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close syncs and finalizes the active segment.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.active == nil {
+		return nil
+	}
+	return w.rotate()
+}
+
+// dueForRotation reports whether the active segment, with pending bytes not
+// yet written, has reached its size or age limit.
+func (w *Writer) dueForRotation(pending int64) bool {
+	if w.size+pending >= w.maxSize {
+		return true
+	}
+	return w.rotateInterval > 0 && w.nowFunc().Sub(w.opened) >= w.rotateInterval
+}
+
+// flush writes buf to the active segment and fsyncs it.
+func (w *Writer) flush(buf *bytes.Buffer) error {
+	if buf.Len() == 0 {
+		return nil
+	}
+	n, err := w.active.Write(buf.Bytes())
+	w.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", w.path, err)
+	}
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("failed to fsync %s: %w", w.path, err)
+	}
+	buf.Reset()
+	return nil
+}
+
+// open starts a new segment whose name records its first event ID.
+func (w *Writer) open(firstEventID int64) error {
+	now := w.nowFunc().UTC()
+	name := fmt.Sprintf("%s-%020d-%s%s", w.prefix, firstEventID, now.Format("20060102T150405Z"), segmentExt)
+	path := filepath.Join(w.dir, name)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open segment: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to stat segment: %w", err)
+	}
+	// Make the new directory entry durable along with the data
+	if err := syncDir(w.dir); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	w.active, w.path, w.size, w.opened = f, path, info.Size(), now
+	w.logger.Info("Opened event file segment", "path", path)
+	return nil
+}
+
+// rotate closes the active segment, compresses it and applies retention.
+func (w *Writer) rotate() error {
+	path := w.path
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("failed to fsync %s: %w", path, err)
+	}
+	if err := w.active.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	w.active, w.path, w.size = nil, "", 0
+
+	if err := w.finalize(path); err != nil {
+		return err
+	}
+	w.applyRetention()
+	return nil
+}
+
+// finalize compresses a closed segment. The compressed copy is fsynced and
+// renamed into place before the original is removed, so a crash at any point
+// leaves at least one complete copy.
+func (w *Writer) finalize(path string) error {
+	var ext string
+	switch w.compression {
+	case CompressionGzip:
+		ext = ".gz"
+	case CompressionZstd:
+		ext = ".zst"
+	default:
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s for compression: %w", path, err)
+	}
+	defer func() { _ = src.Close() }()
+
+	tmp := path + ext + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmp, err)
+	}
+	if err := compress(dst, src, w.compression); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := dst.Sync(); err != nil {
+		_ = dst.Close()
+		return fmt.Errorf("failed to fsync %s: %w", tmp, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path+ext); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmp, err)
+	}
+	if err := syncDir(w.dir); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	w.logger.Info("Rotated event file segment", "path", path+ext)
+	return nil
+}
+
+func compress(dst io.Writer, src io.Reader, compression string) error {
+	var zw io.WriteCloser
+	if compression == CompressionZstd {
+		enc, err := zstd.NewWriter(dst)
+		if err != nil {
+			return err
+		}
+		zw = enc
+	} else {
+		zw = gzip.NewWriter(dst)
+	}
+	if _, err := io.Copy(zw, src); err != nil {
+		_ = zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// recover finalizes segments of this consumer left behind by a previous run
+// and removes half-written compressed copies.
+func (w *Writer) recover() error {
+	entries, err := w.segments()
+	if err != nil {
+		return err
+	}
+	for _, name := range entries {
+		path := filepath.Join(w.dir, name)
+		switch {
+		case strings.HasSuffix(name, ".tmp"):
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove incomplete %s: %w", path, err)
+			}
+		case strings.HasSuffix(name, segmentExt) && w.compression != CompressionNone:
+			w.logger.Info("Finalizing segment from previous run", "path", path)
+			if err := w.finalize(path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyRetention deletes rotated segments older than the retention period.
+// Failures are logged: retention must never block delivery.
+func (w *Writer) applyRetention() {
+	if w.retention <= 0 {
+		return
+	}
+	names, err := w.segments()
+	if err != nil {
+		w.logger.Warn("Failed to list segments for retention", "error", err)
+		return
+	}
+	cutoff := w.nowFunc().Add(-w.retention)
+	for _, name := range names {
+		if strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		path := filepath.Join(w.dir, name)
+		if path == w.path {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			w.logger.Warn("Failed to delete expired segment", "path", path, "error", err)
+			continue
+		}
+		w.logger.Info("Deleted expired event file segment", "path", path)
+	}
+}
+
+// segments returns the sorted file names belonging to this consumer.
+func (w *Writer) segments() ([]string, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file dir: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || !w.segmentName.MatchString(e.Name()) {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// syncDir fsyncs a directory so created, renamed and removed entries persist.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open dir %s: %w", dir, err)
+	}
+	defer func() { _ = d.Close() }()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to fsync dir %s: %w", dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents(from, to int64) []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var list []events.Event
+	for id := from; id <= to; id++ {
+		list = append(list, events.Event{ID: id, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: "acc1"})
+	}
+	return list
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// readSegment returns the event IDs in a (possibly compressed) segment.
+func readSegment(t *testing.T, path string) []int64 {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	var r io.Reader = bytes.NewReader(data)
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatalf("invalid gzip segment %s: %v", path, err)
+		}
+		r = gz
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			t.Fatalf("invalid zstd segment %s: %v", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	var ids []int64
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line struct {
+			EventID int64 `json:"event_id"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		ids = append(ids, line.EventID)
+	}
+	return ids
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing dir")
+	}
+	if _, err := NewWriter(&Config{Dir: t.TempDir(), Compression: "lz4"}, testLogger()); err == nil {
+		t.Error("Expected error for unsupported compression")
+	}
+}
+
+func TestSendEvents_AppendsAndSyncs(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(&Config{Dir: dir, ConsumerID: "eventsproc-prod/emea"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.nowFunc = func() time.Time { return time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC) }
+
+	ctx := context.Background()
+	if err := w.SendEvents(ctx, testEvents(7, 9)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if err := w.SendEvents(ctx, testEvents(10, 10)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	// Data is on disk before Close
+	want := "eventsproc-prod_emea-00000000000000000007-20260128T103000Z.ndjson"
+	names := listDir(t, dir)
+	if len(names) != 1 || names[0] != want {
+		t.Fatalf("Expected active segment %s, got %v", want, names)
+	}
+	if ids := readSegment(t, filepath.Join(dir, want)); len(ids) != 4 || ids[0] != 7 || ids[3] != 10 {
+		t.Errorf("Unexpected event IDs %v", ids)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	names = listDir(t, dir)
+	if len(names) != 1 || names[0] != want+".gz" {
+		t.Fatalf("Expected segment to be gzipped on close, got %v", names)
+	}
+	if ids := readSegment(t, filepath.Join(dir, names[0])); len(ids) != 4 {
+		t.Errorf("Expected 4 events in compressed segment, got %v", ids)
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(&Config{Dir: dir}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.formatter = formattest.Formatter{Bad: map[int64]bool{2: true}, Next: w.formatter}
+
+	err = w.SendEvents(context.Background(), testEvents(1, 3))
+	formattest.CheckRejected(t, err, 2)
+	names := listDir(t, dir)
+	if len(names) != 1 {
+		t.Fatalf("Expected one segment, got %v", names)
+	}
+	if ids := readSegment(t, filepath.Join(dir, names[0])); len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+		t.Errorf("Expected the other events to be written, got %v", ids)
+	}
+}
+
+func TestSendEvents_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWriter(&Config{Dir: dir, ConsumerID: "c1", MaxSize: 400, Compression: CompressionZstd}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents(1, 10)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	if len(names) < 3 {
+		t.Fatalf("Expected several segments, got %v", names)
+	}
+	var all []int64
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".ndjson.zst") {
+			t.Errorf("Expected zstd segment, got %s", name)
+		}
+		ids := readSegment(t, filepath.Join(dir, name))
+		// The name records the first event ID of the segment
+		firstID, err := strconv.ParseInt(name[len("c1-"):len("c1-")+20], 10, 64)
+		if err != nil || firstID != ids[0] {
+			t.Errorf("Segment %s starts at event %d", name, ids[0])
+		}
+		all = append(all, ids...)
+	}
+	for i, id := range all {
+		if id != int64(i+1) {
+			t.Fatalf("Expected events 1..10 in order across segments, got %v", all)
+		}
+	}
+}
+
+func TestSendEvents_RotatesByTime(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	w, err := NewWriter(&Config{Dir: dir, ConsumerID: "c1", RotateInterval: time.Hour}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.nowFunc = func() time.Time { return now }
+
+	ctx := context.Background()
+	if err := w.SendEvents(ctx, testEvents(1, 2)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	now = now.Add(30 * time.Minute)
+	if err := w.SendEvents(ctx, testEvents(3, 3)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	now = now.Add(31 * time.Minute)
+	if err := w.SendEvents(ctx, testEvents(4, 5)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	want := []string{
+		"c1-00000000000000000001-20260128T100000Z.ndjson.gz",
+		"c1-00000000000000000004-20260128T110100Z.ndjson",
+	}
+	if len(names) != 2 || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+	if ids := readSegment(t, filepath.Join(dir, names[0])); len(ids) != 3 {
+		t.Errorf("Expected 3 events in the rotated segment, got %v", ids)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "c1-00000000000000000001-20251201T000000Z.ndjson.gz")
+	other := filepath.Join(dir, "c10-00000000000000000001-20251201T000000Z.ndjson.gz")
+	for _, path := range []string{old, other} {
+		if err := os.WriteFile(path, nil, 0o640); err != nil {
+			t.Fatal(err)
+		}
+		expired := time.Now().Add(-10 * 24 * time.Hour)
+		if err := os.Chtimes(path, expired, expired); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := NewWriter(&Config{Dir: dir, ConsumerID: "c1", Retention: 7 * 24 * time.Hour}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents(5, 6)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected expired segment to be deleted")
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Error("Expected another consumer's segment to be kept")
+	}
+	if names := listDir(t, dir); len(names) != 2 {
+		t.Errorf("Expected the new segment and the other consumer's file, got %v", names)
+	}
+}
+
+func TestNewWriter_RecoversPreviousRun(t *testing.T) {
+	dir := t.TempDir()
+	leftover := filepath.Join(dir, "c1-00000000000000000001-20260128T100000Z.ndjson")
+	partial := filepath.Join(dir, "c1-00000000000000000001-20260127T100000Z.ndjson.gz.tmp")
+	if err := os.WriteFile(leftover, []byte(`{"event_id":1}`+"\n"+`{"event_id":2}`+"\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(partial, []byte("garbage"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewWriter(&Config{Dir: dir, ConsumerID: "c1"}, testLogger()); err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	names := listDir(t, dir)
+	if len(names) != 1 || names[0] != filepath.Base(leftover)+".gz" {
+		t.Fatalf("Expected leftover segment to be compressed and the partial copy removed, got %v", names)
+	}
+	if ids := readSegment(t, filepath.Join(dir, names[0])); len(ids) != 2 {
+		t.Errorf("Expected recovered events, got %v", ids)
+	}
+}
@@ -0,0 +1,113 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// CEF renders events in ArcSight Common Event Format:
+//
+//	CEF:0|NetBird|eventsproc|<version>|<Activity>|<ActivityName>|<severity>|<extensions>
+//
+// Activity is the SignatureID and ActivityName the Name. The initiator maps to
+// suid/suser and the target to duid/duser (email when known, otherwise the ID).
+// Meta fields become meta_<key> extensions.
+type CEF struct{}
+
+// Format implements Formatter.
+func (CEF) Format(evt events.Event) (string, error) {
+	var b strings.Builder
+	b.WriteString("CEF:0|")
+	b.WriteString(cefHeaderEscaper.Replace(DeviceVendor))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(DeviceProduct))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(DeviceVersion))
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(evt.Activity))
+	b.WriteByte('|')
+	b.WriteString(cefHeaderEscaper.Replace(evt.ActivityName))
+	b.WriteByte('|')
+	b.WriteString(strconv.Itoa(ActivitySeverity(evt.ActivityCode).score()))
+	b.WriteByte('|')
+
+	for i, ext := range extensions(evt) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(ext.Key)
+		b.WriteByte('=')
+		b.WriteString(cefValueEscaper.Replace(ext.Value))
+	}
+	return b.String(), nil
+}
+
+// cefHeaderEscaper escapes '\' and '|' in header fields; line breaks are not
+// allowed in the header and are flattened to spaces.
+var cefHeaderEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r\n", " ", "\n", " ", "\r", " ")
+
+// cefValueEscaper escapes '\' and '=' in extension values and encodes line breaks.
+var cefValueEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+
+// extension is one key=value pair in a CEF or LEEF record.
+type extension struct {
+	Key   string
+	Value string
+}
+
+// extensions returns the CEF/LEEF key/value pairs for an event in a fixed order.
+// Empty values are omitted.
+func extensions(evt events.Event) []extension {
+	exts := []extension{
+		{"rt", strconv.FormatInt(evt.Timestamp.UnixMilli(), 10)},
+		{"externalId", strconv.FormatInt(evt.ID, 10)},
+		{"act", evt.ActivityCode},
+	}
+	if evt.AccountID != "" {
+		exts = append(exts, extension{"cs1Label", "accountId"}, extension{"cs1", evt.AccountID})
+	}
+	if evt.Source != "" {
+		exts = append(exts, extension{"cs2Label", "source"}, extension{"cs2", evt.Source})
+	}
+	exts = append(exts,
+		extension{"suid", evt.InitiatorID},
+		extension{"suser", firstNonEmpty(evt.InitiatorEmail, evt.InitiatorID)},
+		extension{"duid", evt.TargetID},
+		extension{"duser", firstNonEmpty(evt.TargetEmail, evt.TargetID)},
+	)
+	for _, f := range FlattenMeta(evt.Meta) {
+		exts = append(exts, extension{"meta_" + extensionKey(f.Key), valueString(f.Value)})
+	}
+
+	out := exts[:0]
+	for _, ext := range exts {
+		if ext.Value != "" {
+			out = append(out, ext)
+		}
+	}
+	return out
+}
+
+// extensionKey restricts a meta key to the characters CEF and LEEF allow in
+// keys (letters, digits, underscore); anything else becomes '_'.
+func extensionKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func TestCEF(t *testing.T) {
+	line, err := CEF{}.Format(testEvent())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	want := `CEF:0|NetBird|eventsproc|dev|57|User role updated|7|` +
+		`rt=1769596200123 externalId=123 act=user.role.update cs1Label=accountId cs1=acc1 ` +
+		`suid=admin1 suser=admin@example.com duid=user2 duser=bob@example.com ` +
+		`meta_groups=["a","b"] meta_ip=10.0.0.1 meta_role=admin`
+	if line != want {
+		t.Errorf("CEF format:\n got: %s\nwant: %s", line, want)
+	}
+}
+
+func TestCEF_Escaping(t *testing.T) {
+	evt := events.Event{
+		ID:           1,
+		Activity:     1,
+		ActivityName: `Name|with\pipe`,
+		ActivityCode: "peer.setupkey.add",
+		Meta:         `{"note":"a=b\\c\nd","bad key!":"x"}`,
+	}
+
+	line, err := CEF{}.Format(evt)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(line, `|Name\|with\\pipe|5|`) {
+		t.Errorf("Expected escaped header name, got %s", line)
+	}
+	if !strings.Contains(line, `meta_note=a\=b\\c\nd`) {
+		t.Errorf("Expected escaped extension value, got %s", line)
+	}
+	if !strings.Contains(line, "meta_bad_key_=x") {
+		t.Errorf("Expected sanitised extension key, got %s", line)
+	}
+	if strings.Contains(line, "cs1Label") {
+		t.Errorf("Expected no account label without account, got %s", line)
+	}
+}
+
+func TestCEF_UserFallsBackToID(t *testing.T) {
+	line, err := CEF{}.Format(events.Event{ActivityCode: "user.join", InitiatorID: "u1", TargetID: "u2"})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(line, "suser=u1") || !strings.Contains(line, "duser=u2") {
+		t.Errorf("Expected user IDs when emails are missing, got %s", line)
+	}
+}
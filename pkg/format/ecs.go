@@ -0,0 +1,191 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/xh63/netbird-events/pkg/activity"
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// ECSVersion is the Elastic Common Schema version written to ecs.version.
+const ECSVersion = "8.11.0"
+
+// ECS renders events as Elastic Common Schema documents. event.category and
+// event.type are derived from the same per-activity table as OCSF, so both
+// schemas classify an activity the same way.
+type ECS struct{}
+
+// Format implements Formatter.
+func (ECS) Format(evt events.Event) (string, error) {
+	b, err := json.Marshal(ecsDocument(evt))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ECS event: %w", err)
+	}
+	return string(b), nil
+}
+
+type ecsDoc struct {
+	Timestamp    string           `json:"@timestamp"`
+	Message      string           `json:"message,omitempty"`
+	ECS          ecsVersion       `json:"ecs"`
+	Event        ecsEvent         `json:"event"`
+	User         *ecsUser         `json:"user,omitempty"`
+	Organization *ecsOrganization `json:"organization,omitempty"`
+	Host         *ecsHost         `json:"host,omitempty"`
+	NetBird      ecsNetBird       `json:"netbird"`
+}
+
+type ecsVersion struct {
+	Version string `json:"version"`
+}
+
+type ecsEvent struct {
+	ID       string   `json:"id"`
+	Action   string   `json:"action"`
+	Code     string   `json:"code"`
+	Kind     string   `json:"kind"`
+	Category []string `json:"category"`
+	Type     []string `json:"type"`
+	Outcome  string   `json:"outcome"`
+	Severity int      `json:"severity"`
+	Provider string   `json:"provider"`
+	Dataset  string   `json:"dataset"`
+	Module   string   `json:"module"`
+}
+
+type ecsUser struct {
+	ID     string         `json:"id,omitempty"`
+	Email  string         `json:"email,omitempty"`
+	Target *ecsUserTarget `json:"target,omitempty"`
+}
+
+type ecsUserTarget struct {
+	ID    string `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+type ecsOrganization struct {
+	ID string `json:"id"`
+}
+
+type ecsHost struct {
+	ID       string   `json:"id,omitempty"`
+	Name     string   `json:"name,omitempty"`
+	Hostname string   `json:"hostname,omitempty"`
+	IP       []string `json:"ip,omitempty"`
+}
+
+// ecsNetBird holds NetBird-specific fields that have no ECS equivalent.
+type ecsNetBird struct {
+	Activity     int            `json:"activity"`
+	ActivityCode string         `json:"activity_code"`
+	Source       string         `json:"source,omitempty"`
+	TargetID     string         `json:"target_id,omitempty"`
+	Meta         map[string]any `json:"meta,omitempty"`
+}
+
+// ecsDocument builds the ECS document for an event.
+func ecsDocument(evt events.Event) ecsDoc {
+	m, ok := ocsfMappings[activity.Activity(evt.Activity)]
+	if !ok {
+		m = ocsfMapping{class: ocsfClassAPIActivity, activityID: opOther}
+	}
+	category, typ := ecsCategorization(m)
+
+	meta := map[string]any{}
+	for _, f := range FlattenMeta(evt.Meta) {
+		meta[f.Key] = f.Value
+	}
+
+	doc := ecsDoc{
+		Timestamp: evt.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+		Message:   evt.ActivityName,
+		ECS:       ecsVersion{Version: ECSVersion},
+		Event: ecsEvent{
+			ID:       strconv.FormatInt(evt.ID, 10),
+			Action:   evt.ActivityCode,
+			Code:     strconv.Itoa(evt.Activity),
+			Kind:     "event",
+			Category: category,
+			Type:     typ,
+			Outcome:  "success",
+			Severity: ActivitySeverity(evt.ActivityCode).score(),
+			Provider: "netbird",
+			Dataset:  "netbird.audit",
+			Module:   "netbird",
+		},
+		NetBird: ecsNetBird{Activity: evt.Activity, ActivityCode: evt.ActivityCode, Source: evt.Source},
+	}
+	if len(meta) > 0 {
+		doc.NetBird.Meta = meta
+	}
+
+	// user.target is used when the target is a user (account changes, or any
+	// event with a resolved target email); other targets go to netbird.target_id
+	targetIsUser := (m.class == ocsfClassAccountChange || evt.TargetEmail != "") &&
+		(evt.TargetID != "" || evt.TargetEmail != "")
+	if evt.InitiatorID != "" || evt.InitiatorEmail != "" || targetIsUser {
+		doc.User = &ecsUser{ID: evt.InitiatorID, Email: evt.InitiatorEmail}
+	}
+	if targetIsUser {
+		doc.User.Target = &ecsUserTarget{ID: evt.TargetID, Email: evt.TargetEmail}
+	} else {
+		doc.NetBird.TargetID = evt.TargetID
+	}
+
+	if evt.AccountID != "" {
+		doc.Organization = &ecsOrganization{ID: evt.AccountID}
+	}
+
+	if m.device {
+		doc.Host = &ecsHost{
+			ID:       evt.TargetID,
+			Name:     metaString(meta, "name"),
+			Hostname: metaString(meta, "fqdn"),
+		}
+		if ip := metaString(meta, "ip"); ip != "" {
+			doc.Host.IP = []string{ip}
+		}
+	}
+	return doc
+}
+
+// ecsCategorization derives event.category and event.type from the activity's
+// OCSF mapping.
+func ecsCategorization(m ocsfMapping) (category, typ []string) {
+	switch m.class {
+	case ocsfClassAuthentication:
+		if m.activityID == authLogoff {
+			return []string{"authentication", "session"}, []string{"end"}
+		}
+		return []string{"authentication", "session"}, []string{"start"}
+	case ocsfClassAccountChange:
+		switch m.activityID {
+		case acctCreate:
+			return []string{"iam"}, []string{"user", "creation"}
+		case acctDelete:
+			return []string{"iam"}, []string{"user", "deletion"}
+		default:
+			return []string{"iam"}, []string{"user", "change"}
+		}
+	case ocsfClassAPIActivity:
+		return []string{"iam", "configuration"}, []string{ecsChangeType(m.activityID)}
+	default:
+		return []string{"configuration"}, []string{ecsChangeType(m.activityID)}
+	}
+}
+
+// ecsChangeType maps an Entity Management / API Activity activity ID to an
+// ECS event.type value.
+func ecsChangeType(activityID int) string {
+	switch activityID {
+	case opCreate, opEnroll:
+		return "creation"
+	case opDelete, opUnenroll:
+		return "deletion"
+	default:
+		return "change"
+	}
+}
@@ -0,0 +1,97 @@
+package format
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/xh63/netbird-events/pkg/activity"
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func formatECS(t *testing.T, evt events.Event) map[string]any {
+	t.Helper()
+	line, err := ECS{}.Format(evt)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(line), &doc); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestECS_AccountChange(t *testing.T) {
+	doc := formatECS(t, ocsfTestEvent(activity.UserRoleUpdated))
+
+	if doc["@timestamp"] != "2026-01-28T10:30:00.123Z" {
+		t.Errorf("Unexpected @timestamp %v", doc["@timestamp"])
+	}
+	event := doc["event"].(map[string]any)
+	if event["action"] != "user.role.update" || event["id"] != "1022" || event["code"] != "22" {
+		t.Errorf("Unexpected event fields: %v", event)
+	}
+	if !reflect.DeepEqual(event["category"], []any{"iam"}) {
+		t.Errorf("Expected iam category, got %v", event["category"])
+	}
+	if !reflect.DeepEqual(event["type"], []any{"user", "change"}) {
+		t.Errorf("Unexpected event.type %v", event["type"])
+	}
+
+	user := doc["user"].(map[string]any)
+	if user["id"] != "admin1" || user["email"] != "admin@example.com" {
+		t.Errorf("Unexpected user fields: %v", user)
+	}
+	target := user["target"].(map[string]any)
+	if target["id"] != "target1" || target["email"] != "target@example.com" {
+		t.Errorf("Unexpected user.target fields: %v", target)
+	}
+	if doc["organization"].(map[string]any)["id"] != "acc1" {
+		t.Errorf("Expected organization.id=acc1, got %v", doc["organization"])
+	}
+	if doc["ecs"].(map[string]any)["version"] != ECSVersion {
+		t.Errorf("Expected ecs.version=%s", ECSVersion)
+	}
+}
+
+func TestECS_PeerTargetIsHost(t *testing.T) {
+	evt := ocsfTestEvent(activity.PeerRenamed)
+	evt.TargetEmail = ""
+
+	doc := formatECS(t, evt)
+
+	if _, ok := doc["user"].(map[string]any)["target"]; ok {
+		t.Error("Expected no user.target for a peer target")
+	}
+	host := doc["host"].(map[string]any)
+	if host["id"] != "target1" || host["hostname"] != "laptop-1.netbird.cloud" {
+		t.Errorf("Unexpected host fields: %v", host)
+	}
+	if doc["netbird"].(map[string]any)["target_id"] != "target1" {
+		t.Errorf("Expected netbird.target_id, got %v", doc["netbird"])
+	}
+}
+
+func TestECS_Categorization(t *testing.T) {
+	tests := []struct {
+		act      activity.Activity
+		category []any
+		typ      []any
+	}{
+		{activity.DashboardLogin, []any{"authentication", "session"}, []any{"start"}},
+		{activity.PeerLoginExpired, []any{"authentication", "session"}, []any{"end"}},
+		{activity.UserJoined, []any{"iam"}, []any{"user", "creation"}},
+		{activity.UserDeleted, []any{"iam"}, []any{"user", "deletion"}},
+		{activity.PolicyAdded, []any{"configuration"}, []any{"creation"}},
+		{activity.RouteUpdated, []any{"configuration"}, []any{"change"}},
+		{activity.PeerRemovedByUser, []any{"configuration"}, []any{"deletion"}},
+		{activity.PersonalAccessTokenCreated, []any{"iam", "configuration"}, []any{"creation"}},
+	}
+	for _, tt := range tests {
+		event := formatECS(t, ocsfTestEvent(tt.act))["event"].(map[string]any)
+		if !reflect.DeepEqual(event["category"], tt.category) || !reflect.DeepEqual(event["type"], tt.typ) {
+			t.Errorf("%s: got category=%v type=%v, want %v %v", tt.act.StringCode(), event["category"], event["type"], tt.category, tt.typ)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package format renders events as text lines for the output writers.
+//
+// Every writer that emits text (stdout, syslog, Loki, Splunk) takes a Formatter,
+// so the same event can be shipped as flattened JSON, key/value pairs, the
+// SIEM-native ArcSight CEF and QRadar LEEF encodings, or the OCSF and ECS schemas.
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// Supported formatter names.
+const (
+	NameJSON = "json" // flattened event JSON (default)
+	NameKV   = "kv"   // key="value" pairs
+	NameCEF  = "cef"  // ArcSight Common Event Format (CEF:0)
+	NameLEEF = "leef" // IBM QRadar Log Event Extended Format (LEEF:2.0)
+	NameOCSF = "ocsf" // Open Cybersecurity Schema Framework JSON (latest version, or "ocsf-<version>")
+	NameECS  = "ecs"  // Elastic Common Schema JSON
+)
+
+// Device identification used in CEF and LEEF headers.
+const (
+	DeviceVendor  = "NetBird"
+	DeviceProduct = "eventsproc"
+)
+
+// DeviceVersion is reported in CEF and LEEF headers. main sets it to the build version.
+var DeviceVersion = "dev"
+
+// Formatter renders a single event as one line (without trailing newline).
+type Formatter interface {
+	Format(evt events.Event) (string, error)
+}
+
+// New returns the formatter for name. An empty name selects JSON.
+func New(name string) (Formatter, error) {
+	switch name {
+	case "", NameJSON:
+		return JSON{}, nil
+	case NameKV:
+		return KV{}, nil
+	case NameCEF:
+		return CEF{}, nil
+	case NameLEEF:
+		return LEEF{}, nil
+	case NameOCSF:
+		return OCSF{Version: OCSFVersion}, nil
+	case NameECS:
+		return ECS{}, nil
+	}
+
+	if version, ok := strings.CutPrefix(name, NameOCSF+"-"); ok {
+		if !ocsfVersions[version] {
+			return nil, fmt.Errorf("unsupported OCSF schema version %q (supported: %s)", version, OCSFVersion)
+		}
+		return OCSF{Version: version}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q (use json, kv, cef, leef, ocsf or ecs)", name)
+}
+
+// IsJSON reports whether f produces JSON objects, so writers with structured
+// payloads (e.g. Splunk HEC) can embed the line as an object instead of a string.
+func IsJSON(f Formatter) bool {
+	switch f.(type) {
+	case JSON, OCSF, ECS:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetaField is one flattened meta entry.
+type MetaField struct {
+	Key   string
+	Value any
+}
+
+// FlattenMeta parses the event meta JSON into key-sorted fields. Meta that is
+// not a JSON object is returned as a single "unparsable" field so nothing is lost.
+func FlattenMeta(meta string) []MetaField {
+	if meta == "" {
+		return nil
+	}
+	var metaData map[string]any
+	if err := json.Unmarshal([]byte(meta), &metaData); err != nil {
+		return []MetaField{{Key: "unparsable", Value: meta}}
+	}
+
+	fields := make([]MetaField, 0, len(metaData))
+	for k, v := range metaData {
+		fields = append(fields, MetaField{Key: k, Value: v})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+// valueString renders a meta value for text formats: strings as-is, numbers
+// and booleans in their JSON form, nested objects and arrays as compact JSON.
+func valueString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,120 @@
+package format
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func testEvent() events.Event {
+	return events.Event{
+		ID:             123,
+		Timestamp:      time.Date(2026, 1, 28, 10, 30, 0, 123_000_000, time.UTC),
+		Activity:       57,
+		ActivityName:   "User role updated",
+		ActivityCode:   "user.role.update",
+		AccountID:      "acc1",
+		InitiatorID:    "admin1",
+		InitiatorEmail: "admin@example.com",
+		TargetID:       "user2",
+		TargetEmail:    "bob@example.com",
+		Meta:           `{"role":"admin","ip":"10.0.0.1","groups":["a","b"]}`,
+	}
+}
+
+func TestNew(t *testing.T) {
+	for name, want := range map[string]Formatter{"": JSON{}, "json": JSON{}, "kv": KV{}, "cef": CEF{}, "leef": LEEF{}, "ocsf": OCSF{Version: OCSFVersion}} {
+		f, err := New(name)
+		if err != nil {
+			t.Errorf("New(%q) failed: %v", name, err)
+			continue
+		}
+		if f != want {
+			t.Errorf("New(%q) = %T, want %T", name, f, want)
+		}
+	}
+	if _, err := New("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestIsJSON(t *testing.T) {
+	if !IsJSON(JSON{}) || !IsJSON(OCSF{}) {
+		t.Error("Expected json and ocsf to be JSON formats")
+	}
+	if IsJSON(CEF{}) || IsJSON(KV{}) {
+		t.Error("Expected cef and kv to be text formats")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	line, err := JSON{}.Format(testEvent())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal([]byte(line), &result); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if result["event_id"] != float64(123) || result["activity_code"] != "user.role.update" {
+		t.Errorf("Unexpected event fields: %v", result)
+	}
+	if result["meta_role"] != "admin" {
+		t.Errorf("Expected flattened meta_role, got %v", result["meta_role"])
+	}
+	if result["timestamp"] != "2026-01-28T10:30:00.123Z" {
+		t.Errorf("Unexpected timestamp %v", result["timestamp"])
+	}
+}
+
+func TestJSON_UnparsableMeta(t *testing.T) {
+	evt := testEvent()
+	evt.Meta = "not json"
+
+	line, err := JSON{}.Format(evt)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var result map[string]any
+	_ = json.Unmarshal([]byte(line), &result)
+	if result["meta_unparsable"] != "not json" {
+		t.Errorf("Expected meta_unparsable, got %v", result["meta_unparsable"])
+	}
+}
+
+func TestKV(t *testing.T) {
+	evt := events.Event{ID: 1, Activity: 43, ActivityCode: "dashboard.login", AccountID: "acc1", Meta: `{"ip":"10.0.0.1","n":2}`}
+
+	line, err := KV{}.Format(evt)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	want := `account_id="acc1" activity="43" activity_code="dashboard.login" activity_name="" ` +
+		`event_id="1" event_source="netbird" level="info" log_type="application" ` +
+		`meta_ip="10.0.0.1" meta_n="2" timestamp="0001-01-01T00:00:00.000Z"`
+	if line != want {
+		t.Errorf("KV format:\n got: %s\nwant: %s", line, want)
+	}
+}
+
+func TestActivitySeverity(t *testing.T) {
+	tests := []struct {
+		code string
+		want Severity
+	}{
+		{"dashboard.login", SeverityInfo},
+		{"user.role.update", SeverityWarning},
+		{"peer.delete", SeverityWarning},
+		{"policy.add", SeverityNotice},
+		{"user.unblock", SeverityNotice},
+		{"UNKNOWN_ACTIVITY", SeverityInfo},
+	}
+	for _, tt := range tests {
+		if got := ActivitySeverity(tt.code); got != tt.want {
+			t.Errorf("ActivitySeverity(%s) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package formattest helps test how writers handle events their formatter
+// cannot format.
+package formattest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// ErrCannotFormat is the error Formatter returns for its bad events.
+var ErrCannotFormat = errors.New("cannot format")
+
+// Formatter fails for the event IDs in Bad and formats the others with Next,
+// or as "event <id>" when Next is nil.
+type Formatter struct {
+	Bad  map[int64]bool
+	Next format.Formatter
+}
+
+// FailOn returns a Formatter failing for ids.
+func FailOn(ids ...int64) Formatter {
+	bad := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		bad[id] = true
+	}
+	return Formatter{Bad: bad}
+}
+
+// Format implements format.Formatter.
+func (f Formatter) Format(evt events.Event) (string, error) {
+	if f.Bad[evt.ID] {
+		return "", ErrCannotFormat
+	}
+	if f.Next != nil {
+		return f.Next.Format(evt)
+	}
+	return fmt.Sprintf("event %d", evt.ID), nil
+}
+
+// CheckRejected fails the test unless err is a writer.RejectedError for
+// exactly the events ids, in order, with the Formatter's error as reason.
+func CheckRejected(t testing.TB, err error, ids ...int64) {
+	t.Helper()
+	var rejected *writer.RejectedError
+	if !errors.As(err, &rejected) || len(rejected.Rejected) != len(ids) {
+		t.Fatalf("Expected events %v to be rejected, got %v", ids, err)
+	}
+	for i, r := range rejected.Rejected {
+		if r.Event.ID != ids[i] || r.Reason != ErrCannotFormat.Error() {
+			t.Fatalf("Expected event %d rejected with %q, got event %d with %q", ids[i], ErrCannotFormat, r.Event.ID, r.Reason)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// JSON renders events as a single flattened JSON object, the line format the
+// stdout writer has always emitted.
+type JSON struct{}
+
+// Format implements Formatter.
+func (JSON) Format(evt events.Event) (string, error) {
+	return EventToJSON(evt)
+}
+
+// EventToJSON converts an event to JSON format with flattened meta fields.
+func EventToJSON(event events.Event) (string, error) {
+	jsonBytes, err := json.Marshal(eventFields(event))
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal event to JSON: %w", err)
+	}
+
+	return string(jsonBytes), nil
+}
+
+// eventFields returns the flattened field map shared by the JSON and KV formats.
+func eventFields(event events.Event) map[string]any {
+	// Create a map to hold all fields
+	data := make(map[string]any)
+
+	// Add classification fields for log filtering and routing
+	data["level"] = "info"
+	data["log_type"] = "application"
+	data["event_source"] = "netbird"
+
+	// Add event fields
+	data["event_id"] = event.ID
+	data["timestamp"] = event.Timestamp.Format("2006-01-02T15:04:05.000Z07:00")
+	data["activity"] = event.Activity
+	data["activity_name"] = event.ActivityName
+	data["activity_code"] = event.ActivityCode
+	data["account_id"] = event.AccountID
+	if event.Source != "" {
+		data["source"] = event.Source
+	}
+
+	// Add initiator fields if they exist
+	if event.InitiatorID != "" {
+		data["initiator_id"] = event.InitiatorID
+	}
+	if event.InitiatorEmail != "" {
+		data["initiator_email"] = event.InitiatorEmail
+	}
+
+	// Add target fields if they exist
+	if event.TargetID != "" {
+		data["target_id"] = event.TargetID
+	}
+	if event.TargetEmail != "" {
+		data["target_email"] = event.TargetEmail
+	}
+
+	// Flatten meta JSON into individual fields with meta_ prefix
+	for _, f := range FlattenMeta(event.Meta) {
+		data["meta_"+f.Key] = f.Value
+	}
+
+	return data
+}
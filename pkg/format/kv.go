@@ -0,0 +1,34 @@
+package format
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// KV renders events as space-separated key="value" pairs sorted by key.
+// It carries the same fields as JSON.
+type KV struct{}
+
+// Format implements Formatter.
+func (KV) Format(evt events.Event) (string, error) {
+	fields := eventFields(evt)
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(valueString(fields[k])))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,57 @@
+package format
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func TestLEEF(t *testing.T) {
+	line, err := LEEF{}.Format(testEvent())
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	header := "LEEF:2.0|NetBird|eventsproc|dev|57|x09|"
+	if !strings.HasPrefix(line, header) {
+		t.Fatalf("Unexpected header: %s", line)
+	}
+
+	attrs := strings.Split(strings.TrimPrefix(line, header), "\t")
+	want := []string{
+		"devTime=Jan 28 2026 10:30:00.123 UTC",
+		"devTimeFormat=MMM dd yyyy HH:mm:ss.SSS z",
+		"cat=user.role.update",
+		"sev=7",
+		"name=User role updated",
+		"usrName=admin@example.com",
+		"accountId=acc1",
+		"externalId=123",
+		"suid=admin1",
+		"suser=admin@example.com",
+		"duid=user2",
+		"duser=bob@example.com",
+		`meta_groups=["a","b"]`,
+		"meta_ip=10.0.0.1",
+		"meta_role=admin",
+	}
+	if strings.Join(attrs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("LEEF attributes:\n got: %q\nwant: %q", attrs, want)
+	}
+}
+
+func TestLEEF_Escaping(t *testing.T) {
+	evt := events.Event{ActivityCode: "group.add", Meta: "{\"note\":\"a\\tb\\nc\\\\d\"}"}
+
+	line, err := LEEF{}.Format(evt)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	if !strings.Contains(line, `meta_note=a\tb\nc\\d`) {
+		t.Errorf("Expected escaped attribute value, got %q", line)
+	}
+	if strings.Count(line, "\t") != strings.Count(line, "=")-1 {
+		t.Errorf("Expected one delimiter between each attribute, got %q", line)
+	}
+}
@@ -0,0 +1,261 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xh63/netbird-events/pkg/activity"
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// OCSFVersion is the OCSF schema version the mapping targets. It is written to
+// metadata.version so consumers can pick the matching parser.
+const OCSFVersion = "1.3.0"
+
+// ocsfVersions lists the schema versions New accepts as "ocsf-<version>".
+var ocsfVersions = map[string]bool{OCSFVersion: true}
+
+// OCSF category and class identifiers used by the mapping.
+const (
+	ocsfCategoryIAM         = 3 // Identity & Access Management
+	ocsfCategoryApplication = 6 // Application Activity
+
+	ocsfClassAccountChange    = 3001
+	ocsfClassAuthentication   = 3002
+	ocsfClassEntityManagement = 3004
+	ocsfClassAPIActivity      = 6003
+)
+
+var ocsfCategoryNames = map[int]string{
+	ocsfCategoryIAM:         "Identity & Access Management",
+	ocsfCategoryApplication: "Application Activity",
+}
+
+var ocsfClasses = map[int]struct {
+	name     string
+	category int
+}{
+	ocsfClassAccountChange:    {"Account Change", ocsfCategoryIAM},
+	ocsfClassAuthentication:   {"Authentication", ocsfCategoryIAM},
+	ocsfClassEntityManagement: {"Entity Management", ocsfCategoryIAM},
+	ocsfClassAPIActivity:      {"API Activity", ocsfCategoryApplication},
+}
+
+// ocsfActivityNames holds the activity_id captions per class.
+var ocsfActivityNames = map[int]map[int]string{
+	ocsfClassAccountChange: {
+		1: "Create", 2: "Enable", 3: "Password Change", 4: "Password Reset", 5: "Disable",
+		6: "Delete", 7: "Attach Policy", 8: "Detach Policy", 9: "Lock", 99: "Other",
+	},
+	ocsfClassAuthentication: {
+		1: "Logon", 2: "Logoff", 99: "Other",
+	},
+	ocsfClassEntityManagement: {
+		1: "Create", 2: "Read", 3: "Update", 4: "Delete", 5: "Move", 6: "Enroll", 7: "Unenroll",
+		8: "Enable", 9: "Disable", 10: "Activate", 11: "Deactivate", 12: "Suspend", 13: "Resume", 99: "Other",
+	},
+	ocsfClassAPIActivity: {
+		1: "Create", 2: "Read", 3: "Update", 4: "Delete", 99: "Other",
+	},
+}
+
+// OCSF renders events as OCSF JSON objects. Each NetBird activity is mapped
+// to one of Account Change, Authentication, Entity Management or API Activity
+// by ocsfMappings; activities without a mapping fall back to API Activity "Other".
+type OCSF struct {
+	// Version is the schema version reported in metadata.version.
+	Version string
+}
+
+// Format implements Formatter.
+func (o OCSF) Format(evt events.Event) (string, error) {
+	// Class and category names contain '&', which must stay readable
+	var buf strings.Builder
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(o.record(evt)); err != nil {
+		return "", fmt.Errorf("failed to marshal OCSF event: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// ocsfRecord is the subset of OCSF base event and class attributes eventsproc fills.
+type ocsfRecord struct {
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name"`
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name"`
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name"`
+	TypeUID      int    `json:"type_uid"`
+	TypeName     string `json:"type_name"`
+	SeverityID   int    `json:"severity_id"`
+	Severity     string `json:"severity"`
+	StatusID     int    `json:"status_id"`
+	Status       string `json:"status"`
+	Time         int64  `json:"time"`
+	Message      string `json:"message,omitempty"`
+
+	Metadata ocsfMetadata `json:"metadata"`
+	Actor    *ocsfActor   `json:"actor,omitempty"`
+
+	// Class-specific objects
+	User      *ocsfUser     `json:"user,omitempty"`      // Account Change, Authentication
+	Entity    *ocsfEntity   `json:"entity,omitempty"`    // Entity Management
+	API       *ocsfAPI      `json:"api,omitempty"`       // API Activity
+	Resources []ocsfEntity  `json:"resources,omitempty"` // API Activity
+	Device    *ocsfDevice   `json:"device,omitempty"`    // peer-related activities
+	Unmapped  *ocsfUnmapped `json:"unmapped,omitempty"`
+}
+
+type ocsfMetadata struct {
+	Version   string      `json:"version"`
+	UID       string      `json:"uid"`
+	TenantUID string      `json:"tenant_uid,omitempty"`
+	LogName   string      `json:"log_name"`
+	Product   ocsfProduct `json:"product"`
+}
+
+type ocsfProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version"`
+}
+
+type ocsfActor struct {
+	User *ocsfUser `json:"user,omitempty"`
+}
+
+type ocsfUser struct {
+	UID       string `json:"uid,omitempty"`
+	EmailAddr string `json:"email_addr,omitempty"`
+}
+
+type ocsfEntity struct {
+	UID  string `json:"uid,omitempty"`
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+}
+
+type ocsfAPI struct {
+	Operation string      `json:"operation"`
+	Service   ocsfService `json:"service"`
+}
+
+type ocsfService struct {
+	Name string `json:"name"`
+}
+
+type ocsfDevice struct {
+	UID      string `json:"uid,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	IP       string `json:"ip,omitempty"`
+	TypeID   int    `json:"type_id"`
+	Type     string `json:"type"`
+}
+
+// ocsfUnmapped carries NetBird fields OCSF has no attribute for.
+type ocsfUnmapped struct {
+	Activity     int            `json:"activity"`
+	ActivityCode string         `json:"activity_code"`
+	Source       string         `json:"source,omitempty"`
+	Meta         map[string]any `json:"meta,omitempty"`
+}
+
+// ocsfSeverity maps the activity severity category to OCSF severity_id.
+var ocsfSeverity = map[Severity]struct {
+	id   int
+	name string
+}{
+	SeverityInfo:    {1, "Informational"},
+	SeverityNotice:  {2, "Low"},
+	SeverityWarning: {3, "Medium"},
+}
+
+// record builds the OCSF object for an event.
+func (o OCSF) record(evt events.Event) ocsfRecord {
+	m, ok := ocsfMappings[activity.Activity(evt.Activity)]
+	if !ok {
+		m = ocsfMapping{class: ocsfClassAPIActivity, activityID: 99, entity: "Unknown"}
+	}
+	class := ocsfClasses[m.class]
+	activityName := ocsfActivityNames[m.class][m.activityID]
+	sev := ocsfSeverity[ActivitySeverity(evt.ActivityCode)]
+
+	meta := map[string]any{}
+	for _, f := range FlattenMeta(evt.Meta) {
+		meta[f.Key] = f.Value
+	}
+
+	rec := ocsfRecord{
+		CategoryUID:  class.category,
+		CategoryName: ocsfCategoryNames[class.category],
+		ClassUID:     m.class,
+		ClassName:    class.name,
+		ActivityID:   m.activityID,
+		ActivityName: activityName,
+		TypeUID:      m.class*100 + m.activityID,
+		TypeName:     class.name + ": " + activityName,
+		SeverityID:   sev.id,
+		Severity:     sev.name,
+		StatusID:     1,
+		Status:       "Success",
+		Time:         evt.Timestamp.UnixMilli(),
+		Message:      evt.ActivityName,
+		Metadata: ocsfMetadata{
+			Version:   o.Version,
+			UID:       strconv.FormatInt(evt.ID, 10),
+			TenantUID: evt.AccountID,
+			LogName:   "netbird.audit",
+			Product:   ocsfProduct{Name: DeviceProduct, VendorName: DeviceVendor, Version: DeviceVersion},
+		},
+		Unmapped: &ocsfUnmapped{Activity: evt.Activity, ActivityCode: evt.ActivityCode, Source: evt.Source},
+	}
+	if len(meta) > 0 {
+		rec.Unmapped.Meta = meta
+	}
+
+	initiator := newOCSFUser(evt.InitiatorID, evt.InitiatorEmail)
+	if initiator != nil {
+		rec.Actor = &ocsfActor{User: initiator}
+	}
+
+	switch m.class {
+	case ocsfClassAccountChange:
+		rec.User = newOCSFUser(evt.TargetID, evt.TargetEmail)
+	case ocsfClassAuthentication:
+		rec.User = initiator
+	case ocsfClassEntityManagement:
+		rec.Entity = &ocsfEntity{UID: evt.TargetID, Name: metaString(meta, "name"), Type: m.entity}
+	case ocsfClassAPIActivity:
+		rec.API = &ocsfAPI{Operation: evt.ActivityCode, Service: ocsfService{Name: "NetBird Management API"}}
+		rec.Resources = []ocsfEntity{{UID: evt.TargetID, Name: metaString(meta, "name"), Type: m.entity}}
+	}
+
+	if m.device {
+		rec.Device = &ocsfDevice{
+			UID:      evt.TargetID,
+			Name:     metaString(meta, "name"),
+			Hostname: metaString(meta, "fqdn"),
+			IP:       metaString(meta, "ip"),
+			TypeID:   0,
+			Type:     "Unknown",
+		}
+	}
+	return rec
+}
+
+func newOCSFUser(uid, email string) *ocsfUser {
+	if uid == "" && email == "" {
+		return nil
+	}
+	return &ocsfUser{UID: uid, EmailAddr: email}
+}
+
+func metaString(meta map[string]any, key string) string {
+	s, _ := meta[key].(string)
+	return s
+}
@@ -0,0 +1,183 @@
+package format
+
+import "github.com/xh63/netbird-events/pkg/activity"
+
+// ocsfMapping places one NetBird activity in the OCSF schema.
+type ocsfMapping struct {
+	class      int    // class_uid
+	activityID int    // activity_id within the class
+	entity     string // managed entity / resource type (Entity Management, API Activity)
+	device     bool   // the target is a peer: populate the device object
+}
+
+// Shorthands for the table below.
+func accountChange(activityID int) ocsfMapping {
+	return ocsfMapping{class: ocsfClassAccountChange, activityID: activityID}
+}
+
+func authentication(activityID int, device bool) ocsfMapping {
+	return ocsfMapping{class: ocsfClassAuthentication, activityID: activityID, device: device}
+}
+
+func entityMgmt(activityID int, entity string) ocsfMapping {
+	return ocsfMapping{class: ocsfClassEntityManagement, activityID: activityID, entity: entity}
+}
+
+func peerMgmt(activityID int) ocsfMapping {
+	return ocsfMapping{class: ocsfClassEntityManagement, activityID: activityID, entity: "Peer", device: true}
+}
+
+func apiActivity(activityID int, resource string) ocsfMapping {
+	return ocsfMapping{class: ocsfClassAPIActivity, activityID: activityID, entity: resource}
+}
+
+// Activity IDs shared by Entity Management and API Activity.
+const (
+	opCreate     = 1
+	opUpdate     = 3
+	opDelete     = 4
+	opEnroll     = 6
+	opUnenroll   = 7
+	opEnable     = 8
+	opDisable    = 9
+	opActivate   = 10
+	opDeactivate = 11
+	opOther      = 99
+)
+
+// Account Change activity IDs.
+const (
+	acctCreate       = 1
+	acctEnable       = 2
+	acctDisable      = 5
+	acctDelete       = 6
+	acctAttachPolicy = 7
+	acctDetachPolicy = 8
+)
+
+// Authentication activity IDs.
+const (
+	authLogon  = 1
+	authLogoff = 2
+)
+
+// ocsfMappings assigns every NetBird activity an OCSF class and activity:
+//   - user lifecycle, role and group membership -> Account Change
+//   - dashboard and peer logins, login expiry -> Authentication
+//   - CRUD on peers, groups, policies, routes, networks, settings -> Entity Management
+//   - API credentials and integrations -> API Activity
+var ocsfMappings = map[activity.Activity]ocsfMapping{
+	// Users
+	activity.UserJoined:           accountChange(acctCreate),
+	activity.UserInvited:          accountChange(acctCreate),
+	activity.ServiceUserCreated:   accountChange(acctCreate),
+	activity.ServiceUserDeleted:   accountChange(acctDelete),
+	activity.UserDeleted:          accountChange(acctDelete),
+	activity.UserBlocked:          accountChange(acctDisable),
+	activity.UserUnblocked:        accountChange(acctEnable),
+	activity.UserApproved:         accountChange(acctEnable),
+	activity.UserRejected:         accountChange(acctDelete),
+	activity.UserRoleUpdated:      accountChange(acctAttachPolicy),
+	activity.TransferredOwnerRole: accountChange(acctAttachPolicy),
+	activity.GroupAddedToUser:     accountChange(acctAttachPolicy),
+	activity.GroupRemovedFromUser: accountChange(acctDetachPolicy),
+
+	// Logins
+	activity.DashboardLogin:   authentication(authLogon, false),
+	activity.UserLoggedInPeer: authentication(authLogon, true),
+	activity.PeerLoginExpired: authentication(authLogoff, true),
+
+	// API credentials and integrations
+	activity.PersonalAccessTokenCreated: apiActivity(opCreate, "Personal Access Token"),
+	activity.PersonalAccessTokenDeleted: apiActivity(opDelete, "Personal Access Token"),
+	activity.IntegrationCreated:         apiActivity(opCreate, "Integration"),
+	activity.IntegrationUpdated:         apiActivity(opUpdate, "Integration"),
+	activity.IntegrationDeleted:         apiActivity(opDelete, "Integration"),
+
+	// Account
+	activity.AccountCreated: entityMgmt(opCreate, "Account"),
+	activity.AccountDeleted: entityMgmt(opDelete, "Account"),
+
+	// Peers
+	activity.PeerAddedByUser:                  peerMgmt(opEnroll),
+	activity.PeerAddedWithSetupKey:            peerMgmt(opEnroll),
+	activity.PeerRemovedByUser:                peerMgmt(opUnenroll),
+	activity.PeerRenamed:                      peerMgmt(opUpdate),
+	activity.PeerIPUpdated:                    peerMgmt(opUpdate),
+	activity.GroupAddedToPeer:                 peerMgmt(opUpdate),
+	activity.GroupRemovedFromPeer:             peerMgmt(opUpdate),
+	activity.PeerSSHEnabled:                   peerMgmt(opEnable),
+	activity.PeerSSHDisabled:                  peerMgmt(opDisable),
+	activity.PeerLoginExpirationEnabled:       peerMgmt(opEnable),
+	activity.PeerLoginExpirationDisabled:      peerMgmt(opDisable),
+	activity.PeerInactivityExpirationEnabled:  peerMgmt(opEnable),
+	activity.PeerInactivityExpirationDisabled: peerMgmt(opDisable),
+	activity.PeerApproved:                     peerMgmt(opActivate),
+	activity.PeerApprovalRevoked:              peerMgmt(opDeactivate),
+
+	// Access control
+	activity.RuleAdded:           entityMgmt(opCreate, "Rule"),
+	activity.RuleUpdated:         entityMgmt(opUpdate, "Rule"),
+	activity.RuleRemoved:         entityMgmt(opDelete, "Rule"),
+	activity.PolicyAdded:         entityMgmt(opCreate, "Policy"),
+	activity.PolicyUpdated:       entityMgmt(opUpdate, "Policy"),
+	activity.PolicyRemoved:       entityMgmt(opDelete, "Policy"),
+	activity.PostureCheckCreated: entityMgmt(opCreate, "Posture Check"),
+	activity.PostureCheckUpdated: entityMgmt(opUpdate, "Posture Check"),
+	activity.PostureCheckDeleted: entityMgmt(opDelete, "Posture Check"),
+
+	// Setup keys
+	activity.SetupKeyCreated:          entityMgmt(opCreate, "Setup Key"),
+	activity.SetupKeyUpdated:          entityMgmt(opUpdate, "Setup Key"),
+	activity.SetupKeyRevoked:          entityMgmt(opDeactivate, "Setup Key"),
+	activity.SetupKeyOverused:         entityMgmt(opOther, "Setup Key"),
+	activity.SetupKeyDeleted:          entityMgmt(opDelete, "Setup Key"),
+	activity.GroupAddedToSetupKey:     entityMgmt(opUpdate, "Setup Key"),
+	activity.GroupRemovedFromSetupKey: entityMgmt(opUpdate, "Setup Key"),
+
+	// Groups
+	activity.GroupCreated:             entityMgmt(opCreate, "Group"),
+	activity.GroupUpdated:             entityMgmt(opUpdate, "Group"),
+	activity.GroupDeleted:             entityMgmt(opDelete, "Group"),
+	activity.ResourceAddedToGroup:     entityMgmt(opUpdate, "Group"),
+	activity.ResourceRemovedFromGroup: entityMgmt(opUpdate, "Group"),
+
+	// Routes and DNS
+	activity.RouteCreated:                             entityMgmt(opCreate, "Route"),
+	activity.RouteUpdated:                             entityMgmt(opUpdate, "Route"),
+	activity.RouteRemoved:                             entityMgmt(opDelete, "Route"),
+	activity.NameserverGroupCreated:                   entityMgmt(opCreate, "Nameserver Group"),
+	activity.NameserverGroupUpdated:                   entityMgmt(opUpdate, "Nameserver Group"),
+	activity.NameserverGroupDeleted:                   entityMgmt(opDelete, "Nameserver Group"),
+	activity.GroupAddedToDisabledManagementGroups:     entityMgmt(opUpdate, "DNS Settings"),
+	activity.GroupRemovedFromDisabledManagementGroups: entityMgmt(opUpdate, "DNS Settings"),
+
+	// Networks
+	activity.NetworkCreated:         entityMgmt(opCreate, "Network"),
+	activity.NetworkUpdated:         entityMgmt(opUpdate, "Network"),
+	activity.NetworkDeleted:         entityMgmt(opDelete, "Network"),
+	activity.NetworkResourceCreated: entityMgmt(opCreate, "Network Resource"),
+	activity.NetworkResourceUpdated: entityMgmt(opUpdate, "Network Resource"),
+	activity.NetworkResourceDeleted: entityMgmt(opDelete, "Network Resource"),
+	activity.NetworkRouterCreated:   entityMgmt(opCreate, "Network Router"),
+	activity.NetworkRouterUpdated:   entityMgmt(opUpdate, "Network Router"),
+	activity.NetworkRouterDeleted:   entityMgmt(opDelete, "Network Router"),
+
+	// Account settings
+	activity.AccountPeerLoginExpirationEnabled:              entityMgmt(opEnable, "Account Settings"),
+	activity.AccountPeerLoginExpirationDisabled:             entityMgmt(opDisable, "Account Settings"),
+	activity.AccountPeerLoginExpirationDurationUpdated:      entityMgmt(opUpdate, "Account Settings"),
+	activity.AccountPeerApprovalEnabled:                     entityMgmt(opEnable, "Account Settings"),
+	activity.AccountPeerApprovalDisabled:                    entityMgmt(opDisable, "Account Settings"),
+	activity.AccountPeerInactivityExpirationEnabled:         entityMgmt(opEnable, "Account Settings"),
+	activity.AccountPeerInactivityExpirationDisabled:        entityMgmt(opDisable, "Account Settings"),
+	activity.AccountPeerInactivityExpirationDurationUpdated: entityMgmt(opUpdate, "Account Settings"),
+	activity.UserGroupPropagationEnabled:                    entityMgmt(opEnable, "Account Settings"),
+	activity.UserGroupPropagationDisabled:                   entityMgmt(opDisable, "Account Settings"),
+	activity.AccountRoutingPeerDNSResolutionEnabled:         entityMgmt(opEnable, "Account Settings"),
+	activity.AccountRoutingPeerDNSResolutionDisabled:        entityMgmt(opDisable, "Account Settings"),
+	activity.AccountDNSDomainUpdated:                        entityMgmt(opUpdate, "Account Settings"),
+	activity.AccountLazyConnectionEnabled:                   entityMgmt(opEnable, "Account Settings"),
+	activity.AccountLazyConnectionDisabled:                  entityMgmt(opDisable, "Account Settings"),
+	activity.AccountNetworkRangeUpdated:                     entityMgmt(opUpdate, "Account Settings"),
+}
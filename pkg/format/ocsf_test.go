@@ -0,0 +1,118 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/activity"
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// Regenerate golden files with: go test ./pkg/format -run TestOCSF_Golden -update
+var update = flag.Bool("update", false, "update golden files")
+
+// ocsfTestEvent builds a deterministic event for an activity, enriched the way
+// the readers enrich events.
+func ocsfTestEvent(act activity.Activity) events.Event {
+	evt := events.Event{
+		ID:             1000 + int64(act),
+		Timestamp:      time.Date(2026, 1, 28, 10, 30, 0, 123_000_000, time.UTC),
+		Activity:       int(act),
+		AccountID:      "acc1",
+		InitiatorID:    "admin1",
+		InitiatorEmail: "admin@example.com",
+		TargetID:       "target1",
+		TargetEmail:    "target@example.com",
+		Meta:           `{"name":"laptop-1","fqdn":"laptop-1.netbird.cloud","ip":"100.64.0.7"}`,
+	}
+	events.EnrichActivityInfo(&evt)
+	return evt
+}
+
+func TestOCSF_MappingCoversActivityMap(t *testing.T) {
+	for _, act := range activity.Activities() {
+		m, ok := ocsfMappings[act]
+		if !ok {
+			t.Errorf("activity %d (%s) has no OCSF mapping", act, act.StringCode())
+			continue
+		}
+		if _, ok := ocsfActivityNames[m.class][m.activityID]; !ok {
+			t.Errorf("activity %d (%s): activity_id %d is not defined for class %d", act, act.StringCode(), m.activityID, m.class)
+		}
+	}
+}
+
+// TestOCSF_Golden renders every code in the activity map and compares it with
+// testdata/ocsf/<code>.json.
+func TestOCSF_Golden(t *testing.T) {
+	f := OCSF{Version: OCSFVersion}
+
+	for _, act := range activity.Activities() {
+		code := act.StringCode()
+		t.Run(code, func(t *testing.T) {
+			line, err := f.Format(ocsfTestEvent(act))
+			if err != nil {
+				t.Fatalf("Format failed: %v", err)
+			}
+			var got bytes.Buffer
+			if err := json.Indent(&got, []byte(line), "", "  "); err != nil {
+				t.Fatalf("Output is not valid JSON: %v", err)
+			}
+			got.WriteByte('\n')
+
+			path := filepath.Join("testdata", "ocsf", code+".json")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, got.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file (run with -update): %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("OCSF output for %s differs from %s:\n got: %s\nwant: %s", code, path, got.String(), want)
+			}
+		})
+	}
+}
+
+func TestOCSF_UnknownActivity(t *testing.T) {
+	line, err := OCSF{Version: OCSFVersion}.Format(events.Event{ID: 1, Activity: 12345, ActivityCode: "UNKNOWN_ACTIVITY"})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		t.Fatalf("Output is not valid JSON: %v", err)
+	}
+	if rec["class_uid"] != float64(ocsfClassAPIActivity) || rec["activity_id"] != float64(99) {
+		t.Errorf("Expected API Activity/Other fallback, got class_uid=%v activity_id=%v", rec["class_uid"], rec["activity_id"])
+	}
+	if _, ok := rec["actor"]; ok {
+		t.Error("Expected no actor without initiator")
+	}
+}
+
+func TestNew_OCSFVersion(t *testing.T) {
+	f, err := New("ocsf-" + OCSFVersion)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if f.(OCSF).Version != OCSFVersion {
+		t.Errorf("Expected version %s, got %s", OCSFVersion, f.(OCSF).Version)
+	}
+	if _, err := New("ocsf-0.9.0"); err == nil {
+		t.Error("Expected error for unsupported OCSF version")
+	}
+}
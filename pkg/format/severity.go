@@ -0,0 +1,79 @@
+package format
+
+import "strings"
+
+// Severity is the coarse risk category of an activity, shared by the syslog
+// severity and the CEF/LEEF severity fields.
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // logins and everything else
+	SeverityNotice                  // configuration changes
+	SeverityWarning                 // privilege changes and destructive actions
+)
+
+// severityRules maps the trailing action of an activity code to a severity.
+// NetBird codes read "<object>.<action>" (e.g. "setupkey.revoke",
+// "user.role.update"), so the action suffix is a stable category signal:
+//   - privilege changes and destructive actions are warnings, so SIEM rules
+//     can alert on them without enumerating every code
+//   - configuration changes are notices
+//   - logins and everything else are informational
+var severityRules = []struct {
+	suffix   string
+	severity Severity
+}{
+	{"role.update", SeverityWarning},
+	{"owner.role", SeverityWarning},
+	{".delete", SeverityWarning},
+	{".remove", SeverityWarning},
+	{".revoke", SeverityWarning},
+	{".block", SeverityWarning},
+	{".reject", SeverityWarning},
+	{".overuse", SeverityWarning},
+	{".expire", SeverityWarning},
+	{".disable", SeverityNotice},
+	{".enable", SeverityNotice},
+	{".create", SeverityNotice},
+	{".add", SeverityNotice},
+	{".update", SeverityNotice},
+	{".rename", SeverityNotice},
+	{".approve", SeverityNotice},
+	{".unblock", SeverityNotice},
+	{".invite", SeverityNotice},
+}
+
+// ActivitySeverity returns the severity category for a NetBird activity code.
+// Unknown codes map to SeverityInfo.
+func ActivitySeverity(code string) Severity {
+	for _, rule := range severityRules {
+		if strings.HasSuffix(code, rule.suffix) {
+			return rule.severity
+		}
+	}
+	return SeverityInfo
+}
+
+// String returns the lower-case severity name ("info", "notice", "warning").
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNotice:
+		return "notice"
+	default:
+		return "info"
+	}
+}
+
+// score maps a severity to the 0-10 scale used by CEF and LEEF.
+func (s Severity) score() int {
+	switch s {
+	case SeverityWarning:
+		return 7
+	case SeverityNotice:
+		return 5
+	default:
+		return 3
+	}
+}
@@ -0,0 +1,302 @@
+// Package kafka produces events to a Kafka topic with an idempotent producer,
+// keyed so that all events of one account land on the same partition in order.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Supported values for Config.KeyField.
+const (
+	KeyAccountID    = "account_id" // default
+	KeyActivityCode = "activity_code"
+	KeyInitiatorID  = "initiator_id"
+	KeyTargetID     = "target_id"
+	KeyEventID      = "event_id"
+	KeyNone         = "none" // no key: records are spread over partitions
+)
+
+// Supported values for Config.SASLMechanism.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// Record header names.
+const (
+	HeaderActivityCode = "activity_code"
+	HeaderEventID      = "event_id"
+)
+
+// Config holds configuration for the Kafka writer.
+type Config struct {
+	// Brokers are the seed brokers, e.g. ["kafka-1:9092", "kafka-2:9092"].
+	Brokers []string
+
+	// Topic receives all events.
+	Topic string
+
+	// KeyField selects the event field used as record key, and therefore the
+	// partition: "account_id" (default), "activity_code", "initiator_id",
+	// "target_id", "event_id" or "none".
+	KeyField string
+
+	// Format of each record value: "json" (default) or any other format.New name.
+	Format string
+
+	// Compression of produced batches: "snappy" (default), "gzip", "lz4", "zstd" or "none".
+	Compression string
+
+	// ClientID is sent to the brokers (default: eventsproc).
+	ClientID string
+
+	// SASLMechanism enables SASL authentication: "PLAIN", "SCRAM-SHA-256" or "SCRAM-SHA-512".
+	SASLMechanism string
+	Username      string
+	Password      string
+
+	// TLSEnabled connects over TLS. It is implied when any TLS option is set.
+	TLSEnabled bool
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+
+	// Timeout bounds how long a batch may take to be acknowledged. Default: 30s.
+	Timeout time.Duration
+}
+
+// Writer produces events to Kafka. The producer is idempotent and waits for
+// acknowledgement from all in-sync replicas, so broker retries never duplicate
+// or reorder records within a partition.
+type Writer struct {
+	client    *kgo.Client
+	topic     string
+	keyField  string
+	formatter format.Formatter
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+// NewWriter creates a Kafka writer. Brokers are contacted lazily on the first
+// batch, so an unreachable cluster does not prevent startup.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers are required")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+
+	keyField := cfg.KeyField
+	if keyField == "" {
+		keyField = KeyAccountID
+	}
+	switch keyField {
+	case KeyAccountID, KeyActivityCode, KeyInitiatorID, KeyTargetID, KeyEventID, KeyNone:
+	default:
+		return nil, fmt.Errorf("unsupported kafka key_field %q", keyField)
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka format: %w", err)
+	}
+
+	compression, err := compressionCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "eventsproc"
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.ClientID(clientID),
+		// Idempotent writes are the franz-go default and require acks=all
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.ProducerBatchCompression(compression),
+		kgo.RecordDeliveryTimeout(timeout),
+		kgo.ProduceRequestTimeout(timeout),
+		// Hash keys like the Java client (murmur2), so consumers in other
+		// languages can compute the partition of an account
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.WithLogger(&kgoLogger{logger: logger}),
+	}
+
+	if cfg.TLSEnabled || !cfg.TLS.IsZero() {
+		tlsCfg, err := cfg.TLS.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid kafka tls config: %w", err)
+		}
+		opts = append(opts, kgo.DialTLSConfig(tlsCfg))
+	}
+
+	switch strings.ToUpper(cfg.SASLMechanism) {
+	case "":
+	case SASLPlain:
+		opts = append(opts, kgo.SASL(plain.Auth{User: cfg.Username, Pass: cfg.Password}.AsMechanism()))
+	case SASLScramSHA256:
+		opts = append(opts, kgo.SASL(scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha256Mechanism()))
+	case SASLScramSHA512:
+		opts = append(opts, kgo.SASL(scram.Auth{User: cfg.Username, Pass: cfg.Password}.AsSha512Mechanism()))
+	default:
+		return nil, fmt.Errorf("unsupported kafka sasl mechanism %q (use PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512)", cfg.SASLMechanism)
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka client: %w", err)
+	}
+
+	return &Writer{
+		client:    client,
+		topic:     cfg.Topic,
+		keyField:  keyField,
+		formatter: formatter,
+		timeout:   timeout,
+		logger:    logger,
+	}, nil
+}
+
+// compressionCodec maps Config.Compression to a franz-go codec.
+func compressionCodec(name string) (kgo.CompressionCodec, error) {
+	switch name {
+	case "", "snappy":
+		return kgo.SnappyCompression(), nil
+	case "gzip":
+		return kgo.GzipCompression(), nil
+	case "lz4":
+		return kgo.Lz4Compression(), nil
+	case "zstd":
+		return kgo.ZstdCompression(), nil
+	case "none":
+		return kgo.NoCompression(), nil
+	}
+	return kgo.CompressionCodec{}, fmt.Errorf("unsupported kafka compression %q (use snappy, gzip, lz4, zstd or none)", name)
+}
+
+// SendEvents produces one record per event and returns once every record has
+// been acknowledged by the cluster. If any record fails the batch fails, so the
+// checkpoint does not advance; records that did succeed are produced again on
+// retry, so consumers should deduplicate on the event_id header. Events that
+// cannot be formatted are skipped and returned in a writer.RejectedError once
+// the rest was produced.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	records := make([]*kgo.Record, 0, len(eventsList))
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		value, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		// Timestamp is left to the client (produce time): franz-go measures the
+		// delivery timeout from it, so old events would expire immediately
+		records = append(records, &kgo.Record{
+			Topic: w.topic,
+			Key:   w.key(evt),
+			Value: []byte(value),
+			Headers: []kgo.RecordHeader{
+				{Key: HeaderActivityCode, Value: []byte(evt.ActivityCode)},
+				{Key: HeaderEventID, Value: []byte(evt.DedupID())},
+			},
+		})
+	}
+	if len(records) == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	var errs []error
+	for _, res := range w.client.ProduceSync(ctx, records...) {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to produce %d of %d events to kafka topic %s: %w",
+			len(errs), len(records), w.topic, errors.Join(errs...))
+	}
+
+	w.logger.Debug("Produced events to Kafka", "topic", w.topic, "count", len(records))
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent produces a single event
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the client. SendEvents only returns after delivery, so there
+// are no buffered records left to flush.
+func (w *Writer) Close() error {
+	w.client.Close()
+	return nil
+}
+
+// key returns the record key for evt, or nil when keying is disabled.
+func (w *Writer) key(evt events.Event) []byte {
+	switch w.keyField {
+	case KeyActivityCode:
+		return []byte(evt.ActivityCode)
+	case KeyInitiatorID:
+		return []byte(evt.InitiatorID)
+	case KeyTargetID:
+		return []byte(evt.TargetID)
+	case KeyEventID:
+		return []byte(evt.DedupID())
+	case KeyNone:
+		return nil
+	}
+	return []byte(evt.AccountID)
+}
+
+// kgoLogger forwards franz-go client logs to slog. Client internals are
+// noisy at info level, so everything below warn is logged at debug.
+type kgoLogger struct {
+	logger *slog.Logger
+}
+
+func (l *kgoLogger) Level() kgo.LogLevel {
+	return kgo.LogLevelInfo
+}
+
+func (l *kgoLogger) Log(level kgo.LogLevel, msg string, keyvals ...any) {
+	switch level {
+	case kgo.LogLevelError:
+		l.logger.Error(msg, keyvals...)
+	case kgo.LogLevelWarn:
+		l.logger.Warn(msg, keyvals...)
+	default:
+		l.logger.Debug(msg, keyvals...)
+	}
+}
@@ -0,0 +1,592 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var list []events.Event
+	for id := int64(1); id <= 6; id++ {
+		acc := "acc1"
+		if id%2 == 0 {
+			acc = "acc2"
+		}
+		list = append(list, events.Event{ID: id, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: acc})
+	}
+	return list
+}
+
+// Kafka API keys handled by the fake broker.
+const (
+	keyProduce          int16 = 0
+	keyMetadata         int16 = 3
+	keySASLHandshake    int16 = 17
+	keyApiVersions      int16 = 18
+	keyInitProducerID   int16 = 22
+	keySASLAuthenticate int16 = 36
+)
+
+// Kafka error codes used by the tests.
+const (
+	errNotEnoughReplicas        int16 = 19
+	errTopicAuthorizationFailed int16 = 29
+	errSASLAuthenticationFailed int16 = 58
+)
+
+const fakeProducerID = 1000
+
+// fakeBroker is a single-node, in-process Kafka broker speaking just enough of
+// the protocol for an idempotent producer: ApiVersions, Metadata,
+// InitProducerID, Produce and SASL/SCRAM-SHA-256.
+//
+// Tests set the configuration fields and then call start.
+type fakeBroker struct {
+	topic      string
+	partitions int32
+
+	// user and pass require SCRAM-SHA-256 authentication when set.
+	user, pass string
+
+	ln net.Listener
+	mu sync.Mutex
+	// records holds the produced records per partition, in offset order.
+	records map[int32][]kmsg.Record
+	// produceErrs are returned (in order) for the next produced partitions.
+	produceErrs []int16
+	// failAlways is returned for every produced partition when set.
+	failAlways int16
+	problems   []string
+	acks       []int16
+}
+
+func (b *fakeBroker) start(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	b.ln = ln
+	b.records = map[int32][]kmsg.Record{}
+	go b.serve()
+	t.Cleanup(func() { _ = ln.Close() })
+}
+
+func (b *fakeBroker) addr() string { return b.ln.Addr().String() }
+
+// problem records a protocol violation; reported once the test reads results.
+func (b *fakeBroker) problem(format string, args ...any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.problems = append(b.problems, fmt.Sprintf(format, args...))
+}
+
+func (b *fakeBroker) checkProblems(t *testing.T) {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, p := range b.problems {
+		t.Error(p)
+	}
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handleConn(conn)
+	}
+}
+
+// connState is the per-connection SASL state.
+type connState struct {
+	authenticated bool
+	scram         *scramServer
+}
+
+func (b *fakeBroker) handleConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	state := &connState{authenticated: b.user == ""}
+	for {
+		var size int32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		key := int16(binary.BigEndian.Uint16(msg[0:]))
+		version := int16(binary.BigEndian.Uint16(msg[2:]))
+		correlationID := binary.BigEndian.Uint32(msg[4:])
+		req := kmsg.RequestForKey(key)
+		if req == nil {
+			b.problem("unexpected request key %d", key)
+			return
+		}
+		req.SetVersion(version)
+
+		// Request header v1: client ID as a nullable string; v2 adds tagged fields
+		body := msg[8:]
+		if n := int16(binary.BigEndian.Uint16(body)); n > 0 {
+			body = body[2+int(n):]
+		} else {
+			body = body[2:]
+		}
+		if req.IsFlexible() {
+			body = skipTags(body)
+		}
+		if err := req.ReadFrom(body); err != nil {
+			b.problem("failed to decode request key %d v%d: %v", key, version, err)
+			return
+		}
+
+		if !state.authenticated && key != keyApiVersions && key != keySASLHandshake && key != keySASLAuthenticate {
+			b.problem("request key %d before SASL authentication", key)
+			return
+		}
+
+		resp := b.handle(req, state)
+
+		out := binary.BigEndian.AppendUint32(make([]byte, 4, 64), correlationID)
+		// Flexible responses carry empty tagged fields, except ApiVersions
+		// which always uses response header v0
+		if req.IsFlexible() && key != keyApiVersions {
+			out = append(out, 0)
+		}
+		out = resp.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+// skipTags skips the tagged fields of a flexible request header.
+func skipTags(b []byte) []byte {
+	n, used := binary.Uvarint(b)
+	b = b[used:]
+	for range n {
+		_, used = binary.Uvarint(b) // tag
+		b = b[used:]
+		size, used := binary.Uvarint(b)
+		b = b[used+int(size):]
+	}
+	return b
+}
+
+func (b *fakeBroker) handle(req kmsg.Request, state *connState) kmsg.Response {
+	switch req := req.(type) {
+	case *kmsg.ApiVersionsRequest:
+		resp := req.ResponseKind().(*kmsg.ApiVersionsResponse)
+		for _, key := range []int16{keyProduce, keyMetadata, keySASLHandshake, keyApiVersions, keyInitProducerID, keySASLAuthenticate} {
+			resp.ApiKeys = append(resp.ApiKeys, kmsg.ApiVersionsResponseApiKey{
+				ApiKey:     key,
+				MaxVersion: kmsg.RequestForKey(key).MaxVersion(),
+			})
+		}
+		return resp
+
+	case *kmsg.SASLHandshakeRequest:
+		resp := req.ResponseKind().(*kmsg.SASLHandshakeResponse)
+		resp.SupportedMechanisms = []string{SASLScramSHA256}
+		if req.Mechanism != SASLScramSHA256 {
+			resp.ErrorCode = 33 // UNSUPPORTED_SASL_MECHANISM
+		}
+		state.scram = &scramServer{user: b.user, pass: b.pass}
+		return resp
+
+	case *kmsg.SASLAuthenticateRequest:
+		resp := req.ResponseKind().(*kmsg.SASLAuthenticateResponse)
+		if state.scram == nil {
+			resp.ErrorCode = errSASLAuthenticationFailed
+			return resp
+		}
+		out, done, err := state.scram.step(req.SASLAuthBytes)
+		if err != nil {
+			msg := err.Error()
+			resp.ErrorCode = errSASLAuthenticationFailed
+			resp.ErrorMessage = &msg
+			return resp
+		}
+		resp.SASLAuthBytes = out
+		state.authenticated = done
+		return resp
+
+	case *kmsg.MetadataRequest:
+		resp := req.ResponseKind().(*kmsg.MetadataResponse)
+		host, port, _ := net.SplitHostPort(b.addr())
+		p, _ := strconv.Atoi(port)
+		broker := kmsg.NewMetadataResponseBroker()
+		broker.NodeID = 0
+		broker.Host = host
+		broker.Port = int32(p)
+		resp.Brokers = []kmsg.MetadataResponseBroker{broker}
+		resp.ControllerID = 0
+		topic := kmsg.NewMetadataResponseTopic()
+		topic.Topic = &b.topic
+		for i := range b.partitions {
+			part := kmsg.NewMetadataResponseTopicPartition()
+			part.Partition = i
+			part.Leader = 0
+			part.Replicas = []int32{0}
+			part.ISR = []int32{0}
+			topic.Partitions = append(topic.Partitions, part)
+		}
+		resp.Topics = []kmsg.MetadataResponseTopic{topic}
+		return resp
+
+	case *kmsg.InitProducerIDRequest:
+		resp := req.ResponseKind().(*kmsg.InitProducerIDResponse)
+		resp.ProducerID = fakeProducerID
+		return resp
+
+	case *kmsg.ProduceRequest:
+		return b.produce(req)
+	}
+	b.problem("unhandled request %T", req)
+	return req.ResponseKind()
+}
+
+func (b *fakeBroker) produce(req *kmsg.ProduceRequest) kmsg.Response {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.acks = append(b.acks, req.Acks)
+	resp := req.ResponseKind().(*kmsg.ProduceResponse)
+	for _, topic := range req.Topics {
+		rt := kmsg.NewProduceResponseTopic()
+		rt.Topic = topic.Topic
+		for _, part := range topic.Partitions {
+			rp := kmsg.NewProduceResponseTopicPartition()
+			rp.Partition = part.Partition
+			rp.BaseOffset = int64(len(b.records[part.Partition]))
+
+			var batch kmsg.RecordBatch
+			if err := batch.ReadFrom(part.Records); err != nil {
+				b.problems = append(b.problems, fmt.Sprintf("invalid record batch: %v", err))
+				continue
+			}
+			if batch.ProducerID != fakeProducerID {
+				b.problems = append(b.problems, fmt.Sprintf("batch without idempotent producer ID: %d", batch.ProducerID))
+			}
+			// Idempotent producers number records per partition without gaps,
+			// also when a batch is retried
+			if int(batch.FirstSequence) != len(b.records[part.Partition]) {
+				b.problems = append(b.problems, fmt.Sprintf("partition %d: sequence %d, want %d",
+					part.Partition, batch.FirstSequence, len(b.records[part.Partition])))
+			}
+
+			switch {
+			case b.failAlways != 0:
+				rp.ErrorCode = b.failAlways
+			case len(b.produceErrs) > 0:
+				rp.ErrorCode = b.produceErrs[0]
+				b.produceErrs = b.produceErrs[1:]
+			default:
+				b.records[part.Partition] = append(b.records[part.Partition], readRecords(int(batch.NumRecords), batch.Records)...)
+			}
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		resp.Topics = append(resp.Topics, rt)
+	}
+	return resp
+}
+
+// readRecords decodes the length-prefixed records of an uncompressed batch.
+func readRecords(n int, in []byte) []kmsg.Record {
+	var records []kmsg.Record
+	for range n {
+		length, used := binary.Varint(in)
+		total := used + int(length)
+		var r kmsg.Record
+		if err := r.ReadFrom(in[:total]); err != nil {
+			return records
+		}
+		records = append(records, r)
+		in = in[total:]
+	}
+	return records
+}
+
+// scramServer is the broker side of a SCRAM-SHA-256 exchange (RFC 5802).
+type scramServer struct {
+	user, pass  string
+	clientFirst string
+	serverFirst string
+	salted      []byte
+}
+
+func (s *scramServer) step(msg []byte) ([]byte, bool, error) {
+	if s.serverFirst == "" {
+		bare, ok := strings.CutPrefix(string(msg), "n,,")
+		if !ok {
+			return nil, false, errors.New("unexpected gs2 header")
+		}
+		attrs := scramAttrs(bare)
+		if attrs["n"] != s.user {
+			return nil, false, errors.New("unknown user")
+		}
+		salt := []byte("netbird-salt")
+		var err error
+		s.salted, err = pbkdf2.Key(sha256.New, s.pass, salt, 4096, sha256.Size)
+		if err != nil {
+			return nil, false, err
+		}
+		s.clientFirst = bare
+		s.serverFirst = "r=" + attrs["r"] + "srvnonce,s=" + base64.StdEncoding.EncodeToString(salt) + ",i=4096"
+		return []byte(s.serverFirst), false, nil
+	}
+
+	final := string(msg)
+	idx := strings.LastIndex(final, ",p=")
+	if idx < 0 {
+		return nil, false, errors.New("missing proof")
+	}
+	proof, err := base64.StdEncoding.DecodeString(final[idx+3:])
+	if err != nil {
+		return nil, false, err
+	}
+	authMessage := s.clientFirst + "," + s.serverFirst + "," + final[:idx]
+
+	clientKey := hmacSHA256(s.salted, "Client Key")
+	storedKey := sha256.Sum256(clientKey)
+	signature := hmacSHA256(storedKey[:], authMessage)
+	if len(proof) != len(signature) {
+		return nil, false, errors.New("invalid proof")
+	}
+	for i := range proof {
+		proof[i] ^= signature[i]
+	}
+	if got := sha256.Sum256(proof); !hmac.Equal(got[:], storedKey[:]) {
+		return nil, false, errors.New("authentication failed")
+	}
+
+	serverKey := hmacSHA256(s.salted, "Server Key")
+	return []byte("v=" + base64.StdEncoding.EncodeToString(hmacSHA256(serverKey, authMessage))), true, nil
+}
+
+func scramAttrs(msg string) map[string]string {
+	attrs := map[string]string{}
+	for _, kv := range strings.Split(msg, ",") {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			attrs[k] = v
+		}
+	}
+	return attrs
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+func newTestWriter(t *testing.T, cfg Config) *Writer {
+	t.Helper()
+	if cfg.Compression == "" {
+		cfg.Compression = "none" // the fake broker reads uncompressed batches
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	w, err := NewWriter(&cfg, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing brokers", Config{Topic: "events"}},
+		{"missing topic", Config{Brokers: []string{"localhost:9092"}}},
+		{"bad key field", Config{Brokers: []string{"localhost:9092"}, Topic: "events", KeyField: "email"}},
+		{"bad compression", Config{Brokers: []string{"localhost:9092"}, Topic: "events", Compression: "brotli"}},
+		{"bad sasl", Config{Brokers: []string{"localhost:9092"}, Topic: "events", SASLMechanism: "GSSAPI"}},
+		{"bad tls", Config{Brokers: []string{"localhost:9092"}, Topic: "events", TLS: tlsutil.Config{CertFile: "client.pem"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWriter(&tt.cfg, testLogger()); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestSendEvents_KeyedByAccount(t *testing.T) {
+	b := &fakeBroker{topic: "netbird-events", partitions: 8}
+	b.start(t)
+	w := newTestWriter(t, Config{Brokers: []string{b.addr()}, Topic: "netbird-events"})
+
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	b.checkProblems(t)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, acks := range b.acks {
+		if acks != -1 {
+			t.Errorf("Expected acks=all (-1), got %d", acks)
+		}
+	}
+
+	total := 0
+	partitionOf := map[string]int32{}
+	for partition, records := range b.records {
+		var lastID int64
+		for _, r := range records {
+			total++
+			account := string(r.Key)
+			if p, ok := partitionOf[account]; ok && p != partition {
+				t.Errorf("Account %s spread over partitions %d and %d", account, p, partition)
+			}
+			partitionOf[account] = partition
+
+			headers := map[string]string{}
+			for _, h := range r.Headers {
+				headers[h.Key] = string(h.Value)
+			}
+			if headers[HeaderActivityCode] != "dashboard.login" {
+				t.Errorf("Unexpected headers %v", headers)
+			}
+			id, _ := strconv.ParseInt(headers[HeaderEventID], 10, 64)
+			if id <= lastID {
+				t.Errorf("Partition %d: event %d after %d", partition, id, lastID)
+			}
+			lastID = id
+
+			var doc map[string]any
+			if err := json.Unmarshal(r.Value, &doc); err != nil {
+				t.Fatalf("Expected JSON value, got %q: %v", r.Value, err)
+			}
+			if doc["account_id"] != account || doc["event_id"] != float64(id) {
+				t.Errorf("Value %s does not match key %s and event %d", r.Value, account, id)
+			}
+		}
+	}
+	if total != 6 || len(partitionOf) != 2 {
+		t.Errorf("Expected 6 records for 2 accounts, got %d for %v", total, partitionOf)
+	}
+}
+
+func TestSendEvents_RetriesRetriableErrors(t *testing.T) {
+	b := &fakeBroker{topic: "netbird-events", partitions: 1, produceErrs: []int16{errNotEnoughReplicas}}
+	b.start(t)
+	// Retries wait for a metadata refresh, which franz-go limits to one per 5s
+	w := newTestWriter(t, Config{Brokers: []string{b.addr()}, Topic: "netbird-events", KeyField: KeyNone, Timeout: 10 * time.Second})
+
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	b.checkProblems(t)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	records := b.records[0]
+	if len(records) != 6 {
+		t.Fatalf("Expected each event exactly once after retries, got %d records", len(records))
+	}
+	for i, r := range records {
+		if r.Key != nil {
+			t.Errorf("Expected no key with key_field none, got %q", r.Key)
+		}
+		if !bytes.Contains(r.Value, []byte(`"event_id":`+strconv.Itoa(i+1))) {
+			t.Errorf("Record %d out of order: %s", i, r.Value)
+		}
+	}
+}
+
+func TestSendEvents_FailsBatchOnRejectedRecords(t *testing.T) {
+	b := &fakeBroker{topic: "netbird-events", partitions: 2, failAlways: errTopicAuthorizationFailed}
+	b.start(t)
+	w := newTestWriter(t, Config{Brokers: []string{b.addr()}, Topic: "netbird-events"})
+
+	err := w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error when the broker rejects the records")
+	}
+	if !strings.Contains(err.Error(), "6 of 6") {
+		t.Errorf("Expected error to count the failed records, got %v", err)
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	b := &fakeBroker{topic: "netbird-events", partitions: 1}
+	b.start(t)
+	w := newTestWriter(t, Config{Brokers: []string{b.addr()}, Topic: "netbird-events"})
+	w.formatter = formattest.FailOn(3)
+
+	err := w.SendEvents(context.Background(), testEvents())
+	formattest.CheckRejected(t, err, 3)
+	b.checkProblems(t)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if records := b.records[0]; len(records) != 5 || string(records[2].Value) != "event 4" {
+		t.Errorf("Expected the other 5 events to be produced, got %d records", len(records))
+	}
+}
+
+func TestSendEvents_SCRAM(t *testing.T) {
+	b := &fakeBroker{topic: "netbird-events", partitions: 1, user: "eventsproc", pass: "s3cret"}
+	b.start(t)
+
+	w := newTestWriter(t, Config{
+		Brokers:       []string{b.addr()},
+		Topic:         "netbird-events",
+		SASLMechanism: "scram-sha-256",
+		Username:      "eventsproc",
+		Password:      "s3cret",
+	})
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	b.checkProblems(t)
+
+	bad := newTestWriter(t, Config{
+		Brokers:       []string{b.addr()},
+		Topic:         "netbird-events",
+		SASLMechanism: SASLScramSHA256,
+		Username:      "eventsproc",
+		Password:      "wrong",
+		Timeout:       2 * time.Second,
+	})
+	if err := bad.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected error with wrong SASL password")
+	}
+}
@@ -0,0 +1,39 @@
+package loki
+
+import "google.golang.org/protobuf/encoding/protowire"
+
+// encodePushRequest hand-encodes Loki's logproto.PushRequest so we avoid
+// pulling in the Loki module and its generated code. Only the fields Loki
+// needs for ingestion are written:
+//
+//	message PushRequest   { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter  { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+//	message Timestamp     { int64 seconds = 1; int32 nanos = 2; }
+func encodePushRequest(streams []*stream) []byte {
+	var req []byte
+	for _, s := range streams {
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+		sb = protowire.AppendString(sb, labelString(s.labels))
+		for _, e := range s.entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.ts.Nanosecond()))
+
+			var eb []byte
+			eb = protowire.AppendTag(eb, 1, protowire.BytesType)
+			eb = protowire.AppendBytes(eb, ts)
+			eb = protowire.AppendTag(eb, 2, protowire.BytesType)
+			eb = protowire.AppendString(eb, e.line)
+
+			sb = protowire.AppendTag(sb, 2, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, eb)
+		}
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, sb)
+	}
+	return req
+}
@@ -0,0 +1,345 @@
+package loki
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// pushPath is appended to Config.URL when it does not already point at the push endpoint.
+const pushPath = "/loki/api/v1/push"
+
+// Supported values for Config.Compression.
+const (
+	CompressionGzip   = "gzip"   // JSON body, Content-Encoding: gzip
+	CompressionSnappy = "snappy" // protobuf body, snappy block encoding (Promtail wire format)
+	CompressionNone   = "none"   // plain JSON body
+)
+
+// Config holds configuration for the Loki push writer.
+type Config struct {
+	// URL is the Loki base URL (e.g. "https://loki.example.com:3100") or the full
+	// push URL. "/loki/api/v1/push" is appended when missing.
+	URL string
+
+	// TenantID is sent as X-Scope-OrgID for multi-tenant Loki. Empty = no header.
+	TenantID string
+
+	// Username and Password enable HTTP basic auth (e.g. Grafana Cloud).
+	Username string
+	Password string
+
+	// Compression selects the push encoding: "gzip" (default), "snappy" or "none".
+	Compression string
+
+	// Labels are static stream labels attached to every stream
+	// (e.g. service_name, platform, region).
+	Labels map[string]string
+
+	// EventLabels lists event fields promoted to stream labels.
+	// Supported: "account_id", "activity_code", "activity", "source". Keep this list short —
+	// every distinct combination of values creates a new Loki stream.
+	EventLabels []string
+
+	// Format selects the log line encoding: "json" (default), "kv", "cef", "leef" or "ocsf".
+	Format string
+
+	// Timeout bounds each push request. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+}
+
+// Writer pushes events straight to Loki's /loki/api/v1/push endpoint.
+// Each event becomes one log line in the configured format (flattened JSON by
+// default, like the stdout writer), grouped into streams by the configured labels.
+type Writer struct {
+	client      *http.Client
+	url         string
+	tenantID    string
+	username    string
+	password    string
+	compression string
+	labels      map[string]string
+	eventLabels []string
+	formatter   format.Formatter
+	logger      *slog.Logger
+}
+
+// NewWriter creates a Loki writer. It does not contact Loki; connection errors
+// surface on the first SendEvents call.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("loki url is required")
+	}
+
+	compression := cfg.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	switch compression {
+	case CompressionGzip, CompressionSnappy, CompressionNone:
+	default:
+		return nil, fmt.Errorf("unsupported loki compression %q (use gzip, snappy or none)", compression)
+	}
+
+	for _, name := range cfg.EventLabels {
+		if _, ok := eventLabelValue(events.Event{}, name); !ok {
+			return nil, fmt.Errorf("unsupported loki event label %q", name)
+		}
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid loki format: %w", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid loki tls config: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	url := strings.TrimRight(cfg.URL, "/")
+	if !strings.HasSuffix(url, pushPath) {
+		url += pushPath
+	}
+
+	return &Writer{
+		client:      &http.Client{Timeout: timeout, Transport: transport},
+		url:         url,
+		tenantID:    cfg.TenantID,
+		username:    cfg.Username,
+		password:    cfg.Password,
+		compression: compression,
+		labels:      cfg.Labels,
+		eventLabels: cfg.EventLabels,
+		formatter:   formatter,
+		logger:      logger,
+	}, nil
+}
+
+// stream is a set of log lines sharing one label set.
+type stream struct {
+	labels  map[string]string
+	entries []entry
+}
+
+// entry is a single Loki log line.
+type entry struct {
+	ts   time.Time
+	line string
+}
+
+// SendEvents pushes a batch of events to Loki in a single request.
+// Any non-2xx response is returned as an error so the checkpoint is not advanced.
+// Events that cannot be formatted are skipped and returned in a
+// writer.RejectedError once the rest was pushed.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	streams, rejected := w.groupStreams(eventsList)
+	if len(streams) == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	body, contentType, contentEncoding, err := w.encode(streams)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create loki request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	req.Header.Set("User-Agent", "eventsproc")
+	if w.tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.tenantID)
+	}
+	if w.username != "" {
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("loki push failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("loki push returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+		if writer.IsPermanentStatus(resp.StatusCode) {
+			return writer.Permanent(err)
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	w.logger.Debug("Pushed events to Loki", "count", len(eventsList)-len(rejected), "streams", len(streams))
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent pushes a single event to Loki
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close releases idle HTTP connections. Nothing is buffered, so there is nothing to flush.
+func (w *Writer) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// groupStreams converts events to log lines and groups them by label set,
+// preserving event order within each stream. Events that cannot be formatted
+// are returned as rejections.
+func (w *Writer) groupStreams(eventsList []events.Event) ([]*stream, []writer.Rejection) {
+	byKey := map[string]*stream{}
+	var ordered []*stream
+	var rejected []writer.Rejection
+
+	for _, evt := range eventsList {
+		line, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+
+		labels := make(map[string]string, len(w.labels)+len(w.eventLabels))
+		for k, v := range w.labels {
+			labels[k] = v
+		}
+		for _, name := range w.eventLabels {
+			if v, _ := eventLabelValue(evt, name); v != "" {
+				labels[name] = v
+			}
+		}
+
+		key := labelString(labels)
+		s, ok := byKey[key]
+		if !ok {
+			s = &stream{labels: labels}
+			byKey[key] = s
+			ordered = append(ordered, s)
+		}
+		s.entries = append(s.entries, entry{ts: evt.Timestamp, line: line})
+	}
+	return ordered, rejected
+}
+
+// encode serialises streams according to the configured compression.
+func (w *Writer) encode(streams []*stream) (body []byte, contentType, contentEncoding string, err error) {
+	if w.compression == CompressionSnappy {
+		return snappy.Encode(nil, encodePushRequest(streams)), "application/x-protobuf", "", nil
+	}
+
+	payload, err := json.Marshal(toJSONPush(streams))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to marshal loki push request: %w", err)
+	}
+	if w.compression == CompressionNone {
+		return payload, "application/json", "", nil
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(payload); err != nil {
+		return nil, "", "", fmt.Errorf("failed to gzip loki push request: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, "", "", fmt.Errorf("failed to gzip loki push request: %w", err)
+	}
+	return buf.Bytes(), "application/json", "gzip", nil
+}
+
+// jsonPush mirrors Loki's JSON push body:
+// {"streams":[{"stream":{...},"values":[["<unix ns>","<line>"]]}]}
+type jsonPush struct {
+	Streams []jsonStream `json:"streams"`
+}
+
+type jsonStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func toJSONPush(streams []*stream) jsonPush {
+	push := jsonPush{Streams: make([]jsonStream, 0, len(streams))}
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels, Values: make([][2]string, 0, len(s.entries))}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.ts.UnixNano(), 10), e.line})
+		}
+		push.Streams = append(push.Streams, js)
+	}
+	return push
+}
+
+// eventLabelValue returns the value of a supported event field used as a label.
+func eventLabelValue(evt events.Event, name string) (string, bool) {
+	switch name {
+	case "account_id":
+		return evt.AccountID, true
+	case "activity_code":
+		return evt.ActivityCode, true
+	case "activity":
+		return strconv.Itoa(evt.Activity), true
+	case "source":
+		return evt.Source, true
+	default:
+		return "", false
+	}
+}
+
+// labelString renders labels in Prometheus selector form with sorted keys,
+// e.g. {platform="prod", region="emea"}. Used as the stream key and in the
+// protobuf push body.
+func labelString(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,333 @@
+package loki
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// capturedRequest holds what the fake Loki received.
+type capturedRequest struct {
+	path            string
+	contentType     string
+	contentEncoding string
+	tenant          string
+	user, pass      string
+	body            []byte
+}
+
+// newFakeLoki starts an httptest server that records the last push and replies with status.
+func newFakeLoki(t *testing.T, status int) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		captured.path = r.URL.Path
+		captured.contentType = r.Header.Get("Content-Type")
+		captured.contentEncoding = r.Header.Get("Content-Encoding")
+		captured.tenant = r.Header.Get("X-Scope-OrgID")
+		captured.user, captured.pass, _ = r.BasicAuth()
+		captured.body = body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	return []events.Event{
+		{ID: 1, Timestamp: ts, Activity: 43, ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorID: "user1"},
+		{ID: 2, Timestamp: ts.Add(time.Second), Activity: 43, ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorID: "user2"},
+		{ID: 3, Timestamp: ts.Add(2 * time.Second), Activity: 2, ActivityCode: "user.join", AccountID: "acc1", InitiatorID: "user3"},
+	}
+}
+
+func decodeJSONPush(t *testing.T, c *capturedRequest) jsonPush {
+	t.Helper()
+	body := c.body
+	if c.contentEncoding == "gzip" {
+		gz, err := gzip.NewReader(strings.NewReader(string(body)))
+		if err != nil {
+			t.Fatalf("body is not gzip: %v", err)
+		}
+		body, err = io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("failed to gunzip body: %v", err)
+		}
+	}
+	var push jsonPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		t.Fatalf("body is not a Loki JSON push: %v", err)
+	}
+	return push
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing url")
+	}
+	if _, err := NewWriter(&Config{URL: "http://loki", Compression: "lz4"}, testLogger()); err == nil {
+		t.Error("Expected error for unsupported compression")
+	}
+	if _, err := NewWriter(&Config{URL: "http://loki", EventLabels: []string{"initiator_email"}}, testLogger()); err == nil {
+		t.Error("Expected error for unsupported event label")
+	}
+	if _, err := NewWriter(&Config{URL: "http://loki", Format: "xml"}, testLogger()); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestSendEvents_GzipJSON(t *testing.T) {
+	srv, captured := newFakeLoki(t, http.StatusNoContent)
+
+	w, err := NewWriter(&Config{
+		URL:         srv.URL,
+		TenantID:    "netbird",
+		Username:    "loki-user",
+		Password:    "secret",
+		Labels:      map[string]string{"service_name": "eventsproc", "platform": "prod", "region": "emea"},
+		EventLabels: []string{"account_id", "activity_code"},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if captured.path != pushPath {
+		t.Errorf("Expected push path %s, got %s", pushPath, captured.path)
+	}
+	if captured.contentEncoding != "gzip" || captured.contentType != "application/json" {
+		t.Errorf("Unexpected encoding headers: %q %q", captured.contentType, captured.contentEncoding)
+	}
+	if captured.tenant != "netbird" {
+		t.Errorf("Expected tenant header netbird, got %q", captured.tenant)
+	}
+	if captured.user != "loki-user" || captured.pass != "secret" {
+		t.Errorf("Expected basic auth credentials, got %q/%q", captured.user, captured.pass)
+	}
+
+	push := decodeJSONPush(t, captured)
+	if len(push.Streams) != 2 {
+		t.Fatalf("Expected 2 streams (one per activity_code), got %d", len(push.Streams))
+	}
+
+	first := push.Streams[0]
+	if first.Stream["activity_code"] != "dashboard.login" || first.Stream["account_id"] != "acc1" {
+		t.Errorf("Unexpected event labels: %v", first.Stream)
+	}
+	if first.Stream["platform"] != "prod" || first.Stream["region"] != "emea" {
+		t.Errorf("Missing static labels: %v", first.Stream)
+	}
+	if len(first.Values) != 2 {
+		t.Fatalf("Expected 2 entries in first stream, got %d", len(first.Values))
+	}
+	if first.Values[0][0] != "1769596200000000000" {
+		t.Errorf("Expected nanosecond timestamp, got %s", first.Values[0][0])
+	}
+
+	var line map[string]any
+	if err := json.Unmarshal([]byte(first.Values[0][1]), &line); err != nil {
+		t.Fatalf("Line is not JSON: %v", err)
+	}
+	if line["event_id"] != float64(1) || line["initiator_id"] != "user1" {
+		t.Errorf("Unexpected line content: %v", line)
+	}
+}
+
+func TestSendEvents_NoCompression(t *testing.T) {
+	srv, captured := newFakeLoki(t, http.StatusNoContent)
+
+	w, err := NewWriter(&Config{URL: srv.URL + pushPath, Compression: CompressionNone}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if captured.path != pushPath {
+		t.Errorf("Push path should not be appended twice, got %s", captured.path)
+	}
+	if captured.contentEncoding != "" {
+		t.Errorf("Expected no Content-Encoding, got %q", captured.contentEncoding)
+	}
+	push := decodeJSONPush(t, captured)
+	if len(push.Streams) != 1 || len(push.Streams[0].Values) != 3 {
+		t.Errorf("Expected a single stream with 3 entries, got %+v", push.Streams)
+	}
+	if captured.tenant != "" {
+		t.Errorf("Expected no tenant header, got %q", captured.tenant)
+	}
+}
+
+func TestSendEvents_CEFFormat(t *testing.T) {
+	srv, captured := newFakeLoki(t, http.StatusNoContent)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Compression: CompressionNone, Format: "cef"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	push := decodeJSONPush(t, captured)
+	line := push.Streams[0].Values[0][1]
+	if !strings.HasPrefix(line, "CEF:0|NetBird|eventsproc|") {
+		t.Errorf("Expected CEF log line, got %q", line)
+	}
+}
+
+func TestSendEvents_SnappyProtobuf(t *testing.T) {
+	srv, captured := newFakeLoki(t, http.StatusNoContent)
+
+	w, err := NewWriter(&Config{
+		URL:         srv.URL,
+		Compression: CompressionSnappy,
+		Labels:      map[string]string{"service_name": "eventsproc"},
+		EventLabels: []string{"activity_code"},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if captured.contentType != "application/x-protobuf" {
+		t.Errorf("Expected protobuf content type, got %q", captured.contentType)
+	}
+	raw, err := snappy.Decode(nil, captured.body)
+	if err != nil {
+		t.Fatalf("Body is not snappy-encoded: %v", err)
+	}
+
+	// Walk the top-level PushRequest and collect the stream label strings.
+	var labels []string
+	for len(raw) > 0 {
+		num, typ, n := protowire.ConsumeTag(raw)
+		if n < 0 || num != 1 || typ != protowire.BytesType {
+			t.Fatalf("Unexpected field in PushRequest: %d/%d", num, typ)
+		}
+		raw = raw[n:]
+		streamBytes, n := protowire.ConsumeBytes(raw)
+		raw = raw[n:]
+
+		_, _, n = protowire.ConsumeTag(streamBytes)
+		label, _ := protowire.ConsumeString(streamBytes[n:])
+		labels = append(labels, label)
+	}
+
+	want := []string{
+		`{activity_code="dashboard.login", service_name="eventsproc"}`,
+		`{activity_code="user.join", service_name="eventsproc"}`,
+	}
+	if strings.Join(labels, "|") != strings.Join(want, "|") {
+		t.Errorf("Unexpected stream labels:\n got %v\nwant %v", labels, want)
+	}
+}
+
+func TestSendEvents_ErrorStatus(t *testing.T) {
+	srv, _ := newFakeLoki(t, http.StatusTooManyRequests)
+
+	w, err := NewWriter(&Config{URL: srv.URL}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error for 429 response")
+	}
+	if !strings.Contains(err.Error(), "429") {
+		t.Errorf("Expected status in error, got %v", err)
+	}
+	if writer.IsPermanent(err) {
+		t.Error("Expected rate limiting to be retryable")
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	srv, captured := newFakeLoki(t, http.StatusNoContent)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Compression: CompressionNone}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.formatter = formattest.FailOn(2)
+
+	err = w.SendEvents(context.Background(), testEvents())
+	formattest.CheckRejected(t, err, 2)
+	push := decodeJSONPush(t, captured)
+	if len(push.Streams) != 1 || len(push.Streams[0].Values) != 2 || push.Streams[0].Values[1][1] != "event 3" {
+		t.Errorf("Expected the other events to be pushed, got %+v", push.Streams)
+	}
+}
+
+func TestSendEvents_Empty(t *testing.T) {
+	w, err := NewWriter(&Config{URL: "http://127.0.0.1:1"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), nil); err != nil {
+		t.Errorf("Expected no request for an empty batch, got %v", err)
+	}
+}
+
+func TestSendEvents_TLS(t *testing.T) {
+	var received bool
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewWriter(&Config{URL: srv.URL, TLS: tlsutil.Config{CAFile: caPath}}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvent(context.Background(), testEvents()[0]); err != nil {
+		t.Fatalf("SendEvent over TLS failed: %v", err)
+	}
+	if !received {
+		t.Error("Expected fake Loki to receive the push")
+	}
+}
+
+func TestLabelString(t *testing.T) {
+	got := labelString(map[string]string{"region": "emea", "account_id": `a"b`})
+	want := `{account_id="a\"b", region="emea"}`
+	if got != want {
+		t.Errorf("labelString() = %s, want %s", got, want)
+	}
+}
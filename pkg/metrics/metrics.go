@@ -2,6 +2,9 @@ package metrics
 
 import "github.com/prometheus/client_golang/prometheus"
 
+// Metrics of the processing pipeline carry a "source" label: the instance
+// name in multi-source mode, empty otherwise. Writer circuit, retry and
+// routing metrics describe the shared sinks and are not labeled by source.
 var (
 	// custom registry to avoid exposing all golang metrics
 	MyRegistry = prometheus.NewRegistry()
@@ -12,43 +15,206 @@ var (
 			Name: "eventsproc_events_processed_total",
 			Help: "Total number of events processed",
 		},
-		[]string{"error"},
+		[]string{"source", "error"},
 	)
 
 	// ProcessingDuration records how long each poll cycle takes end-to-end
-	ProcessingDuration = prometheus.NewHistogram(
+	ProcessingDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "eventsproc_processing_duration_seconds",
 			Help:    "Duration of each processing cycle in seconds",
 			Buckets: prometheus.DefBuckets,
 		},
+		[]string{"source"},
 	)
 
 	// BatchSize records how many events were fetched per batch
-	BatchSize = prometheus.NewHistogram(
+	BatchSize = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "eventsproc_batch_size_events",
 			Help:    "Number of events fetched per batch",
 			Buckets: []float64{10, 50, 100, 250, 500, 1000},
 		},
+		[]string{"source"},
 	)
 
-	// LastEventID tracks the ID of the last successfully processed event
-	LastEventID = prometheus.NewGauge(
+	// WriterEventsProcessed counts events sent to each writer, labeled by writer
+	// name and error status
+	WriterEventsProcessed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_writer_events_processed_total",
+			Help: "Total number of events sent to each writer",
+		},
+		[]string{"source", "writer", "error"},
+	)
+
+	// LastEventID tracks the ID of the last successfully processed event.
+	// With several writers it is the checkpoint of the writer furthest behind.
+	LastEventID = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "eventsproc_last_event_id",
 			Help: "ID of the last successfully processed event",
 		},
+		[]string{"source"},
 	)
 
 	// CheckpointLagSeconds is the most important metric: seconds between the
 	// last processed event timestamp and now. A growing value means processing
-	// is falling behind or has stopped entirely.
-	CheckpointLagSeconds = prometheus.NewGauge(
+	// is falling behind or has stopped entirely. With several writers it is
+	// the lag of the writer furthest behind.
+	CheckpointLagSeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "eventsproc_checkpoint_lag_seconds",
 			Help: "Seconds between last processed event timestamp and now",
 		},
+		[]string{"source"},
+	)
+
+	// WriterLastEventID tracks the checkpoint of each writer
+	WriterLastEventID = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_writer_last_event_id",
+			Help: "ID of the last event successfully sent to each writer",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WriterCheckpointLagSeconds is CheckpointLagSeconds per writer. It is
+	// refreshed after every cycle, including failed ones, so a writer whose
+	// sink is down shows a growing lag while healthy writers stay current.
+	WriterCheckpointLagSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_writer_checkpoint_lag_seconds",
+			Help: "Seconds between the last event sent to each writer and now",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WriterCircuitState is the circuit breaker state of each writer with
+	// retries enabled: 0 = closed, 1 = half-open, 2 = open.
+	WriterCircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_writer_circuit_state",
+			Help: "Circuit breaker state per writer (0 = closed, 1 = half-open, 2 = open)",
+		},
+		[]string{"writer"},
+	)
+
+	// WriterRetries counts batches sent again after a retryable failure.
+	WriterRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_writer_retries_total",
+			Help: "Total number of batch retries per writer",
+		},
+		[]string{"writer"},
+	)
+
+	// WriterEventsFiltered counts events a writer's routes did not select.
+	WriterEventsFiltered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_writer_events_filtered_total",
+			Help: "Total number of events not routed to a writer",
+		},
+		[]string{"writer"},
+	)
+
+	// MultiWriterFailures counts failed sends per writer of a writer group,
+	// including those its delivery policy tolerated.
+	MultiWriterFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_multi_writer_failures_total",
+			Help: "Total number of failed sends per writer of a writer group",
+		},
+		[]string{"group", "writer"},
+	)
+
+	// DeadLetters counts events a writer permanently rejected and that were
+	// stored in the dead-letter queue instead of blocking the batch.
+	DeadLetters = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_dead_letters_total",
+			Help: "Total number of events dead-lettered per writer",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WriterLateEvents counts events delivered after a writer's checkpoint
+	// had already moved past their ID, found by gap detection.
+	WriterLateEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_writer_late_events_total",
+			Help: "Total number of events delivered after later event IDs per writer",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WriterSkippedEventIDs counts missing event IDs gap detection gave up on.
+	// Most are IDs of rolled back inserts that never existed.
+	WriterSkippedEventIDs = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_writer_skipped_event_ids_total",
+			Help: "Total number of missing event IDs given up on per writer",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WriterMissingEventIDs is the number of missing event IDs gap detection
+	// is still re-checking.
+	WriterMissingEventIDs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_writer_missing_event_ids",
+			Help: "Number of missing event IDs being re-checked per writer",
+		},
+		[]string{"source", "writer"},
+	)
+
+	// WALSizeBytes is the size of the write-ahead log. It only grows while a
+	// writer is behind; approaching wal.max_size_mb means fetching will pause.
+	WALSizeBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_wal_size_bytes",
+			Help: "Size of the write-ahead log in bytes",
+		},
+		[]string{"source"},
+	)
+
+	// WALLastEventID is the ID of the newest event appended to the write-ahead log
+	WALLastEventID = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_wal_last_event_id",
+			Help: "ID of the last event appended to the write-ahead log",
+		},
+		[]string{"source"},
+	)
+
+	// NotifyListenerUp is 1 while the PostgreSQL LISTEN connection of push
+	// mode is established, 0 while it is reconnecting.
+	NotifyListenerUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "eventsproc_notify_listener_up",
+			Help: "1 if the push mode LISTEN connection is established, 0 otherwise",
+		},
+		[]string{"source"},
+	)
+
+	// DecryptedValues counts encrypted email/name values decrypted, by the ID
+	// of the keyring key that opened them. Values counted for an older key
+	// show rows not yet re-encrypted after a key rotation.
+	DecryptedValues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "eventsproc_decrypted_values_total",
+			Help: "Total number of encrypted values decrypted, by key ID",
+		},
+		[]string{"key_id"},
+	)
+
+	// UndecryptableValues counts values that look like NetBird ciphertext but
+	// that no key of the keyring opens, e.g. after an unnoticed key rotation.
+	UndecryptableValues = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "eventsproc_undecryptable_values_total",
+			Help: "Total number of values that look encrypted but no configured key decrypts",
+		},
 	)
 
 	// IsLeader is 1 when this instance holds the Redis leader lock, 0 otherwise.
@@ -64,11 +230,12 @@ var (
 	// Updated every cycle regardless of whether events were found.
 	// Use this to detect if the service has stopped running:
 	//   time() - eventsproc_last_poll_timestamp_seconds > polling_interval + buffer
-	LastPollTime = prometheus.NewGauge(
+	LastPollTime = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "eventsproc_last_poll_timestamp_seconds",
 			Help: "Unix timestamp of the last completed poll cycle",
 		},
+		[]string{"source"},
 	)
 
 	// DBQueryDuration records how long each database operation takes.
@@ -79,7 +246,7 @@ var (
 			Help:    "Duration of database queries in seconds",
 			Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1},
 		},
-		[]string{"operation"},
+		[]string{"source", "operation"},
 	)
 )
 
@@ -89,6 +256,22 @@ func init() {
 	MyRegistry.MustRegister(BatchSize)
 	MyRegistry.MustRegister(LastEventID)
 	MyRegistry.MustRegister(CheckpointLagSeconds)
+	MyRegistry.MustRegister(WriterEventsProcessed)
+	MyRegistry.MustRegister(WriterLastEventID)
+	MyRegistry.MustRegister(WriterCheckpointLagSeconds)
+	MyRegistry.MustRegister(WriterCircuitState)
+	MyRegistry.MustRegister(WriterRetries)
+	MyRegistry.MustRegister(DeadLetters)
+	MyRegistry.MustRegister(MultiWriterFailures)
+	MyRegistry.MustRegister(WriterEventsFiltered)
+	MyRegistry.MustRegister(WriterLateEvents)
+	MyRegistry.MustRegister(WriterSkippedEventIDs)
+	MyRegistry.MustRegister(WriterMissingEventIDs)
+	MyRegistry.MustRegister(WALSizeBytes)
+	MyRegistry.MustRegister(WALLastEventID)
+	MyRegistry.MustRegister(NotifyListenerUp)
+	MyRegistry.MustRegister(DecryptedValues)
+	MyRegistry.MustRegister(UndecryptableValues)
 	MyRegistry.MustRegister(IsLeader)
 	MyRegistry.MustRegister(LastPollTime)
 	MyRegistry.MustRegister(DBQueryDuration)
@@ -0,0 +1,213 @@
+// Package nats publishes events to a NATS JetStream subject with publish
+// acknowledgements, so internal consumers can subscribe to NetBird audit
+// events in near real time.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Config holds configuration for the NATS JetStream writer.
+type Config struct {
+	// URL is the server URL, e.g. "nats://nats.example.com:4222".
+	// Several servers can be given comma-separated.
+	URL string
+
+	// Subject events are published to (default: netbird.events). A JetStream
+	// stream must capture it.
+	Subject string
+
+	// Stream, when set, makes the server reject publishes that would land in
+	// any other stream (Nats-Expected-Stream).
+	Stream string
+
+	// Format of each message body: "json" (default) or any other format.New name.
+	Format string
+
+	// CredsFile is a NATS .creds file (JWT + NKey seed).
+	CredsFile string
+
+	// Token, or Username and Password, for servers without decentralized auth.
+	Token    string
+	Username string
+	Password string
+
+	// ClientName is shown in server monitoring (default: eventsproc).
+	ClientName string
+
+	// TLS configures server verification and mutual TLS. When empty, TLS is
+	// still used for tls:// URLs or when the server requires it.
+	TLS tlsutil.Config
+
+	// Timeout bounds connecting and waiting for the acks of a batch. Default: 10s.
+	Timeout time.Duration
+}
+
+// Writer publishes one message per event. Each message carries the event ID
+// as Nats-Msg-Id, so the stream drops the copies a retried batch republishes
+// within its duplicate window.
+type Writer struct {
+	conn      *natsgo.Conn
+	js        jetstream.JetStream
+	subject   string
+	stream    string
+	formatter format.Formatter
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+// NewWriter connects to NATS. After startup the client reconnects forever;
+// batches published while disconnected fail and are retried by the processor.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("nats url is required")
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nats format: %w", err)
+	}
+
+	subject := cfg.Subject
+	if subject == "" {
+		subject = "netbird.events"
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	name := cfg.ClientName
+	if name == "" {
+		name = "eventsproc"
+	}
+
+	opts := []natsgo.Option{
+		natsgo.Name(name),
+		natsgo.Timeout(timeout),
+		natsgo.MaxReconnects(-1),
+		natsgo.DisconnectErrHandler(func(_ *natsgo.Conn, err error) {
+			if err != nil {
+				logger.Warn("Disconnected from NATS", "error", err)
+			}
+		}),
+		natsgo.ReconnectHandler(func(nc *natsgo.Conn) {
+			logger.Info("Reconnected to NATS", "url", nc.ConnectedUrlRedacted())
+		}),
+	}
+	if !cfg.TLS.IsZero() {
+		tlsCfg, err := cfg.TLS.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid nats tls config: %w", err)
+		}
+		opts = append(opts, natsgo.Secure(tlsCfg))
+	}
+	switch {
+	case cfg.CredsFile != "":
+		opts = append(opts, natsgo.UserCredentials(cfg.CredsFile))
+	case cfg.Token != "":
+		opts = append(opts, natsgo.Token(cfg.Token))
+	case cfg.Username != "":
+		opts = append(opts, natsgo.UserInfo(cfg.Username, cfg.Password))
+	}
+
+	conn, err := natsgo.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats (%s): %w", cfg.URL, err)
+	}
+
+	// Unacknowledged publishes fail after the batch timeout instead of
+	// lingering in the client
+	js, err := jetstream.New(conn, jetstream.WithPublishAsyncTimeout(timeout))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create jetstream context: %w", err)
+	}
+
+	return &Writer{
+		conn:      conn,
+		js:        js,
+		subject:   subject,
+		stream:    cfg.Stream,
+		formatter: formatter,
+		timeout:   timeout,
+		logger:    logger,
+	}, nil
+}
+
+// SendEvents publishes the batch asynchronously and returns once JetStream
+// has acknowledged every message. Acks flagged as duplicates count as success.
+// Events that cannot be formatted are skipped and returned in a
+// writer.RejectedError once the rest was published.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	futures := make([]jetstream.PubAckFuture, 0, len(eventsList))
+	published := make([]events.Event, 0, len(eventsList))
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		data, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		opts := []jetstream.PublishOpt{jetstream.WithMsgID(evt.DedupID())}
+		if w.stream != "" {
+			opts = append(opts, jetstream.WithExpectStream(w.stream))
+		}
+		f, err := w.js.PublishMsgAsync(&natsgo.Msg{Subject: w.subject, Data: []byte(data)}, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to publish event %d to nats subject %s: %w", evt.ID, w.subject, err)
+		}
+		futures = append(futures, f)
+		published = append(published, evt)
+	}
+
+	var errs []error
+	duplicates := 0
+	for i, f := range futures {
+		select {
+		case ack := <-f.Ok():
+			if ack.Duplicate {
+				duplicates++
+			}
+		case err := <-f.Err():
+			errs = append(errs, fmt.Errorf("event %d: %w", published[i].ID, err))
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for nats publish acks on %s: %w", w.subject, ctx.Err())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to publish %d of %d events to nats subject %s: %w",
+			len(errs), len(published), w.subject, errors.Join(errs...))
+	}
+
+	w.logger.Debug("Published events to NATS", "subject", w.subject, "count", len(published), "duplicates", duplicates)
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent publishes a single event
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close drains the connection. SendEvents waits for every ack, so no
+// publishes are pending.
+func (w *Writer) Close() error {
+	return w.conn.Drain()
+}
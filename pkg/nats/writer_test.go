@@ -0,0 +1,354 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents(from, to int64) []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var list []events.Event
+	for id := from; id <= to; id++ {
+		list = append(list, events.Event{ID: id, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: "acc1"})
+	}
+	return list
+}
+
+// storedMsg is a message accepted by the fake JetStream stream.
+type storedMsg struct {
+	msgID string
+	data  string
+}
+
+// fakeJetStream is an in-process NATS server speaking just enough of the
+// client protocol for JetStream publishes: it stores messages published to
+// subject, acks them on the reply subject and drops duplicate Nats-Msg-Ids
+// like a stream's duplicate window.
+//
+// Tests set the configuration fields and then call start.
+type fakeJetStream struct {
+	stream  string
+	subject string
+	// token requires this auth token in CONNECT when set.
+	token string
+	// reject fails publishes of these message IDs with a JetStream API error.
+	reject map[string]bool
+
+	ln       net.Listener
+	mu       sync.Mutex
+	msgs     []storedMsg
+	seen     map[string]bool
+	problems []string
+}
+
+func (s *fakeJetStream) start(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	s.ln = ln
+	s.seen = map[string]bool{}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handleConn(conn)
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+}
+
+func (s *fakeJetStream) url() string { return "nats://" + s.ln.Addr().String() }
+
+func (s *fakeJetStream) stored() []storedMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]storedMsg(nil), s.msgs...)
+}
+
+func (s *fakeJetStream) checkProblems(t *testing.T) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.problems {
+		t.Error(p)
+	}
+}
+
+func (s *fakeJetStream) handleConn(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+	var wmu sync.Mutex
+	write := func(format string, args ...any) {
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, _ = fmt.Fprintf(conn, format, args...)
+	}
+
+	write("INFO {\"server_id\":\"fake\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576,\"auth_required\":%t}\r\n", s.token != "")
+
+	// subs maps subscription subjects (possibly ending in ".*") to their sid
+	subs := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(strings.TrimSpace(line))
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "CONNECT":
+			var opts struct {
+				AuthToken string `json:"auth_token"`
+			}
+			_ = json.Unmarshal([]byte(strings.TrimSpace(line)[len("CONNECT "):]), &opts)
+			if opts.AuthToken != s.token {
+				write("-ERR 'Authorization Violation'\r\n")
+				return
+			}
+		case "PING":
+			write("PONG\r\n")
+		case "SUB":
+			subs[fields[1]] = fields[len(fields)-1]
+		case "PUB", "HPUB":
+			// PUB <subject> [reply] <size>, HPUB <subject> [reply] <hdr size> <total size>
+			total, _ := strconv.Atoi(fields[len(fields)-1])
+			hdrLen := 0
+			if fields[0] == "HPUB" {
+				hdrLen, _ = strconv.Atoi(fields[len(fields)-2])
+			}
+			reply := ""
+			if (fields[0] == "PUB" && len(fields) == 4) || (fields[0] == "HPUB" && len(fields) == 5) {
+				reply = fields[2]
+			}
+			payload := make([]byte, total+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			var header textproto.MIMEHeader
+			if hdrLen > 0 {
+				tp := textproto.NewReader(bufio.NewReader(strings.NewReader(string(payload[:hdrLen]))))
+				_, _ = tp.ReadLine() // NATS/1.0
+				header, _ = tp.ReadMIMEHeader()
+			}
+			if fields[1] != s.subject || reply == "" {
+				continue
+			}
+			ack := s.publish(header, string(payload[hdrLen:total]))
+			for subject, sid := range subs {
+				if subject == reply || (strings.HasSuffix(subject, ".*") && strings.HasPrefix(reply, strings.TrimSuffix(subject, "*"))) {
+					write("MSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+				}
+			}
+		}
+	}
+}
+
+// publish stores a message and returns the JetStream ack (or error) JSON.
+func (s *fakeJetStream) publish(header textproto.MIMEHeader, data string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgID := header.Get("Nats-Msg-Id")
+	if msgID == "" {
+		s.problems = append(s.problems, "publish without Nats-Msg-Id")
+	}
+	if expected := header.Get("Nats-Expected-Stream"); expected != "" && expected != s.stream {
+		return `{"error":{"code":400,"err_code":10060,"description":"expected stream does not match"}}`
+	}
+	if s.reject[msgID] {
+		return `{"error":{"code":503,"err_code":10077,"description":"maximum messages exceeded"}}`
+	}
+	if s.seen[msgID] {
+		return fmt.Sprintf(`{"stream":%q,"seq":%d,"duplicate":true}`, s.stream, len(s.msgs))
+	}
+	s.seen[msgID] = true
+	s.msgs = append(s.msgs, storedMsg{msgID: msgID, data: data})
+	return fmt.Sprintf(`{"stream":%q,"seq":%d}`, s.stream, len(s.msgs))
+}
+
+func newTestWriter(t *testing.T, cfg Config) *Writer {
+	t.Helper()
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	w, err := NewWriter(&cfg, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing url")
+	}
+	if _, err := NewWriter(&Config{URL: "nats://127.0.0.1:1", Format: "xml"}, testLogger()); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := NewWriter(&Config{URL: "nats://127.0.0.1:1", Timeout: 100 * time.Millisecond}, testLogger()); err == nil {
+		t.Error("Expected error when NATS is unreachable")
+	}
+}
+
+func TestSendEvents_PublishesWithMsgID(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "netbird.events"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url(), Stream: "NETBIRD"})
+
+	if err := w.SendEvents(context.Background(), testEvents(1, 5)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	srv.checkProblems(t)
+
+	msgs := srv.stored()
+	if len(msgs) != 5 {
+		t.Fatalf("Expected 5 messages, got %d", len(msgs))
+	}
+	for i, msg := range msgs {
+		if msg.msgID != strconv.Itoa(i+1) {
+			t.Errorf("Expected Nats-Msg-Id %d, got %q", i+1, msg.msgID)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(msg.data), &doc); err != nil {
+			t.Fatalf("Expected JSON body, got %q: %v", msg.data, err)
+		}
+		if doc["event_id"] != float64(i+1) {
+			t.Errorf("Unexpected body %v", doc)
+		}
+	}
+}
+
+func TestSendEvents_RetriedBatchIsDeduplicated(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "audit.netbird"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url(), Subject: "audit.netbird"})
+
+	ctx := context.Background()
+	if err := w.SendEvents(ctx, testEvents(1, 3)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	// The processor retries the whole batch after a failure
+	if err := w.SendEvents(ctx, testEvents(1, 4)); err != nil {
+		t.Fatalf("SendEvents with duplicates failed: %v", err)
+	}
+
+	if msgs := srv.stored(); len(msgs) != 4 {
+		t.Errorf("Expected duplicates to be dropped, got %d messages", len(msgs))
+	}
+}
+
+func TestSendEvents_SourcesShareEventIDs(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "netbird.events"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url()})
+
+	// Two instances in multi-source mode with the same event IDs
+	ctx := context.Background()
+	for _, source := range []string{"emea", "apac"} {
+		list := testEvents(1, 2)
+		for i := range list {
+			list[i].Source = source
+		}
+		if err := w.SendEvents(ctx, list); err != nil {
+			t.Fatalf("SendEvents failed: %v", err)
+		}
+	}
+
+	msgs := srv.stored()
+	if len(msgs) != 4 {
+		t.Fatalf("Expected no event to be dropped as a duplicate, got %d messages", len(msgs))
+	}
+	if msgs[0].msgID != "emea:1" || msgs[2].msgID != "apac:1" {
+		t.Errorf("Expected Nats-Msg-Id prefixed with the source, got %q and %q", msgs[0].msgID, msgs[2].msgID)
+	}
+}
+
+func TestSendEvents_FailsOnNegativeAck(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "netbird.events", reject: map[string]bool{"2": true}}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url()})
+
+	err := w.SendEvents(context.Background(), testEvents(1, 3))
+	if err == nil {
+		t.Fatal("Expected error when JetStream rejects a message")
+	}
+	if !strings.Contains(err.Error(), "1 of 3") || !strings.Contains(err.Error(), "event 2") {
+		t.Errorf("Expected error naming the rejected event, got %v", err)
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "netbird.events"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url()})
+	w.formatter = formattest.FailOn(2)
+
+	err := w.SendEvents(context.Background(), testEvents(1, 3))
+	formattest.CheckRejected(t, err, 2)
+	srv.checkProblems(t)
+	if msgs := srv.stored(); len(msgs) != 2 || msgs[1].data != "event 3" || msgs[1].msgID != "3" {
+		t.Errorf("Expected the other events to be published, got %+v", msgs)
+	}
+}
+
+func TestSendEvents_WrongStream(t *testing.T) {
+	srv := &fakeJetStream{stream: "OTHER", subject: "netbird.events"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url(), Stream: "NETBIRD"})
+
+	if err := w.SendEvents(context.Background(), testEvents(1, 1)); err == nil {
+		t.Error("Expected error when the subject is captured by another stream")
+	}
+	if msgs := srv.stored(); len(msgs) != 0 {
+		t.Errorf("Expected no stored messages, got %d", len(msgs))
+	}
+}
+
+func TestSendEvents_NoAck(t *testing.T) {
+	// Nothing captures the subject, so no ack ever arrives
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "other.subject"}
+	srv.start(t)
+	w := newTestWriter(t, Config{URL: srv.url(), Timeout: 200 * time.Millisecond})
+
+	if err := w.SendEvents(context.Background(), testEvents(1, 2)); err == nil {
+		t.Error("Expected error without publish acks")
+	}
+}
+
+func TestNewWriter_TokenAuth(t *testing.T) {
+	srv := &fakeJetStream{stream: "NETBIRD", subject: "netbird.events", token: "s3cret"}
+	srv.start(t)
+
+	if _, err := NewWriter(&Config{URL: srv.url(), Token: "wrong", Timeout: time.Second}, testLogger()); err == nil {
+		t.Error("Expected error with wrong token")
+	}
+
+	w := newTestWriter(t, Config{URL: srv.url(), Token: "s3cret"})
+	if err := w.SendEvents(context.Background(), testEvents(1, 1)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+}
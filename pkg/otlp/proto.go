@@ -0,0 +1,294 @@
+package otlp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// attr is one OTLP KeyValue. value holds a string, bool, int64 or float64.
+type attr struct {
+	key   string
+	value any
+}
+
+// logRecord is the subset of the OTLP LogRecord the writer fills.
+type logRecord struct {
+	time           time.Time
+	severityNumber int
+	severityText   string
+	eventName      string
+	body           string
+	attrs          []attr
+}
+
+// encodeLogsRequest hand-encodes an ExportLogsServiceRequest with a single
+// ResourceLogs/ScopeLogs pair, so we avoid pulling in the generated OTLP
+// modules. Field numbers follow opentelemetry-proto v1:
+//
+//	message ExportLogsServiceRequest { repeated ResourceLogs resource_logs = 1; }
+//	message ResourceLogs  { Resource resource = 1; repeated ScopeLogs scope_logs = 2; }
+//	message Resource      { repeated KeyValue attributes = 1; }
+//	message ScopeLogs     { InstrumentationScope scope = 1; repeated LogRecord log_records = 2; }
+//	message InstrumentationScope { string name = 1; string version = 2; }
+//	message LogRecord {
+//	  fixed64 time_unix_nano = 1; SeverityNumber severity_number = 2; string severity_text = 3;
+//	  AnyValue body = 5; repeated KeyValue attributes = 6;
+//	  fixed64 observed_time_unix_nano = 11; string event_name = 12;
+//	}
+func encodeLogsRequest(resource []attr, scopeName, scopeVersion string, records []logRecord) []byte {
+	var res []byte
+	for _, a := range resource {
+		res = appendKeyValue(res, 1, a)
+	}
+
+	var scope []byte
+	scope = protowire.AppendTag(scope, 1, protowire.BytesType)
+	scope = protowire.AppendString(scope, scopeName)
+	if scopeVersion != "" {
+		scope = protowire.AppendTag(scope, 2, protowire.BytesType)
+		scope = protowire.AppendString(scope, scopeVersion)
+	}
+
+	var sl []byte
+	sl = protowire.AppendTag(sl, 1, protowire.BytesType)
+	sl = protowire.AppendBytes(sl, scope)
+	for _, r := range records {
+		sl = protowire.AppendTag(sl, 2, protowire.BytesType)
+		sl = protowire.AppendBytes(sl, encodeLogRecord(r))
+	}
+
+	var rl []byte
+	rl = protowire.AppendTag(rl, 1, protowire.BytesType)
+	rl = protowire.AppendBytes(rl, res)
+	rl = protowire.AppendTag(rl, 2, protowire.BytesType)
+	rl = protowire.AppendBytes(rl, sl)
+
+	var req []byte
+	req = protowire.AppendTag(req, 1, protowire.BytesType)
+	req = protowire.AppendBytes(req, rl)
+	return req
+}
+
+func encodeLogRecord(r logRecord) []byte {
+	ts := uint64(r.time.UnixNano())
+
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, ts)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(r.severityNumber))
+	b = protowire.AppendTag(b, 3, protowire.BytesType)
+	b = protowire.AppendString(b, r.severityText)
+	if r.body != "" {
+		b = protowire.AppendTag(b, 5, protowire.BytesType)
+		b = protowire.AppendBytes(b, encodeAnyValue(r.body))
+	}
+	for _, a := range r.attrs {
+		b = appendKeyValue(b, 6, a)
+	}
+	// The audit event time is also the observed time: eventsproc reads events
+	// after the fact, and the read time says nothing about the activity.
+	b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, ts)
+	if r.eventName != "" {
+		b = protowire.AppendTag(b, 12, protowire.BytesType)
+		b = protowire.AppendString(b, r.eventName)
+	}
+	return b
+}
+
+// appendKeyValue appends a KeyValue { string key = 1; AnyValue value = 2; } as field num.
+func appendKeyValue(b []byte, num protowire.Number, a attr) []byte {
+	var kv []byte
+	kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+	kv = protowire.AppendString(kv, a.key)
+	kv = protowire.AppendTag(kv, 2, protowire.BytesType)
+	kv = protowire.AppendBytes(kv, encodeAnyValue(a.value))
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, kv)
+}
+
+// encodeAnyValue encodes an AnyValue:
+//
+//	oneof { string string_value = 1; bool bool_value = 2; int64 int_value = 3; double double_value = 4; }
+func encodeAnyValue(v any) []byte {
+	var b []byte
+	switch val := v.(type) {
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(val))
+	case int64:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(val))
+	case float64:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(val))
+	default:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, fmt.Sprint(val))
+	}
+	return b
+}
+
+// metaValue converts a flattened meta value to an AnyValue-compatible type:
+// integral JSON numbers become int64, nested objects and arrays compact JSON.
+func metaValue(v any) any {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string, bool:
+		return val
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+		return val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
+
+// sortAttrs orders attributes by key so requests are deterministic.
+func sortAttrs(attrs []attr) {
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].key < attrs[j].key })
+}
+
+// partialSuccess is the ExportLogsPartialSuccess of an export response.
+type partialSuccess struct {
+	rejected int64
+	message  string
+}
+
+// decodeExportResponse reads an ExportLogsServiceResponse:
+//
+//	message ExportLogsServiceResponse { ExportLogsPartialSuccess partial_success = 1; }
+//	message ExportLogsPartialSuccess  { int64 rejected_log_records = 1; string error_message = 2; }
+//
+// An empty body is a full success.
+func decodeExportResponse(b []byte) (partialSuccess, error) {
+	var ps partialSuccess
+	err := walkFields(b, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		return walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error {
+			switch {
+			case num == 1 && typ == protowire.VarintType:
+				ps.rejected = int64(n)
+			case num == 2 && typ == protowire.BytesType:
+				ps.message = string(v)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return partialSuccess{}, fmt.Errorf("invalid OTLP export response: %w", err)
+	}
+	return ps, nil
+}
+
+// rpcStatus is the part of a google.rpc.Status the writer uses.
+type rpcStatus struct {
+	message    string
+	retryDelay time.Duration
+}
+
+// retryInfoType is the Any type URL of google.rpc.RetryInfo.
+const retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+
+// decodeStatus reads a google.rpc.Status, extracting the message and the
+// RetryInfo delay a throttling collector may attach:
+//
+//	message Status    { int32 code = 1; string message = 2; repeated google.protobuf.Any details = 3; }
+//	message Any       { string type_url = 1; bytes value = 2; }
+//	message RetryInfo { google.protobuf.Duration retry_delay = 1; }
+//	message Duration  { int64 seconds = 1; int32 nanos = 2; }
+func decodeStatus(b []byte) (rpcStatus, error) {
+	var st rpcStatus
+	err := walkFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch {
+		case num == 2 && typ == protowire.BytesType:
+			st.message = string(v)
+		case num == 3 && typ == protowire.BytesType:
+			var typeURL string
+			var value []byte
+			if err := walkFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if typ == protowire.BytesType && num == 1 {
+					typeURL = string(v)
+				} else if typ == protowire.BytesType && num == 2 {
+					value = v
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			if typeURL != retryInfoType {
+				return nil
+			}
+			return walkFields(value, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num != 1 || typ != protowire.BytesType {
+					return nil
+				}
+				return walkFields(v, func(num protowire.Number, typ protowire.Type, _ []byte, n uint64) error {
+					switch {
+					case num == 1 && typ == protowire.VarintType:
+						st.retryDelay += time.Duration(int64(n)) * time.Second
+					case num == 2 && typ == protowire.VarintType:
+						st.retryDelay += time.Duration(int32(n))
+					}
+					return nil
+				})
+			})
+		}
+		return nil
+	})
+	return st, err
+}
+
+// walkFields calls fn for every field in a protobuf message. Length-delimited
+// fields are passed as v, varints and fixed-width values as n.
+func walkFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, n uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var v []byte
+		var x uint64
+		switch typ {
+		case protowire.VarintType:
+			x, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			x, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var x32 uint32
+			x32, n = protowire.ConsumeFixed32(b)
+			x = uint64(x32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		if err := fn(num, typ, v, x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,537 @@
+// Package otlp exports events as OpenTelemetry log records over OTLP/HTTP or
+// OTLP/gRPC, so eventsproc can feed an OTel Collector (or any OTLP backend)
+// without a journald receiver in between.
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Supported values for Config.Protocol.
+const (
+	ProtocolHTTP = "http/protobuf" // POST <endpoint>/v1/logs (default)
+	ProtocolGRPC = "grpc"          // LogsService/Export over HTTP/2
+)
+
+// Supported values for Config.Compression.
+const (
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+)
+
+const (
+	httpLogsPath = "/v1/logs"
+	grpcLogsPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+
+	// scopeName identifies eventsproc as the instrumentation scope.
+	scopeName = "github.com/xh63/netbird-events"
+
+	// maxBackoff caps the delay between export attempts.
+	maxBackoff = 30 * time.Second
+)
+
+// Config holds configuration for the OTLP logs writer.
+type Config struct {
+	// Endpoint is the collector URL. For http/protobuf, "/v1/logs" is appended
+	// when the URL has no path (e.g. "https://otel.example.com:4318").
+	// For grpc only scheme and host are used (e.g. "http://otel.example.com:4317";
+	// "http" means plaintext HTTP/2, "https" TLS).
+	Endpoint string
+
+	// Protocol is "http/protobuf" (default) or "grpc".
+	Protocol string
+
+	// Headers are sent with every export request (e.g. authentication headers).
+	Headers map[string]string
+
+	// Compression is "gzip" (default) or "none".
+	Compression string
+
+	// ResourceAttributes describe the emitting service (service.name,
+	// host.name, ...). They are sent once per request.
+	ResourceAttributes map[string]string
+
+	// MaxRetries is how many times a retryable failure is retried within one
+	// SendEvents call. Default: 3.
+	MaxRetries int
+
+	// RetryBackoff is the initial delay between retries, doubled on every
+	// attempt up to 30s. A delay requested by the collector takes precedence.
+	// Default: 1s.
+	RetryBackoff time.Duration
+
+	// Timeout bounds each export request. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+}
+
+// Writer exports one LogRecord per event. A batch is a single export request;
+// retryable failures (throttling, unavailable collector) are retried with
+// backoff, while records the collector reports as rejected in a partial
+// success are logged and never resent, as the OTLP specification requires.
+type Writer struct {
+	client       *http.Client
+	url          string
+	protocol     string
+	headers      map[string]string
+	compression  string
+	resource     []attr
+	maxRetries   int
+	retryBackoff time.Duration
+	logger       *slog.Logger
+}
+
+// NewWriter creates an OTLP writer. It does not contact the collector;
+// connection errors surface on the first SendEvents call.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.Endpoint == "" {
+		return nil, fmt.Errorf("otlp endpoint is required")
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid otlp endpoint %q (expected http(s)://host:port)", cfg.Endpoint)
+	}
+
+	protocol := cfg.Protocol
+	if protocol == "" {
+		protocol = ProtocolHTTP
+	}
+	compression := cfg.Compression
+	if compression == "" {
+		compression = CompressionGzip
+	}
+	switch compression {
+	case CompressionGzip, CompressionNone:
+	default:
+		return nil, fmt.Errorf("unsupported otlp compression %q (use gzip or none)", compression)
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid otlp tls config: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	var endpoint string
+	switch protocol {
+	case ProtocolHTTP:
+		endpoint = strings.TrimRight(cfg.Endpoint, "/")
+		if u.Path == "" || u.Path == "/" {
+			endpoint += httpLogsPath
+		}
+	case ProtocolGRPC:
+		// gRPC requires HTTP/2; "http" endpoints use it without TLS (h2c)
+		var protocols http.Protocols
+		if u.Scheme == "http" {
+			protocols.SetUnencryptedHTTP2(true)
+		} else {
+			protocols.SetHTTP2(true)
+		}
+		transport.Protocols = &protocols
+		endpoint = u.Scheme + "://" + u.Host + grpcLogsPath
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %q (use http/protobuf or grpc)", protocol)
+	}
+
+	maxRetries := cfg.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 3
+	}
+	retryBackoff := cfg.RetryBackoff
+	if retryBackoff == 0 {
+		retryBackoff = time.Second
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	resource := make([]attr, 0, len(cfg.ResourceAttributes))
+	for k, v := range cfg.ResourceAttributes {
+		if v != "" {
+			resource = append(resource, attr{key: k, value: v})
+		}
+	}
+	sortAttrs(resource)
+
+	return &Writer{
+		client:       &http.Client{Timeout: timeout, Transport: transport},
+		url:          endpoint,
+		protocol:     protocol,
+		headers:      cfg.Headers,
+		compression:  compression,
+		resource:     resource,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+		logger:       logger,
+	}, nil
+}
+
+// SendEvents exports a batch of events in a single request.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	records := make([]logRecord, len(eventsList))
+	for i, evt := range eventsList {
+		records[i] = newLogRecord(evt)
+	}
+	payload := encodeLogsRequest(w.resource, scopeName, format.DeviceVersion, records)
+
+	backoff := w.retryBackoff
+	for attempt := 0; ; attempt++ {
+		ps, err := w.export(ctx, payload)
+		if err == nil {
+			w.reportPartialSuccess(ps, len(records))
+			w.logger.Debug("Exported events via OTLP", "count", len(records), "protocol", w.protocol)
+			return nil
+		}
+
+		var exportErr *exportError
+		if errors.As(err, &exportErr) && !exportErr.retryable {
+			// Only a rejected payload is the events' fault; authentication
+			// and configuration failures are left to the circuit breaker
+			if exportErr.permanent {
+				return writer.Permanent(fmt.Errorf("otlp export failed: %w", err))
+			}
+			return fmt.Errorf("otlp export failed: %w", err)
+		}
+		if exportErr == nil || attempt >= w.maxRetries {
+			return fmt.Errorf("otlp export failed: %w", err)
+		}
+
+		delay := backoff
+		if exportErr.delay > 0 {
+			delay = exportErr.delay
+		}
+		w.logger.Warn("OTLP export failed, retrying", "attempt", attempt+1, "delay", delay, "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("otlp export failed: %w", ctx.Err())
+		case <-timer.C:
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// reportPartialSuccess logs records the collector accepted the request for but
+// rejected. They must not be retried, so the batch still counts as sent.
+func (w *Writer) reportPartialSuccess(ps partialSuccess, sent int) {
+	switch {
+	case ps.rejected > 0:
+		w.logger.Warn("OTLP collector rejected log records",
+			"rejected", ps.rejected, "sent", sent, "message", ps.message)
+	case ps.message != "":
+		w.logger.Warn("OTLP collector returned a warning", "message", ps.message)
+	}
+}
+
+// SendEvent exports a single event
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close releases idle HTTP connections. Nothing is buffered, so there is nothing to flush.
+func (w *Writer) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// severityNumbers maps activity severities to OTLP SeverityNumber and text.
+var severityNumbers = map[format.Severity]struct {
+	number int
+	text   string
+}{
+	format.SeverityInfo:    {9, "INFO"},
+	format.SeverityNotice:  {10, "NOTICE"},
+	format.SeverityWarning: {13, "WARN"},
+}
+
+// newLogRecord converts an event to a log record. The activity name is the
+// body; identifiers and the flattened meta are attributes. It cannot fail:
+// meta that is not JSON is kept as netbird.meta.unparsable, so unlike the
+// formatted writers this one never rejects an event before the export.
+func newLogRecord(evt events.Event) logRecord {
+	sev := severityNumbers[format.ActivitySeverity(evt.ActivityCode)]
+
+	attrs := []attr{
+		{"event.id", evt.ID},
+		{"netbird.activity", int64(evt.Activity)},
+		{"netbird.activity_code", evt.ActivityCode},
+		{"netbird.account_id", evt.AccountID},
+	}
+	for _, kv := range []attr{
+		{"netbird.initiator_id", evt.InitiatorID},
+		{"netbird.initiator_email", evt.InitiatorEmail},
+		{"netbird.target_id", evt.TargetID},
+		{"netbird.target_email", evt.TargetEmail},
+	} {
+		if kv.value != "" {
+			attrs = append(attrs, kv)
+		}
+	}
+	for _, f := range format.FlattenMeta(evt.Meta) {
+		attrs = append(attrs, attr{key: "netbird.meta." + f.Key, value: metaValue(f.Value)})
+	}
+	sortAttrs(attrs)
+
+	return logRecord{
+		time:           evt.Timestamp,
+		severityNumber: sev.number,
+		severityText:   sev.text,
+		eventName:      evt.ActivityCode,
+		body:           evt.ActivityName,
+		attrs:          attrs,
+	}
+}
+
+// exportError is a failed export attempt. retryable marks failures the OTLP
+// specification allows to retry; permanent marks the collector rejecting the
+// payload itself; delay is the wait the collector asked for.
+type exportError struct {
+	msg       string
+	retryable bool
+	permanent bool
+	delay     time.Duration
+}
+
+func (e *exportError) Error() string { return e.msg }
+
+// export sends one request and returns the collector's partial success.
+func (w *Writer) export(ctx context.Context, payload []byte) (partialSuccess, error) {
+	if w.protocol == ProtocolGRPC {
+		return w.exportGRPC(ctx, payload)
+	}
+	return w.exportHTTP(ctx, payload)
+}
+
+// exportHTTP implements OTLP/HTTP. 429, 502, 503 and 504 are retryable and
+// may carry a Retry-After; the other error statuses are not retried, and
+// permanent as writer.IsPermanentStatus decides.
+func (w *Writer) exportHTTP(ctx context.Context, payload []byte) (partialSuccess, error) {
+	body := payload
+	if w.compression == CompressionGzip {
+		var err error
+		if body, err = gzipBytes(payload); err != nil {
+			return partialSuccess{}, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return partialSuccess{}, fmt.Errorf("failed to create otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if w.compression == CompressionGzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	w.setHeaders(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return partialSuccess{}, &exportError{msg: err.Error(), retryable: true}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return partialSuccess{}, &exportError{msg: fmt.Sprintf("failed to read otlp response: %v", err), retryable: true}
+	}
+
+	if resp.StatusCode/100 != 2 {
+		msg := strings.TrimSpace(string(data))
+		if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/x-protobuf") {
+			if st, err := decodeStatus(data); err == nil {
+				msg = st.message
+			}
+		}
+		if len(msg) > 512 {
+			msg = msg[:512]
+		}
+		e := &exportError{
+			msg:       fmt.Sprintf("collector returned %s: %s", resp.Status, msg),
+			permanent: writer.IsPermanentStatus(resp.StatusCode),
+		}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			e.retryable = true
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+				e.delay = time.Duration(secs) * time.Second
+			}
+		}
+		return partialSuccess{}, e
+	}
+	return decodeExportResponse(data)
+}
+
+// gRPC status codes the OTLP specification treats as retryable.
+// RESOURCE_EXHAUSTED (8) is only retryable when the collector attaches RetryInfo.
+var retryableGRPCCodes = map[int]bool{
+	1:  true, // CANCELLED
+	4:  true, // DEADLINE_EXCEEDED
+	10: true, // ABORTED
+	11: true, // OUT_OF_RANGE
+	14: true, // UNAVAILABLE
+	15: true, // DATA_LOSS
+}
+
+// grpcInvalidArgument is the only gRPC status code blaming the payload;
+// UNAUTHENTICATED, PERMISSION_DENIED, NOT_FOUND and the like point at the
+// configuration.
+const grpcInvalidArgument = 3
+
+// exportGRPC implements a unary LogsService/Export call over HTTP/2, framing
+// the message as gRPC does (1-byte compressed flag, 4-byte length).
+func (w *Writer) exportGRPC(ctx context.Context, payload []byte) (partialSuccess, error) {
+	compressed := byte(0)
+	msg := payload
+	if w.compression == CompressionGzip {
+		var err error
+		if msg, err = gzipBytes(payload); err != nil {
+			return partialSuccess{}, err
+		}
+		compressed = 1
+	}
+	frame := make([]byte, 5, 5+len(msg))
+	frame[0] = compressed
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(frame))
+	if err != nil {
+		return partialSuccess{}, fmt.Errorf("failed to create otlp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	if w.compression == CompressionGzip {
+		req.Header.Set("Grpc-Encoding", "gzip")
+	}
+	w.setHeaders(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return partialSuccess{}, &exportError{msg: err.Error(), retryable: true}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4*1024*1024))
+	if err != nil {
+		return partialSuccess{}, &exportError{msg: fmt.Sprintf("failed to read otlp response: %v", err), retryable: true}
+	}
+	if resp.StatusCode != http.StatusOK {
+		e := &exportError{msg: "collector returned " + resp.Status}
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			e.retryable = true
+		}
+		return partialSuccess{}, e
+	}
+
+	// Trailers-only responses carry the status in the headers
+	status := resp.Trailer.Get("Grpc-Status")
+	trailer := resp.Trailer
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		trailer = resp.Header
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return partialSuccess{}, &exportError{msg: fmt.Sprintf("missing grpc-status in otlp response (%q)", status), retryable: true}
+	}
+	if code != 0 {
+		return partialSuccess{}, grpcError(code, trailer)
+	}
+
+	if len(data) == 0 {
+		return partialSuccess{}, nil
+	}
+	if len(data) < 5 || int(binary.BigEndian.Uint32(data[1:5])) != len(data)-5 {
+		return partialSuccess{}, fmt.Errorf("malformed grpc response frame")
+	}
+	body := data[5:]
+	if data[0] == 1 {
+		if resp.Header.Get("Grpc-Encoding") != "gzip" {
+			return partialSuccess{}, fmt.Errorf("unsupported grpc response encoding %q", resp.Header.Get("Grpc-Encoding"))
+		}
+		if body, err = gunzipBytes(body); err != nil {
+			return partialSuccess{}, err
+		}
+	}
+	return decodeExportResponse(body)
+}
+
+// grpcError builds the error for a non-OK grpc-status, reading the message and
+// RetryInfo from grpc-message / grpc-status-details-bin.
+func grpcError(code int, trailer http.Header) error {
+	msg, _ := url.PathUnescape(trailer.Get("Grpc-Message"))
+	var delay time.Duration
+	if details := trailer.Get("Grpc-Status-Details-Bin"); details != "" {
+		if b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(details, "=")); err == nil {
+			if st, err := decodeStatus(b); err == nil {
+				delay = st.retryDelay
+				if msg == "" {
+					msg = st.message
+				}
+			}
+		}
+	}
+	return &exportError{
+		msg:       fmt.Sprintf("grpc status %d: %s", code, msg),
+		retryable: retryableGRPCCodes[code] || (code == 8 && delay > 0),
+		permanent: code == grpcInvalidArgument,
+		delay:     delay,
+	}
+}
+
+func (w *Writer) setHeaders(req *http.Request) {
+	req.Header.Set("User-Agent", "eventsproc")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+}
+
+func gzipBytes(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(b); err != nil {
+		return nil, fmt.Errorf("failed to gzip otlp request: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to gzip otlp request: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func gunzipBytes(b []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("invalid gzip otlp response: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+	return io.ReadAll(io.LimitReader(gz, 4*1024*1024))
+}
@@ -0,0 +1,598 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 123000000, time.UTC)
+	return []events.Event{
+		{
+			ID: 42, Timestamp: ts, Activity: 51, ActivityName: "Dashboard login", ActivityCode: "dashboard.login",
+			AccountID: "acc1", InitiatorID: "user1", InitiatorEmail: "alice@example.com",
+			Meta: `{"ip":"10.0.0.1","port":443,"ratio":0.5,"admin":true,"tags":["a","b"]}`,
+		},
+		{
+			ID: 43, Timestamp: ts.Add(time.Second), Activity: 11, ActivityName: "User role updated", ActivityCode: "user.role.update",
+			AccountID: "acc1", InitiatorID: "user1", TargetID: "user2",
+		},
+	}
+}
+
+// decodedRecord is a LogRecord read back by the fake collector.
+type decodedRecord struct {
+	timeUnixNano     uint64
+	observedUnixNano uint64
+	severityNumber   uint64
+	severityText     string
+	body             string
+	eventName        string
+	attrs            map[string]any
+}
+
+// decodedRequest is an ExportLogsServiceRequest read back by the fake collector.
+type decodedRequest struct {
+	resource     map[string]any
+	scopeName    string
+	scopeVersion string
+	records      []decodedRecord
+}
+
+func decodeRequest(t *testing.T, b []byte) decodedRequest {
+	t.Helper()
+	req := decodedRequest{resource: map[string]any{}}
+	must(t, walkFields(b, func(_ protowire.Number, _ protowire.Type, rl []byte, _ uint64) error {
+		return walkFields(rl, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+			switch num {
+			case 1:
+				return walkFields(v, func(_ protowire.Number, _ protowire.Type, kv []byte, _ uint64) error {
+					k, val := decodeKeyValue(t, kv)
+					req.resource[k] = val
+					return nil
+				})
+			case 2:
+				return walkFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+					if num == 1 {
+						return walkFields(v, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+							if num == 1 {
+								req.scopeName = string(v)
+							} else {
+								req.scopeVersion = string(v)
+							}
+							return nil
+						})
+					}
+					req.records = append(req.records, decodeRecord(t, v))
+					return nil
+				})
+			}
+			return nil
+		})
+	}))
+	return req
+}
+
+func decodeRecord(t *testing.T, b []byte) decodedRecord {
+	t.Helper()
+	r := decodedRecord{attrs: map[string]any{}}
+	must(t, walkFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			r.timeUnixNano = n
+		case 2:
+			r.severityNumber = n
+		case 3:
+			r.severityText = string(v)
+		case 5:
+			r.body, _ = decodeAnyValue(t, v).(string)
+		case 6:
+			k, val := decodeKeyValue(t, v)
+			r.attrs[k] = val
+		case 11:
+			r.observedUnixNano = n
+		case 12:
+			r.eventName = string(v)
+		}
+		return nil
+	}))
+	return r
+}
+
+func decodeKeyValue(t *testing.T, b []byte) (string, any) {
+	var key string
+	var val any
+	must(t, walkFields(b, func(num protowire.Number, _ protowire.Type, v []byte, _ uint64) error {
+		if num == 1 {
+			key = string(v)
+		} else {
+			val = decodeAnyValue(t, v)
+		}
+		return nil
+	}))
+	return key, val
+}
+
+func decodeAnyValue(t *testing.T, b []byte) any {
+	var val any
+	must(t, walkFields(b, func(num protowire.Number, _ protowire.Type, v []byte, n uint64) error {
+		switch num {
+		case 1:
+			val = string(v)
+		case 2:
+			val = n != 0
+		case 3:
+			val = int64(n)
+		case 4:
+			val = math.Float64frombits(n)
+		}
+		return nil
+	}))
+	return val
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+}
+
+func encodePartialSuccess(rejected int64, message string) []byte {
+	var ps []byte
+	ps = protowire.AppendTag(ps, 1, protowire.VarintType)
+	ps = protowire.AppendVarint(ps, uint64(rejected))
+	ps = protowire.AppendTag(ps, 2, protowire.BytesType)
+	ps = protowire.AppendString(ps, message)
+
+	var resp []byte
+	resp = protowire.AppendTag(resp, 1, protowire.BytesType)
+	return protowire.AppendBytes(resp, ps)
+}
+
+func gunzip(t *testing.T, b []byte) []byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	out, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("invalid gzip body: %v", err)
+	}
+	return out
+}
+
+// fakeCollector records OTLP/HTTP requests and replies with the queued responses.
+type fakeCollector struct {
+	mu        sync.Mutex
+	requests  []decodedRequest
+	headers   []http.Header
+	responses []func(w http.ResponseWriter)
+}
+
+func (c *fakeCollector) serveHTTP(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body = gunzip(t, body)
+		}
+		c.requests = append(c.requests, decodeRequest(t, body))
+		c.headers = append(c.headers, r.Header.Clone())
+
+		n := len(c.requests) - 1
+		if n < len(c.responses) {
+			c.responses[n](w)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing endpoint", Config{}},
+		{"no scheme", Config{Endpoint: "otel:4318"}},
+		{"bad protocol", Config{Endpoint: "http://otel:4318", Protocol: "http/json"}},
+		{"bad compression", Config{Endpoint: "http://otel:4318", Compression: "zstd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWriter(&tt.cfg, testLogger()); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestSendEvents_HTTPLogRecords(t *testing.T) {
+	c := &fakeCollector{}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{
+		Endpoint:           srv.URL,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ResourceAttributes: map[string]string{"service.name": "eventsproc", "region": "emea", "consumer_id": "eventsproc-prod-emea", "host.name": "mgmt-1"},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if len(c.requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(c.requests))
+	}
+	if c.headers[0].Get("Authorization") != "Bearer token" || c.headers[0].Get("Content-Encoding") != "gzip" {
+		t.Errorf("Unexpected headers %v", c.headers[0])
+	}
+	req := c.requests[0]
+	if req.resource["service.name"] != "eventsproc" || req.resource["host.name"] != "mgmt-1" ||
+		req.resource["consumer_id"] != "eventsproc-prod-emea" || req.resource["region"] != "emea" {
+		t.Errorf("Unexpected resource attributes %v", req.resource)
+	}
+	if req.scopeName != scopeName {
+		t.Errorf("Unexpected scope %q", req.scopeName)
+	}
+	if len(req.records) != 2 {
+		t.Fatalf("Expected 2 log records, got %d", len(req.records))
+	}
+
+	r := req.records[0]
+	wantTime := uint64(testEvents()[0].Timestamp.UnixNano())
+	if r.timeUnixNano != wantTime || r.observedUnixNano != wantTime {
+		t.Errorf("Expected event time as time and observed time, got %d/%d", r.timeUnixNano, r.observedUnixNano)
+	}
+	if r.severityNumber != 9 || r.severityText != "INFO" {
+		t.Errorf("Expected INFO severity, got %d %q", r.severityNumber, r.severityText)
+	}
+	if r.body != "Dashboard login" || r.eventName != "dashboard.login" {
+		t.Errorf("Unexpected body/event name %q %q", r.body, r.eventName)
+	}
+	wantAttrs := map[string]any{
+		"event.id":                int64(42),
+		"netbird.activity":        int64(51),
+		"netbird.activity_code":   "dashboard.login",
+		"netbird.account_id":      "acc1",
+		"netbird.initiator_id":    "user1",
+		"netbird.initiator_email": "alice@example.com",
+		"netbird.meta.ip":         "10.0.0.1",
+		"netbird.meta.port":       int64(443),
+		"netbird.meta.ratio":      0.5,
+		"netbird.meta.admin":      true,
+		"netbird.meta.tags":       `["a","b"]`,
+	}
+	for k, want := range wantAttrs {
+		if got := r.attrs[k]; got != want {
+			t.Errorf("attribute %s = %v (%T), want %v (%T)", k, got, got, want, want)
+		}
+	}
+	if _, ok := r.attrs["netbird.target_id"]; ok {
+		t.Error("Expected empty target_id to be omitted")
+	}
+
+	if req.records[1].severityNumber != 13 || req.records[1].severityText != "WARN" {
+		t.Errorf("Expected WARN severity for role change, got %d", req.records[1].severityNumber)
+	}
+}
+
+func TestSendEvents_PartialSuccessIsNotRetried(t *testing.T) {
+	c := &fakeCollector{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.Header().Set("Content-Type", "application/x-protobuf")
+			_, _ = w.Write(encodePartialSuccess(1, "attribute too long"))
+		},
+	}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, Compression: CompressionNone, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Expected partial success to count as sent, got %v", err)
+	}
+	if len(c.requests) != 1 {
+		t.Errorf("Expected rejected records not to be resent, got %d requests", len(c.requests))
+	}
+}
+
+func TestSendEvents_HTTPRetryable(t *testing.T) {
+	c := &fakeCollector{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusTooManyRequests) },
+	}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if len(c.requests) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(c.requests))
+	}
+}
+
+func TestSendEvents_HTTPPermanentError(t *testing.T) {
+	c := &fakeCollector{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) { http.Error(w, "bad payload", http.StatusBadRequest) },
+	}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if !writer.IsPermanent(err) {
+		t.Fatalf("Expected a permanent error for 400 response, got %v", err)
+	}
+	if len(c.requests) != 1 {
+		t.Errorf("Expected no retry for 400, got %d requests", len(c.requests))
+	}
+}
+
+// TestSendEvents_HTTPUnauthorized verifies that an authentication failure is
+// not retried by the writer but is not permanent either, so the batch is not
+// dead-lettered.
+func TestSendEvents_HTTPUnauthorized(t *testing.T) {
+	c := &fakeCollector{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) { http.Error(w, "token expired", http.StatusUnauthorized) },
+	}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil || writer.IsPermanent(err) {
+		t.Fatalf("Expected a non-permanent error for 401 response, got %v", err)
+	}
+	if len(c.requests) != 1 {
+		t.Errorf("Expected no retry for 401, got %d requests", len(c.requests))
+	}
+}
+
+func TestSendEvents_HTTPRetriesExhausted(t *testing.T) {
+	unavailable := func(w http.ResponseWriter) { w.WriteHeader(http.StatusBadGateway) }
+	c := &fakeCollector{responses: []func(http.ResponseWriter){unavailable, unavailable, unavailable}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, MaxRetries: 2, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Fatal("Expected error once retries are exhausted")
+	}
+	if len(c.requests) != 3 {
+		t.Errorf("Expected 3 attempts, got %d", len(c.requests))
+	}
+}
+
+// grpcCollector is a cleartext HTTP/2 server speaking the LogsService/Export
+// unary call. reply returns the grpc-status, trailers and response message.
+type grpcCollector struct {
+	mu       sync.Mutex
+	requests []decodedRequest
+	reply    func(n int) (status string, trailers map[string]string, msg []byte)
+}
+
+func (c *grpcCollector) serve(t *testing.T) *httptest.Server {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if r.ProtoMajor != 2 || r.URL.Path != grpcLogsPath || r.Header.Get("Content-Type") != "application/grpc" {
+			t.Errorf("Unexpected request %s %s %s", r.Proto, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		frame, _ := io.ReadAll(r.Body)
+		if len(frame) < 5 || int(binary.BigEndian.Uint32(frame[1:5])) != len(frame)-5 {
+			t.Fatalf("Malformed grpc frame")
+		}
+		msg := frame[5:]
+		if frame[0] == 1 {
+			msg = gunzip(t, msg)
+		}
+		c.requests = append(c.requests, decodeRequest(t, msg))
+
+		status, trailers, resp := c.reply(len(c.requests) - 1)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin")
+		w.WriteHeader(http.StatusOK)
+		if resp != nil {
+			out := make([]byte, 5, 5+len(resp))
+			binary.BigEndian.PutUint32(out[1:], uint32(len(resp)))
+			_, _ = w.Write(append(out, resp...))
+		}
+		w.Header().Set("Grpc-Status", status)
+		for k, v := range trailers {
+			w.Header().Set(k, v)
+		}
+	}))
+	srv.Config.Protocols = new(http.Protocols)
+	srv.Config.Protocols.SetUnencryptedHTTP2(true)
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSendEvents_GRPC(t *testing.T) {
+	c := &grpcCollector{reply: func(int) (string, map[string]string, []byte) {
+		return "0", nil, encodePartialSuccess(0, "")
+	}}
+	srv := c.serve(t)
+
+	w, err := NewWriter(&Config{
+		Endpoint:           srv.URL,
+		Protocol:           ProtocolGRPC,
+		ResourceAttributes: map[string]string{"service.name": "eventsproc"},
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if len(c.requests) != 1 || len(c.requests[0].records) != 2 {
+		t.Fatalf("Expected 1 request with 2 records, got %+v", c.requests)
+	}
+	if c.requests[0].resource["service.name"] != "eventsproc" {
+		t.Errorf("Unexpected resource %v", c.requests[0].resource)
+	}
+}
+
+func TestSendEvents_GRPCRetryableStatus(t *testing.T) {
+	c := &grpcCollector{reply: func(n int) (string, map[string]string, []byte) {
+		if n == 0 {
+			return "14", map[string]string{"Grpc-Message": "collector%20restarting"}, nil // UNAVAILABLE
+		}
+		return "0", nil, nil
+	}}
+	srv := c.serve(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, Protocol: ProtocolGRPC, RetryBackoff: time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("Expected UNAVAILABLE to be retried, got %v", err)
+	}
+	if len(c.requests) != 2 {
+		t.Errorf("Expected 2 attempts, got %d", len(c.requests))
+	}
+}
+
+// TestSendEvents_GRPCPermanentStatus verifies that only INVALID_ARGUMENT is
+// permanent; UNAUTHENTICATED fails the batch for the circuit breaker.
+func TestSendEvents_GRPCPermanentStatus(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        string
+		wantPermanent bool
+	}{
+		{"INVALID_ARGUMENT", "3", true},
+		{"UNAUTHENTICATED", "16", false},
+		{"PERMISSION_DENIED", "7", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &grpcCollector{reply: func(int) (string, map[string]string, []byte) {
+				return tt.status, nil, nil
+			}}
+			srv := c.serve(t)
+
+			w, err := NewWriter(&Config{Endpoint: srv.URL, Protocol: ProtocolGRPC, RetryBackoff: time.Millisecond}, testLogger())
+			if err != nil {
+				t.Fatalf("NewWriter failed: %v", err)
+			}
+			err = w.SendEvents(context.Background(), testEvents())
+			if err == nil || writer.IsPermanent(err) != tt.wantPermanent {
+				t.Errorf("SendEvents error = %v, want permanent %v", err, tt.wantPermanent)
+			}
+			if len(c.requests) != 1 {
+				t.Errorf("Expected no retry, got %d requests", len(c.requests))
+			}
+		})
+	}
+}
+
+func TestSendEvents_GRPCResourceExhausted(t *testing.T) {
+	// RetryInfo { retry_delay = Duration { nanos = 1000000 } }
+	var duration, retryInfo, anyMsg, status []byte
+	duration = protowire.AppendTag(duration, 2, protowire.VarintType)
+	duration = protowire.AppendVarint(duration, uint64(time.Millisecond))
+	retryInfo = protowire.AppendTag(retryInfo, 1, protowire.BytesType)
+	retryInfo = protowire.AppendBytes(retryInfo, duration)
+	anyMsg = protowire.AppendTag(anyMsg, 1, protowire.BytesType)
+	anyMsg = protowire.AppendString(anyMsg, retryInfoType)
+	anyMsg = protowire.AppendTag(anyMsg, 2, protowire.BytesType)
+	anyMsg = protowire.AppendBytes(anyMsg, retryInfo)
+	status = protowire.AppendTag(status, 3, protowire.BytesType)
+	status = protowire.AppendBytes(status, anyMsg)
+	details := base64.RawStdEncoding.EncodeToString(status)
+
+	tests := []struct {
+		name         string
+		trailers     map[string]string
+		wantErr      bool
+		wantRequests int
+	}{
+		{"without RetryInfo is not retried", nil, true, 1},
+		{"with RetryInfo is retried", map[string]string{"Grpc-Status-Details-Bin": details}, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &grpcCollector{reply: func(n int) (string, map[string]string, []byte) {
+				if n == 0 {
+					return "8", tt.trailers, nil // RESOURCE_EXHAUSTED
+				}
+				return "0", nil, nil
+			}}
+			srv := c.serve(t)
+
+			w, err := NewWriter(&Config{Endpoint: srv.URL, Protocol: ProtocolGRPC, RetryBackoff: time.Hour}, testLogger())
+			if err != nil {
+				t.Fatalf("NewWriter failed: %v", err)
+			}
+			err = w.SendEvents(context.Background(), testEvents())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SendEvents error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(c.requests) != tt.wantRequests {
+				t.Errorf("Expected %d requests, got %d", tt.wantRequests, len(c.requests))
+			}
+		})
+	}
+}
+
+func TestSendEvents_ContextCancelledDuringBackoff(t *testing.T) {
+	c := &fakeCollector{responses: []func(http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+	}}
+	srv := c.serveHTTP(t)
+
+	w, err := NewWriter(&Config{Endpoint: srv.URL, RetryBackoff: time.Hour}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := w.SendEvents(ctx, testEvents()); err == nil {
+		t.Error("Expected error when the context is cancelled")
+	}
+}
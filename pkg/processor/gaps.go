@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// gapRecheckChunk is the most missing IDs read again in one query, well below
+// the bound parameter limits of every supported database.
+const gapRecheckChunk = 500
+
+// gapTracker remembers the event IDs missing below a writer's checkpoint.
+// Event IDs are allocated when an insert starts but become visible when its
+// transaction commits, so a reader that resumes after the highest ID it has
+// seen skips an event committed after a higher one. Such IDs are read again
+// until they appear or the window passes; most that never appear belong to
+// rolled back inserts. The IDs are kept in memory only.
+type gapTracker struct {
+	window     time.Duration
+	maxPending int
+	pending    map[int64]time.Time // missing ID -> when it was first missed
+	now        func() time.Time    // replaced in tests
+}
+
+func newGapTracker(window time.Duration, maxPending int) *gapTracker {
+	if maxPending <= 0 {
+		maxPending = 10000
+	}
+	return &gapTracker{
+		window:     window,
+		maxPending: maxPending,
+		pending:    make(map[int64]time.Time),
+		now:        time.Now,
+	}
+}
+
+// observe records the IDs missing before and between the IDs of batch, which
+// is sorted by ID and was read after the ID after. With after 0, the first
+// read, IDs before the batch are not missing but older than the lookback.
+// It returns how many missing IDs were not tracked because maxPending was
+// reached.
+func (g *gapTracker) observe(after int64, batch []events.Event) int {
+	now := g.now()
+	dropped := 0
+	prev := after
+	for _, evt := range batch {
+		if prev > 0 {
+			for id := prev + 1; id < evt.ID; id++ {
+				if len(g.pending) >= g.maxPending {
+					dropped += int(evt.ID - id)
+					break
+				}
+				g.pending[id] = now
+			}
+		}
+		prev = evt.ID
+	}
+	return dropped
+}
+
+// missing returns the tracked IDs in ascending order.
+func (g *gapTracker) missing() []int64 {
+	ids := make([]int64, 0, len(g.pending))
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// found forgets an ID that has been read.
+func (g *gapTracker) found(id int64) {
+	delete(g.pending, id)
+}
+
+// expire forgets and returns, in ascending order, the IDs missing for longer
+// than the window.
+func (g *gapTracker) expire() []int64 {
+	now := g.now()
+	var expired []int64
+	for id, since := range g.pending {
+		if now.Sub(since) >= g.window {
+			expired = append(expired, id)
+			delete(g.pending, id)
+		}
+	}
+	slices.Sort(expired)
+	return expired
+}
+
+// observeGaps tracks the IDs missing from a batch the writer has delivered.
+// after is the writer's checkpoint before the batch.
+func (p *Processor) observeGaps(ws *writerState, after int64, batch []events.Event, logger *slog.Logger) {
+	if ws.gaps == nil {
+		return
+	}
+	if dropped := ws.gaps.observe(after, batch); dropped > 0 {
+		logger.Warn("Too many missing event IDs, skipping without re-checking",
+			"count", dropped,
+			"max_pending", ws.gaps.maxPending,
+		)
+		metrics.WriterSkippedEventIDs.WithLabelValues(p.source, ws.name).Add(float64(dropped))
+	}
+	metrics.WriterMissingEventIDs.WithLabelValues(p.source, ws.name).Set(float64(len(ws.gaps.pending)))
+}
+
+// recheckGaps reads the writer's missing event IDs again and sends the events
+// that have appeared since, then gives up on the IDs missing for longer than
+// gap_detection.window. Late events are read from the database even with the
+// write-ahead log enabled, as it only buffers events after its head.
+func (p *Processor) recheckGaps(ctx context.Context, ws *writerState, logger *slog.Logger) error {
+	if ws.gaps == nil || len(ws.gaps.pending) == 0 {
+		return nil
+	}
+
+	for chunk := range slices.Chunk(ws.gaps.missing(), gapRecheckChunk) {
+		dbStart := time.Now()
+		late, err := p.eventReader.GetEvents(ctx, events.EventQueryOptions{
+			EventIDs: chunk,
+			Limit:    len(chunk),
+			OrderAsc: true,
+		})
+		metrics.DBQueryDuration.WithLabelValues(p.source, "get_events").Observe(time.Since(dbStart).Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to re-check missing events for writer %s: %w", ws.name, err)
+		}
+		if len(late) == 0 {
+			continue
+		}
+
+		if err := p.send(ctx, ws, late); err != nil {
+			metrics.EventsProcessed.WithLabelValues(p.source, "true").Add(float64(len(late)))
+			metrics.WriterEventsProcessed.WithLabelValues(p.source, ws.name, "true").Add(float64(len(late)))
+			return fmt.Errorf("failed to send late events to writer %s: %w", ws.name, err)
+		}
+		for _, evt := range late {
+			ws.gaps.found(evt.ID)
+			logger.Info("Delivered late event", "event_id", evt.ID, "last_event_id", ws.checkpoint.LastEventID)
+		}
+		metrics.EventsProcessed.WithLabelValues(p.source, "false").Add(float64(len(late)))
+		metrics.WriterEventsProcessed.WithLabelValues(p.source, ws.name, "false").Add(float64(len(late)))
+		metrics.WriterLateEvents.WithLabelValues(p.source, ws.name).Add(float64(len(late)))
+		p.progressMu.Lock()
+		ws.checkpoint.TotalEventsProcessed += int64(len(late))
+		p.progressMu.Unlock()
+	}
+
+	if expired := ws.gaps.expire(); len(expired) > 0 {
+		logger.Warn("Gave up on missing event IDs",
+			"count", len(expired),
+			"first_id", expired[0],
+			"last_id", expired[len(expired)-1],
+			"window_s", p.config.GapDetection.Window,
+		)
+		metrics.WriterSkippedEventIDs.WithLabelValues(p.source, ws.name).Add(float64(len(expired)))
+	}
+	metrics.WriterMissingEventIDs.WithLabelValues(p.source, ws.name).Set(float64(len(ws.gaps.pending)))
+	return nil
+}
@@ -0,0 +1,163 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/xh63/netbird-events/pkg/config"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Runner is a Processor or, in multi-source mode, a MultiProcessor.
+type Runner interface {
+	Run(ctx context.Context) error
+	Close() error
+}
+
+// New creates a MultiProcessor when instances are configured, a Processor
+// otherwise.
+func New(cfg *config.Config, logFactory config.LogFactory) (Runner, error) {
+	if len(cfg.Instances) > 0 {
+		m, err := NewMultiProcessor(cfg, logFactory)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	p, err := NewProcessor(cfg, logFactory)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MultiProcessor runs one Processor per configured instance (multi-source
+// mode). Every processor has its own reader, checkpoints, write-ahead log and
+// dead-letter queue, so an instance that is slow or unreachable does not hold
+// back the others. The writers are built once and shared.
+type MultiProcessor struct {
+	processors []*Processor
+	writers    []*writerState // shared by the processors
+	logger     *slog.Logger
+}
+
+// NewMultiProcessor creates the writers and a processor for every instance
+// in cfg.Instances.
+func NewMultiProcessor(cfg *config.Config, logFactory config.LogFactory) (*MultiProcessor, error) {
+	logger := logFactory.New("system")
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	writers, err := buildWriters(cfg, true, logger, hostname)
+	if err != nil {
+		return nil, err
+	}
+	m := &MultiProcessor{writers: writers, logger: logger}
+
+	for _, inst := range cfg.Instances {
+		instCfg := cfg.ForInstance(inst)
+		p, err := newProcessor(instCfg, sourceLogFactory{logFactory, inst.Name}, shareWriters(writers))
+		if err != nil {
+			_ = m.Close()
+			return nil, fmt.Errorf("instance %s: %w", inst.Name, err)
+		}
+		m.processors = append(m.processors, p)
+		logger.Info("Initialized instance",
+			"source", inst.Name,
+			"driver", instCfg.EventsDatabase().Driver,
+			"consumer_id", instCfg.ConsumerID,
+		)
+	}
+	return m, nil
+}
+
+// Run runs every processor until ctx is cancelled, or once each when
+// polling_interval is 0. A processor that fails stops the others, as a
+// single processor that fails stops the service.
+func (m *MultiProcessor) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make([]error, len(m.processors))
+	var wg sync.WaitGroup
+	for i, p := range m.processors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Run(runCtx); err != nil && !errors.Is(err, context.Canceled) {
+				m.logger.Error("Instance failed, stopping", "source", p.source, "error", err)
+				errs[i] = fmt.Errorf("instance %s: %w", p.source, err)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// Close closes the shared writers first, so buffered events are flushed
+// before the databases go away, then every processor.
+func (m *MultiProcessor) Close() error {
+	closeWriters(m.writers, m.logger)
+	var errs []error
+	for _, p := range m.processors {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// shareWriters returns new writer states, whose checkpoints are loaded by
+// the processor, on the writers of shared.
+func shareWriters(shared []*writerState) []*writerState {
+	result := make([]*writerState, len(shared))
+	for i, ws := range shared {
+		result[i] = &writerState{name: ws.name, writer: ws.writer}
+	}
+	return result
+}
+
+// lockedWriter sends to a writer shared by several processors one batch at a
+// time. It wraps the sink itself, below the retries, so the lock is only held
+// while a batch is being sent.
+type lockedWriter struct {
+	w  writer.EventWriter
+	mu sync.Mutex
+}
+
+// SendEvents implements writer.EventWriter.
+func (l *lockedWriter) SendEvents(ctx context.Context, batch []events.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.SendEvents(ctx, batch)
+}
+
+// SendEvent implements writer.EventWriter.
+func (l *lockedWriter) SendEvent(ctx context.Context, evt events.Event) error {
+	return l.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the wrapped writer.
+func (l *lockedWriter) Close() error {
+	return l.w.Close()
+}
+
+// sourceLogFactory adds the instance name to every logger.
+type sourceLogFactory struct {
+	config.LogFactory
+	source string
+}
+
+func (f sourceLogFactory) New(logType string) *slog.Logger {
+	return f.LogFactory.New(logType).With("source", f.source)
+}
@@ -1,16 +1,38 @@
 package processor
 
 import (
+	"cmp"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"math"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/xh63/netbird-events/pkg/config"
+	"github.com/xh63/netbird-events/pkg/dlq"
+	"github.com/xh63/netbird-events/pkg/elastic"
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/file"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/kafka"
+	"github.com/xh63/netbird-events/pkg/loki"
 	"github.com/xh63/netbird-events/pkg/metrics"
+	"github.com/xh63/netbird-events/pkg/nats"
+	"github.com/xh63/netbird-events/pkg/otlp"
+	"github.com/xh63/netbird-events/pkg/redisstream"
+	"github.com/xh63/netbird-events/pkg/splunk"
 	"github.com/xh63/netbird-events/pkg/stdout"
+	"github.com/xh63/netbird-events/pkg/syslog"
+	"github.com/xh63/netbird-events/pkg/wal"
+	"github.com/xh63/netbird-events/pkg/webhook"
+	"github.com/xh63/netbird-events/pkg/writer"
 )
 
 // EventWriter interface for sending events to an output (allows mocking in tests)
@@ -18,41 +40,95 @@ type EventWriter interface {
 	SendEvents(ctx context.Context, events []events.Event) error
 }
 
-// Processor orchestrates reading events from DB and sending to stdout (journal)
-// Uses single checkpoint model (after migration 003)
+// Processor orchestrates reading events from DB and sending them to the configured writers.
+// Every writer resumes from its own checkpoint, keyed by (consumer_id, writer_type),
+// so a slow or failing sink falls behind on its own while the others keep flowing.
 type Processor struct {
-	eventReader events.ReaderInterface
-	writer      EventWriter                  // Single stdout writer
-	checkpoint  *events.ProcessingCheckpoint // Single checkpoint
-	config      *config.Config
-	logFactory  config.LogFactory // creates typed loggers at runtime (e.g. "security", "audit")
-	logger      *slog.Logger      // system logger, pre-created from logFactory
-	hostname    string            // for processing_node tracking
+	eventReader   events.ReaderInterface
+	writers       []*writerState // Configured writers, each with its own checkpoint
+	sharedWriters bool           // writers belong to a MultiProcessor, which closes them
+	config        *config.Config
+	source        string            // instance name in multi-source mode, set on events and metrics
+	logFactory    config.LogFactory // creates typed loggers at runtime (e.g. "security", "audit")
+	logger        *slog.Logger      // system logger, pre-created from logFactory
+	hostname      string            // for processing_node tracking
+
+	// wal buffers fetched events on disk when wal.enabled is set; writers then
+	// drain it instead of querying the database themselves
+	wal     *wal.Log
+	walHead int64 // ID of the last event appended to wal, owned by ingest
+
+	// ingestWake is signalled in push mode (notify.enabled) when new events
+	// were inserted, so ingest need not wait for the next poll; nil otherwise
+	ingestWake chan struct{}
+
+	// dlq stores events a writer permanently rejected when dlq.enabled is set
+	dlq dlq.Store
+
+	progressMu sync.Mutex // guards checkpoint progress read across writers for the overall metrics
+}
+
+// writerState is one configured writer and the checkpoint it resumes from.
+type writerState struct {
+	name       string // checkpoint writer_type, e.g. "loki" or "webhook:siem"
+	writer     EventWriter
+	checkpoint *events.ProcessingCheckpoint // loaded in Run()
+	wake       chan struct{}                // signalled when new events are buffered or, in push mode, inserted
+	gaps       *gapTracker                  // missing event IDs to re-check; nil when gap detection is disabled
 }
 
 // NewProcessor creates a new event processor.
 // logFactory is used to create typed loggers; call logFactory.New("<type>") anywhere
 // in the processor to emit logs with a specific log_type without changing signatures.
 func NewProcessor(cfg *config.Config, logFactory config.LogFactory) (*Processor, error) {
+	return newProcessor(cfg, logFactory, nil)
+}
+
+// newProcessor creates a processor on the given writers, or on writers built
+// from cfg when there are none. Given writers are shared: the caller closes
+// them.
+func newProcessor(cfg *config.Config, logFactory config.LogFactory, shared []*writerState) (*Processor, error) {
 	logger := logFactory.New("system")
 
 	// Validate cluster + SQLite constraint
-	if cfg.DatabaseDriver == "sqlite" && cfg.Cluster.Enabled {
+	if (cfg.EventsDatabase().Driver == "sqlite" || cfg.CheckpointDatabase().Driver == "sqlite") && cfg.Cluster.Enabled {
 		return nil, fmt.Errorf("cluster mode is not supported with SQLite; use PostgreSQL for HA")
 	}
+	if cfg.CheckpointDatabase().Driver == "api" && cfg.Cluster.Enabled {
+		return nil, fmt.Errorf("cluster mode needs shared checkpoints; configure sources.checkpoint with the api driver")
+	}
 
 	// Create event reader based on configured driver
 	var eventReader events.ReaderInterface
-	switch cfg.DatabaseDriver {
-	case "sqlite":
-		db, err := config.GetSQLiteDB(cfg.SQLitePath, logger)
+	var db *sql.DB // holds the checkpoints, and the dead letters with dlq.type database
+	var err error
+	switch {
+	case cfg.EventsDatabase().Driver == "api":
+		eventReader, db, err = openAPIReader(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.SplitSources():
+		eventReader, db, err = openSplitReader(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.DatabaseDriver == "sqlite":
+		db, err = config.GetSQLiteDB(cfg.SQLitePath, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
 		}
 		eventReader = events.NewSQLiteEventReader(db, logger, &cfg.EmailEnrichment)
 		logger.Info("Using SQLite event reader", "path", cfg.SQLitePath)
+	case cfg.DatabaseDriver == "mysql":
+		db, err = config.GetMySQLDB(cfg.MySQLDSN, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to MySQL database: %w", err)
+		}
+		eventReader = events.NewMySQLEventReader(db, logger, &cfg.EmailEnrichment)
+		logger.Info("Using MySQL event reader")
 	default: // "postgres" or empty
-		db, err := config.GetDB(cfg.PostgresURL, logger)
+		db, err = config.GetDB(cfg.PostgresURL, logger)
 		if err != nil {
 			return nil, fmt.Errorf("failed to connect to database: %w", err)
 		}
@@ -60,10 +136,6 @@ func NewProcessor(cfg *config.Config, logFactory config.LogFactory) (*Processor,
 		logger.Info("Using PostgreSQL event reader")
 	}
 
-	// Create stdout writer (outputs JSON to journal)
-	stdoutWriter := stdout.NewStdoutWriter(logger)
-	logger.Info("Initialized stdout writer for journal output")
-
 	// Get hostname for processing_node tracking
 	hostname, err := os.Hostname()
 	if err != nil {
@@ -71,18 +143,710 @@ func NewProcessor(cfg *config.Config, logFactory config.LogFactory) (*Processor,
 		logger.Warn("Failed to get hostname", "error", err)
 	}
 
-	return &Processor{
-		eventReader: eventReader,
-		writer:      stdoutWriter,
-		checkpoint:  nil, // Will be loaded in Run()
-		config:      cfg,
-		logFactory:  logFactory,
-		logger:      logger,
-		hostname:    hostname,
-	}, nil
+	// Create output writers (stdout by default)
+	writers := shared
+	if writers == nil {
+		writers, err = buildWriters(cfg, false, logger, hostname)
+		if err != nil {
+			_ = eventReader.Close()
+			return nil, err
+		}
+	}
+
+	p := &Processor{
+		eventReader:   eventReader,
+		writers:       writers,
+		sharedWriters: shared != nil,
+		config:        cfg,
+		source:        cfg.Source,
+		logFactory:    logFactory,
+		logger:        logger,
+		hostname:      hostname,
+	}
+
+	// Buffer fetched events on disk so sink outages do not reach the database
+	if cfg.WAL.Enabled {
+		p.wal, err = wal.Open(&wal.Config{
+			Dir:         cfg.WAL.Dir,
+			SegmentSize: int64(cfg.WAL.SegmentSizeMB) << 20,
+			MaxSize:     int64(cfg.WAL.MaxSizeMB) << 20,
+		}, logger)
+		if err != nil {
+			_ = p.Close()
+			return nil, fmt.Errorf("failed to open write-ahead log: %w", err)
+		}
+		for _, ws := range writers {
+			ws.wake = make(chan struct{}, 1)
+		}
+	}
+
+	// Push mode: writers, or ingest with the write-ahead log, are woken by
+	// NOTIFY as well as by the ticker
+	if cfg.Notify.Enabled {
+		if p.wal != nil {
+			p.ingestWake = make(chan struct{}, 1)
+		} else {
+			for _, ws := range writers {
+				ws.wake = make(chan struct{}, 1)
+			}
+		}
+	}
+
+	// Keep permanently rejected events instead of blocking or dropping them
+	if cfg.DLQ.Enabled {
+		p.dlq, err = OpenDeadLetterStore(cfg, db)
+		if err != nil {
+			_ = p.Close()
+			return nil, err
+		}
+		logger.Info("Dead-letter queue enabled", "type", cfg.DLQ.Type, "path", cfg.DLQ.Path)
+	}
+
+	return p, nil
+}
+
+// openSplitReader opens the databases of the events, enrichment and checkpoint
+// sources, each once, and returns a reader over them and the checkpoint
+// database. Email enrichment keeps using JOINs when the user tables are in the
+// events database.
+func openSplitReader(cfg *config.Config, logger *slog.Logger) (events.ReaderInterface, *sql.DB, error) {
+	opened := make(map[config.DatabaseConfig]*sql.DB)
+	closeAll := func() {
+		for _, db := range opened {
+			_ = db.Close()
+		}
+	}
+	open := func(source string, d config.DatabaseConfig) (*sql.DB, error) {
+		if db, ok := opened[d]; ok {
+			return db, nil
+		}
+		db, err := config.OpenDatabase(d, logger)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("failed to open %s database: %w", source, err)
+		}
+		opened[d] = db
+		logger.Info("Opened database", "source", source, "driver", d.Driver, "read_only", d.ReadOnly)
+		return db, nil
+	}
+
+	eventsSource := cfg.EventsDatabase()
+	eventsDB, err := open("events", eventsSource)
+	if err != nil {
+		return nil, nil, err
+	}
+	checkpointSource := cfg.CheckpointDatabase()
+	checkpointDB, err := open("checkpoint", checkpointSource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var eventsReader events.ReaderInterface
+	var enricher *events.UserEnricher
+	enrichmentSource := cfg.EnrichmentDatabase()
+	if cfg.EmailEnrichment.GetSource() == "none" || enrichmentSource == eventsSource {
+		eventsReader = newEventReader(eventsSource.Driver, eventsDB, logger, &cfg.EmailEnrichment)
+	} else {
+		enrichmentDB, err := open("enrichment", enrichmentSource)
+		if err != nil {
+			return nil, nil, err
+		}
+		eventsReader = newEventReader(eventsSource.Driver, eventsDB, logger, events.NoEnrichment)
+		enricher = events.NewUserEnricher(enrichmentDB, enrichmentSource.Driver, logger, &cfg.EmailEnrichment)
+	}
+	checkpoints := newEventReader(checkpointSource.Driver, checkpointDB, logger, events.NoEnrichment)
+
+	return events.NewSplitReader(eventsReader, enricher, checkpoints), checkpointDB, nil
+}
+
+// openAPIReader creates the NetBird API reader. Its checkpoints are kept in
+// netbird_api.checkpoint_file, or in the sources.checkpoint database, which is
+// then returned as well.
+func openAPIReader(cfg *config.Config, logger *slog.Logger) (events.ReaderInterface, *sql.DB, error) {
+	// The writers of one poll share a fetched list; a single run fetches once
+	cacheFor := time.Duration(cfg.PollingInterval) * time.Second / 2
+	if cfg.PollingInterval == 0 {
+		cacheFor = math.MaxInt64
+	}
+	apiReader, err := events.NewAPIEventReader(events.APIReaderOptions{
+		URL:            cfg.NetBirdAPI.URL,
+		Token:          cfg.NetBirdAPI.Token,
+		Timeout:        time.Duration(cfg.NetBirdAPI.Timeout) * time.Second,
+		AccountID:      cfg.NetBirdAPI.AccountID,
+		CheckpointFile: cfg.NetBirdAPI.CheckpointFile,
+		CacheFor:       cacheFor,
+	}, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create NetBird API reader: %w", err)
+	}
+	logger.Info("Using NetBird API event reader", "url", cfg.NetBirdAPI.URL)
+
+	checkpointSource := cfg.CheckpointDatabase()
+	if checkpointSource.Driver == "api" {
+		logger.Info("Keeping checkpoints in file", "path", cfg.NetBirdAPI.CheckpointFile)
+		return apiReader, nil, nil
+	}
+	db, err := config.OpenDatabase(checkpointSource, logger)
+	if err != nil {
+		_ = apiReader.Close()
+		return nil, nil, fmt.Errorf("failed to open checkpoint database: %w", err)
+	}
+	checkpoints := newEventReader(checkpointSource.Driver, db, logger, events.NoEnrichment)
+	return events.NewSplitReader(apiReader, nil, checkpoints), db, nil
+}
+
+// newEventReader creates the reader of a database driver.
+func newEventReader(driver string, db *sql.DB, logger *slog.Logger, emailConf events.EmailEnrichmentConfig) events.ReaderInterface {
+	switch driver {
+	case "sqlite":
+		return events.NewSQLiteEventReader(db, logger, emailConf)
+	case "mysql":
+		return events.NewMySQLEventReader(db, logger, emailConf)
+	default:
+		return events.NewPostgresEventReader(db, logger, emailConf)
+	}
+}
+
+// OpenDeadLetterStore creates the dead-letter store selected by dlq.type.
+// db is the event database, used by the "database" store.
+func OpenDeadLetterStore(cfg *config.Config, db *sql.DB) (dlq.Store, error) {
+	switch cfg.DLQ.Type {
+	case dlq.TypeDatabase:
+		return dlq.NewSQLStore(db, cfg.CheckpointDatabase().Driver), nil
+	case dlq.TypeFile, "":
+		store, err := dlq.NewFileStore(cfg.DLQ.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dead-letter queue: %w", err)
+		}
+		return store, nil
+	}
+	return nil, fmt.Errorf("unsupported dlq.type %q", cfg.DLQ.Type)
+}
+
+// BuildWriter creates the single writer whose checkpoint writer_type is name,
+// e.g. "loki", "webhook:siem" or "group:siem", with its retry settings. It is
+// used to re-drive dead letters outside the service, so its routes are left
+// out: an entry the current routes filter out would otherwise be dropped and
+// removed from the queue as if it had been delivered.
+func BuildWriter(cfg *config.Config, logger *slog.Logger, name string) (writer.EventWriter, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	// Build just that writer; webhooks and group members are built together
+	// and the others closed
+	single := withoutRoutes(*cfg)
+	single.WriterGroups = nil
+	if groupName, ok := strings.CutPrefix(name, "group:"); ok {
+		for _, g := range cfg.WriterGroups {
+			if g.Name == groupName {
+				single.WriterGroups = []config.WriterGroupConfig{g}
+			}
+		}
+		if single.WriterGroups == nil {
+			return nil, fmt.Errorf("writer %q is not configured", name)
+		}
+	} else {
+		base, _, _ := strings.Cut(name, ":")
+		if !slices.Contains(cfg.Writers, base) {
+			return nil, fmt.Errorf("writer %q is not configured", name)
+		}
+		single.Writers = []string{base}
+	}
+	writers, err := buildWriters(&single, false, logger, hostname)
+	if err != nil {
+		return nil, err
+	}
+	var found writer.EventWriter
+	var rest []*writerState
+	for _, ws := range writers {
+		if w, ok := ws.writer.(writer.EventWriter); ok && ws.name == name {
+			found = w
+			continue
+		}
+		rest = append(rest, ws)
+	}
+	closeWriters(rest, logger)
+	if found == nil {
+		return nil, fmt.Errorf("writer %q is not configured", name)
+	}
+	return found, nil
+}
+
+// withoutRoutes returns cfg with the routes of every writer removed.
+func withoutRoutes(cfg config.Config) config.Config {
+	for _, rc := range []*config.RouteConfig{
+		&cfg.Stdout.Route, &cfg.Loki.Route, &cfg.Splunk.Route, &cfg.Syslog.Route,
+		&cfg.Elasticsearch.Route, &cfg.OTLP.Route, &cfg.File.Route, &cfg.Kafka.Route,
+		&cfg.RedisStream.Route, &cfg.NATS.Route,
+	} {
+		*rc = config.RouteConfig{}
+	}
+	cfg.Webhooks = slices.Clone(cfg.Webhooks)
+	for i := range cfg.Webhooks {
+		cfg.Webhooks[i].Route = config.RouteConfig{}
+	}
+	return cfg
+}
+
+// buildWriters creates every writer listed in cfg.Writers (stdout when empty).
+// Each writer is named after its checkpoint writer_type: the writer name, or
+// "webhook:<name>" for every webhook destination. Writers shared by several
+// processors are locked, see newWriterState. On error, the writers already
+// created are closed.
+func buildWriters(cfg *config.Config, shared bool, logger *slog.Logger, hostname string) (_ []*writerState, err error) {
+	names := cfg.Writers
+	if len(names) == 0 {
+		names = []string{"stdout"}
+	}
+
+	var writers []*writerState
+	defer func() {
+		if err != nil {
+			closeWriters(writers, logger)
+		}
+	}()
+	for _, name := range names {
+		switch name {
+		case "stdout":
+			// Outputs one line per event to stdout, captured by the systemd journal
+			f, err := format.New(cfg.Stdout.Format)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create stdout writer: %w", err)
+			}
+			writers = append(writers, newWriterState(name, stdout.NewStdoutWriterWithFormatter(logger, f), cfg.Stdout.Retry, cfg.Stdout.Route, shared, logger))
+			logger.Info("Initialized stdout writer for journal output", "format", cfg.Stdout.Format)
+		case "loki":
+			lw, err := createLokiWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, lw, cfg.Loki.Retry, cfg.Loki.Route, shared, logger))
+			logger.Info("Initialized Loki writer", "url", cfg.Loki.URL, "compression", cfg.Loki.Compression)
+		case "splunk":
+			sw, err := createSplunkWriter(cfg, logger, hostname)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, sw, cfg.Splunk.Retry, cfg.Splunk.Route, shared, logger))
+			logger.Info("Initialized Splunk HEC writer", "url", cfg.Splunk.URL, "index", cfg.Splunk.Index, "ack_enabled", cfg.Splunk.AckEnabled)
+		case "syslog":
+			sw, err := createSyslogWriter(cfg, logger, hostname)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, sw, cfg.Syslog.Retry, cfg.Syslog.Route, shared, logger))
+			logger.Info("Initialized syslog writer", "address", cfg.Syslog.Address, "network", cfg.Syslog.Network, "framing", cfg.Syslog.Framing)
+		case "elasticsearch":
+			ew, err := createElasticWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, ew, cfg.Elasticsearch.Retry, cfg.Elasticsearch.Route, shared, logger))
+			logger.Info("Initialized Elasticsearch writer", "url", cfg.Elasticsearch.URL, "index", cfg.Elasticsearch.Index, "format", cfg.Elasticsearch.Format)
+		case "otlp":
+			ow, err := createOTLPWriter(cfg, logger, hostname)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, ow, cfg.OTLP.Retry, cfg.OTLP.Route, shared, logger))
+			logger.Info("Initialized OTLP writer", "endpoint", cfg.OTLP.Endpoint, "protocol", cfg.OTLP.Protocol)
+		case "webhook":
+			// Each destination is a separate writer with its own timeout
+			for _, whCfg := range cfg.Webhooks {
+				ww, err := createWebhookWriter(whCfg, logger)
+				if err != nil {
+					return nil, err
+				}
+				writers = append(writers, newWriterState("webhook:"+ww.Name(), ww, whCfg.Retry, whCfg.Route, shared, logger))
+				logger.Info("Initialized webhook writer", "name", whCfg.Name, "url", whCfg.URL, "mode", whCfg.Mode)
+			}
+		case "file":
+			fw, err := createFileWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, fw, cfg.File.Retry, cfg.File.Route, shared, logger))
+			logger.Info("Initialized file writer", "dir", cfg.File.Dir, "compression", cfg.File.Compression, "retention_days", cfg.File.RetentionDays)
+		case "kafka":
+			kw, err := createKafkaWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, kw, cfg.Kafka.Retry, cfg.Kafka.Route, shared, logger))
+			logger.Info("Initialized Kafka writer", "brokers", cfg.Kafka.Brokers, "topic", cfg.Kafka.Topic, "key_field", cfg.Kafka.KeyField)
+		case "redis_stream":
+			rw, err := createRedisStreamWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, rw, cfg.RedisStream.Retry, cfg.RedisStream.Route, shared, logger))
+			logger.Info("Initialized Redis Streams writer", "stream", cfg.RedisStream.Stream, "max_len", cfg.RedisStream.MaxLen)
+		case "nats":
+			nw, err := createNATSWriter(cfg, logger)
+			if err != nil {
+				return nil, err
+			}
+			writers = append(writers, newWriterState(name, nw, cfg.NATS.Retry, cfg.NATS.Route, shared, logger))
+			logger.Info("Initialized NATS JetStream writer", "subject", cfg.NATS.Subject, "stream", cfg.NATS.Stream)
+		default:
+			return nil, fmt.Errorf("unknown writer %q", name)
+		}
+	}
+
+	// Names key the checkpoints, so two writers must never share one
+	seen := make(map[string]bool, len(writers))
+	for _, ws := range writers {
+		if seen[ws.name] {
+			return nil, fmt.Errorf("writer %q is configured more than once; each writer needs a unique name for its checkpoint", ws.name)
+		}
+		seen[ws.name] = true
+	}
+
+	return groupWriters(writers, cfg.WriterGroups, logger)
+}
+
+// groupWriters replaces the members of every writer group with one writer,
+// named "group:<name>", that sends to them concurrently under the group's
+// delivery policy.
+func groupWriters(writers []*writerState, groups []config.WriterGroupConfig, logger *slog.Logger) ([]*writerState, error) {
+	if len(groups) == 0 {
+		return writers, nil
+	}
+	byName := make(map[string]*writerState, len(writers))
+	for _, ws := range writers {
+		byName[ws.name] = ws
+	}
+
+	grouped := make(map[string]bool)
+	var groupStates []*writerState
+	for _, g := range groups {
+		targets := make([]writer.Target, 0, len(g.Writers))
+		for _, member := range g.Writers {
+			ws, ok := byName[member]
+			if !ok {
+				return nil, fmt.Errorf("writer group %q: writer %q is not configured", g.Name, member)
+			}
+			w, ok := ws.writer.(writer.EventWriter)
+			if !ok || grouped[member] {
+				return nil, fmt.Errorf("writer group %q: writer %q cannot be grouped", g.Name, member)
+			}
+			targets = append(targets, writer.Target{Name: member, Writer: w})
+			grouped[member] = true
+		}
+		mw, err := writer.NewMultiWriterWithConfig(&writer.MultiConfig{
+			Name:    g.Name,
+			Policy:  writer.Policy(g.Policy),
+			Quorum:  g.Quorum,
+			Timeout: time.Duration(g.Timeout) * time.Second,
+		}, logger, targets...)
+		if err != nil {
+			return nil, fmt.Errorf("writer group %q: %w", g.Name, err)
+		}
+		groupStates = append(groupStates, &writerState{name: "group:" + g.Name, writer: mw})
+		logger.Info("Initialized writer group", "group", g.Name, "writers", g.Writers, "policy", g.Policy, "quorum", g.Quorum)
+	}
+
+	var result []*writerState
+	for _, ws := range writers {
+		if !grouped[ws.name] {
+			result = append(result, ws)
+		}
+	}
+	return append(result, groupStates...), nil
+}
+
+// newWriterState names a writer and wraps it with its retries and circuit
+// breaker when its retry section is enabled, then with its routes when it has
+// any, so filtered events never reach the retry loop. A shared writer is
+// locked inside the retries, so a processor waiting to retry does not hold
+// the sink.
+func newWriterState(name string, w writer.EventWriter, rc config.RetryConfig, rt config.RouteConfig, shared bool, logger *slog.Logger) *writerState {
+	if shared {
+		w = &lockedWriter{w: w}
+	}
+	if rc.Enabled {
+		logger.Info("Enabled retries for writer", "writer", name, "max_attempts", rc.MaxAttempts, "failure_threshold", rc.FailureThreshold)
+		w = writer.NewRetryWriter(name, w, &writer.RetryConfig{
+			MaxAttempts:      rc.MaxAttempts,
+			InitialBackoff:   time.Duration(rc.InitialBackoff) * time.Second,
+			MaxBackoff:       time.Duration(rc.MaxBackoff) * time.Second,
+			FailureThreshold: rc.FailureThreshold,
+			OpenTimeout:      time.Duration(rc.OpenTimeout) * time.Second,
+		}, logger)
+	}
+	if len(rt.Include) > 0 || len(rt.Exclude) > 0 {
+		logger.Info("Enabled routing for writer", "writer", name, "include_rules", len(rt.Include), "exclude_rules", len(rt.Exclude))
+		w = writer.NewRouteWriter(name, w, &writer.Routes{
+			Include: routeRules(rt.Include),
+			Exclude: routeRules(rt.Exclude),
+		})
+	}
+	return &writerState{name: name, writer: w}
+}
+
+// routeRules converts configured route rules. The meta matches were
+// validated by config.LoadConfig.
+func routeRules(rules []config.RouteRule) []writer.Rule {
+	result := make([]writer.Rule, len(rules))
+	for i, r := range rules {
+		result[i] = writer.Rule{ActivityCodes: r.ActivityCodes, AccountIDs: r.AccountIDs}
+		for _, m := range r.Meta {
+			key, pattern, _ := strings.Cut(m, "=")
+			if result[i].Meta == nil {
+				result[i].Meta = make(map[string]string, len(r.Meta))
+			}
+			result[i].Meta[key] = pattern
+		}
+	}
+	return result
+}
+
+// createLokiWriter creates a Loki writer with TLS support.
+// service_name, platform and region are always attached as stream labels so
+// LogQL queries match the labels the Alloy/OTel pipeline used to set.
+func createLokiWriter(cfg *config.Config, logger *slog.Logger) (*loki.Writer, error) {
+	labels := map[string]string{
+		"service_name": "eventsproc",
+		"platform":     cfg.Platform,
+		"region":       cfg.Region,
+	}
+	for k, v := range cfg.Loki.Labels {
+		labels[k] = v
+	}
+
+	w, err := loki.NewWriter(&loki.Config{
+		URL:         cfg.Loki.URL,
+		TenantID:    cfg.Loki.TenantID,
+		Username:    cfg.Loki.Username,
+		Password:    cfg.Loki.Password,
+		Compression: cfg.Loki.Compression,
+		Labels:      labels,
+		EventLabels: cfg.Loki.EventLabels,
+		Format:      cfg.Loki.Format,
+		Timeout:     time.Duration(cfg.Loki.Timeout) * time.Second,
+		TLS:         cfg.Loki.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loki writer: %w", err)
+	}
+	return w, nil
+}
+
+// createSplunkWriter creates a Splunk HEC writer. The HEC host field defaults
+// to this machine's hostname so events can be traced back to the node.
+func createSplunkWriter(cfg *config.Config, logger *slog.Logger, hostname string) (*splunk.Writer, error) {
+	host := cfg.Splunk.Host
+	if host == "" {
+		host = hostname
+	}
+
+	w, err := splunk.NewWriter(&splunk.Config{
+		URL:             cfg.Splunk.URL,
+		Token:           cfg.Splunk.Token,
+		Index:           cfg.Splunk.Index,
+		Source:          cfg.Splunk.Source,
+		SourceType:      cfg.Splunk.SourceType,
+		Host:            host,
+		AckEnabled:      cfg.Splunk.AckEnabled,
+		Channel:         cfg.Splunk.Channel,
+		AckTimeout:      time.Duration(cfg.Splunk.AckTimeout) * time.Second,
+		AckPollInterval: time.Duration(cfg.Splunk.AckPollInterval) * time.Second,
+		Format:          cfg.Splunk.Format,
+		Timeout:         time.Duration(cfg.Splunk.Timeout) * time.Second,
+		TLS:             cfg.Splunk.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create splunk writer: %w", err)
+	}
+	return w, nil
+}
+
+// createSyslogWriter creates an RFC 5424 syslog writer. The HOSTNAME header
+// defaults to this machine's hostname, like the Splunk host field.
+func createSyslogWriter(cfg *config.Config, logger *slog.Logger, hostname string) (*syslog.Writer, error) {
+	host := cfg.Syslog.Hostname
+	if host == "" {
+		host = hostname
+	}
+
+	w, err := syslog.NewWriter(&syslog.Config{
+		Network:           cfg.Syslog.Network,
+		Address:           cfg.Syslog.Address,
+		Framing:           cfg.Syslog.Framing,
+		Facility:          cfg.Syslog.Facility,
+		Hostname:          host,
+		AppName:           cfg.Syslog.AppName,
+		SDID:              cfg.Syslog.SDID,
+		MessageFormat:     cfg.Syslog.MessageFormat,
+		SeverityOverrides: cfg.Syslog.SeverityOverrides,
+		Timeout:           time.Duration(cfg.Syslog.Timeout) * time.Second,
+		TLS:               cfg.Syslog.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create syslog writer: %w", err)
+	}
+	return w, nil
+}
+
+// createElasticWriter creates an Elasticsearch/OpenSearch bulk writer.
+func createElasticWriter(cfg *config.Config, logger *slog.Logger) (*elastic.Writer, error) {
+	w, err := elastic.NewWriter(&elastic.Config{
+		URL:          cfg.Elasticsearch.URL,
+		Index:        cfg.Elasticsearch.Index,
+		DataStream:   cfg.Elasticsearch.DataStream,
+		Username:     cfg.Elasticsearch.Username,
+		Password:     cfg.Elasticsearch.Password,
+		APIKey:       cfg.Elasticsearch.APIKey,
+		Pipeline:     cfg.Elasticsearch.Pipeline,
+		Format:       cfg.Elasticsearch.Format,
+		TemplateName: cfg.Elasticsearch.TemplateName,
+		Timeout:      time.Duration(cfg.Elasticsearch.Timeout) * time.Second,
+		TLS:          cfg.Elasticsearch.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create elasticsearch writer: %w", err)
+	}
+	return w, nil
+}
+
+// createOTLPWriter creates an OTLP logs writer. The resource describes this
+// instance the way the OTel Collector's journald pipeline used to, so existing
+// queries on service.name, platform and region keep working.
+func createOTLPWriter(cfg *config.Config, logger *slog.Logger, hostname string) (*otlp.Writer, error) {
+	resource := map[string]string{
+		"service.name":    "eventsproc",
+		"service.version": format.DeviceVersion,
+		"platform":        cfg.Platform,
+		"region":          cfg.Region,
+		"consumer_id":     cfg.ConsumerID,
+		"host.name":       hostname,
+	}
+	for k, v := range cfg.OTLP.ResourceAttributes {
+		resource[k] = v
+	}
+
+	w, err := otlp.NewWriter(&otlp.Config{
+		Endpoint:           cfg.OTLP.Endpoint,
+		Protocol:           cfg.OTLP.Protocol,
+		Headers:            cfg.OTLP.Headers,
+		Compression:        cfg.OTLP.Compression,
+		ResourceAttributes: resource,
+		MaxRetries:         cfg.OTLP.MaxRetries,
+		RetryBackoff:       time.Duration(cfg.OTLP.RetryBackoff) * time.Second,
+		Timeout:            time.Duration(cfg.OTLP.Timeout) * time.Second,
+		TLS:                cfg.OTLP.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp writer: %w", err)
+	}
+	return w, nil
+}
+
+// createWebhookWriter creates the writer for one webhook destination.
+func createWebhookWriter(whCfg config.WebhookConfig, logger *slog.Logger) (*webhook.Writer, error) {
+	w, err := webhook.NewWriter(&webhook.Config{
+		Name:            whCfg.Name,
+		URL:             whCfg.URL,
+		Method:          whCfg.Method,
+		Headers:         whCfg.Headers,
+		Mode:            whCfg.Mode,
+		Template:        whCfg.Template,
+		Secret:          whCfg.Secret,
+		SignatureHeader: whCfg.SignatureHeader,
+		TimestampHeader: whCfg.TimestampHeader,
+		Timeout:         time.Duration(whCfg.Timeout) * time.Second,
+		TLS:             whCfg.TLS,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook writer: %w", err)
+	}
+	return w, nil
+}
+
+// createFileWriter creates the rotating NDJSON file writer. Segments are
+// prefixed with the consumer ID so several instances can share a directory.
+func createFileWriter(cfg *config.Config, logger *slog.Logger) (*file.Writer, error) {
+	w, err := file.NewWriter(&file.Config{
+		Dir:            cfg.File.Dir,
+		ConsumerID:     cfg.ConsumerID,
+		Format:         cfg.File.Format,
+		MaxSize:        int64(cfg.File.MaxSizeMB) << 20,
+		RotateInterval: time.Duration(cfg.File.RotateIntervalHours) * time.Hour,
+		Compression:    cfg.File.Compression,
+		Retention:      time.Duration(cfg.File.RetentionDays) * 24 * time.Hour,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file writer: %w", err)
+	}
+	return w, nil
+}
+
+// createKafkaWriter creates a Kafka producer writer with SASL and TLS support.
+func createKafkaWriter(cfg *config.Config, logger *slog.Logger) (*kafka.Writer, error) {
+	w, err := kafka.NewWriter(&kafka.Config{
+		Brokers:       cfg.Kafka.Brokers,
+		Topic:         cfg.Kafka.Topic,
+		KeyField:      cfg.Kafka.KeyField,
+		Format:        cfg.Kafka.Format,
+		Compression:   cfg.Kafka.Compression,
+		ClientID:      cfg.ConsumerID,
+		SASLMechanism: cfg.Kafka.SASLMechanism,
+		Username:      cfg.Kafka.Username,
+		Password:      cfg.Kafka.Password,
+		TLSEnabled:    cfg.Kafka.TLSEnabled,
+		TLS:           cfg.Kafka.TLS,
+		Timeout:       time.Duration(cfg.Kafka.Timeout) * time.Second,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka writer: %w", err)
+	}
+	return w, nil
+}
+
+// createRedisStreamWriter creates a Redis Streams writer. Without its own URL
+// it uses the cluster Redis, which HA deployments already run.
+func createRedisStreamWriter(cfg *config.Config, logger *slog.Logger) (*redisstream.Writer, error) {
+	url := cfg.RedisStream.URL
+	if url == "" {
+		url = cfg.Cluster.RedisURL
+	}
+	w, err := redisstream.NewWriter(&redisstream.Config{
+		URL:       url,
+		Stream:    cfg.RedisStream.Stream,
+		MaxLen:    cfg.RedisStream.MaxLen,
+		ExactTrim: cfg.RedisStream.ExactTrim,
+		Format:    cfg.RedisStream.Format,
+		Timeout:   time.Duration(cfg.RedisStream.Timeout) * time.Second,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redis stream writer: %w", err)
+	}
+	return w, nil
+}
+
+// createNATSWriter creates a NATS JetStream writer with TLS support.
+func createNATSWriter(cfg *config.Config, logger *slog.Logger) (*nats.Writer, error) {
+	w, err := nats.NewWriter(&nats.Config{
+		URL:        cfg.NATS.URL,
+		Subject:    cfg.NATS.Subject,
+		Stream:     cfg.NATS.Stream,
+		Format:     cfg.NATS.Format,
+		CredsFile:  cfg.NATS.CredsFile,
+		Token:      cfg.NATS.Token,
+		Username:   cfg.NATS.Username,
+		Password:   cfg.NATS.Password,
+		ClientName: cfg.ConsumerID,
+		TLS:        cfg.NATS.TLS,
+		Timeout:    time.Duration(cfg.NATS.Timeout) * time.Second,
+	}, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nats writer: %w", err)
+	}
+	return w, nil
 }
 
-// createLokiWriter creates a Loki writer with TLS support
 // Run starts the event processor
 func (p *Processor) Run(ctx context.Context) error {
 	p.logger.Info("Starting events processor",
@@ -95,67 +859,353 @@ func (p *Processor) Run(ctx context.Context) error {
 		"processing_node", p.hostname,
 	)
 
-	// Load checkpoint from database (single checkpoint model)
-	checkpoint, err := p.eventReader.GetCheckpoint(ctx, p.config.ConsumerID)
-	if err != nil {
-		return fmt.Errorf("failed to load checkpoint: %w", err)
+	// Load every writer's checkpoint from the database
+	for _, ws := range p.writers {
+		if err := p.loadCheckpoint(ctx, ws); err != nil {
+			return err
+		}
 	}
 
-	if checkpoint == nil {
-		// Initialize checkpoint
-		p.checkpoint = &events.ProcessingCheckpoint{
-			ConsumerID:           p.config.ConsumerID,
-			LastEventID:          0,
-			LastEventTimestamp:   time.Time{},
-			TotalEventsProcessed: 0,
-			ProcessingNode:       p.hostname,
+	if p.wal != nil {
+		// Continue after the buffered events, or from the slowest writer when
+		// the log is empty
+		p.walHead = p.wal.LastID()
+		if p.walHead == 0 {
+			p.walHead = p.minCheckpoint()
 		}
-		p.logger.Info("No existing checkpoint found, starting fresh")
-	} else {
-		p.checkpoint = checkpoint
+		metrics.WALSizeBytes.WithLabelValues(p.source).Set(float64(p.wal.Size()))
+		metrics.WALLastEventID.WithLabelValues(p.source).Set(float64(p.walHead))
+	}
+
+	// Run once or continuously based on polling_interval
+	if p.config.PollingInterval == 0 {
+		// Run once and exit. Writers still drain what is already buffered
+		// when fetching new events fails.
+		var ingestErr error
+		if p.wal != nil {
+			ingestErr = p.ingest(ctx)
+		}
+		return errors.Join(ingestErr, p.processEvents(ctx))
+	}
+
+	// Run continuously with polling. Each writer polls on its own schedule,
+	// so a writer stuck retrying a slow sink never delays the others.
+	var wg sync.WaitGroup
+	if p.config.Notify.Enabled {
+		notifications, err := p.listen(ctx)
+		if err != nil {
+			return err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.forwardNotifications(notifications)
+		}()
+	}
+	if p.wal != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.pollIngest(ctx)
+		}()
+	}
+	for _, ws := range p.writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.pollWriter(ctx, ws)
+		}()
+	}
+	wg.Wait()
+
+	p.logger.Info("Context cancelled, shutting down")
+	return ctx.Err()
+}
+
+// loadCheckpoint loads the checkpoint of one writer. A writer without a
+// checkpoint of its own starts from the consumer's single-checkpoint row
+// (writer_type "default") when one exists, so upgrading from the single
+// checkpoint model does not resend events; otherwise it starts fresh.
+func (p *Processor) loadCheckpoint(ctx context.Context, ws *writerState) error {
+	checkpoint, err := p.eventReader.GetWriterCheckpoint(ctx, p.config.ConsumerID, ws.name)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint for writer %s: %w", ws.name, err)
+	}
+	if checkpoint != nil {
+		ws.checkpoint = checkpoint
 		p.logger.Info("Resuming from checkpoint",
+			"writer", ws.name,
 			"last_event_id", checkpoint.LastEventID,
 			"last_event_timestamp", checkpoint.LastEventTimestamp,
 			"total_events_processed", checkpoint.TotalEventsProcessed,
 		)
+		return nil
 	}
 
-	// Run once or continuously based on polling_interval
-	if p.config.PollingInterval == 0 {
-		// Run once and exit
-		return p.processEvents(ctx)
+	legacy, err := p.eventReader.GetWriterCheckpoint(ctx, p.config.ConsumerID, events.LegacyWriterType)
+	if err != nil {
+		return fmt.Errorf("failed to load checkpoint for writer %s: %w", ws.name, err)
+	}
+	if legacy != nil {
+		ws.checkpoint = &events.ProcessingCheckpoint{
+			ConsumerID:           p.config.ConsumerID,
+			WriterType:           ws.name,
+			LastEventID:          legacy.LastEventID,
+			LastEventTimestamp:   legacy.LastEventTimestamp,
+			TotalEventsProcessed: legacy.TotalEventsProcessed,
+			ProcessingNode:       p.hostname,
+		}
+		p.logger.Info("No checkpoint found for writer, resuming from single consumer checkpoint",
+			"writer", ws.name,
+			"last_event_id", legacy.LastEventID,
+		)
+		return nil
+	}
+
+	// Initialize checkpoint
+	ws.checkpoint = &events.ProcessingCheckpoint{
+		ConsumerID:           p.config.ConsumerID,
+		WriterType:           ws.name,
+		LastEventID:          0,
+		LastEventTimestamp:   time.Time{},
+		TotalEventsProcessed: 0,
+		ProcessingNode:       p.hostname,
+	}
+	p.logger.Info("No existing checkpoint found, starting fresh", "writer", ws.name)
+	return nil
+}
+
+// listen starts push mode on the event reader.
+func (p *Processor) listen(ctx context.Context) (<-chan struct{}, error) {
+	listener, ok := p.eventReader.(events.Listener)
+	if !ok {
+		return nil, fmt.Errorf("notify.enabled is not supported by the %s event reader", p.config.EventsDatabase().Driver)
+	}
+	notifications, err := listener.Listen(ctx, events.NotifyOptions{
+		ConnString:           p.config.EventsDatabase().PostgresURL,
+		Channel:              p.config.Notify.Channel,
+		InstallTrigger:       p.config.Notify.InstallTrigger,
+		MinReconnectInterval: time.Duration(p.config.Notify.MinReconnectInterval) * time.Second,
+		MaxReconnectInterval: time.Duration(p.config.Notify.MaxReconnectInterval) * time.Second,
+		Source:               p.source,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start push mode: %w", err)
+	}
+	p.logger.Info("Push mode enabled", "channel", p.config.Notify.Channel)
+	return notifications, nil
+}
+
+// forwardNotifications wakes ingest, or every writer when the write-ahead log
+// is off, for each notification until the channel is closed. A wake-up that
+// is already pending absorbs the new one.
+func (p *Processor) forwardNotifications(notifications <-chan struct{}) {
+	for range notifications {
+		if p.ingestWake != nil {
+			select {
+			case p.ingestWake <- struct{}{}:
+			default:
+			}
+			continue
+		}
+		for _, ws := range p.writers {
+			select {
+			case ws.wake <- struct{}{}:
+			default:
+			}
+		}
 	}
+}
 
-	// Run continuously with polling
+// pollIngest appends new database events to the write-ahead log immediately
+// and then at every polling interval, or as soon as push mode reports new
+// events, until ctx is cancelled.
+func (p *Processor) pollIngest(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(p.config.PollingInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if err := p.ingest(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Error("Error buffering events", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.ingestWake:
+		}
+	}
+}
+
+// ingest fetches the events after the head of the write-ahead log from the
+// database and appends them, batch by batch, until it has caught up or the
+// log is full. Writers are woken after every batch.
+func (p *Processor) ingest(ctx context.Context) error {
+	opts := events.EventQueryOptions{
+		Limit:    p.config.BatchSize,
+		OrderAsc: true,
+	}
+	if p.walHead == 0 && p.config.LookbackHours > 0 {
+		lookbackTime := time.Now().Add(-time.Duration(p.config.LookbackHours) * time.Hour)
+		opts.StartTime = &lookbackTime
+	}
+	if p.config.Streaming.Enabled {
+		return p.ingestStream(ctx, opts)
+	}
+
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if p.walHead > 0 {
+			head := p.walHead
+			opts.MinEventID = &head
+		}
+
+		dbStart := time.Now()
+		eventBatch, err := p.eventReader.GetEvents(ctx, opts)
+		metrics.DBQueryDuration.WithLabelValues(p.source, "get_events").Observe(time.Since(dbStart).Seconds())
+		if err != nil {
+			return fmt.Errorf("failed to fetch events for write-ahead log: %w", err)
+		}
+		if len(eventBatch) == 0 {
+			return nil
+		}
+
+		// The log is ordered by event ID
+		slices.SortFunc(eventBatch, func(a, b events.Event) int { return cmp.Compare(a.ID, b.ID) })
+		if full, err := p.appendWAL(eventBatch); full || err != nil {
+			return err
+		}
+
+		if len(eventBatch) < p.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+// ingestStream is ingest reading from a single database cursor. Events are
+// appended in batches of batch_size.
+func (p *Processor) ingestStream(ctx context.Context, opts events.EventQueryOptions) error {
+	if p.walHead > 0 {
+		head := p.walHead
+		opts.MinEventID = &head
+	}
+	opts.Limit = 0
+	opts.FetchSize = p.config.Streaming.FetchSize
+
+	batch := make([]events.Event, 0, p.config.BatchSize)
+	for evt, err := range p.eventReader.StreamEvents(ctx, opts) {
+		if err != nil {
+			return fmt.Errorf("failed to stream events for write-ahead log: %w", err)
+		}
+		batch = append(batch, evt)
+		if len(batch) < p.config.BatchSize {
+			continue
+		}
+		if full, err := p.appendWAL(batch); full || err != nil {
+			return err
+		}
+		batch = batch[:0]
+	}
+	if len(batch) > 0 {
+		_, err := p.appendWAL(batch)
+		return err
+	}
+	return nil
+}
+
+// appendWAL appends a batch sorted by event ID to the write-ahead log and
+// wakes the writers. It reports whether the log is full, in which case
+// nothing was appended.
+func (p *Processor) appendWAL(eventBatch []events.Event) (bool, error) {
+	if err := p.wal.Append(eventBatch); err != nil {
+		if errors.Is(err, wal.ErrFull) {
+			p.logger.Warn("Write-ahead log is full, pausing fetching until writers catch up",
+				"size_bytes", p.wal.Size(),
+				"max_size_mb", p.config.WAL.MaxSizeMB,
+			)
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to append events to write-ahead log: %w", err)
+	}
+	p.walHead = eventBatch[len(eventBatch)-1].ID
+	metrics.WALSizeBytes.WithLabelValues(p.source).Set(float64(p.wal.Size()))
+	metrics.WALLastEventID.WithLabelValues(p.source).Set(float64(p.walHead))
+	p.logger.Debug("Buffered events in write-ahead log", "count", len(eventBatch), "last_event_id", p.walHead)
+
+	for _, ws := range p.writers {
+		select {
+		case ws.wake <- struct{}{}:
+		default:
+		}
+	}
+	return false, nil
+}
+
+// pollWriter processes events for one writer immediately and then at every
+// polling interval, or as soon as new events are buffered in the write-ahead
+// log or reported by push mode, until ctx is cancelled.
+func (p *Processor) pollWriter(ctx context.Context, ws *writerState) {
 	ticker := time.NewTicker(time.Duration(p.config.PollingInterval) * time.Second)
 	defer ticker.Stop()
 
 	// Process immediately on start
-	if err := p.processEvents(ctx); err != nil {
-		p.logger.Error("Error processing events", "error", err)
+	if err := p.processWriter(ctx, ws); err != nil && ctx.Err() == nil {
+		p.logger.Error("Error processing events", "writer", ws.name, "error", err)
 	}
 
 	// Then poll at intervals
 	for {
 		select {
 		case <-ctx.Done():
-			p.logger.Info("Context cancelled, shutting down")
-			return ctx.Err()
+			return
 		case <-ticker.C:
-			if err := p.processEvents(ctx); err != nil {
-				p.logger.Error("Error processing events", "error", err)
-			}
+		case <-ws.wake:
+		}
+		if err := p.processWriter(ctx, ws); err != nil && ctx.Err() == nil {
+			p.logger.Error("Error processing events", "writer", ws.name, "error", err)
 		}
 	}
 }
 
-// processEvents processes events and sends to stdout writer
+// processEvents runs one processing cycle for every writer concurrently and
+// returns the errors of the writers that failed.
 func (p *Processor) processEvents(ctx context.Context) error {
+	errs := make([]error, len(p.writers))
+	var wg sync.WaitGroup
+	for i, ws := range p.writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = p.processWriter(ctx, ws)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// processWriter sends the events after the writer's checkpoint to the writer,
+// batch by batch, until it has caught up
+func (p *Processor) processWriter(ctx context.Context, ws *writerState) error {
 	startTime := time.Now()
 	defer func() {
-		metrics.ProcessingDuration.Observe(time.Since(startTime).Seconds())
+		metrics.ProcessingDuration.WithLabelValues(p.source).Observe(time.Since(startTime).Seconds())
+		// Lag is refreshed even when the cycle fails, so a stuck writer shows
+		// a growing lag instead of the value from its last success
+		if !ws.checkpoint.LastEventTimestamp.IsZero() {
+			metrics.WriterCheckpointLagSeconds.WithLabelValues(p.source, ws.name).Set(time.Since(ws.checkpoint.LastEventTimestamp).Seconds())
+		}
 	}()
-	p.logger.Info("Starting to process events")
+	logger := p.logger.With("writer", ws.name)
+	logger.Info("Starting to process events")
+
+	if ws.gaps == nil && p.config.GapDetection.Window > 0 {
+		ws.gaps = newGapTracker(time.Duration(p.config.GapDetection.Window)*time.Second, p.config.GapDetection.MaxPending)
+	}
+	if err := p.recheckGaps(ctx, ws, logger); err != nil {
+		return err
+	}
 
 	// Build query options
 	opts := events.EventQueryOptions{
@@ -165,110 +1215,384 @@ func (p *Processor) processEvents(ctx context.Context) error {
 	}
 
 	// Resume from checkpoint
-	if p.checkpoint.LastEventID > 0 {
-		opts.MinEventID = &p.checkpoint.LastEventID
-		p.logger.Debug("Resuming from checkpoint",
-			"last_event_id", p.checkpoint.LastEventID,
+	if ws.checkpoint.LastEventID > 0 {
+		lastEventID := ws.checkpoint.LastEventID
+		opts.MinEventID = &lastEventID
+		logger.Debug("Resuming from checkpoint",
+			"last_event_id", ws.checkpoint.LastEventID,
 		)
 	} else {
 		// First run - use lookback hours if configured
 		if p.config.LookbackHours > 0 {
 			lookbackTime := time.Now().Add(-time.Duration(p.config.LookbackHours) * time.Hour)
 			opts.StartTime = &lookbackTime
-			p.logger.Info("First run, using lookback",
+			logger.Info("First run, using lookback",
 				"lookback_hours", p.config.LookbackHours,
 			)
 		}
 	}
 
-	totalProcessed := 0
+	var totalProcessed int
+	var err error
+	if p.config.Streaming.Enabled && !p.readsWAL(opts) {
+		totalProcessed, err = p.streamWriter(ctx, ws, opts, logger)
+	} else {
+		totalProcessed, err = p.batchWriter(ctx, ws, opts, logger)
+	}
+	if err != nil {
+		return err
+	}
 
+	// Always record poll time regardless of whether events were found.
+	// This is the primary liveness signal — use it to detect a stopped service.
+	metrics.LastPollTime.WithLabelValues(p.source).SetToCurrentTime()
+
+	duration := time.Since(startTime)
+	if totalProcessed > 0 {
+		logger.Info("Finished processing events",
+			"events_processed", totalProcessed,
+			"last_event_id", ws.checkpoint.LastEventID,
+			"duration_s", duration.Seconds(),
+		)
+	} else {
+		logger.Debug("No events to process")
+	}
+
+	return nil
+}
+
+// batchWriter sends the events after opts.MinEventID to the writer, one
+// query (or write-ahead log read) per batch, until it has caught up. It
+// returns the number of events delivered.
+func (p *Processor) batchWriter(ctx context.Context, ws *writerState, opts events.EventQueryOptions, logger *slog.Logger) (int, error) {
+	totalProcessed := 0
 	for {
 		// Check context
 		if ctx.Err() != nil {
-			return ctx.Err()
+			return totalProcessed, ctx.Err()
 		}
 
 		// Fetch batch of events
-		dbStart := time.Now()
-		eventBatch, err := p.eventReader.GetEvents(ctx, opts)
-		metrics.DBQueryDuration.WithLabelValues("get_events").Observe(time.Since(dbStart).Seconds())
+		eventBatch, err := p.fetchBatch(ctx, opts)
 		if err != nil {
-			return fmt.Errorf("failed to fetch events: %w", err)
+			return totalProcessed, fmt.Errorf("failed to fetch events for writer %s: %w", ws.name, err)
 		}
 
 		// If no more events, we're done
 		if len(eventBatch) == 0 {
-			break
+			return totalProcessed, nil
 		}
 
-		// Send to stdout writer
-		sendStart := time.Now()
-		if err := p.writer.SendEvents(ctx, eventBatch); err != nil {
-			metrics.EventsProcessed.WithLabelValues("true").Add(float64(len(eventBatch)))
-			// Return error - checkpoint won't be updated, will retry next time
-			return fmt.Errorf("failed to send events: %w", err)
+		if err := p.deliver(ctx, ws, eventBatch, logger); err != nil {
+			return totalProcessed, err
 		}
-
-		p.logger.Debug("Sent events to stdout",
-			"count", len(eventBatch),
-			"duration_ms", time.Since(sendStart).Milliseconds(),
-		)
-
-		metrics.EventsProcessed.WithLabelValues("false").Add(float64(len(eventBatch)))
-		metrics.BatchSize.Observe(float64(len(eventBatch)))
 		totalProcessed += len(eventBatch)
-
-		// Update checkpoint
-		lastEvent := eventBatch[len(eventBatch)-1]
-		p.checkpoint.LastEventID = lastEvent.ID
-		p.checkpoint.LastEventTimestamp = lastEvent.Timestamp
-		metrics.LastEventID.Set(float64(lastEvent.ID))
-		metrics.CheckpointLagSeconds.Set(time.Since(lastEvent.Timestamp).Seconds())
-		p.checkpoint.TotalEventsProcessed += int64(len(eventBatch))
-		p.checkpoint.ProcessingNode = p.hostname
-
-		// Save checkpoint to database
-		dbStart = time.Now()
-		if err := p.eventReader.SaveCheckpoint(ctx, p.checkpoint); err != nil {
-			return fmt.Errorf("failed to save checkpoint: %w", err)
-		}
-		metrics.DBQueryDuration.WithLabelValues("save_checkpoint").Observe(time.Since(dbStart).Seconds())
-
-		p.logger.Debug("Updated checkpoint",
-			"last_event_id", p.checkpoint.LastEventID,
-			"total_events", p.checkpoint.TotalEventsProcessed,
-		)
+		if err := p.saveCheckpoint(ctx, ws, logger); err != nil {
+			return totalProcessed, err
+		}
 
 		// If we got fewer events than batch size, we're done
 		if len(eventBatch) < p.config.BatchSize {
-			break
+			return totalProcessed, nil
 		}
 
 		// Move to next batch - update MinEventID for next iteration
-		opts.MinEventID = &p.checkpoint.LastEventID
+		lastEventID := ws.checkpoint.LastEventID
+		opts.MinEventID = &lastEventID
 		opts.Offset = 0 // Reset offset since we're using MinEventID
 	}
+}
 
-	// Always record poll time regardless of whether events were found.
-	// This is the primary liveness signal — use it to detect a stopped service.
-	metrics.LastPollTime.SetToCurrentTime()
+// streamWriter sends the events after opts.MinEventID to the writer from a
+// single database cursor, in batches of batch_size, and saves the checkpoint
+// every streaming.checkpoint_every events and at the end. It returns the
+// number of events delivered.
+func (p *Processor) streamWriter(ctx context.Context, ws *writerState, opts events.EventQueryOptions, logger *slog.Logger) (int, error) {
+	opts.Limit = 0
+	opts.FetchSize = p.config.Streaming.FetchSize
 
-	duration := time.Since(startTime)
-	if totalProcessed > 0 {
-		p.logger.Info("Finished processing events",
-			"events_processed", totalProcessed,
-			"last_event_id", p.checkpoint.LastEventID,
-			"duration_s", duration.Seconds(),
-		)
-	} else {
-		p.logger.Debug("No events to process")
+	totalProcessed, unsaved := 0, 0
+	// The batch is reused: writers do not keep the slice after SendEvents
+	batch := make([]events.Event, 0, p.config.BatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := p.deliver(ctx, ws, batch, logger); err != nil {
+			return err
+		}
+		totalProcessed += len(batch)
+		unsaved += len(batch)
+		batch = batch[:0]
+		if unsaved >= p.config.Streaming.CheckpointEvery {
+			if err := p.saveCheckpoint(ctx, ws, logger); err != nil {
+				return err
+			}
+			unsaved = 0
+		}
+		return nil
+	}
+
+	err := func() error {
+		for evt, err := range p.eventReader.StreamEvents(ctx, opts) {
+			if err != nil {
+				return fmt.Errorf("failed to stream events for writer %s: %w", ws.name, err)
+			}
+			batch = append(batch, evt)
+			if len(batch) >= p.config.BatchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return flush()
+	}()
+	if unsaved > 0 {
+		// Keep the progress made before a failure, too
+		if saveErr := p.saveCheckpoint(ctx, ws, logger); err == nil {
+			err = saveErr
+		}
+	}
+	return totalProcessed, err
+}
+
+// deliver sends a batch to the writer and advances its checkpoint in memory.
+// The checkpoint is not saved; see saveCheckpoint.
+func (p *Processor) deliver(ctx context.Context, ws *writerState, eventBatch []events.Event, logger *slog.Logger) error {
+	// Send to writer
+	sendStart := time.Now()
+	if err := p.send(ctx, ws, eventBatch); err != nil {
+		metrics.EventsProcessed.WithLabelValues(p.source, "true").Add(float64(len(eventBatch)))
+		metrics.WriterEventsProcessed.WithLabelValues(p.source, ws.name, "true").Add(float64(len(eventBatch)))
+		// Return error - checkpoint won't be updated, will retry next time
+		return fmt.Errorf("failed to send events to writer %s: %w", ws.name, err)
+	}
+
+	logger.Debug("Sent events to writer",
+		"count", len(eventBatch),
+		"duration_ms", time.Since(sendStart).Milliseconds(),
+	)
+
+	metrics.EventsProcessed.WithLabelValues(p.source, "false").Add(float64(len(eventBatch)))
+	metrics.WriterEventsProcessed.WithLabelValues(p.source, ws.name, "false").Add(float64(len(eventBatch)))
+	metrics.BatchSize.WithLabelValues(p.source).Observe(float64(len(eventBatch)))
+
+	p.observeGaps(ws, ws.checkpoint.LastEventID, eventBatch, logger)
+
+	// Update checkpoint
+	lastEvent := eventBatch[len(eventBatch)-1]
+	p.progressMu.Lock()
+	ws.checkpoint.LastEventID = lastEvent.ID
+	ws.checkpoint.LastEventTimestamp = lastEvent.Timestamp
+	ws.checkpoint.TotalEventsProcessed += int64(len(eventBatch))
+	ws.checkpoint.ProcessingNode = p.hostname
+	p.updateProgressMetrics()
+	p.progressMu.Unlock()
+	metrics.WriterLastEventID.WithLabelValues(p.source, ws.name).Set(float64(lastEvent.ID))
+	metrics.WriterCheckpointLagSeconds.WithLabelValues(p.source, ws.name).Set(time.Since(lastEvent.Timestamp).Seconds())
+	return nil
+}
+
+// saveCheckpoint saves the writer's checkpoint to the database and drops the
+// buffered events every writer has delivered.
+func (p *Processor) saveCheckpoint(ctx context.Context, ws *writerState, logger *slog.Logger) error {
+	dbStart := time.Now()
+	if err := p.eventReader.SaveWriterCheckpoint(ctx, ws.checkpoint); err != nil {
+		return fmt.Errorf("failed to save checkpoint for writer %s: %w", ws.name, err)
+	}
+	metrics.DBQueryDuration.WithLabelValues(p.source, "save_checkpoint").Observe(time.Since(dbStart).Seconds())
+
+	// Drop the buffered events every writer has now delivered
+	if p.wal != nil {
+		p.progressMu.Lock()
+		delivered := p.minCheckpoint()
+		p.progressMu.Unlock()
+		if err := p.wal.Truncate(delivered); err != nil {
+			logger.Warn("Failed to remove delivered events from write-ahead log", "error", err)
+		}
+		metrics.WALSizeBytes.WithLabelValues(p.source).Set(float64(p.wal.Size()))
 	}
 
+	logger.Debug("Updated checkpoint",
+		"last_event_id", ws.checkpoint.LastEventID,
+		"total_events", ws.checkpoint.TotalEventsProcessed,
+	)
 	return nil
 }
 
-// Close cleans up resources
+// send delivers a batch to the writer. Events the writer rejected for good
+// are dead-lettered and nil is returned, so the checkpoint moves past them;
+// without a dead-letter queue they are logged and skipped. Any other error is
+// returned and the batch is sent again next time.
+func (p *Processor) send(ctx context.Context, ws *writerState, batch []events.Event) error {
+	if p.source != "" {
+		for i := range batch {
+			batch[i].Source = p.source
+		}
+	}
+	err := ws.writer.SendEvents(ctx, batch)
+	if err == nil {
+		return nil
+	}
+	var rejectedErr *writer.RejectedError
+	if errors.As(err, &rejectedErr) {
+		return p.storeDeadLetters(ctx, ws, rejectedErr.Rejected)
+	}
+	if p.dlq == nil || !writer.IsPermanent(err) {
+		return err
+	}
+
+	// The sink refused the whole request: send the events one by one to find
+	// the ones it refuses, delivering the rest
+	if len(batch) == 1 {
+		return p.storeDeadLetters(ctx, ws, []writer.Rejection{{Event: batch[0], Reason: err.Error()}})
+	}
+	p.logger.Warn("Writer rejected batch, resending events one by one",
+		"writer", ws.name,
+		"count", len(batch),
+		"error", err,
+	)
+	var rejected []writer.Rejection
+	for _, evt := range batch {
+		err := ws.writer.SendEvents(ctx, []events.Event{evt})
+		switch {
+		case err == nil:
+		case errors.As(err, &rejectedErr):
+			rejected = append(rejected, rejectedErr.Rejected...)
+		case writer.IsPermanent(err):
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+		default:
+			return err
+		}
+	}
+	return p.storeDeadLetters(ctx, ws, rejected)
+}
+
+// storeDeadLetters adds the rejected events to the dead-letter queue, or logs
+// them when it is disabled.
+func (p *Processor) storeDeadLetters(ctx context.Context, ws *writerState, rejected []writer.Rejection) error {
+	logger := p.logger.With("writer", ws.name)
+	if p.dlq == nil {
+		for _, r := range rejected {
+			logger.Warn("Writer rejected event, skipping", "event_id", r.Event.ID, "reason", r.Reason)
+		}
+		return nil
+	}
+
+	now := time.Now().UTC()
+	entries := make([]dlq.Entry, len(rejected))
+	for i, r := range rejected {
+		entries[i] = dlq.Entry{
+			ConsumerID: p.config.ConsumerID,
+			Writer:     ws.name,
+			Reason:     r.Reason,
+			FailedAt:   now,
+			Event:      r.Event,
+		}
+	}
+	if err := p.dlq.Add(ctx, entries); err != nil {
+		return fmt.Errorf("failed to dead-letter %d rejected events: %w", len(entries), err)
+	}
+	for _, e := range entries {
+		logger.Warn("Dead-lettered rejected event", "event_id", e.Event.ID, "dlq_id", e.ID, "reason", e.Reason)
+	}
+	metrics.DeadLetters.WithLabelValues(p.source, ws.name).Add(float64(len(entries)))
+	return nil
+}
+
+// fetchBatch returns the next batch of events for a writer. With the
+// write-ahead log enabled the batch comes from the log, except for a writer
+// that is behind the oldest buffered event (e.g. one just added to the
+// config), which reads the database until it has caught up.
+func (p *Processor) fetchBatch(ctx context.Context, opts events.EventQueryOptions) ([]events.Event, error) {
+	if p.readsWAL(opts) {
+		var after int64
+		if opts.MinEventID != nil {
+			after = *opts.MinEventID
+		}
+		return p.wal.Read(after, opts.Limit)
+	}
+
+	dbStart := time.Now()
+	eventBatch, err := p.eventReader.GetEvents(ctx, opts)
+	metrics.DBQueryDuration.WithLabelValues(p.source, "get_events").Observe(time.Since(dbStart).Seconds())
+	return eventBatch, err
+}
+
+// readsWAL reports whether the events after opts.MinEventID are read from the
+// write-ahead log rather than the database.
+func (p *Processor) readsWAL(opts events.EventQueryOptions) bool {
+	if p.wal == nil {
+		return false
+	}
+	var after int64
+	if opts.MinEventID != nil {
+		after = *opts.MinEventID
+	}
+	first := p.wal.FirstID()
+	return first == 0 || after >= first-1
+}
+
+// minCheckpoint returns the last event ID of the writer that is furthest
+// behind. Every event up to it has been delivered to all writers.
+func (p *Processor) minCheckpoint() int64 {
+	var lowest int64 = -1
+	for _, ws := range p.writers {
+		if ws.checkpoint != nil && (lowest < 0 || ws.checkpoint.LastEventID < lowest) {
+			lowest = ws.checkpoint.LastEventID
+		}
+	}
+	return max(lowest, 0)
+}
+
+// updateProgressMetrics sets the overall last event ID and checkpoint lag from
+// the writer that is furthest behind. Callers hold progressMu.
+func (p *Processor) updateProgressMetrics() {
+	var slowest *events.ProcessingCheckpoint
+	for _, ws := range p.writers {
+		if ws.checkpoint == nil {
+			continue
+		}
+		if slowest == nil || ws.checkpoint.LastEventID < slowest.LastEventID {
+			slowest = ws.checkpoint
+		}
+	}
+	if slowest == nil {
+		return
+	}
+	metrics.LastEventID.WithLabelValues(p.source).Set(float64(slowest.LastEventID))
+	if !slowest.LastEventTimestamp.IsZero() {
+		metrics.CheckpointLagSeconds.WithLabelValues(p.source).Set(time.Since(slowest.LastEventTimestamp).Seconds())
+	}
+}
+
+// Close cleans up resources. The writers are closed first so buffered
+// events are flushed before the database connection goes away. Shared
+// writers are left open.
 func (p *Processor) Close() error {
+	if !p.sharedWriters {
+		closeWriters(p.writers, p.logger)
+	}
+	if p.wal != nil {
+		if err := p.wal.Close(); err != nil {
+			p.logger.Warn("Failed to close write-ahead log", "error", err)
+		}
+	}
+	if p.dlq != nil {
+		if err := p.dlq.Close(); err != nil {
+			p.logger.Warn("Failed to close dead-letter queue", "error", err)
+		}
+	}
 	return p.eventReader.Close()
 }
+
+// closeWriters closes every writer that holds resources.
+func closeWriters(writers []*writerState, logger *slog.Logger) {
+	for _, ws := range writers {
+		if c, ok := ws.writer.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				logger.Warn("Failed to close writer", "writer", ws.name, "error", err)
+			}
+		}
+	}
+}
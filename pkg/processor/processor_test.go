@@ -4,22 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/xh63/netbird-events/pkg/config"
+	"github.com/xh63/netbird-events/pkg/dlq"
+	"github.com/xh63/netbird-events/pkg/elastic"
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/file"
+	"github.com/xh63/netbird-events/pkg/kafka"
+	"github.com/xh63/netbird-events/pkg/loki"
 	"github.com/xh63/netbird-events/pkg/metrics"
+	"github.com/xh63/netbird-events/pkg/otlp"
+	"github.com/xh63/netbird-events/pkg/redisstream"
+	"github.com/xh63/netbird-events/pkg/splunk"
+	"github.com/xh63/netbird-events/pkg/stdout"
+	"github.com/xh63/netbird-events/pkg/syslog"
+	"github.com/xh63/netbird-events/pkg/wal"
+	"github.com/xh63/netbird-events/pkg/webhook"
+	"github.com/xh63/netbird-events/pkg/writer"
 )
 
 // ============================================================================
 // Helpers
 // ============================================================================
 
+// testWriterType is the checkpoint writer_type of the writer in makeTestProcessor.
+const testWriterType = "stdout"
+
 // makeTestProcessor builds a Processor directly, bypassing NewProcessor's DB
 // dialling. Uses source="none" so GetEvents produces the simplest query
 // (no JOINs), making mock expectations easier to write.
@@ -40,8 +60,7 @@ func makeTestProcessor(db *sql.DB, writer EventWriter, checkpoint *events.Proces
 	logger := logFactory.New("system")
 	return &Processor{
 		eventReader: events.NewPostgresEventReader(db, logger, &cfg.EmailEnrichment),
-		writer:      writer,
-		checkpoint:  checkpoint,
+		writers:     []*writerState{{name: testWriterType, writer: writer, checkpoint: checkpoint}},
 		config:      cfg,
 		logFactory:  logFactory,
 		logger:      logger,
@@ -53,6 +72,7 @@ func makeTestProcessor(db *sql.DB, writer EventWriter, checkpoint *events.Proces
 func freshCheckpoint() *events.ProcessingCheckpoint {
 	return &events.ProcessingCheckpoint{
 		ConsumerID:           "test-consumer",
+		WriterType:           testWriterType,
 		LastEventID:          0,
 		TotalEventsProcessed: 0,
 		ProcessingNode:       "test-node",
@@ -71,34 +91,44 @@ func addEventRow(rows *sqlmock.Rows, id int64, ts time.Time, activity int) *sqlm
 	return rows.AddRow(id, ts, activity, "user1", "peer1", "account1", "{}", "user1@example.com", "peer1@example.com")
 }
 
-// expectSaveCheckpoint sets up the sqlmock INSERT expectation for SaveCheckpoint.
-// Uses AnyArg() for the timestamp to avoid brittle time comparisons.
+// expectSaveCheckpoint sets up the sqlmock INSERT expectation for the test
+// writer's SaveWriterCheckpoint.
 func expectSaveCheckpoint(mock sqlmock.Sqlmock, consumerID string, lastEventID, totalProcessed int64, node string) {
+	expectSaveWriterCheckpoint(mock, consumerID, testWriterType, lastEventID, totalProcessed, node)
+}
+
+// expectSaveWriterCheckpoint sets up the sqlmock INSERT expectation for SaveWriterCheckpoint.
+// Uses AnyArg() for the timestamp to avoid brittle time comparisons.
+func expectSaveWriterCheckpoint(mock sqlmock.Sqlmock, consumerID, writerType string, lastEventID, totalProcessed int64, node string) {
 	mock.ExpectExec("INSERT INTO idp.event_processing_checkpoint").
-		WithArgs(consumerID, lastEventID, sqlmock.AnyArg(), totalProcessed, node).
+		WithArgs(consumerID, writerType, lastEventID, sqlmock.AnyArg(), totalProcessed, node).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 }
 
-// checkpointCols are the 7 columns GetCheckpoint scans (matches the SELECT in reader.go).
+// checkpointCols are the 8 columns GetWriterCheckpoint scans (matches the SELECT in postgres_reader.go).
 var checkpointCols = []string{
-	"consumer_id", "last_event_id", "last_event_timestamp",
+	"consumer_id", "writer_type", "last_event_id", "last_event_timestamp",
 	"total_events_processed", "processing_node", "updated_at", "created_at",
 }
 
-// expectGetCheckpointEmpty makes GetCheckpoint return no rows → fresh start.
+// expectGetCheckpointEmpty makes GetWriterCheckpoint return no rows for the
+// test writer and for the single consumer checkpoint → fresh start.
 func expectGetCheckpointEmpty(mock sqlmock.Sqlmock) {
 	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
-		WithArgs("test-consumer").
+		WithArgs("test-consumer", testWriterType).
+		WillReturnRows(sqlmock.NewRows(checkpointCols))
+	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
+		WithArgs("test-consumer", events.LegacyWriterType).
 		WillReturnRows(sqlmock.NewRows(checkpointCols))
 }
 
-// expectGetCheckpointFound makes GetCheckpoint return an existing checkpoint row.
+// expectGetCheckpointFound makes GetWriterCheckpoint return an existing checkpoint row.
 func expectGetCheckpointFound(mock sqlmock.Sqlmock, lastEventID, totalProcessed int64, node string) {
 	ts := time.Now()
 	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
-		WithArgs("test-consumer").
+		WithArgs("test-consumer", testWriterType).
 		WillReturnRows(sqlmock.NewRows(checkpointCols).
-			AddRow("test-consumer", lastEventID, ts, totalProcessed, node, ts, ts))
+			AddRow("test-consumer", testWriterType, lastEventID, ts, totalProcessed, node, ts, ts))
 }
 
 // ============================================================================
@@ -133,8 +163,8 @@ func TestProcessEvents_FreshStart(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1, writer.callCount, "writer should be called once")
 	assert.Equal(t, 2, len(writer.sentEvents[0]), "both events should be sent")
-	assert.Equal(t, int64(2), proc.checkpoint.LastEventID)
-	assert.Equal(t, int64(2), proc.checkpoint.TotalEventsProcessed)
+	assert.Equal(t, int64(2), proc.writers[0].checkpoint.LastEventID)
+	assert.Equal(t, int64(2), proc.writers[0].checkpoint.TotalEventsProcessed)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -157,7 +187,7 @@ func TestProcessEvents_EmptyBatch(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, 0, writer.callCount, "writer should not be called when no events")
-	assert.Equal(t, int64(0), proc.checkpoint.LastEventID, "checkpoint should not advance")
+	assert.Equal(t, int64(0), proc.writers[0].checkpoint.LastEventID, "checkpoint should not advance")
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -183,7 +213,7 @@ func TestProcessEvents_WriterFails(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "stdout broken")
-	assert.Equal(t, int64(0), proc.checkpoint.LastEventID, "checkpoint must not advance on failure")
+	assert.Equal(t, int64(0), proc.writers[0].checkpoint.LastEventID, "checkpoint must not advance on failure")
 	require.NoError(t, mock.ExpectationsWereMet(), "no checkpoint INSERT should have been called")
 }
 
@@ -222,8 +252,8 @@ func TestProcessEvents_MultipleBatches(t *testing.T) {
 	assert.Equal(t, 2, writer.callCount, "writer should be called once per batch")
 	assert.Equal(t, 2, len(writer.sentEvents[0]), "first batch has 2 events")
 	assert.Equal(t, 1, len(writer.sentEvents[1]), "second batch has 1 event")
-	assert.Equal(t, int64(3), proc.checkpoint.LastEventID)
-	assert.Equal(t, int64(3), proc.checkpoint.TotalEventsProcessed)
+	assert.Equal(t, int64(3), proc.writers[0].checkpoint.LastEventID)
+	assert.Equal(t, int64(3), proc.writers[0].checkpoint.TotalEventsProcessed)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -245,6 +275,7 @@ func TestProcessEvents_ResumeFromCheckpoint(t *testing.T) {
 
 	checkpoint := &events.ProcessingCheckpoint{
 		ConsumerID:           "test-consumer",
+		WriterType:           testWriterType,
 		LastEventID:          42,
 		TotalEventsProcessed: 100,
 		ProcessingNode:       "test-node",
@@ -257,11 +288,231 @@ func TestProcessEvents_ResumeFromCheckpoint(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, 1, writer.callCount)
-	assert.Equal(t, int64(43), proc.checkpoint.LastEventID, "checkpoint should advance to new event")
-	assert.Equal(t, int64(101), proc.checkpoint.TotalEventsProcessed, "total should be 100+1")
+	assert.Equal(t, int64(43), proc.writers[0].checkpoint.LastEventID, "checkpoint should advance to new event")
+	assert.Equal(t, int64(101), proc.writers[0].checkpoint.TotalEventsProcessed, "total should be 100+1")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_GapDetectionDeliversLateEvent verifies that an event
+// committed after a higher ID, and so skipped by the writer's checkpoint, is
+// read again and delivered on the next cycle.
+func TestProcessEvents_GapDetectionDeliversLateEvent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ts := time.Now()
+	// Event 43 is not visible yet when 44 is read
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(42), 1000, 0).
+		WillReturnRows(addEventRow(sqlmock.NewRows(eventCols), 44, ts, 1))
+	expectSaveCheckpoint(mock, "test-consumer", 44, 101, "test-node")
+	// Next cycle: 43 has committed
+	mock.ExpectQuery("SELECT.*FROM events.*e.id IN").
+		WithArgs(int64(43), 1, 0).
+		WillReturnRows(addEventRow(sqlmock.NewRows(eventCols), 43, ts, 1))
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(44), 1000, 0).
+		WillReturnRows(sqlmock.NewRows(eventCols))
+
+	checkpoint := freshCheckpoint()
+	checkpoint.LastEventID = 42
+	checkpoint.TotalEventsProcessed = 100
+	writer := &mockWriter{}
+	proc := makeTestProcessor(db, writer, checkpoint, 1000)
+	proc.config.GapDetection = config.GapDetectionConfig{Window: 300}
+	late := testutil.ToFloat64(metrics.WriterLateEvents.WithLabelValues("", testWriterType))
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	assert.Equal(t, []int64{43}, proc.writers[0].gaps.missing())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.WriterMissingEventIDs.WithLabelValues("", testWriterType)))
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	require.Len(t, writer.sentEvents, 2)
+	assert.Equal(t, int64(43), writer.sentEvents[1][0].ID, "late event should be delivered")
+	assert.Equal(t, int64(44), proc.writers[0].checkpoint.LastEventID, "checkpoint should not move back")
+	assert.Empty(t, proc.writers[0].gaps.missing())
+	assert.Equal(t, late+1, testutil.ToFloat64(metrics.WriterLateEvents.WithLabelValues("", testWriterType)))
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestProcessEvents_Streaming verifies that events are read from a single
+// cursor, sent in batches of batch_size and checkpointed every
+// checkpoint_every events and at the end of the stream.
+func TestProcessEvents_Streaming(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ts := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE eventsproc_events NO SCROLL CURSOR FOR SELECT.*FROM events e WHERE e.id > \\$1 ORDER BY e.id ASC$").
+		WithArgs(int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows(eventCols)
+	rows = addEventRow(rows, 43, ts, 1)
+	rows = addEventRow(rows, 44, ts, 1)
+	mock.ExpectQuery("FETCH FORWARD 2 FROM eventsproc_events").WillReturnRows(rows)
+	expectSaveCheckpoint(mock, "test-consumer", 44, 102, "test-node")
+	mock.ExpectQuery("FETCH FORWARD 2 FROM eventsproc_events").
+		WillReturnRows(addEventRow(sqlmock.NewRows(eventCols), 45, ts, 1))
+	mock.ExpectRollback()
+	expectSaveCheckpoint(mock, "test-consumer", 45, 103, "test-node")
+
+	checkpoint := freshCheckpoint()
+	checkpoint.LastEventID = 42
+	checkpoint.TotalEventsProcessed = 100
+	writer := &mockWriter{}
+	proc := makeTestProcessor(db, writer, checkpoint, 2)
+	proc.config.Streaming = config.StreamingConfig{Enabled: true, FetchSize: 2, CheckpointEvery: 2}
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	require.Len(t, writer.sentEvents, 2)
+	assert.Len(t, writer.sentEvents[0], 2)
+	assert.Equal(t, int64(45), writer.sentEvents[1][0].ID)
+	assert.Equal(t, int64(45), proc.writers[0].checkpoint.LastEventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_StreamingSavesProgressOnFailure verifies that the events
+// delivered before a failing batch are checkpointed.
+func TestProcessEvents_StreamingSavesProgressOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ts := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE eventsproc_events").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows(eventCols)
+	for id := int64(1); id <= 3; id++ {
+		rows = addEventRow(rows, id, ts, 1)
+	}
+	mock.ExpectQuery("FETCH FORWARD 1000 FROM eventsproc_events").WillReturnRows(rows)
+	mock.ExpectRollback()
+	expectSaveCheckpoint(mock, "test-consumer", 2, 2, "test-node")
+
+	writer := &failAfterWriter{ok: 1}
+	proc := makeTestProcessor(db, writer, freshCheckpoint(), 2)
+	proc.config.Streaming = config.StreamingConfig{Enabled: true, FetchSize: 1000, CheckpointEvery: 10000}
+
+	require.Error(t, proc.processEvents(context.Background()))
+	assert.Equal(t, int64(2), proc.writers[0].checkpoint.LastEventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_StreamingRetriesFailedSave verifies that progress whose
+// periodic save failed is saved again when the stream ends.
+func TestProcessEvents_StreamingRetriesFailedSave(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ts := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE eventsproc_events").WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows(eventCols)
+	rows = addEventRow(rows, 1, ts, 1)
+	rows = addEventRow(rows, 2, ts, 1)
+	mock.ExpectQuery("FETCH FORWARD 1000 FROM eventsproc_events").WillReturnRows(rows)
+	mock.ExpectExec("INSERT INTO idp.event_processing_checkpoint").WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	expectSaveCheckpoint(mock, "test-consumer", 2, 2, "test-node")
+
+	proc := makeTestProcessor(db, &mockWriter{}, freshCheckpoint(), 2)
+	proc.config.Streaming = config.StreamingConfig{Enabled: true, FetchSize: 1000, CheckpointEvery: 2}
+
+	require.Error(t, proc.processEvents(context.Background()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_StreamingSQLite verifies streaming from a real SQLite
+// file with more events than checkpoint_every, so checkpoints are saved to
+// the file the events are read from while the stream is open.
+func TestProcessEvents_StreamingSQLite(t *testing.T) {
+	db, err := config.GetSQLiteDB(filepath.Join(t.TempDir(), "store.db"), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.Exec(`
+		CREATE TABLE events (
+			id INTEGER PRIMARY KEY, timestamp DATETIME, activity INTEGER,
+			initiator_id TEXT, target_id TEXT, account_id TEXT, meta TEXT
+		);
+		CREATE TABLE event_processing_checkpoint (
+			consumer_id TEXT NOT NULL,
+			writer_type TEXT NOT NULL DEFAULT 'default',
+			last_event_id INTEGER NOT NULL DEFAULT 0,
+			last_event_timestamp DATETIME,
+			total_events_processed INTEGER NOT NULL DEFAULT 0,
+			processing_node TEXT,
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (consumer_id, writer_type)
+		)`)
+	require.NoError(t, err)
+	ts := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	for id := 1; id <= 25; id++ {
+		_, err := db.Exec("INSERT INTO events VALUES (?, ?, 1, 'u1', 'u2', 'acc', '{}')", id, ts)
+		require.NoError(t, err)
+	}
+
+	writer := &mockWriter{}
+	proc := makeTestProcessor(db, writer, freshCheckpoint(), 4)
+	proc.eventReader = events.NewSQLiteEventReader(db, proc.logger, &proc.config.EmailEnrichment)
+	proc.config.Streaming = config.StreamingConfig{Enabled: true, FetchSize: 10, CheckpointEvery: 8}
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	sent := 0
+	for _, batch := range writer.sentEvents {
+		sent += len(batch)
+	}
+	assert.Equal(t, 25, sent)
+	saved, err := proc.eventReader.GetWriterCheckpoint(context.Background(), "test-consumer", testWriterType)
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, int64(25), saved.LastEventID)
+	assert.Equal(t, int64(25), saved.TotalEventsProcessed)
+}
+
+// failAfterWriter accepts ok calls, then fails.
+type failAfterWriter struct {
+	mockWriter
+	ok int
+}
+
+func (w *failAfterWriter) SendEvents(_ context.Context, _ []events.Event) error {
+	if w.ok == 0 {
+		return errors.New("sink down")
+	}
+	w.ok--
+	return nil
+}
+
+// TestGapTracker verifies which IDs are tracked as missing, the max_pending
+// cap and expiry after the window.
+func TestGapTracker(t *testing.T) {
+	clock := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	g := newGapTracker(time.Minute, 4)
+	g.now = func() time.Time { return clock }
+
+	// The first read has no predecessor: IDs before it are not missing
+	assert.Equal(t, 0, g.observe(0, []events.Event{{ID: 5}, {ID: 7}}))
+	assert.Equal(t, []int64{6}, g.missing())
+
+	clock = clock.Add(30 * time.Second)
+	assert.Equal(t, 0, g.observe(7, []events.Event{{ID: 9}, {ID: 10}}))
+	assert.Equal(t, []int64{6, 8}, g.missing())
+
+	// Only two more fit; the remaining four are dropped
+	assert.Equal(t, 4, g.observe(10, []events.Event{{ID: 17}}))
+	assert.Equal(t, []int64{6, 8, 11, 12}, g.missing())
+
+	g.found(8)
+	clock = clock.Add(30 * time.Second)
+	assert.Equal(t, []int64{6}, g.expire(), "only the ID missing for the whole window expires")
+	assert.Equal(t, []int64{11, 12}, g.missing())
+}
+
 // TestProcessEvents_DBQueryFails verifies that a database error on GetEvents
 // is propagated and no writer call is made.
 func TestProcessEvents_DBQueryFails(t *testing.T) {
@@ -302,8 +553,8 @@ func TestProcessEvents_MetricsUpdated(t *testing.T) {
 	expectSaveCheckpoint(mock, "test-consumer", 12, 3, "test-node")
 
 	// Record metric values BEFORE the run (counters accumulate across tests)
-	beforeProcessed := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("false"))
-	beforeErrors := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("true"))
+	beforeProcessed := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "false"))
+	beforeErrors := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "true"))
 
 	writer := &mockWriter{}
 	proc := makeTestProcessor(db, writer, freshCheckpoint(), 1000)
@@ -312,14 +563,14 @@ func TestProcessEvents_MetricsUpdated(t *testing.T) {
 	require.NoError(t, err)
 
 	// Counter delta: 3 events processed successfully, 0 errors
-	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("false"))-beforeProcessed)
-	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("true"))-beforeErrors)
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "false"))-beforeProcessed)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "true"))-beforeErrors)
 
 	// Gauge: last event ID set to 12
-	assert.Equal(t, float64(12), testutil.ToFloat64(metrics.LastEventID))
+	assert.Equal(t, float64(12), testutil.ToFloat64(metrics.LastEventID.WithLabelValues("")))
 
 	// Gauge: lag should be ~30s (event was 30s ago), allow generous range
-	lag := testutil.ToFloat64(metrics.CheckpointLagSeconds)
+	lag := testutil.ToFloat64(metrics.CheckpointLagSeconds.WithLabelValues(""))
 	assert.GreaterOrEqual(t, lag, float64(25), "lag should be around 30s")
 	assert.LessOrEqual(t, lag, float64(60), "lag should not be unreasonably large")
 
@@ -339,8 +590,8 @@ func TestProcessEvents_WriterFailsMetrics(t *testing.T) {
 		WithArgs(1000, 0).
 		WillReturnRows(rows)
 
-	beforeErrors := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("true"))
-	beforeSuccess := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("false"))
+	beforeErrors := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "true"))
+	beforeSuccess := testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "false"))
 
 	writer := &mockWriter{shouldFail: true, failError: "pipe broken"}
 	proc := makeTestProcessor(db, writer, freshCheckpoint(), 1000)
@@ -348,8 +599,8 @@ func TestProcessEvents_WriterFailsMetrics(t *testing.T) {
 	err = proc.processEvents(context.Background())
 	assert.Error(t, err)
 
-	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("true"))-beforeErrors)
-	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("false"))-beforeSuccess)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "true"))-beforeErrors)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.EventsProcessed.WithLabelValues("", "false"))-beforeSuccess)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -375,7 +626,98 @@ func TestProcessEvents_LookbackHours(t *testing.T) {
 	err = proc.processEvents(context.Background())
 
 	require.NoError(t, err)
-	assert.Equal(t, int64(1), proc.checkpoint.LastEventID)
+	assert.Equal(t, int64(1), proc.writers[0].checkpoint.LastEventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// addTestWriter adds a second writer with its own checkpoint to proc.
+func addTestWriter(proc *Processor, name string, w EventWriter, lastEventID int64) {
+	proc.writers = append(proc.writers, &writerState{
+		name:   name,
+		writer: w,
+		checkpoint: &events.ProcessingCheckpoint{
+			ConsumerID:     "test-consumer",
+			WriterType:     name,
+			LastEventID:    lastEventID,
+			ProcessingNode: "test-node",
+		},
+	})
+}
+
+// TestProcessEvents_FailingWriterDoesNotStallOthers verifies that a failing
+// writer keeps its checkpoint while a healthy writer delivers and advances.
+func TestProcessEvents_FailingWriterDoesNotStallOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	// Writers run concurrently, so their queries interleave
+	mock.MatchExpectationsInOrder(false)
+
+	ts := time.Now().Add(-1 * time.Minute)
+	for range 2 {
+		rows := sqlmock.NewRows(eventCols)
+		rows = addEventRow(rows, 1, ts, 1)
+		rows = addEventRow(rows, 2, ts, 2)
+		mock.ExpectQuery("SELECT.*FROM events").
+			WithArgs(1000, 0).
+			WillReturnRows(rows)
+	}
+	// Only the healthy writer saves its checkpoint
+	expectSaveWriterCheckpoint(mock, "test-consumer", "loki", 2, 2, "test-node")
+
+	broken := &mockWriter{shouldFail: true, failError: "splunk unavailable"}
+	healthy := &mockWriter{}
+	proc := makeTestProcessor(db, broken, freshCheckpoint(), 1000)
+	addTestWriter(proc, "loki", healthy, 0)
+
+	err = proc.processEvents(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "writer stdout")
+	assert.Contains(t, err.Error(), "splunk unavailable")
+	assert.Equal(t, int64(0), proc.writers[0].checkpoint.LastEventID, "failing writer must not advance")
+	assert.Equal(t, 1, healthy.callCount)
+	assert.Equal(t, int64(2), proc.writers[1].checkpoint.LastEventID, "healthy writer should advance")
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.WriterLastEventID.WithLabelValues("", "loki")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.LastEventID.WithLabelValues("")), "overall progress follows the slowest writer")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_WritersResumeIndependently verifies that each writer
+// queries from its own checkpoint and reports its own lag.
+func TestProcessEvents_WritersResumeIndependently(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	ts := time.Now().Add(-2 * time.Minute)
+	// stdout is at event 10, loki lags behind at event 5
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(10), 1000, 0).
+		WillReturnRows(addEventRow(sqlmock.NewRows(eventCols), 11, ts, 1))
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(5), 1000, 0).
+		WillReturnRows(addEventRow(addEventRow(sqlmock.NewRows(eventCols), 6, ts, 1), 11, ts, 1))
+	expectSaveWriterCheckpoint(mock, "test-consumer", "stdout", 11, 11, "test-node")
+	expectSaveWriterCheckpoint(mock, "test-consumer", "loki", 11, 2, "test-node")
+
+	checkpoint := freshCheckpoint()
+	checkpoint.LastEventID = 10
+	checkpoint.TotalEventsProcessed = 10
+	stdoutWriter := &mockWriter{}
+	lokiWriter := &mockWriter{}
+	proc := makeTestProcessor(db, stdoutWriter, checkpoint, 1000)
+	addTestWriter(proc, "loki", lokiWriter, 5)
+
+	require.NoError(t, proc.processEvents(context.Background()))
+
+	require.Len(t, stdoutWriter.sentEvents, 1)
+	assert.Len(t, stdoutWriter.sentEvents[0], 1)
+	require.Len(t, lokiWriter.sentEvents, 1)
+	assert.Len(t, lokiWriter.sentEvents[0], 2)
+	lag := testutil.ToFloat64(metrics.WriterCheckpointLagSeconds.WithLabelValues("", "loki"))
+	assert.GreaterOrEqual(t, lag, float64(100), "lag should be around 120s")
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -406,7 +748,7 @@ func TestProcessor_Run_FreshStart(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, 1, writer.callCount)
-	assert.Equal(t, int64(1), proc.checkpoint.LastEventID)
+	assert.Equal(t, int64(1), proc.writers[0].checkpoint.LastEventID)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -433,7 +775,38 @@ func TestProcessor_Run_ResumeFromCheckpoint(t *testing.T) {
 
 	require.NoError(t, err)
 	assert.Equal(t, 1, writer.callCount)
-	assert.Equal(t, int64(51), proc.checkpoint.LastEventID)
+	assert.Equal(t, int64(51), proc.writers[0].checkpoint.LastEventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessor_Run_UpgradeFromSingleCheckpoint verifies that a writer
+// without its own checkpoint resumes from the single consumer checkpoint
+// instead of replaying events, and then saves a checkpoint of its own.
+func TestProcessor_Run_UpgradeFromSingleCheckpoint(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	ts := time.Now().Add(-1 * time.Minute)
+
+	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
+		WithArgs("test-consumer", testWriterType).
+		WillReturnRows(sqlmock.NewRows(checkpointCols))
+	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
+		WithArgs("test-consumer", events.LegacyWriterType).
+		WillReturnRows(sqlmock.NewRows(checkpointCols).
+			AddRow("test-consumer", events.LegacyWriterType, int64(70), ts, int64(70), "old-node", ts, ts))
+	rows := addEventRow(sqlmock.NewRows(eventCols), 71, ts, 1)
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(70), 1000, 0). // WHERE e.id > 70
+		WillReturnRows(rows)
+	expectSaveCheckpoint(mock, "test-consumer", 71, 71, "test-node")
+
+	proc := makeTestProcessor(db, &mockWriter{}, nil, 1000)
+
+	require.NoError(t, proc.Run(context.Background()))
+	assert.Equal(t, testWriterType, proc.writers[0].checkpoint.WriterType)
+	assert.Equal(t, int64(71), proc.writers[0].checkpoint.LastEventID)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -445,7 +818,7 @@ func TestProcessor_Run_GetCheckpointFails(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
-		WithArgs("test-consumer").
+		WithArgs("test-consumer", testWriterType).
 		WillReturnError(errors.New("db timeout"))
 
 	proc := makeTestProcessor(db, &mockWriter{}, freshCheckpoint(), 1000)
@@ -500,6 +873,732 @@ func TestProcessor_Close(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestProcessor_Close_ClosesWriter verifies that Close also closes writers
+// that hold resources (e.g. Loki HTTP connections).
+func TestProcessor_Close_ClosesWriter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectClose()
+
+	w := &closingWriter{}
+	proc := makeTestProcessor(db, w, freshCheckpoint(), 1000)
+
+	require.NoError(t, proc.Close())
+	assert.True(t, w.closed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ============================================================================
+// buildWriters tests
+// ============================================================================
+
+// TestBuildWriter_DefaultsToStdout verifies that an empty writer list keeps
+// the original stdout-only behaviour.
+func TestBuildWriter_DefaultsToStdout(t *testing.T) {
+	cfg := &config.Config{}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &stdout.StdoutWriter{}, ws[0].writer)
+}
+
+// TestBuildWriter_StdoutFormat verifies that stdout.format selects the formatter
+// and unknown formats are rejected.
+func TestBuildWriter_StdoutFormat(t *testing.T) {
+	cfg := &config.Config{Writers: []string{"stdout"}, Stdout: config.StdoutConfig{Format: "cef"}}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &stdout.StdoutWriter{}, ws[0].writer)
+
+	cfg.Stdout.Format = "xml"
+	_, err = buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.Error(t, err)
+}
+
+// TestBuildWriter_StdoutAndLoki verifies that several writers are kept apart,
+// each named after its checkpoint writer_type.
+func TestBuildWriter_StdoutAndLoki(t *testing.T) {
+	cfg := &config.Config{
+		Platform: "prod",
+		Region:   "emea",
+		Writers:  []string{"stdout", "loki"},
+		Loki:     config.LokiConfig{URL: "http://loki.example.com:3100"},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 2)
+	assert.Equal(t, "stdout", ws[0].name)
+	assert.IsType(t, &stdout.StdoutWriter{}, ws[0].writer)
+	assert.Equal(t, "loki", ws[1].name)
+	assert.IsType(t, &loki.Writer{}, ws[1].writer)
+}
+
+// TestBuildWriter_LokiOnly verifies that a single Loki writer is used directly.
+func TestBuildWriter_LokiOnly(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"loki"},
+		Loki:    config.LokiConfig{URL: "http://loki.example.com:3100", Compression: "snappy"},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &loki.Writer{}, ws[0].writer)
+}
+
+// TestBuildWriter_Splunk verifies that the Splunk writer is created with the
+// hostname as the default HEC host.
+func TestBuildWriter_Splunk(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"splunk"},
+		Splunk:  config.SplunkConfig{URL: "https://splunk.example.com:8088", Token: "hec-token", AckEnabled: true},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &splunk.Writer{}, ws[0].writer)
+}
+
+// TestBuildWriter_Syslog verifies that the syslog writer is created without
+// dialling the collector.
+func TestBuildWriter_Syslog(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"syslog"},
+		Syslog:  config.SyslogConfig{Network: "udp", Address: "127.0.0.1:514", Facility: "authpriv"},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &syslog.Writer{}, ws[0].writer)
+}
+
+// TestBuildWriter_Elasticsearch verifies that the bulk writer is created
+// without contacting the cluster and rejects non-JSON document formats.
+func TestBuildWriter_Elasticsearch(t *testing.T) {
+	cfg := &config.Config{
+		Writers:       []string{"elasticsearch"},
+		Elasticsearch: config.ElasticConfig{URL: "http://es.example.com:9200", Format: "ecs"},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &elastic.Writer{}, ws[0].writer)
+
+	cfg.Elasticsearch.Format = "cef"
+	_, err = buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.Error(t, err)
+}
+
+// TestBuildWriter_OTLP verifies that the OTLP writer is created for both
+// protocols without contacting the collector.
+func TestBuildWriter_OTLP(t *testing.T) {
+	for _, protocol := range []string{"http/protobuf", "grpc"} {
+		cfg := &config.Config{
+			Writers: []string{"otlp"},
+			OTLP:    config.OTLPConfig{Endpoint: "http://otel.example.com:4317", Protocol: protocol},
+		}
+		ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+		require.NoError(t, err, protocol)
+		require.Len(t, ws, 1)
+		assert.IsType(t, &otlp.Writer{}, ws[0].writer)
+	}
+}
+
+// TestBuildWriter_Webhooks verifies that every webhook destination becomes
+// its own writer and that template errors fail at startup.
+func TestBuildWriter_Webhooks(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"webhook"},
+		Webhooks: []config.WebhookConfig{
+			{Name: "tickets", URL: "https://tickets.example.com/hook", Timeout: 5},
+			{Name: "chatops", URL: "https://chat.example.com/hook", Mode: "event", Template: `{"text": {{json .ActivityName}}}`},
+		},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 2)
+	assert.Equal(t, "webhook:tickets", ws[0].name)
+	assert.Equal(t, "webhook:chatops", ws[1].name)
+
+	cfg.Webhooks[1].Template = "{{.Broken"
+	_, err = buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.Error(t, err)
+}
+
+// TestBuildWriter_Routes verifies that writers with routes only receive the
+// matching events.
+func TestBuildWriter_Routes(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"stdout"},
+		Stdout: config.StdoutConfig{Route: config.RouteConfig{
+			Include: []config.RouteRule{{ActivityCodes: []string{"user.*"}, Meta: []string{"os=linux*"}}},
+		}},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	rw, ok := ws[0].writer.(*writer.RouteWriter)
+	require.True(t, ok, "expected a RouteWriter, got %T", ws[0].writer)
+	assert.NoError(t, rw.SendEvents(context.Background(), []events.Event{{ID: 1, ActivityCode: "peer.login"}}))
+
+	routes := routeRules(cfg.Stdout.Route.Include)
+	assert.Equal(t, map[string]string{"os": "linux*"}, routes[0].Meta)
+}
+
+// TestBuildWriter_Groups verifies that grouped writers are replaced by one
+// group writer with its own checkpoint name.
+func TestBuildWriter_Groups(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"stdout", "loki", "webhook"},
+		Loki:    config.LokiConfig{URL: "http://loki.example.com:3100"},
+		Webhooks: []config.WebhookConfig{
+			{Name: "siem", URL: "https://siem.example.com/hook"},
+		},
+		WriterGroups: []config.WriterGroupConfig{
+			{Name: "siem", Writers: []string{"loki", "webhook:siem"}, Policy: "any", Timeout: 10},
+		},
+	}
+	logger := cfg.NewLogFactory().New("system")
+	ws, err := buildWriters(cfg, false, logger, "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 2)
+	assert.Equal(t, "stdout", ws[0].name)
+	assert.Equal(t, "group:siem", ws[1].name)
+	assert.IsType(t, &writer.MultiWriter{}, ws[1].writer)
+
+	w, err := BuildWriter(cfg, logger, "group:siem")
+	require.NoError(t, err)
+	assert.IsType(t, &writer.MultiWriter{}, w)
+
+	cfg.WriterGroups[0].Writers = []string{"loki", "webhook:other"}
+	_, err = buildWriters(cfg, false, logger, "test-node")
+	require.ErrorContains(t, err, `writer "webhook:other" is not configured`)
+}
+
+// TestBuildWriter_ByName verifies that BuildWriter creates the one writer a
+// dead letter names, including a single webhook destination.
+func TestBuildWriter_ByName(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"stdout", "webhook"},
+		Webhooks: []config.WebhookConfig{
+			{Name: "tickets", URL: "https://tickets.example.com/hook"},
+			{Name: "chatops", URL: "https://chat.example.com/hook"},
+		},
+	}
+	logger := cfg.NewLogFactory().New("system")
+
+	w, err := BuildWriter(cfg, logger, "webhook:chatops")
+	require.NoError(t, err)
+	assert.IsType(t, &webhook.Writer{}, w)
+	assert.Equal(t, "chatops", w.(*webhook.Writer).Name())
+
+	_, err = BuildWriter(cfg, logger, "webhook:missing")
+	require.Error(t, err)
+	_, err = BuildWriter(cfg, logger, "loki")
+	require.Error(t, err)
+}
+
+// TestBuildWriter_IgnoresRoutes verifies that a writer built to re-drive dead
+// letters has no routes, so an entry they would filter out is still sent
+// rather than dropped and deleted.
+func TestBuildWriter_IgnoresRoutes(t *testing.T) {
+	exclude := config.RouteConfig{Exclude: []config.RouteRule{{ActivityCodes: []string{"*"}}}}
+	cfg := &config.Config{
+		Writers:  []string{"stdout", "webhook"},
+		Stdout:   config.StdoutConfig{Route: exclude},
+		Webhooks: []config.WebhookConfig{{Name: "chatops", URL: "https://chat.example.com/hook", Route: exclude}},
+	}
+	logger := cfg.NewLogFactory().New("system")
+
+	w, err := BuildWriter(cfg, logger, "stdout")
+	require.NoError(t, err)
+	assert.IsType(t, &stdout.StdoutWriter{}, w)
+	w, err = BuildWriter(cfg, logger, "webhook:chatops")
+	require.NoError(t, err)
+	assert.IsType(t, &webhook.Writer{}, w)
+	assert.NotEmpty(t, cfg.Webhooks[0].Route.Exclude, "the configuration is not modified")
+}
+
+// TestBuildWriter_RetryOptIn verifies that only writers whose retry section
+// is enabled are wrapped with retries and a circuit breaker.
+func TestBuildWriter_RetryOptIn(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"stdout", "loki", "webhook"},
+		Loki: config.LokiConfig{
+			URL:   "http://loki.example.com:3100",
+			Retry: config.RetryConfig{Enabled: true, MaxAttempts: 5},
+		},
+		Webhooks: []config.WebhookConfig{
+			{Name: "siem", URL: "https://siem.example.com/hook", Retry: config.RetryConfig{Enabled: true}},
+		},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 3)
+	assert.IsType(t, &stdout.StdoutWriter{}, ws[0].writer)
+	assert.IsType(t, &writer.RetryWriter{}, ws[1].writer)
+	assert.IsType(t, &writer.RetryWriter{}, ws[2].writer)
+	assert.Equal(t, "webhook:siem", ws[2].name)
+	assert.Equal(t, float64(writer.CircuitClosed), testutil.ToFloat64(metrics.WriterCircuitState.WithLabelValues("loki")))
+}
+
+// TestBuildWriter_File verifies that the file writer creates its directory.
+func TestBuildWriter_File(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "events")
+	cfg := &config.Config{
+		ConsumerID: "eventsproc-test",
+		Writers:    []string{"file"},
+		File:       config.FileConfig{Dir: dir, Compression: "zstd", MaxSizeMB: 1, RotateIntervalHours: 1},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &file.Writer{}, ws[0].writer)
+	assert.DirExists(t, dir)
+}
+
+func TestBuildWriter_Kafka(t *testing.T) {
+	cfg := &config.Config{
+		ConsumerID: "eventsproc-test",
+		Writers:    []string{"kafka"},
+		Kafka:      config.KafkaConfig{Brokers: []string{"localhost:9092"}, Topic: "netbird-events", SASLMechanism: "SCRAM-SHA-256"},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &kafka.Writer{}, ws[0].writer)
+	require.NoError(t, ws[0].writer.(io.Closer).Close())
+}
+
+func TestBuildWriter_RedisStreamUsesClusterRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	cfg := &config.Config{
+		Writers: []string{"redis_stream"},
+		Cluster: config.ClusterConfig{RedisURL: mr.Addr()},
+	}
+	ws, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.NoError(t, err)
+	require.Len(t, ws, 1)
+	assert.IsType(t, &redisstream.Writer{}, ws[0].writer)
+	require.NoError(t, ws[0].writer.(io.Closer).Close())
+}
+
+func TestBuildWriter_NATSUnreachable(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"nats"},
+		NATS:    config.NATSConfig{URL: "nats://127.0.0.1:1", Timeout: 1},
+	}
+	_, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.Error(t, err)
+}
+
+// TestBuildWriter_DuplicateNames verifies that two writers can never share
+// a checkpoint.
+func TestBuildWriter_DuplicateNames(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"webhook"},
+		Webhooks: []config.WebhookConfig{
+			{URL: "https://hooks.example.com/a"},
+			{URL: "https://hooks.example.com/b"},
+		},
+	}
+	_, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhook:hooks.example.com")
+
+	cfg = &config.Config{Writers: []string{"stdout", "stdout"}}
+	_, err = buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+	require.Error(t, err)
+}
+
+// TestBuildWriter_InvalidLoki verifies that writer construction errors propagate.
+func TestBuildWriter_InvalidLoki(t *testing.T) {
+	cfg := &config.Config{
+		Writers: []string{"loki"},
+		Loki:    config.LokiConfig{URL: "http://loki.example.com:3100", Compression: "lz4"},
+	}
+	_, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.Error(t, err)
+}
+
+// TestBuildWriter_Unknown verifies that unknown writer names are rejected.
+func TestBuildWriter_Unknown(t *testing.T) {
+	cfg := &config.Config{Writers: []string{"kinesis"}}
+	_, err := buildWriters(cfg, false, cfg.NewLogFactory().New("system"), "test-node")
+
+	require.Error(t, err)
+}
+
+// ============================================================================
+// Write-ahead log tests
+// ============================================================================
+
+// enableTestWAL opens a write-ahead log in a temp dir for proc.
+func enableTestWAL(t *testing.T, proc *Processor) {
+	t.Helper()
+	l, err := wal.Open(&wal.Config{Dir: t.TempDir()}, proc.logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	proc.wal = l
+}
+
+// TestProcessEvents_WALDrainsWithoutQueryingDB verifies that events are
+// fetched from the database once and that a writer recovering from an outage
+// catches up from the write-ahead log, which is emptied once every writer has
+// delivered.
+func TestProcessEvents_WALDrainsWithoutQueryingDB(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	ts := time.Now().Add(-1 * time.Minute)
+	// A single events query: the writers never read the database
+	rows := sqlmock.NewRows(eventCols)
+	rows = addEventRow(rows, 2, ts, 2)
+	rows = addEventRow(rows, 1, ts, 1)
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(1000, 0).
+		WillReturnRows(rows)
+	expectSaveWriterCheckpoint(mock, "test-consumer", "loki", 2, 2, "test-node")
+	expectSaveCheckpoint(mock, "test-consumer", 2, 2, "test-node")
+
+	broken := &mockWriter{shouldFail: true, failError: "stdout broken"}
+	healthy := &mockWriter{}
+	proc := makeTestProcessor(db, broken, freshCheckpoint(), 1000)
+	addTestWriter(proc, "loki", healthy, 0)
+	enableTestWAL(t, proc)
+
+	require.NoError(t, proc.ingest(context.Background()))
+	assert.Equal(t, int64(2), proc.walHead)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.WALLastEventID.WithLabelValues("")))
+
+	require.Error(t, proc.processEvents(context.Background()))
+	require.Len(t, healthy.sentEvents, 1)
+	assert.Equal(t, []int64{1, 2}, []int64{healthy.sentEvents[0][0].ID, healthy.sentEvents[0][1].ID}, "events are buffered in ID order")
+	assert.NotZero(t, proc.wal.Size(), "events are kept until every writer has delivered them")
+
+	// The sink recovers: the backlog comes from the log, not the database
+	broken.shouldFail = false
+	require.NoError(t, proc.processEvents(context.Background()))
+	require.Len(t, broken.sentEvents, 1)
+	assert.Len(t, broken.sentEvents[0], 2)
+	assert.Equal(t, int64(0), proc.wal.Size(), "delivered events should be removed")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessor_Run_WALResumesFromSlowestWriter verifies that with an empty
+// log Run fetches from the slowest writer's checkpoint, and that a faster
+// writer skips the buffered events it has already delivered.
+func TestProcessor_Run_WALResumesFromSlowestWriter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	mock.MatchExpectationsInOrder(false)
+
+	ts := time.Now().Add(-1 * time.Minute)
+	expectGetCheckpointFound(mock, 50, 50, "old-node")
+	mock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
+		WithArgs("test-consumer", "loki").
+		WillReturnRows(sqlmock.NewRows(checkpointCols).
+			AddRow("test-consumer", "loki", int64(40), ts, int64(40), "old-node", ts, ts))
+	rows := sqlmock.NewRows(eventCols)
+	for id := int64(41); id <= 51; id++ {
+		rows = addEventRow(rows, id, ts, 1)
+	}
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(int64(40), 1000, 0). // WHERE e.id > 40
+		WillReturnRows(rows)
+	expectSaveCheckpoint(mock, "test-consumer", 51, 51, "test-node")
+	expectSaveWriterCheckpoint(mock, "test-consumer", "loki", 51, 51, "test-node")
+
+	stdoutWriter := &mockWriter{}
+	lokiWriter := &mockWriter{}
+	proc := makeTestProcessor(db, stdoutWriter, nil, 1000)
+	addTestWriter(proc, "loki", lokiWriter, 0)
+	enableTestWAL(t, proc)
+
+	require.NoError(t, proc.Run(context.Background()))
+	require.Len(t, stdoutWriter.sentEvents, 1)
+	assert.Len(t, stdoutWriter.sentEvents[0], 1, "stdout had already delivered up to event 50")
+	require.Len(t, lokiWriter.sentEvents, 1)
+	assert.Len(t, lokiWriter.sentEvents[0], 11)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ============================================================================
+// Dead-letter queue tests
+// ============================================================================
+
+// rejectingWriter delivers every event except those in bad. With
+// wholeBatch set it refuses any batch containing a bad event, like a sink
+// answering 400 to the request, otherwise it reports the bad events in a
+// writer.RejectedError.
+type rejectingWriter struct {
+	bad        map[int64]bool
+	wholeBatch bool
+	delivered  []int64
+}
+
+func (r *rejectingWriter) SendEvents(_ context.Context, evts []events.Event) error {
+	var rejected []writer.Rejection
+	for _, evt := range evts {
+		if r.bad[evt.ID] {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: "mapper_parsing_exception"})
+		}
+	}
+	if r.wholeBatch && len(rejected) > 0 {
+		return writer.Permanent(errors.New("400 Bad Request"))
+	}
+	for _, evt := range evts {
+		if !r.bad[evt.ID] {
+			r.delivered = append(r.delivered, evt.ID)
+		}
+	}
+	if len(rejected) > 0 {
+		return writer.Reject(errors.New("rejected"), rejected...)
+	}
+	return nil
+}
+
+// enableTestDLQ gives proc a file dead-letter queue in a temp dir.
+func enableTestDLQ(t *testing.T, proc *Processor) *dlq.FileStore {
+	t.Helper()
+	store, err := dlq.NewFileStore(filepath.Join(t.TempDir(), "dlq.ndjson"))
+	require.NoError(t, err)
+	proc.dlq = store
+	return store
+}
+
+// expectThreeEvents sets up a single events query returning events 1-3.
+func expectThreeEvents(mock sqlmock.Sqlmock) {
+	ts := time.Now().Add(-1 * time.Minute)
+	rows := sqlmock.NewRows(eventCols)
+	for id := int64(1); id <= 3; id++ {
+		rows = addEventRow(rows, id, ts, 1)
+	}
+	mock.ExpectQuery("SELECT.*FROM events").
+		WithArgs(1000, 0).
+		WillReturnRows(rows)
+}
+
+// TestProcessEvents_DeadLettersRejectedEvents verifies that events a writer
+// rejects are stored in the dead-letter queue and the checkpoint moves on.
+func TestProcessEvents_DeadLettersRejectedEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	expectThreeEvents(mock)
+	expectSaveCheckpoint(mock, "test-consumer", 3, 3, "test-node")
+
+	w := &rejectingWriter{bad: map[int64]bool{2: true}}
+	proc := makeTestProcessor(db, w, freshCheckpoint(), 1000)
+	store := enableTestDLQ(t, proc)
+	before := testutil.ToFloat64(metrics.DeadLetters.WithLabelValues("", testWriterType))
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	assert.Equal(t, []int64{1, 3}, w.delivered)
+	assert.Equal(t, int64(3), proc.writers[0].checkpoint.LastEventID)
+
+	entries, err := store.List(context.Background(), dlq.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].Event.ID)
+	assert.Equal(t, testWriterType, entries[0].Writer)
+	assert.Equal(t, "test-consumer", entries[0].ConsumerID)
+	assert.Equal(t, "mapper_parsing_exception", entries[0].Reason)
+	assert.Equal(t, before+1, testutil.ToFloat64(metrics.DeadLetters.WithLabelValues("", testWriterType)))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_PermanentBatchErrorIsolatesEvents verifies that a batch
+// the sink refuses as a whole is resent event by event, so only the offending
+// event is dead-lettered.
+func TestProcessEvents_PermanentBatchErrorIsolatesEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	expectThreeEvents(mock)
+	expectSaveCheckpoint(mock, "test-consumer", 3, 3, "test-node")
+
+	w := &rejectingWriter{bad: map[int64]bool{2: true}, wholeBatch: true}
+	proc := makeTestProcessor(db, w, freshCheckpoint(), 1000)
+	store := enableTestDLQ(t, proc)
+
+	require.NoError(t, proc.processEvents(context.Background()))
+	assert.Equal(t, []int64{1, 3}, w.delivered)
+
+	entries, err := store.List(context.Background(), dlq.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].Event.ID)
+	assert.Contains(t, entries[0].Reason, "400 Bad Request")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestProcessEvents_RejectedWithoutDLQ verifies the behaviour without a
+// dead-letter queue: rejected events are skipped, while a batch the sink
+// refuses as a whole still fails so nothing is lost.
+func TestProcessEvents_RejectedWithoutDLQ(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	expectThreeEvents(mock)
+	expectSaveCheckpoint(mock, "test-consumer", 3, 3, "test-node")
+
+	w := &rejectingWriter{bad: map[int64]bool{2: true}}
+	proc := makeTestProcessor(db, w, freshCheckpoint(), 1000)
+	require.NoError(t, proc.processEvents(context.Background()))
+	assert.Equal(t, int64(3), proc.writers[0].checkpoint.LastEventID)
+
+	expectThreeEvents(mock)
+	w = &rejectingWriter{bad: map[int64]bool{2: true}, wholeBatch: true}
+	proc = makeTestProcessor(db, w, freshCheckpoint(), 1000)
+	err = proc.processEvents(context.Background())
+	require.Error(t, err)
+	assert.True(t, writer.IsPermanent(err))
+	assert.Equal(t, int64(0), proc.writers[0].checkpoint.LastEventID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ============================================================================
+// Multi-source tests
+// ============================================================================
+
+// makeTestInstance returns a processor of the named instance that sends
+// through shared.
+func makeTestInstance(db *sql.DB, source string, shared EventWriter) *Processor {
+	p := makeTestProcessor(db, nil, freshCheckpoint(), 1000)
+	p.source = source
+	p.writers = shareWriters([]*writerState{{name: testWriterType, writer: shared}})
+	p.sharedWriters = true
+	return p
+}
+
+// TestMultiProcessor_Run verifies that every instance reads its own database
+// and keeps its own checkpoint while sending to the shared writer, and that
+// events and metrics carry the instance name.
+func TestMultiProcessor_Run(t *testing.T) {
+	ts := time.Now().Add(-1 * time.Minute)
+	out := &mockWriter{}
+	shared := &lockedWriter{w: out}
+	m := &MultiProcessor{writers: []*writerState{{name: testWriterType, writer: out}}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	for i, source := range []string{"emea", "apac"} {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer func() { _ = db.Close() }()
+
+		id := int64(i + 1)
+		expectGetCheckpointEmpty(mock)
+		mock.ExpectQuery("SELECT.*FROM events").
+			WithArgs(1000, 0).
+			WillReturnRows(addEventRow(sqlmock.NewRows(eventCols), id, ts, 1))
+		expectSaveCheckpoint(mock, "test-consumer", id, 1, "test-node")
+		t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet(), source) })
+
+		m.processors = append(m.processors, makeTestInstance(db, source, shared))
+	}
+
+	require.NoError(t, m.Run(context.Background()))
+
+	sources := make(map[int64]string)
+	for _, batch := range out.sentEvents {
+		for _, evt := range batch {
+			sources[evt.ID] = evt.Source
+		}
+	}
+	assert.Equal(t, map[int64]string{1: "emea", 2: "apac"}, sources)
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.WriterLastEventID.WithLabelValues("emea", testWriterType)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.WriterLastEventID.WithLabelValues("apac", testWriterType)))
+}
+
+// failOnceWriter fails its first call, closing failed, and accepts the others.
+type failOnceWriter struct {
+	mockWriter
+	failed chan struct{}
+}
+
+func (w *failOnceWriter) SendEvents(ctx context.Context, evts []events.Event) error {
+	if w.callCount == 0 {
+		w.callCount++
+		close(w.failed)
+		return errors.New("sink down")
+	}
+	return w.mockWriter.SendEvents(ctx, evts)
+}
+
+// TestNewWriterState_SharedLockedBelowRetries verifies that a processor
+// waiting to retry a shared writer does not hold its lock, so another
+// processor's batch goes through during the backoff.
+func TestNewWriterState_SharedLockedBelowRetries(t *testing.T) {
+	sink := &failOnceWriter{failed: make(chan struct{})}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ws := newWriterState(testWriterType, sink, config.RetryConfig{Enabled: true, MaxAttempts: 2, InitialBackoff: 2}, config.RouteConfig{}, true, logger)
+
+	retried := make(chan error, 1)
+	go func() {
+		retried <- ws.writer.SendEvents(context.Background(), []events.Event{{ID: 1}})
+	}()
+	<-sink.failed
+
+	start := time.Now()
+	require.NoError(t, ws.writer.SendEvents(context.Background(), []events.Event{{ID: 2}}))
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the second batch waited for the backoff of the first")
+
+	require.NoError(t, <-retried)
+	assert.Equal(t, 3, sink.callCount)
+}
+
+// TestMultiProcessor_RunFailure verifies that an instance failing to start
+// stops the others and is named in the error.
+func TestMultiProcessor_RunFailure(t *testing.T) {
+	out := &mockWriter{}
+	m := &MultiProcessor{logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+
+	healthy, healthyMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = healthy.Close() }()
+	expectGetCheckpointFound(healthyMock, 5, 5, "test-node")
+
+	broken, brokenMock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = broken.Close() }()
+	brokenMock.ExpectQuery("SELECT.*FROM idp.event_processing_checkpoint").
+		WillReturnError(errors.New("connection refused"))
+
+	m.processors = []*Processor{
+		makeTestInstance(healthy, "emea", &lockedWriter{w: out}),
+		makeTestInstance(broken, "apac", &lockedWriter{w: out}),
+	}
+	for _, p := range m.processors {
+		p.config.PollingInterval = 3600
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = m.Run(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instance apac")
+	assert.NoError(t, ctx.Err(), "the healthy instance is stopped, not waited for")
+}
+
 // ============================================================================
 // mockWriter — in-memory EventWriter for tests
 // ============================================================================
@@ -523,6 +1622,23 @@ func (m *mockWriter) SendEvents(_ context.Context, evts []events.Event) error {
 	return nil
 }
 
+func (m *mockWriter) SendEvent(ctx context.Context, evt events.Event) error {
+	return m.SendEvents(ctx, []events.Event{evt})
+}
+
+func (m *mockWriter) Close() error { return nil }
+
+// closingWriter records whether Close was called.
+type closingWriter struct {
+	mockWriter
+	closed bool
+}
+
+func (c *closingWriter) Close() error {
+	c.closed = true
+	return nil
+}
+
 // ============================================================================
 // Existing tests (unchanged)
 // ============================================================================
@@ -570,24 +1686,28 @@ func TestProcessor_Struct(t *testing.T) {
 
 	proc := &Processor{
 		eventReader: nil,
-		writer:      &mockWriter{name: "stdout"},
-		checkpoint: &events.ProcessingCheckpoint{
-			ConsumerID:           cfg.ConsumerID,
-			LastEventID:          0,
-			LastEventTimestamp:   time.Now(),
-			TotalEventsProcessed: 0,
-			ProcessingNode:       "test-node",
-		},
+		writers: []*writerState{{
+			name:   "stdout",
+			writer: &mockWriter{name: "stdout"},
+			checkpoint: &events.ProcessingCheckpoint{
+				ConsumerID:           cfg.ConsumerID,
+				WriterType:           "stdout",
+				LastEventID:          0,
+				LastEventTimestamp:   time.Now(),
+				TotalEventsProcessed: 0,
+				ProcessingNode:       "test-node",
+			},
+		}},
 		config:     cfg,
 		logFactory: logFactory,
 		logger:     logger,
 		hostname:   "test-node",
 	}
 
-	if proc.writer == nil {
+	if len(proc.writers) != 1 || proc.writers[0].writer == nil {
 		t.Error("Expected writer to be set")
 	}
-	if proc.checkpoint == nil {
+	if proc.writers[0].checkpoint == nil {
 		t.Error("Expected checkpoint to be set")
 	}
 	if proc.config != cfg {
@@ -692,3 +1812,36 @@ func TestMockWriter_Failure(t *testing.T) {
 		t.Errorf("Expected no events sent on failure, got %d", len(writer.sentEvents))
 	}
 }
+
+// TestForwardNotifications verifies that push mode wakes every writer, or
+// only ingest when the write-ahead log is enabled, and that pending wake-ups
+// absorb further notifications.
+func TestForwardNotifications(t *testing.T) {
+	newWriterStates := func() []*writerState {
+		return []*writerState{
+			{name: "loki", wake: make(chan struct{}, 1)},
+			{name: "splunk", wake: make(chan struct{}, 1)},
+		}
+	}
+	forward := func(p *Processor, n int) {
+		notifications := make(chan struct{}, n)
+		for range n {
+			notifications <- struct{}{}
+		}
+		close(notifications)
+		p.forwardNotifications(notifications)
+	}
+
+	p := &Processor{writers: newWriterStates()}
+	forward(p, 3)
+	for _, ws := range p.writers {
+		assert.Len(t, ws.wake, 1, "writer %s should have one pending wake-up", ws.name)
+	}
+
+	p = &Processor{writers: newWriterStates(), ingestWake: make(chan struct{}, 1)}
+	forward(p, 2)
+	assert.Len(t, p.ingestWake, 1)
+	for _, ws := range p.writers {
+		assert.Empty(t, ws.wake, "writer %s is woken by ingest, not by push mode", ws.name)
+	}
+}
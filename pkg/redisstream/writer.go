@@ -0,0 +1,169 @@
+// Package redisstream appends events to a Redis Stream, so internal consumers
+// can follow NetBird audit events with XREAD or consumer groups.
+package redisstream
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Stream entry field names.
+const (
+	FieldEventID      = "event_id"
+	FieldActivityCode = "activity_code"
+	FieldAccountID    = "account_id"
+	FieldData         = "data"
+)
+
+// Config holds configuration for the Redis Streams writer.
+type Config struct {
+	// URL is the Redis address. Accepts redis:// and rediss:// URLs or plain host:port.
+	URL string
+
+	// Stream is the stream key (default: netbird:events).
+	Stream string
+
+	// MaxLen trims the stream to about this many entries on every XADD
+	// (default: 100000). Negative disables trimming.
+	MaxLen int64
+
+	// ExactTrim trims to exactly MaxLen ("MAXLEN =") instead of the cheaper
+	// approximate trim ("MAXLEN ~") that Redis applies per radix tree node.
+	ExactTrim bool
+
+	// Format of the data field: "json" (default) or any other format.New name.
+	Format string
+
+	// Timeout bounds each batch. Default: 10s.
+	Timeout time.Duration
+}
+
+// Writer XADDs one entry per event. Entries carry event_id, activity_code
+// and account_id for filtering without decoding, and the formatted event in data.
+type Writer struct {
+	client    *redis.Client
+	stream    string
+	maxLen    int64
+	approx    bool
+	formatter format.Formatter
+	timeout   time.Duration
+	logger    *slog.Logger
+}
+
+// NewWriter creates a Redis Streams writer. Like the leader elector it pings
+// Redis on startup, so a wrong address fails fast.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("redis stream url is required")
+	}
+
+	opt, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		// Not a redis:// URL — treat as a plain host:port address.
+		opt = &redis.Options{Addr: cfg.URL}
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis stream format: %w", err)
+	}
+
+	stream := cfg.Stream
+	if stream == "" {
+		stream = "netbird:events"
+	}
+	maxLen := cfg.MaxLen
+	if maxLen == 0 {
+		maxLen = 100000
+	}
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	client := redis.NewClient(opt)
+
+	pingCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(pingCtx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("redis ping failed (%s): %w", opt.Addr, err)
+	}
+
+	return &Writer{
+		client:    client,
+		stream:    stream,
+		maxLen:    maxLen,
+		approx:    !cfg.ExactTrim,
+		formatter: formatter,
+		timeout:   timeout,
+		logger:    logger,
+	}, nil
+}
+
+// SendEvents XADDs the batch in one pipeline. Redis assigns the entry IDs,
+// so a batch retried after a partial failure appends its events again;
+// consumers should deduplicate on the event_id field. Events that cannot be
+// formatted are skipped and returned in a writer.RejectedError once the rest
+// was added.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	pipe := w.client.Pipeline()
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		data, err := w.formatter.Format(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		args := &redis.XAddArgs{
+			Stream: w.stream,
+			Values: []any{
+				FieldEventID, evt.DedupID(),
+				FieldActivityCode, evt.ActivityCode,
+				FieldAccountID, evt.AccountID,
+				FieldData, data,
+			},
+		}
+		if w.maxLen > 0 {
+			args.MaxLen = w.maxLen
+			args.Approx = w.approx
+		}
+		pipe.XAdd(ctx, args)
+	}
+	added := pipe.Len()
+	if added == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to add %d events to redis stream %s: %w", added, w.stream, err)
+	}
+
+	w.logger.Debug("Added events to Redis stream", "stream", w.stream, "count", added)
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent adds a single event to the stream
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the Redis client. Every batch is written synchronously, so
+// there is nothing to flush.
+func (w *Writer) Close() error {
+	return w.client.Close()
+}
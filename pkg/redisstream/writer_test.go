@@ -0,0 +1,164 @@
+package redisstream
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents(from, to int64) []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var list []events.Event
+	for id := from; id <= to; id++ {
+		list = append(list, events.Event{ID: id, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: "acc1"})
+	}
+	return list
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing url")
+	}
+
+	mr := miniredis.RunT(t)
+	if _, err := NewWriter(&Config{URL: mr.Addr(), Format: "xml"}, testLogger()); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+
+	addr := mr.Addr()
+	mr.Close()
+	if _, err := NewWriter(&Config{URL: addr}, testLogger()); err == nil {
+		t.Error("Expected error when Redis is unreachable")
+	}
+}
+
+func TestSendEvents_AddsEntries(t *testing.T) {
+	mr := miniredis.RunT(t)
+	w, err := NewWriter(&Config{URL: "redis://" + mr.Addr()}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if err := w.SendEvents(context.Background(), testEvents(1, 3)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	entries, err := mr.Stream("netbird:events")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+	for i, entry := range entries {
+		fields := map[string]string{}
+		for j := 0; j+1 < len(entry.Values); j += 2 {
+			fields[entry.Values[j]] = entry.Values[j+1]
+		}
+		if fields[FieldEventID] != strconv.Itoa(i+1) || fields[FieldActivityCode] != "dashboard.login" || fields[FieldAccountID] != "acc1" {
+			t.Errorf("Unexpected entry fields %v", fields)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal([]byte(fields[FieldData]), &doc); err != nil {
+			t.Fatalf("Expected JSON data, got %q: %v", fields[FieldData], err)
+		}
+		if doc["event_id"] != float64(i+1) {
+			t.Errorf("Unexpected data %v", doc)
+		}
+	}
+}
+
+func TestSendEvents_EventIDIncludesSource(t *testing.T) {
+	mr := miniredis.RunT(t)
+	w, err := NewWriter(&Config{URL: mr.Addr()}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	// Two instances in multi-source mode with the same event ID
+	list := append(testEvents(1, 1), testEvents(1, 1)...)
+	list[0].Source, list[1].Source = "emea", "apac"
+	if err := w.SendEvents(context.Background(), list); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	entries, err := mr.Stream("netbird:events")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Values[1] != "emea:1" || entries[1].Values[1] != "apac:1" {
+		t.Errorf("Expected event_id prefixed with the source, got %v", entries)
+	}
+}
+
+func TestSendEvents_TrimsToMaxLen(t *testing.T) {
+	mr := miniredis.RunT(t)
+	w, err := NewWriter(&Config{URL: mr.Addr(), Stream: "audit", MaxLen: 5}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	if err := w.SendEvents(context.Background(), testEvents(1, 8)); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	entries, err := mr.Stream("audit")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("Expected stream trimmed to 5 entries, got %d", len(entries))
+	}
+	if entries[0].Values[1] != "4" {
+		t.Errorf("Expected the oldest entries to be trimmed, first is event %s", entries[0].Values[1])
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	w, err := NewWriter(&Config{URL: mr.Addr()}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+	w.formatter = formattest.FailOn(1)
+
+	err = w.SendEvents(context.Background(), testEvents(1, 3))
+	formattest.CheckRejected(t, err, 1)
+	entries, err := mr.Stream("netbird:events")
+	if err != nil {
+		t.Fatalf("Stream failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Values[1] != "2" {
+		t.Errorf("Expected the other events to be added, got %v", entries)
+	}
+}
+
+func TestSendEvents_RedisDown(t *testing.T) {
+	mr := miniredis.RunT(t)
+	w, err := NewWriter(&Config{URL: mr.Addr(), Timeout: time.Second}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	mr.Close()
+	if err := w.SendEvents(context.Background(), testEvents(1, 2)); err == nil {
+		t.Error("Expected error when Redis is down")
+	}
+}
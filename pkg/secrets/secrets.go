@@ -0,0 +1,243 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+)
+
+// Reference schemes. A setting that starts with one of them names where the
+// secret is kept instead of holding it.
+const (
+	schemeFile       = "file://"       // file:///run/secrets/pg
+	schemeEnv        = "env://"        // env://PG_URL
+	schemeCredential = "credential://" // credential://pg, a systemd credential
+	schemeVault      = "vault://"      // vault://secret/eventsproc#postgres_url
+)
+
+// Config holds the settings of the secret providers.
+type Config struct {
+	// RefreshInterval is how often, in seconds, secret references are
+	// resolved again; connections are re-opened when a secret changed
+	// (default: 0 = resolve once at startup).
+	RefreshInterval int `mapstructure:"refresh_interval"`
+
+	// Vault configures vault:// references.
+	Vault VaultConfig `mapstructure:"vault"`
+}
+
+// VaultConfig configures access to a HashiCorp Vault KV version 2 engine.
+type VaultConfig struct {
+	// Address of the Vault server, e.g. https://vault.example.com:8200
+	// (default: $VAULT_ADDR).
+	Address string `mapstructure:"address"`
+
+	// Token authenticates to Vault (default: $VAULT_TOKEN). It may itself be
+	// a file://, env:// or credential:// reference, e.g. the token sink of a
+	// Vault agent, and is read again for every request.
+	Token string `mapstructure:"token"`
+
+	// Namespace is sent as X-Vault-Namespace (Vault Enterprise).
+	Namespace string `mapstructure:"namespace"`
+
+	// Timeout in seconds for each request (default: 10).
+	Timeout int `mapstructure:"timeout"`
+
+	// TLS configures the connection to Vault.
+	TLS tlsutil.Config `mapstructure:"tls"`
+}
+
+// IsReference reports whether value is a secret reference rather than a
+// secret.
+func IsReference(value string) bool {
+	for _, scheme := range []string{schemeFile, schemeEnv, schemeCredential, schemeVault} {
+		if strings.HasPrefix(value, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolver reads the secrets that references point to.
+type Resolver struct {
+	vaultAddress   string
+	vaultToken     string
+	vaultNamespace string
+	client         *http.Client
+}
+
+// NewResolver creates a resolver. Vault is only contacted when a vault://
+// reference is resolved.
+func NewResolver(cfg Config) (*Resolver, error) {
+	r := &Resolver{
+		vaultAddress:   strings.TrimSuffix(cfg.Vault.Address, "/"),
+		vaultToken:     cfg.Vault.Token,
+		vaultNamespace: cfg.Vault.Namespace,
+	}
+	if r.vaultAddress == "" {
+		r.vaultAddress = strings.TrimSuffix(os.Getenv("VAULT_ADDR"), "/")
+	}
+	if r.vaultToken == "" {
+		r.vaultToken = os.Getenv("VAULT_TOKEN")
+	}
+	if strings.HasPrefix(r.vaultToken, schemeVault) {
+		return nil, errors.New("secrets.vault.token cannot be a vault:// reference")
+	}
+
+	timeout := time.Duration(cfg.Vault.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !cfg.Vault.TLS.IsZero() {
+		tlsCfg, err := cfg.Vault.TLS.Build()
+		if err != nil {
+			return nil, fmt.Errorf("secrets.vault.tls: %w", err)
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+	r.client = &http.Client{Timeout: timeout, Transport: transport}
+	return r, nil
+}
+
+// Resolve returns the secret value references, or value itself if it is not
+// a reference. Errors name the reference but never a secret.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, schemeFile):
+		return readFile(strings.TrimPrefix(value, schemeFile))
+	case strings.HasPrefix(value, schemeEnv):
+		name := strings.TrimPrefix(value, schemeEnv)
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, schemeCredential):
+		return readCredential(strings.TrimPrefix(value, schemeCredential))
+	case strings.HasPrefix(value, schemeVault):
+		return r.readVault(ctx, strings.TrimPrefix(value, schemeVault))
+	}
+	return value, nil
+}
+
+// readFile returns the content of a secret file without the trailing newline.
+func readFile(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		return "", fmt.Errorf("secret file %q must be an absolute path (file:///path)", path)
+	}
+	// #nosec G304 -- Path is provided by the administrator via config, not by an external user.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// readCredential returns a credential systemd passed with LoadCredential= or
+// SetCredentialEncrypted=, from the unit's $CREDENTIALS_DIRECTORY.
+func readCredential(name string) (string, error) {
+	dir := os.Getenv("CREDENTIALS_DIRECTORY")
+	if dir == "" {
+		return "", fmt.Errorf("credential %s: $CREDENTIALS_DIRECTORY is not set (not started by systemd with credentials)", name)
+	}
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid credential name %q", name)
+	}
+	return readFile(filepath.Join(dir, name))
+}
+
+// readVault reads the field of a KV version 2 secret. ref is
+// <mount>/<path>#<field>, e.g. secret/eventsproc#postgres_url.
+func (r *Resolver) readVault(ctx context.Context, ref string) (string, error) {
+	secretPath, field, ok := strings.Cut(ref, "#")
+	mount, secretPath, _ := strings.Cut(secretPath, "/")
+	if !ok || field == "" || mount == "" || secretPath == "" {
+		return "", fmt.Errorf("invalid vault reference %q (want vault://<mount>/<path>#<field>)", schemeVault+ref)
+	}
+	if r.vaultAddress == "" {
+		return "", errors.New("secrets.vault.address (or $VAULT_ADDR) is required for vault:// references")
+	}
+	token, err := r.Resolve(ctx, r.vaultToken)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault token: %w", err)
+	}
+	if token == "" {
+		return "", errors.New("secrets.vault.token (or $VAULT_TOKEN) is required for vault:// references")
+	}
+
+	endpoint := r.vaultAddress + "/v1/" + url.PathEscape(mount) + "/data/" + escapePath(secretPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+	req.Header.Set("X-Vault-Request", "true")
+	if r.vaultNamespace != "" {
+		req.Header.Set("X-Vault-Namespace", r.vaultNamespace)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault secret %s/%s: %w", mount, secretPath, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Vault errors describe the request, not the secret
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		_ = json.Unmarshal(body, &vaultErr)
+		return "", fmt.Errorf("vault returned status %d for %s/%s: %s", resp.StatusCode, mount, secretPath, strings.Join(vaultErr.Errors, "; "))
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return "", fmt.Errorf("failed to decode vault secret %s/%s: %w", mount, secretPath, err)
+	}
+	raw, ok := secret.Data.Data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s/%s has no field %q", mount, secretPath, field)
+	}
+	// Numbers, e.g. a port, are returned as written
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err == nil {
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		}
+	}
+	return "", fmt.Errorf("field %q of vault secret %s/%s is not a string", field, mount, secretPath)
+}
+
+// escapePath escapes each segment of a slash-separated path.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
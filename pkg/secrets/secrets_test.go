@@ -0,0 +1,167 @@
+package secrets
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsReference(t *testing.T) {
+	for value, want := range map[string]bool{
+		"file:///run/secrets/pg":           true,
+		"env://PG_URL":                     true,
+		"credential://pg":                  true,
+		"vault://secret/eventsproc#pg":     true,
+		"postgres://netbird@db/netbird":    false,
+		"user=netbird dbname=netbird":      false,
+		"redis.example.com:6379":           false,
+		"dG9wLXNlY3JldC1rZXktMzItYnl0ZXM=": false,
+	} {
+		if got := IsReference(value); got != want {
+			t.Errorf("IsReference(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestResolve_Local(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "pg")
+	if err := os.WriteFile(secretFile, []byte("postgres://netbird:s3cret@db/netbird\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "redis"), []byte("redis://:s3cret@redis:6379"), 0600); err != nil {
+		t.Fatalf("Failed to write credential: %v", err)
+	}
+	t.Setenv("EP_TEST_SECRET", "from-env")
+	t.Setenv("CREDENTIALS_DIRECTORY", dir)
+
+	r, err := NewResolver(Config{})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	ctx := context.Background()
+	for ref, want := range map[string]string{
+		"file://" + secretFile:        "postgres://netbird:s3cret@db/netbird",
+		"env://EP_TEST_SECRET":        "from-env",
+		"credential://redis":          "redis://:s3cret@redis:6379",
+		"user=netbird dbname=netbird": "user=netbird dbname=netbird",
+	} {
+		got, err := r.Resolve(ctx, ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+
+	for _, ref := range []string{
+		"file://relative/pg",
+		"file://" + filepath.Join(dir, "missing"),
+		"env://EP_TEST_UNSET",
+		"credential://../pg",
+		"vault://secret/eventsproc#pg", // no Vault address
+	} {
+		if _, err := r.Resolve(ctx, ref); err == nil {
+			t.Errorf("Expected an error for %q", ref)
+		}
+	}
+}
+
+// newTestVault serves the KV version 2 secret secret/eventsproc/db like Vault.
+func newTestVault(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "hvs.test" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		if r.Header.Get("X-Vault-Namespace") != "ops" {
+			t.Errorf("Expected namespace ops, got %q", r.Header.Get("X-Vault-Namespace"))
+		}
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/secret/data/eventsproc/db" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(w, `{"errors":[]}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{
+		  "request_id": "1f0c0b8e",
+		  "data": {
+		    "data": {"postgres_url": "postgres://netbird:v4ult@db/netbird", "port": 5432, "tags": ["a"]},
+		    "metadata": {"created_time": "2026-10-01T10:00:00Z", "version": 3}
+		  }
+		}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolve_Vault(t *testing.T) {
+	vault := newTestVault(t)
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("hvs.test\n"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+
+	// The token is read from a Vault agent sink
+	r, err := NewResolver(Config{Vault: VaultConfig{Address: vault.URL + "/", Token: "file://" + tokenFile, Namespace: "ops"}})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	ctx := context.Background()
+	for ref, want := range map[string]string{
+		"vault://secret/eventsproc/db#postgres_url": "postgres://netbird:v4ult@db/netbird",
+		"vault://secret/eventsproc/db#port":         "5432",
+	} {
+		got, err := r.Resolve(ctx, ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+
+	for ref, wantErr := range map[string]string{
+		"vault://secret/eventsproc/db#missing": `has no field "missing"`,
+		"vault://secret/eventsproc/db#tags":    "is not a string",
+		"vault://secret/eventsproc/other#pg":   "status 404",
+		"vault://secret/eventsproc/db":         "invalid vault reference",
+		"vault://secret#pg":                    "invalid vault reference",
+	} {
+		if _, err := r.Resolve(ctx, ref); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Resolve(%q): expected error containing %q, got %v", ref, wantErr, err)
+		}
+	}
+
+	// A rotated token is picked up on the next request
+	if err := os.WriteFile(tokenFile, []byte("hvs.revoked"), 0600); err != nil {
+		t.Fatalf("Failed to write token file: %v", err)
+	}
+	_, err = r.Resolve(ctx, "vault://secret/eventsproc/db#postgres_url")
+	if err == nil || !strings.Contains(err.Error(), "status 403") || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected permission denied, got %v", err)
+	}
+	if strings.Contains(err.Error(), "hvs.") {
+		t.Errorf("Error must not contain the token: %v", err)
+	}
+}
+
+func TestNewResolver_VaultDefaults(t *testing.T) {
+	vault := newTestVault(t)
+	t.Setenv("VAULT_ADDR", vault.URL)
+	t.Setenv("VAULT_TOKEN", "hvs.test")
+
+	r, err := NewResolver(Config{Vault: VaultConfig{Namespace: "ops"}})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	got, err := r.Resolve(context.Background(), "vault://secret/eventsproc/db#postgres_url")
+	if err != nil || got != "postgres://netbird:v4ult@db/netbird" {
+		t.Errorf("Expected the secret via $VAULT_ADDR and $VAULT_TOKEN, got %q, %v", got, err)
+	}
+
+	if _, err := NewResolver(Config{Vault: VaultConfig{Token: "vault://secret/token#t"}}); err == nil {
+		t.Error("Expected an error for a vault:// token")
+	}
+}
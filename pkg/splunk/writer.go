@@ -0,0 +1,372 @@
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+const (
+	eventPath = "/services/collector/event"
+	ackPath   = "/services/collector/ack"
+)
+
+// Config holds configuration for the Splunk HTTP Event Collector writer.
+type Config struct {
+	// URL is the HEC base URL, e.g. "https://splunk.example.com:8088".
+	URL string
+
+	// Token is the HEC token, sent as "Authorization: Splunk <token>".
+	Token string
+
+	// Index, Source, SourceType and Host are attached to every event.
+	// Empty values let the HEC token defaults apply.
+	Index      string
+	Source     string
+	SourceType string
+	Host       string
+
+	// AckEnabled turns on indexer acknowledgement. SendEvents then only
+	// succeeds once Splunk confirms every ackId returned for the batch.
+	// The HEC token must have "Enable indexer acknowledgement" checked.
+	AckEnabled bool
+
+	// Channel is the X-Splunk-Request-Channel GUID used for acknowledgement.
+	// A random GUID is generated when empty.
+	Channel string
+
+	// AckTimeout bounds how long SendEvents waits for acknowledgement. Default: 60s.
+	AckTimeout time.Duration
+
+	// AckPollInterval is the delay between ack status queries. Default: 1s.
+	AckPollInterval time.Duration
+
+	// Format selects the event encoding: "json" (default) and "ocsf" send the
+	// event as a JSON object; "kv", "cef" and "leef" send it as a raw string.
+	Format string
+
+	// Timeout bounds each HTTP request. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+}
+
+// Writer sends events to Splunk via the HTTP Event Collector.
+type Writer struct {
+	client          *http.Client
+	eventURL        string
+	ackURL          string
+	token           string
+	index           string
+	source          string
+	sourceType      string
+	host            string
+	ackEnabled      bool
+	channel         string
+	ackTimeout      time.Duration
+	ackPollInterval time.Duration
+	formatter       format.Formatter
+	logger          *slog.Logger
+}
+
+// NewWriter creates a Splunk HEC writer. It does not contact Splunk; connection
+// errors surface on the first SendEvents call.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("splunk url is required")
+	}
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("splunk token is required")
+	}
+
+	formatter, err := format.New(cfg.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid splunk format: %w", err)
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid splunk tls config: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ackTimeout := cfg.AckTimeout
+	if ackTimeout == 0 {
+		ackTimeout = 60 * time.Second
+	}
+	ackPollInterval := cfg.AckPollInterval
+	if ackPollInterval == 0 {
+		ackPollInterval = time.Second
+	}
+
+	channel := cfg.Channel
+	if channel == "" && cfg.AckEnabled {
+		channel, err = newChannelID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	base := strings.TrimRight(cfg.URL, "/")
+	return &Writer{
+		client:          &http.Client{Timeout: timeout, Transport: transport},
+		eventURL:        base + eventPath,
+		ackURL:          base + ackPath,
+		token:           cfg.Token,
+		index:           cfg.Index,
+		source:          cfg.Source,
+		sourceType:      cfg.SourceType,
+		host:            cfg.Host,
+		ackEnabled:      cfg.AckEnabled,
+		channel:         channel,
+		ackTimeout:      ackTimeout,
+		ackPollInterval: ackPollInterval,
+		formatter:       formatter,
+		logger:          logger,
+	}, nil
+}
+
+// hecEvent is a single HEC event envelope.
+type hecEvent struct {
+	Time       string          `json:"time"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	SourceType string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// hecResponse is the body HEC returns for event and ack requests.
+type hecResponse struct {
+	Text  string `json:"text"`
+	Code  int    `json:"code"`
+	AckID *int64 `json:"ackId"`
+}
+
+// SendEvents posts a batch to HEC as concatenated JSON envelopes. With indexer
+// acknowledgement enabled it blocks until Splunk confirms the batch is indexed,
+// so the processor never advances the checkpoint past uncommitted events.
+// Events that cannot be formatted are skipped and returned in a
+// writer.RejectedError once the rest was sent.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	sent := 0
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		payload, err := w.eventPayload(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		envelope := hecEvent{
+			Time:       formatTime(evt.Timestamp),
+			Host:       w.host,
+			Source:     w.source,
+			SourceType: w.sourceType,
+			Index:      w.index,
+			Event:      payload,
+		}
+		if err := enc.Encode(envelope); err != nil {
+			return fmt.Errorf("failed to encode splunk event: %w", err)
+		}
+		sent++
+	}
+	if sent == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	resp, err := w.post(ctx, w.eventURL, body.Bytes())
+	if err != nil {
+		return fmt.Errorf("splunk hec request failed: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("splunk hec rejected batch: %s (code %d)", resp.Text, resp.Code)
+	}
+
+	if !w.ackEnabled {
+		w.logger.Debug("Sent events to Splunk HEC", "count", sent)
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+	if resp.AckID == nil {
+		return fmt.Errorf("splunk hec returned no ackId; is indexer acknowledgement enabled on the token?")
+	}
+	if err := w.waitForAck(ctx, *resp.AckID); err != nil {
+		return err
+	}
+
+	w.logger.Debug("Splunk acknowledged events", "count", sent, "ack_id", *resp.AckID)
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// eventPayload renders the HEC "event" field: the JSON object itself for JSON
+// formats (json, ocsf), or the formatted line as a JSON string for text formats.
+func (w *Writer) eventPayload(evt events.Event) (json.RawMessage, error) {
+	line, err := w.formatter.Format(evt)
+	if err != nil {
+		return nil, err
+	}
+	if format.IsJSON(w.formatter) {
+		return json.RawMessage(line), nil
+	}
+	return json.Marshal(line)
+}
+
+// SendEvent sends a single event to Splunk
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close releases idle HTTP connections. Nothing is buffered, so there is nothing to flush.
+func (w *Writer) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// waitForAck polls the ack endpoint until ackID is confirmed, the ack timeout
+// expires or ctx is cancelled.
+func (w *Writer) waitForAck(ctx context.Context, ackID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, w.ackTimeout)
+	defer cancel()
+
+	payload, err := json.Marshal(map[string][]int64{"acks": {ackID}})
+	if err != nil {
+		return fmt.Errorf("failed to encode splunk ack request: %w", err)
+	}
+	key := strconv.FormatInt(ackID, 10)
+
+	ticker := time.NewTicker(w.ackPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("splunk did not acknowledge ackId %d within %s: %w", ackID, w.ackTimeout, ctx.Err())
+		case <-ticker.C:
+		}
+
+		acked, err := w.queryAck(ctx, payload, key)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue // report the timeout on the next loop iteration
+			}
+			return err
+		}
+		if acked {
+			return nil
+		}
+	}
+}
+
+// queryAck asks HEC whether the given ack key has been indexed.
+func (w *Writer) queryAck(ctx context.Context, payload []byte, key string) (bool, error) {
+	req, err := w.newRequest(ctx, w.ackURL, payload)
+	if err != nil {
+		return false, err
+	}
+	httpResp, err := w.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("splunk ack request failed: %w", err)
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+	if httpResp.StatusCode/100 != 2 {
+		return false, fmt.Errorf("splunk ack returned %s: %s", httpResp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var status struct {
+		Acks map[string]bool `json:"acks"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return false, fmt.Errorf("invalid splunk ack response: %w", err)
+	}
+	return status.Acks[key], nil
+}
+
+// post sends a HEC request and decodes the standard response body.
+func (w *Writer) post(ctx context.Context, url string, payload []byte) (*hecResponse, error) {
+	req, err := w.newRequest(ctx, url, payload)
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := w.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = httpResp.Body.Close() }()
+
+	data, _ := io.ReadAll(io.LimitReader(httpResp.Body, 64*1024))
+	var resp hecResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		if httpResp.StatusCode/100 != 2 {
+			return nil, statusError(httpResp.StatusCode, fmt.Errorf("%s: %s", httpResp.Status, strings.TrimSpace(string(data))))
+		}
+		return nil, fmt.Errorf("invalid hec response: %w", err)
+	}
+	if httpResp.StatusCode/100 != 2 {
+		return nil, statusError(httpResp.StatusCode, fmt.Errorf("%s: %s (code %d)", httpResp.Status, resp.Text, resp.Code))
+	}
+	return &resp, nil
+}
+
+// statusError marks err as permanent when the HEC status means the request
+// was rejected (bad token, invalid data), so it is not retried.
+func statusError(code int, err error) error {
+	if writer.IsPermanentStatus(code) {
+		return writer.Permanent(err)
+	}
+	return err
+}
+
+func (w *Writer) newRequest(ctx context.Context, url string, payload []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create splunk request: %w", err)
+	}
+	req.Header.Set("Authorization", "Splunk "+w.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eventsproc")
+	if w.channel != "" {
+		req.Header.Set("X-Splunk-Request-Channel", w.channel)
+	}
+	return req, nil
+}
+
+// formatTime renders an event timestamp as HEC epoch seconds with millisecond precision.
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', 3, 64)
+}
+
+// newChannelID generates a random (version 4) GUID for X-Splunk-Request-Channel.
+func newChannelID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate splunk channel id: %w", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
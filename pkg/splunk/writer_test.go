@@ -0,0 +1,301 @@
+package splunk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// fakeHEC is a minimal Splunk HTTP Event Collector stand-in.
+type fakeHEC struct {
+	mu            sync.Mutex
+	authHeaders   []string
+	channels      []string
+	envelopes     []map[string]any
+	ackQueries    int
+	ackAfter      int  // number of ack queries answered false before true
+	omitAckID     bool // reply without ackId
+	eventStatus   int
+	eventResponse string
+}
+
+func (f *fakeHEC) handler(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.authHeaders = append(f.authHeaders, r.Header.Get("Authorization"))
+	f.channels = append(f.channels, r.Header.Get("X-Splunk-Request-Channel"))
+
+	switch r.URL.Path {
+	case eventPath:
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var env map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &env); err == nil {
+				f.envelopes = append(f.envelopes, env)
+			}
+		}
+		if f.eventStatus != 0 {
+			w.WriteHeader(f.eventStatus)
+			_, _ = io.WriteString(w, f.eventResponse)
+			return
+		}
+		if f.omitAckID {
+			_, _ = io.WriteString(w, `{"text":"Success","code":0}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"text":"Success","code":0,"ackId":7}`)
+	case ackPath:
+		f.ackQueries++
+		acked := f.ackQueries > f.ackAfter
+		_ = json.NewEncoder(w).Encode(map[string]any{"acks": map[string]bool{"7": acked}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeHEC(t *testing.T, f *fakeHEC) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(f.handler))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 123_000_000, time.UTC)
+	return []events.Event{
+		{ID: 1, Timestamp: ts, Activity: 43, ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorID: "user1"},
+		{ID: 2, Timestamp: ts.Add(time.Second), Activity: 2, ActivityCode: "user.join", AccountID: "acc1", InitiatorID: "user2"},
+	}
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	if _, err := NewWriter(&Config{Token: "t"}, testLogger()); err == nil {
+		t.Error("Expected error for missing url")
+	}
+	if _, err := NewWriter(&Config{URL: "http://splunk:8088"}, testLogger()); err == nil {
+		t.Error("Expected error for missing token")
+	}
+}
+
+func TestNewWriter_GeneratesChannelWhenAckEnabled(t *testing.T) {
+	w, err := NewWriter(&Config{URL: "http://splunk:8088", Token: "t", AckEnabled: true}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if len(w.channel) != 36 || strings.Count(w.channel, "-") != 4 {
+		t.Errorf("Expected a GUID channel, got %q", w.channel)
+	}
+}
+
+func TestSendEvents_NoAck(t *testing.T) {
+	hec := &fakeHEC{}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{
+		URL:        srv.URL,
+		Token:      "hec-token",
+		Index:      "netbird_events",
+		Source:     "eventsproc",
+		SourceType: "netbird:event",
+		Host:       "mgmt-1",
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if hec.authHeaders[0] != "Splunk hec-token" {
+		t.Errorf("Expected Splunk auth header, got %q", hec.authHeaders[0])
+	}
+	if hec.ackQueries != 0 {
+		t.Errorf("Expected no ack queries, got %d", hec.ackQueries)
+	}
+	if len(hec.envelopes) != 2 {
+		t.Fatalf("Expected 2 HEC envelopes, got %d", len(hec.envelopes))
+	}
+
+	env := hec.envelopes[0]
+	if env["time"] != "1769596200.123" {
+		t.Errorf("Expected event timestamp as time, got %v", env["time"])
+	}
+	if env["index"] != "netbird_events" || env["sourcetype"] != "netbird:event" ||
+		env["source"] != "eventsproc" || env["host"] != "mgmt-1" {
+		t.Errorf("Unexpected envelope metadata: %v", env)
+	}
+	event, ok := env["event"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected event object, got %T", env["event"])
+	}
+	if event["event_id"] != float64(1) || event["activity_code"] != "dashboard.login" {
+		t.Errorf("Unexpected event body: %v", event)
+	}
+}
+
+func TestSendEvents_TextFormat(t *testing.T) {
+	hec := &fakeHEC{}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Token: "hec-token", Format: "leef"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	event, ok := hec.envelopes[0]["event"].(string)
+	if !ok {
+		t.Fatalf("Expected event as string for leef format, got %T", hec.envelopes[0]["event"])
+	}
+	if !strings.HasPrefix(event, "LEEF:2.0|NetBird|eventsproc|") {
+		t.Errorf("Expected LEEF event, got %q", event)
+	}
+}
+
+func TestSendEvents_WaitsForAck(t *testing.T) {
+	hec := &fakeHEC{ackAfter: 2}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{
+		URL:             srv.URL,
+		Token:           "hec-token",
+		AckEnabled:      true,
+		Channel:         "11111111-2222-3333-4444-555555555555",
+		AckPollInterval: 5 * time.Millisecond,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if hec.ackQueries != 3 {
+		t.Errorf("Expected 3 ack queries (2 pending + 1 acked), got %d", hec.ackQueries)
+	}
+	for _, ch := range hec.channels {
+		if ch != "11111111-2222-3333-4444-555555555555" {
+			t.Errorf("Expected channel header on every request, got %q", ch)
+		}
+	}
+}
+
+func TestSendEvents_AckTimeout(t *testing.T) {
+	hec := &fakeHEC{ackAfter: 1 << 30} // never acknowledges
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{
+		URL:             srv.URL,
+		Token:           "hec-token",
+		AckEnabled:      true,
+		AckTimeout:      50 * time.Millisecond,
+		AckPollInterval: 5 * time.Millisecond,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error when Splunk never acknowledges")
+	}
+	if !strings.Contains(err.Error(), "did not acknowledge") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestSendEvents_AckEnabledButNoAckID(t *testing.T) {
+	hec := &fakeHEC{omitAckID: true}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Token: "hec-token", AckEnabled: true}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected error when HEC returns no ackId")
+	}
+}
+
+func TestSendEvents_InvalidToken(t *testing.T) {
+	hec := &fakeHEC{eventStatus: http.StatusForbidden, eventResponse: `{"text":"Invalid token","code":4}`}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Token: "wrong"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error for invalid token")
+	}
+	if !strings.Contains(err.Error(), "Invalid token") {
+		t.Errorf("Expected HEC error text in error, got %v", err)
+	}
+}
+
+func TestSendEvents_ServerBusy(t *testing.T) {
+	hec := &fakeHEC{eventStatus: http.StatusServiceUnavailable, eventResponse: "busy"}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Token: "hec-token"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvent(context.Background(), testEvents()[0]); err == nil {
+		t.Error("Expected error for 503 response")
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	hec := &fakeHEC{}
+	srv := newFakeHEC(t, hec)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Token: "hec-token"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.formatter = formattest.FailOn(1)
+
+	err = w.SendEvents(context.Background(), testEvents())
+	formattest.CheckRejected(t, err, 1)
+	if len(hec.envelopes) != 1 || hec.envelopes[0]["event"] != "event 2" {
+		t.Errorf("Expected the other event to be sent, got %v", hec.envelopes)
+	}
+}
+
+func TestSendEvents_Empty(t *testing.T) {
+	w, err := NewWriter(&Config{URL: "http://127.0.0.1:1", Token: "t"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), nil); err != nil {
+		t.Errorf("Expected no request for an empty batch, got %v", err)
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 5_000_000, time.UTC)
+	if got := formatTime(ts); got != "1769596200.005" {
+		t.Errorf("formatTime() = %s, want 1769596200.005", got)
+	}
+}
@@ -2,37 +2,49 @@ package stdout
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log/slog"
 
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/writer"
 )
 
-// StdoutWriter writes events to stdout in JSON format
+// StdoutWriter writes events to stdout, one line per event (JSON by default)
 // When running under systemd, stdout is captured and stored in the journal
 type StdoutWriter struct {
-	logger *slog.Logger
+	logger    *slog.Logger
+	formatter format.Formatter
 }
 
-// NewStdoutWriter creates a new StdoutWriter
+// NewStdoutWriter creates a new StdoutWriter that emits flattened JSON
 func NewStdoutWriter(logger *slog.Logger) *StdoutWriter {
+	return NewStdoutWriterWithFormatter(logger, format.JSON{})
+}
+
+// NewStdoutWriterWithFormatter creates a StdoutWriter that renders events with f
+// (e.g. CEF or LEEF for a SIEM tailing the journal)
+func NewStdoutWriterWithFormatter(logger *slog.Logger, f format.Formatter) *StdoutWriter {
 	return &StdoutWriter{
-		logger: logger,
+		logger:    logger,
+		formatter: f,
 	}
 }
 
-// SendEvents writes events to stdout in JSON format
+// SendEvents writes events to stdout using the configured format. Events that
+// fail to format are skipped and returned in a writer.RejectedError, so the
+// processor can dead-letter them.
 func (w *StdoutWriter) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	var rejected []writer.Rejection
 	for _, event := range eventsList {
-		line, err := eventToJSON(event)
+		line, err := w.formatter.Format(event)
 		if err != nil {
-			w.logger.Warn("Failed to convert event to JSON", "event_id", event.ID, "error", err)
+			rejected = append(rejected, writer.Rejection{Event: event, Reason: err.Error()})
 			continue
 		}
 		fmt.Println(line)
 	}
-	return nil
+	return writer.RejectUnformatted(rejected, len(eventsList))
 }
 
 // SendEvent writes a single event to stdout
@@ -44,59 +56,3 @@ func (w *StdoutWriter) SendEvent(ctx context.Context, event events.Event) error
 func (w *StdoutWriter) Close() error {
 	return nil
 }
-
-// eventToJSON converts an event to JSON format with flattened meta fields
-func eventToJSON(event events.Event) (string, error) {
-	// Create a map to hold all fields
-	data := make(map[string]interface{})
-
-	// Add classification fields for log filtering and routing
-	data["level"] = "info"
-	data["log_type"] = "application"
-	data["event_source"] = "netbird"
-
-	// Add event fields
-	data["event_id"] = event.ID
-	data["timestamp"] = event.Timestamp.Format("2006-01-02T15:04:05.000Z07:00")
-	data["activity"] = event.Activity
-	data["activity_name"] = event.ActivityName
-	data["activity_code"] = event.ActivityCode
-	data["account_id"] = event.AccountID
-
-	// Add initiator fields if they exist
-	if event.InitiatorID != "" {
-		data["initiator_id"] = event.InitiatorID
-	}
-	if event.InitiatorEmail != "" {
-		data["initiator_email"] = event.InitiatorEmail
-	}
-
-	// Add target fields if they exist
-	if event.TargetID != "" {
-		data["target_id"] = event.TargetID
-	}
-	if event.TargetEmail != "" {
-		data["target_email"] = event.TargetEmail
-	}
-
-	// Flatten meta JSON into individual fields with meta_ prefix
-	if event.Meta != "" {
-		var metaData map[string]interface{}
-		if err := json.Unmarshal([]byte(event.Meta), &metaData); err == nil {
-			for key, value := range metaData {
-				data["meta_"+key] = value
-			}
-		} else {
-			// If meta is not valid JSON, add it as a single field
-			data["meta_unparsable"] = event.Meta
-		}
-	}
-
-	// Marshal to JSON
-	jsonBytes, err := json.Marshal(data)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal event to JSON: %w", err)
-	}
-
-	return string(jsonBytes), nil
-}
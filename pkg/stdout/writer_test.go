@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
 )
 
 // TestSendEvents_WritesToStdout confirms that events are written to stdout in JSON format.
@@ -134,6 +135,28 @@ func TestSendEvent_Single(t *testing.T) {
 	}
 }
 
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w := NewStdoutWriterWithFormatter(logger, formattest.FailOn(2))
+
+	oldStdout := os.Stdout
+	r, pw, _ := os.Pipe()
+	os.Stdout = pw
+
+	err := w.SendEvents(context.Background(), []events.Event{{ID: 1}, {ID: 2}, {ID: 3}})
+
+	_ = pw.Close()
+	os.Stdout = oldStdout
+	var buf bytes.Buffer
+	_, _ = io.Copy(&buf, r)
+	_ = r.Close()
+
+	if got := strings.TrimSpace(buf.String()); got != "event 1\nevent 3" {
+		t.Errorf("Expected the other events to be printed, got %q", got)
+	}
+	formattest.CheckRejected(t, err, 2)
+}
+
 func TestClose(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 	writer := NewStdoutWriter(logger)
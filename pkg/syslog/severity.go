@@ -0,0 +1,71 @@
+package syslog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xh63/netbird-events/pkg/format"
+)
+
+// Syslog severities (RFC 5424 section 6.2.1).
+const (
+	SeverityEmergency     = 0
+	SeverityAlert         = 1
+	SeverityCritical      = 2
+	SeverityError         = 3
+	SeverityWarning       = 4
+	SeverityNotice        = 5
+	SeverityInformational = 6
+	SeverityDebug         = 7
+)
+
+var severityNames = map[string]int{
+	"emerg":   SeverityEmergency,
+	"alert":   SeverityAlert,
+	"crit":    SeverityCritical,
+	"err":     SeverityError,
+	"error":   SeverityError,
+	"warning": SeverityWarning,
+	"warn":    SeverityWarning,
+	"notice":  SeverityNotice,
+	"info":    SeverityInformational,
+	"debug":   SeverityDebug,
+}
+
+// ParseSeverity converts a severity keyword ("warning", "notice", ...) to its numeric value.
+func ParseSeverity(name string) (int, error) {
+	if sev, ok := severityNames[strings.ToLower(name)]; ok {
+		return sev, nil
+	}
+	return 0, fmt.Errorf("unknown syslog severity %q", name)
+}
+
+var facilityNames = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"ntp": 12, "audit": 13, "alert": 14, "clock": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ParseFacility converts a facility keyword ("local0", "authpriv", ...) to its numeric value.
+func ParseFacility(name string) (int, error) {
+	if f, ok := facilityNames[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown syslog facility %q", name)
+}
+
+// SeverityForActivity returns the syslog severity for a NetBird activity code:
+// warning for privilege changes and destructive actions, notice for
+// configuration changes, informational for everything else.
+func SeverityForActivity(code string) int {
+	switch format.ActivitySeverity(code) {
+	case format.SeverityWarning:
+		return SeverityWarning
+	case format.SeverityNotice:
+		return SeverityNotice
+	default:
+		return SeverityInformational
+	}
+}
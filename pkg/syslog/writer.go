@@ -0,0 +1,361 @@
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Supported values for Config.Network.
+const (
+	NetworkUDP = "udp"
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls" // RFC 5425: TCP + TLS, always octet-counted
+)
+
+// Supported values for Config.Framing (stream transports only).
+const (
+	FramingOctetCounting  = "octet-counting"  // RFC 6587 3.4.1: "LEN SP MSG"
+	FramingNonTransparent = "non-transparent" // RFC 6587 3.4.2: "MSG LF"
+)
+
+// Config holds configuration for the RFC 5424 syslog writer.
+type Config struct {
+	// Network is "udp", "tcp" or "tls". Default: "tcp".
+	Network string
+
+	// Address is the collector "host:port".
+	Address string
+
+	// Framing for tcp: "octet-counting" (default) or "non-transparent".
+	// tls always uses octet counting as required by RFC 5425.
+	Framing string
+
+	// Facility keyword, e.g. "local0" (default), "authpriv", "audit".
+	Facility string
+
+	// Hostname, AppName and ProcID fill the RFC 5424 header.
+	// Defaults: machine hostname, "eventsproc", process ID.
+	Hostname string
+	AppName  string
+	ProcID   string
+
+	// SDID is the STRUCTURED-DATA element ID. Default: "netbird@32473"
+	// (32473 is the IANA example enterprise number — use your own PEN if you have one).
+	SDID string
+
+	// MessageFormat of the MSG part: "json" (default), "kv", "cef", "leef" or "ocsf".
+	MessageFormat string
+
+	// SeverityOverrides maps exact activity codes to severity keywords,
+	// overriding the built-in category mapping (e.g. "dashboard.login": "notice").
+	SeverityOverrides map[string]string
+
+	// Timeout bounds dialing and each batch write. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and client certificates (network "tls").
+	TLS tlsutil.Config
+}
+
+// Writer sends events as RFC 5424 syslog messages over UDP, TCP or TLS.
+// Stream connections are dialled lazily and re-established transparently when
+// the collector drops them.
+type Writer struct {
+	network           string
+	address           string
+	framing           string
+	facility          int
+	hostname          string
+	appName           string
+	procID            string
+	sdID              string
+	formatter         format.Formatter
+	severityOverrides map[string]int
+	timeout           time.Duration
+	tlsConfig         *tls.Config
+	logger            *slog.Logger
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewWriter validates the configuration and creates a syslog writer.
+// No connection is made until the first event is sent.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.Address == "" {
+		return nil, fmt.Errorf("syslog address is required")
+	}
+
+	w := &Writer{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		framing:  cfg.Framing,
+		hostname: cfg.Hostname,
+		appName:  cfg.AppName,
+		procID:   cfg.ProcID,
+		sdID:     cfg.SDID,
+		timeout:  cfg.Timeout,
+		logger:   logger,
+	}
+
+	if w.network == "" {
+		w.network = NetworkTCP
+	}
+	switch w.network {
+	case NetworkUDP, NetworkTCP:
+	case NetworkTLS:
+		tlsCfg, err := cfg.TLS.Build()
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog tls config: %w", err)
+		}
+		w.tlsConfig = tlsCfg
+		w.framing = FramingOctetCounting
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q (use udp, tcp or tls)", w.network)
+	}
+
+	if w.framing == "" {
+		w.framing = FramingOctetCounting
+	}
+	if w.framing != FramingOctetCounting && w.framing != FramingNonTransparent {
+		return nil, fmt.Errorf("unsupported syslog framing %q", w.framing)
+	}
+
+	facility := cfg.Facility
+	if facility == "" {
+		facility = "local0"
+	}
+	f, err := ParseFacility(facility)
+	if err != nil {
+		return nil, err
+	}
+	w.facility = f
+
+	w.formatter, err = format.New(cfg.MessageFormat)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog message format: %w", err)
+	}
+
+	w.severityOverrides = make(map[string]int, len(cfg.SeverityOverrides))
+	for code, name := range cfg.SeverityOverrides {
+		sev, err := ParseSeverity(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity override for %q: %w", code, err)
+		}
+		w.severityOverrides[code] = sev
+	}
+
+	if w.hostname == "" {
+		w.hostname, _ = os.Hostname()
+	}
+	if w.appName == "" {
+		w.appName = "eventsproc"
+	}
+	if w.procID == "" {
+		w.procID = strconv.Itoa(os.Getpid())
+	}
+	if w.sdID == "" {
+		w.sdID = "netbird@32473"
+	}
+	if w.timeout == 0 {
+		w.timeout = 10 * time.Second
+	}
+
+	return w, nil
+}
+
+// SendEvents formats every event and writes the batch to the collector.
+// For stream transports the batch is written in one go; if the connection
+// turns out to be broken it is re-dialled and the batch is written once more.
+// Syslog has no application-level acknowledgement, so a successful write means
+// the collector's TCP stack accepted the data. Events that cannot be
+// formatted are skipped and returned in a writer.RejectedError once the rest
+// was written.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	messages := make([]string, 0, len(eventsList))
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		msg, err := w.formatMessage(evt)
+		if err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return writer.RejectUnformatted(rejected, len(eventsList))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.write(ctx, messages); err != nil {
+		return err
+	}
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// write sends messages over the configured transport, re-dialling a broken
+// stream connection once.
+func (w *Writer) write(ctx context.Context, messages []string) error {
+	if w.network == NetworkUDP {
+		return w.sendDatagrams(ctx, messages)
+	}
+
+	payload := w.frame(messages)
+	err := w.writeStream(ctx, payload)
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	w.logger.Warn("Syslog write failed, reconnecting", "address", w.address, "error", err)
+	w.closeConn()
+	if err := w.writeStream(ctx, payload); err != nil {
+		w.closeConn()
+		return fmt.Errorf("syslog write to %s failed after reconnect: %w", w.address, err)
+	}
+	return nil
+}
+
+// SendEvent sends a single event
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the collector connection.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closeConn()
+}
+
+// frame applies the configured RFC 6587 framing to each message.
+func (w *Writer) frame(messages []string) []byte {
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		if w.framing == FramingNonTransparent {
+			// LF is the frame delimiter, so it must not appear inside a message
+			buf.WriteString(strings.ReplaceAll(msg, "\n", " "))
+			buf.WriteByte('\n')
+			continue
+		}
+		buf.WriteString(strconv.Itoa(len(msg)))
+		buf.WriteByte(' ')
+		buf.WriteString(msg)
+	}
+	return buf.Bytes()
+}
+
+// sendDatagrams writes one message per UDP datagram.
+func (w *Writer) sendDatagrams(ctx context.Context, messages []string) error {
+	conn, err := w.connect(ctx)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(w.timeout))
+	for _, msg := range messages {
+		if _, err := conn.Write([]byte(msg)); err != nil {
+			w.closeConn()
+			return fmt.Errorf("syslog udp write to %s failed: %w", w.address, err)
+		}
+	}
+	return nil
+}
+
+// writeStream writes payload on the (possibly new) stream connection.
+func (w *Writer) writeStream(ctx context.Context, payload []byte) error {
+	if w.conn != nil && !w.alive() {
+		w.logger.Info("Syslog collector closed the connection, reconnecting", "address", w.address)
+		w.closeConn()
+	}
+	conn, err := w.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(w.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetWriteDeadline(deadline)
+	_, err = conn.Write(payload)
+	return err
+}
+
+// alive reports whether the stream peer is still connected. Syslog collectors
+// never send data, so a read that returns anything but a timeout means the
+// connection has been closed (EOF) or reset.
+func (w *Writer) alive() bool {
+	_ = w.conn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	var buf [1]byte
+	_, err := w.conn.Read(buf[:])
+	_ = w.conn.SetReadDeadline(time.Time{})
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return err == nil // unexpected data, but the connection is up
+}
+
+// connect returns the current connection, dialling a new one if needed.
+func (w *Writer) connect(ctx context.Context) (net.Conn, error) {
+	if w.conn != nil {
+		return w.conn, nil
+	}
+
+	dialer := &net.Dialer{Timeout: w.timeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch w.network {
+	case NetworkTLS:
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: w.tlsConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", w.address)
+	default:
+		conn, err = dialer.DialContext(ctx, w.network, w.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog collector %s (%s): %w", w.address, w.network, err)
+	}
+
+	w.logger.Debug("Connected to syslog collector", "address", w.address, "network", w.network)
+	w.conn = conn
+	return conn, nil
+}
+
+func (w *Writer) closeConn() error {
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
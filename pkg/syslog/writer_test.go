@@ -0,0 +1,456 @@
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/format/formattest"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 123_000_000, time.UTC)
+	return []events.Event{
+		{ID: 1, Timestamp: ts, Activity: 43, ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorID: "user1", InitiatorEmail: "alice@example.com"},
+		{ID: 2, Timestamp: ts.Add(time.Second), Activity: 6, ActivityCode: "user.role.update", AccountID: "acc1", InitiatorID: "admin", TargetID: "user2"},
+	}
+}
+
+// readOctetCounted reads one RFC 6587 octet-counted frame.
+func readOctetCounted(r *bufio.Reader) (string, error) {
+	lenStr, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// startCollector accepts stream connections on ln and sends every frame read
+// by readFrame to the returned channel.
+func startCollector(t *testing.T, ln net.Listener, readFrame func(*bufio.Reader) (string, error)) <-chan string {
+	t.Helper()
+	t.Cleanup(func() { _ = ln.Close() })
+
+	frames := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer func() { _ = conn.Close() }()
+				r := bufio.NewReader(conn)
+				for {
+					msg, err := readFrame(r)
+					if err != nil {
+						return
+					}
+					frames <- msg
+				}
+			}()
+		}
+	}()
+	return frames
+}
+
+func receive(t *testing.T, frames <-chan string) string {
+	t.Helper()
+	select {
+	case msg := <-frames:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for syslog message")
+		return ""
+	}
+}
+
+func newTestWriter(t *testing.T, cfg *Config) *Writer {
+	t.Helper()
+	if cfg.Hostname == "" {
+		cfg.Hostname = "mgmt-1"
+	}
+	if cfg.ProcID == "" {
+		cfg.ProcID = "42"
+	}
+	w, err := NewWriter(cfg, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	t.Cleanup(func() { _ = w.Close() })
+	return w
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing address", Config{}},
+		{"unknown network", Config{Address: "127.0.0.1:514", Network: "sctp"}},
+		{"unknown framing", Config{Address: "127.0.0.1:514", Framing: "lf"}},
+		{"unknown facility", Config{Address: "127.0.0.1:514", Facility: "local9"}},
+		{"unknown format", Config{Address: "127.0.0.1:514", MessageFormat: "xml"}},
+		{"bad override", Config{Address: "127.0.0.1:514", SeverityOverrides: map[string]string{"dashboard.login": "loud"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWriter(&tt.cfg, testLogger()); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestNewWriter_TLSForcesOctetCounting(t *testing.T) {
+	w := newTestWriter(t, &Config{Address: "127.0.0.1:6514", Network: NetworkTLS, Framing: FramingNonTransparent})
+	if w.framing != FramingOctetCounting {
+		t.Errorf("Expected octet-counting framing for tls, got %s", w.framing)
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	w := newTestWriter(t, &Config{Address: "127.0.0.1:514"})
+
+	msg, err := w.formatMessage(testEvents()[0])
+	if err != nil {
+		t.Fatalf("formatMessage failed: %v", err)
+	}
+
+	// local0 (16) * 8 + informational (6) = 134
+	wantPrefix := `<134>1 2026-01-28T10:30:00.123000Z mgmt-1 eventsproc 42 netbird.audit ` +
+		`[netbird@32473 event_id="1" activity="43" activity_code="dashboard.login" account_id="acc1" ` +
+		`initiator_id="user1" initiator_email="alice@example.com"] ` + utf8BOM + `{`
+	if !strings.HasPrefix(msg, wantPrefix) {
+		t.Errorf("Unexpected message:\n got: %q\nwant prefix: %q", msg, wantPrefix)
+	}
+	if !strings.Contains(msg, `"activity_code":"dashboard.login"`) {
+		t.Errorf("Expected event JSON in MSG, got %q", msg)
+	}
+}
+
+func TestFormatMessage_KV(t *testing.T) {
+	w := newTestWriter(t, &Config{Address: "127.0.0.1:514", MessageFormat: format.NameKV})
+
+	msg, err := w.formatMessage(testEvents()[0])
+	if err != nil {
+		t.Fatalf("formatMessage failed: %v", err)
+	}
+	body := msg[strings.Index(msg, utf8BOM)+len(utf8BOM):]
+	if !strings.HasPrefix(body, `account_id="acc1" activity="43" activity_code="dashboard.login"`) {
+		t.Errorf("Expected sorted key/value pairs, got %q", body)
+	}
+}
+
+func TestFormatMessage_SeverityOverride(t *testing.T) {
+	w := newTestWriter(t, &Config{
+		Address:           "127.0.0.1:514",
+		Facility:          "authpriv",
+		SeverityOverrides: map[string]string{"dashboard.login": "notice"},
+	})
+
+	msg, err := w.formatMessage(testEvents()[0])
+	if err != nil {
+		t.Fatalf("formatMessage failed: %v", err)
+	}
+	// authpriv (10) * 8 + notice (5) = 85
+	if !strings.HasPrefix(msg, "<85>1 ") {
+		t.Errorf("Expected PRI 85, got %q", msg[:8])
+	}
+}
+
+func TestStructuredData_Escaping(t *testing.T) {
+	w := newTestWriter(t, &Config{Address: "127.0.0.1:514"})
+
+	sd := w.structuredData(events.Event{ID: 1, ActivityCode: `x"y]z\`})
+	want := `[netbird@32473 event_id="1" activity="0" activity_code="x\"y\]z\\"]`
+	if sd != want {
+		t.Errorf("structuredData() = %s, want %s", sd, want)
+	}
+}
+
+func TestHeaderField(t *testing.T) {
+	if got := headerField("", 10); got != "-" {
+		t.Errorf("Expected NILVALUE for empty field, got %q", got)
+	}
+	if got := headerField("my host\x01", 10); got != "myhost" {
+		t.Errorf("Expected non-printable characters removed, got %q", got)
+	}
+	if got := headerField("abcdef", 3); got != "abc" {
+		t.Errorf("Expected truncation, got %q", got)
+	}
+}
+
+func TestSeverityForActivity(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"dashboard.login", SeverityInformational},
+		{"user.join", SeverityInformational},
+		{"user.role.update", SeverityWarning},
+		{"transferred.owner.role", SeverityWarning},
+		{"peer.delete", SeverityWarning},
+		{"setupkey.revoke", SeverityWarning},
+		{"user.block", SeverityWarning},
+		{"user.unblock", SeverityNotice},
+		{"policy.add", SeverityNotice},
+		{"route.update", SeverityNotice},
+		{"account.setting.peer.login.expiration.enable", SeverityNotice},
+		{"unknown.code", SeverityInformational},
+	}
+	for _, tt := range tests {
+		if got := SeverityForActivity(tt.code); got != tt.want {
+			t.Errorf("SeverityForActivity(%s) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSendEvents_UDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer func() { _ = pc.Close() }()
+
+	w := newTestWriter(t, &Config{Network: NetworkUDP, Address: pc.LocalAddr().String()})
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	_ = pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 64*1024)
+	for i, wantPRI := range []string{"<134>1 ", "<132>1 "} {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("failed to read datagram %d: %v", i, err)
+		}
+		if !strings.HasPrefix(string(buf[:n]), wantPRI) {
+			t.Errorf("Datagram %d: expected prefix %q, got %q", i, wantPRI, buf[:n])
+		}
+	}
+}
+
+func TestSendEvents_TCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	frames := startCollector(t, ln, readOctetCounted)
+
+	w := newTestWriter(t, &Config{Address: ln.Addr().String()})
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	first := receive(t, frames)
+	second := receive(t, frames)
+	if !strings.Contains(first, `event_id="1"`) || !strings.Contains(second, `event_id="2"`) {
+		t.Errorf("Unexpected frames:\n%s\n%s", first, second)
+	}
+}
+
+func TestSendEvents_TCPNonTransparent(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	frames := startCollector(t, ln, func(r *bufio.Reader) (string, error) {
+		line, err := r.ReadString('\n')
+		return strings.TrimSuffix(line, "\n"), err
+	})
+
+	w := newTestWriter(t, &Config{Address: ln.Addr().String(), Framing: FramingNonTransparent})
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	for i := range 2 {
+		msg := receive(t, frames)
+		if !strings.HasPrefix(msg, "<13") {
+			t.Errorf("Frame %d: expected a syslog message, got %q", i, msg)
+		}
+	}
+}
+
+func TestSendEvents_RejectsUnformattableEvents(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	frames := startCollector(t, ln, readOctetCounted)
+
+	w := newTestWriter(t, &Config{Address: ln.Addr().String()})
+	w.formatter = formattest.FailOn(1)
+
+	err = w.SendEvents(context.Background(), testEvents())
+	formattest.CheckRejected(t, err, 1)
+	if msg := receive(t, frames); !strings.HasSuffix(msg, "event 2") {
+		t.Errorf("Expected the other event to be written, got %q", msg)
+	}
+}
+
+func TestSendEvents_ReconnectsAfterServerClose(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer func() { _ = ln.Close() }()
+
+	frames := make(chan string, 4)
+	go func() {
+		// First connection: read one frame, then hang up.
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		msg, _ := readOctetCounted(bufio.NewReader(conn))
+		frames <- msg
+		_ = conn.Close()
+
+		// Second connection: the writer must come back on its own.
+		conn, err = ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+		msg, _ = readOctetCounted(bufio.NewReader(conn))
+		frames <- msg
+	}()
+
+	w := newTestWriter(t, &Config{Address: ln.Addr().String()})
+	evts := testEvents()
+
+	if err := w.SendEvent(context.Background(), evts[0]); err != nil {
+		t.Fatalf("first SendEvent failed: %v", err)
+	}
+	receive(t, frames)
+
+	time.Sleep(50 * time.Millisecond) // let the FIN arrive
+
+	if err := w.SendEvent(context.Background(), evts[1]); err != nil {
+		t.Fatalf("SendEvent after server close failed: %v", err)
+	}
+	if msg := receive(t, frames); !strings.Contains(msg, `event_id="2"`) {
+		t.Errorf("Expected second event on the new connection, got %q", msg)
+	}
+}
+
+func TestSendEvents_ConnectionRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	w := newTestWriter(t, &Config{Address: addr, Timeout: time.Second})
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected error when collector is down")
+	}
+}
+
+func TestSendEvents_TLS(t *testing.T) {
+	serverCert, caPath := generateServerCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	frames := startCollector(t, ln, readOctetCounted)
+
+	w := newTestWriter(t, &Config{
+		Network: NetworkTLS,
+		Address: ln.Addr().String(),
+		TLS:     tlsutil.Config{CAFile: caPath},
+	})
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if msg := receive(t, frames); !strings.Contains(msg, `event_id="1"`) {
+		t.Errorf("Unexpected TLS frame: %q", msg)
+	}
+	receive(t, frames)
+
+	// The liveness probe must not disturb an established TLS session
+	if err := w.SendEvent(context.Background(), testEvents()[0]); err != nil {
+		t.Fatalf("second SendEvent failed: %v", err)
+	}
+	receive(t, frames)
+}
+
+func TestSendEvents_TLSUntrustedServer(t *testing.T) {
+	serverCert, _ := generateServerCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{serverCert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	startCollector(t, ln, readOctetCounted)
+
+	w := newTestWriter(t, &Config{Network: NetworkTLS, Address: ln.Addr().String(), Timeout: time.Second})
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected certificate verification error")
+	}
+}
+
+// generateServerCert creates a self-signed certificate for 127.0.0.1 and writes
+// it to a temp file so it can be used as the client's CA.
+func generateServerCert(t *testing.T) (tls.Certificate, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "syslog-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	if err := os.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, caPath
+}
@@ -0,0 +1,71 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// Config holds TLS settings shared by the network writers (Loki, Splunk, ...).
+// All fields are optional: an empty Config yields a tls.Config that verifies the
+// server against the system roots and presents no client certificate.
+type Config struct {
+	// CAFile is a PEM bundle used to verify the server certificate.
+	// Leave empty to use the system trust store.
+	CAFile string `mapstructure:"ca_file"`
+
+	// CertFile and KeyFile are the PEM client certificate and private key for
+	// mutual TLS. Both must be set together.
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+
+	// ServerName overrides the hostname used for certificate verification (SNI).
+	ServerName string `mapstructure:"server_name"`
+
+	// InsecureSkipVerify disables server certificate verification.
+	// Only use this in labs — never in production.
+	InsecureSkipVerify bool `mapstructure:"insecure_skip_verify"`
+}
+
+// IsZero reports whether no TLS option has been set.
+func (c Config) IsZero() bool {
+	return c == Config{}
+}
+
+// Build creates a *tls.Config from the settings, loading certificates from disk.
+func (c Config) Build() (*tls.Config, error) {
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+
+	// #nosec G402 -- InsecureSkipVerify is an explicit, documented lab-only opt-in.
+	tlsCfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		// #nosec G304 -- Path is provided by the administrator via config, not by an external user.
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca_file %q: %w", c.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in tls ca_file %q", c.CAFile)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsCfg, nil
+}
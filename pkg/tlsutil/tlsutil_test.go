@@ -0,0 +1,112 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedPair writes a self-signed certificate and its key to dir and
+// returns their paths. The certificate doubles as a CA for the tests below.
+func writeSelfSignedPair(t *testing.T, dir string) (certPath, keyPath string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "eventsproc-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certPath = filepath.Join(dir, "client.crt")
+	keyPath = filepath.Join(dir, "client.key")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestBuild_Empty(t *testing.T) {
+	var c Config
+	if !c.IsZero() {
+		t.Error("Expected empty config to be zero")
+	}
+	tlsCfg, err := c.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if tlsCfg.RootCAs != nil || len(tlsCfg.Certificates) != 0 {
+		t.Error("Expected system roots and no client certificate")
+	}
+}
+
+func TestBuild_ClientCertAndCA(t *testing.T) {
+	certPath, keyPath := writeSelfSignedPair(t, t.TempDir())
+
+	c := Config{CAFile: certPath, CertFile: certPath, KeyFile: keyPath, ServerName: "loki.internal"}
+	tlsCfg, err := c.Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if tlsCfg.RootCAs == nil {
+		t.Error("Expected custom RootCAs")
+	}
+	if len(tlsCfg.Certificates) != 1 {
+		t.Errorf("Expected 1 client certificate, got %d", len(tlsCfg.Certificates))
+	}
+	if tlsCfg.ServerName != "loki.internal" {
+		t.Errorf("Expected ServerName loki.internal, got %q", tlsCfg.ServerName)
+	}
+}
+
+func TestBuild_CertWithoutKey(t *testing.T) {
+	c := Config{CertFile: "/tmp/client.crt"}
+	if _, err := c.Build(); err == nil {
+		t.Error("Expected error when key_file is missing")
+	}
+}
+
+func TestBuild_MissingCAFile(t *testing.T) {
+	c := Config{CAFile: filepath.Join(t.TempDir(), "missing.pem")}
+	if _, err := c.Build(); err == nil {
+		t.Error("Expected error for missing ca_file")
+	}
+}
+
+func TestBuild_InvalidCAFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := Config{CAFile: path}
+	if _, err := c.Build(); err == nil {
+		t.Error("Expected error for ca_file without certificates")
+	}
+}
@@ -0,0 +1,453 @@
+// Package wal is a disk-backed write-ahead buffer between the event reader and
+// the writers. Fetched events are appended to CRC-checked segment files before
+// any writer sees them, and every writer drains the log from its own
+// checkpoint. A sink outage therefore never makes eventsproc re-read the
+// NetBird database, and buffered events survive restarts even if the NetBird
+// events table is pruned in the meantime.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+// Segment files are named "<first event ID, 20 digits>.wal", so a lexical
+// sort orders them by event ID. Each record in a segment is
+//
+//	[4 bytes big-endian payload length][4 bytes CRC-32C of payload][payload]
+//
+// where the payload is one event encoded as JSON.
+const (
+	segmentExt = ".wal"
+	headerSize = 8
+	// maxRecordSize stops a corrupt length field from causing a huge allocation
+	maxRecordSize = 16 << 20
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrFull is returned by Append when the log has reached Config.MaxSize.
+// Appending resumes once the slowest writer has drained enough segments.
+var ErrFull = errors.New("wal is full")
+
+// Config holds configuration for the write-ahead log.
+type Config struct {
+	// Dir holds the segment files. It is created if missing and must not be
+	// shared between consumers.
+	Dir string
+
+	// SegmentSize starts a new segment once the active one reaches this many
+	// bytes. Segments are deleted whole, so smaller segments free disk space
+	// sooner. Default: 16 MiB.
+	SegmentSize int64
+
+	// MaxSize makes Append fail with ErrFull once the log holds this many
+	// bytes. Default: 1 GiB. Negative disables the limit.
+	MaxSize int64
+}
+
+// recordPos locates one record in a segment.
+type recordPos struct {
+	id     int64
+	offset int64
+}
+
+// segment is one segment file and the index of the records it holds.
+type segment struct {
+	path  string
+	size  int64
+	index []recordPos // ascending by id
+}
+
+func (s *segment) firstID() int64 { return s.index[0].id }
+func (s *segment) lastID() int64  { return s.index[len(s.index)-1].id }
+
+// Log is an append-only log of events ordered by event ID. It is safe for
+// concurrent use: one goroutine appends while writers read.
+type Log struct {
+	dir         string
+	segmentSize int64
+	maxSize     int64
+	logger      *slog.Logger
+
+	mu       sync.RWMutex
+	segments []*segment // ordered by event ID; the last one is active
+	active   *os.File   // open for append, nil until the next Append
+	size     int64      // total bytes in all segments
+}
+
+// Open opens the log in cfg.Dir, checking the CRC of every record. A torn or
+// corrupt record (e.g. from a crash during Append) is cut off together with
+// everything after it in its segment, so the log always ends on a complete
+// record.
+func Open(cfg *Config, logger *slog.Logger) (*Log, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("wal dir is required")
+	}
+	segmentSize := cfg.SegmentSize
+	if segmentSize == 0 {
+		segmentSize = 16 << 20
+	}
+	maxSize := cfg.MaxSize
+	if maxSize == 0 {
+		maxSize = 1 << 30
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create wal dir: %w", err)
+	}
+
+	l := &Log{
+		dir:         cfg.Dir,
+		segmentSize: segmentSize,
+		maxSize:     maxSize,
+		logger:      logger,
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.Dir, "*"+segmentExt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wal segments: %w", err)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(path), segmentExt), 10, 64); err != nil {
+			continue // not a segment
+		}
+		seg, err := l.loadSegment(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(seg.index) == 0 {
+			// Created just before a crash, or emptied by recovery
+			if err := os.Remove(path); err != nil {
+				return nil, fmt.Errorf("failed to remove empty wal segment: %w", err)
+			}
+			continue
+		}
+		if n := len(l.segments); n > 0 && seg.firstID() <= l.segments[n-1].lastID() {
+			return nil, fmt.Errorf("wal segment %s overlaps the previous segment", path)
+		}
+		l.segments = append(l.segments, seg)
+		l.size += seg.size
+	}
+
+	if n := len(l.segments); n > 0 {
+		active, err := os.OpenFile(l.segments[n-1].path, os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open wal segment: %w", err)
+		}
+		l.active = active
+	}
+
+	l.logger.Info("Opened write-ahead log",
+		"dir", l.dir,
+		"segments", len(l.segments),
+		"size_bytes", l.size,
+		"first_event_id", l.FirstID(),
+		"last_event_id", l.LastID(),
+	)
+	return l, nil
+}
+
+// loadSegment indexes the records of one segment file, truncating the file
+// at the first record that is incomplete or fails its CRC.
+func (l *Log) loadSegment(path string) (*segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	seg := &segment{path: path}
+	r := bufio.NewReader(f)
+	for {
+		evt, n, err := readRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			l.logger.Warn("Truncating wal segment at corrupt record",
+				"segment", path,
+				"offset", seg.size,
+				"error", err,
+			)
+			if err := os.Truncate(path, seg.size); err != nil {
+				return nil, fmt.Errorf("failed to truncate wal segment: %w", err)
+			}
+			break
+		}
+		if len(seg.index) > 0 && evt.ID <= seg.lastID() {
+			return nil, fmt.Errorf("wal segment %s is not ordered by event ID at offset %d", path, seg.size)
+		}
+		seg.index = append(seg.index, recordPos{id: evt.ID, offset: seg.size})
+		seg.size += n
+	}
+	return seg, nil
+}
+
+// readRecord reads one record and returns the event and the record size.
+// It returns io.EOF at a clean end of segment and an error for a torn or
+// corrupt record.
+func readRecord(r io.Reader) (events.Event, int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF {
+			return events.Event{}, 0, io.EOF
+		}
+		return events.Event{}, 0, fmt.Errorf("incomplete record header: %w", err)
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > maxRecordSize {
+		return events.Event{}, 0, fmt.Errorf("record length %d exceeds limit", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return events.Event{}, 0, fmt.Errorf("incomplete record: %w", err)
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return events.Event{}, 0, fmt.Errorf("record checksum mismatch")
+	}
+	var evt events.Event
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return events.Event{}, 0, fmt.Errorf("failed to decode record: %w", err)
+	}
+	return evt, headerSize + int64(length), nil
+}
+
+// Append writes the events to the active segment and fsyncs it, so once
+// Append returns the events survive a crash. Event IDs must be ascending and
+// greater than LastID. A failed Append leaves the log unchanged.
+func (l *Log) Append(eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lastID := l.lastID()
+	for _, evt := range eventsList {
+		if evt.ID <= lastID {
+			return fmt.Errorf("event %d is not after wal position %d", evt.ID, lastID)
+		}
+		lastID = evt.ID
+	}
+	if l.maxSize > 0 && l.size >= l.maxSize {
+		return ErrFull
+	}
+
+	// Rotate between batches only, so a batch never spans two segments
+	if l.active == nil || l.segments[len(l.segments)-1].size == 0 || l.segments[len(l.segments)-1].size >= l.segmentSize {
+		if err := l.rotate(eventsList[0].ID); err != nil {
+			return err
+		}
+	}
+	seg := l.segments[len(l.segments)-1]
+
+	var buf []byte
+	index := make([]recordPos, 0, len(eventsList))
+	for _, evt := range eventsList {
+		payload, err := json.Marshal(evt)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %d: %w", evt.ID, err)
+		}
+		index = append(index, recordPos{id: evt.ID, offset: seg.size + int64(len(buf))})
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(payload)))
+		buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(payload, crcTable))
+		buf = append(buf, payload...)
+	}
+
+	if _, err := l.active.Write(buf); err != nil {
+		l.rollback(seg)
+		return fmt.Errorf("failed to write wal segment: %w", err)
+	}
+	if err := l.active.Sync(); err != nil {
+		l.rollback(seg)
+		return fmt.Errorf("failed to sync wal segment: %w", err)
+	}
+
+	seg.index = append(seg.index, index...)
+	seg.size += int64(len(buf))
+	l.size += int64(len(buf))
+	return nil
+}
+
+// rollback cuts a partially written batch off the active segment.
+func (l *Log) rollback(seg *segment) {
+	if err := l.active.Truncate(seg.size); err != nil {
+		l.logger.Error("Failed to roll back partial wal write", "segment", seg.path, "error", err)
+	}
+}
+
+// rotate closes the active segment and starts a new one named after firstID.
+// Callers hold mu.
+func (l *Log) rotate(firstID int64) error {
+	if l.active != nil {
+		if err := l.active.Close(); err != nil {
+			return fmt.Errorf("failed to close wal segment: %w", err)
+		}
+		l.active = nil
+	}
+	// An empty segment left by a failed first write is named after an event
+	// that may never be appended, so it is replaced
+	if n := len(l.segments); n > 0 && len(l.segments[n-1].index) == 0 {
+		if err := os.Remove(l.segments[n-1].path); err != nil {
+			return fmt.Errorf("failed to remove empty wal segment: %w", err)
+		}
+		l.segments = l.segments[:n-1]
+	}
+
+	path := filepath.Join(l.dir, fmt.Sprintf("%020d%s", firstID, segmentExt))
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create wal segment: %w", err)
+	}
+	// Persist the directory entry, or the segment could vanish after a crash
+	if dir, err := os.Open(l.dir); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	l.active = f
+	l.segments = append(l.segments, &segment{path: path})
+	return nil
+}
+
+// Read returns up to limit events with an ID greater than afterID, in ID order.
+func (l *Log) Read(afterID int64, limit int) ([]events.Event, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var result []events.Event
+	for _, seg := range l.segments {
+		if len(seg.index) == 0 || seg.lastID() <= afterID {
+			continue
+		}
+		start := sort.Search(len(seg.index), func(i int) bool { return seg.index[i].id > afterID })
+		batch, err := readSegment(seg, start, limit-len(result))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, batch...)
+		if len(result) >= limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// readSegment reads up to limit records of seg, starting at index position start.
+func readSegment(seg *segment, start, limit int) ([]events.Event, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open wal segment: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	end := min(start+limit, len(seg.index))
+	offset := seg.index[start].offset
+	r := bufio.NewReader(io.NewSectionReader(f, offset, seg.size-offset))
+	result := make([]events.Event, 0, end-start)
+	for i := start; i < end; i++ {
+		evt, _, err := readRecord(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read event %d from wal segment %s: %w", seg.index[i].id, seg.path, err)
+		}
+		result = append(result, evt)
+	}
+	return result, nil
+}
+
+// Truncate deletes the segments whose events all have an ID of at most
+// throughID, i.e. that every writer has delivered. When that includes the
+// active segment the next Append starts a new one.
+func (l *Log) Truncate(throughID int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := 0
+	for removed < len(l.segments) {
+		seg := l.segments[removed]
+		if len(seg.index) > 0 && seg.lastID() > throughID {
+			break
+		}
+		if removed == len(l.segments)-1 && l.active != nil {
+			if err := l.active.Close(); err != nil {
+				l.logger.Warn("Failed to close wal segment", "segment", seg.path, "error", err)
+			}
+			l.active = nil
+		}
+		if err := os.Remove(seg.path); err != nil {
+			l.segments = l.segments[removed:]
+			return fmt.Errorf("failed to remove wal segment: %w", err)
+		}
+		l.size -= seg.size
+		removed++
+	}
+	if removed > 0 {
+		l.segments = l.segments[removed:]
+		l.logger.Debug("Removed delivered wal segments", "count", removed, "through_event_id", throughID)
+	}
+	return nil
+}
+
+// FirstID returns the ID of the oldest event in the log, or 0 when it is empty.
+func (l *Log) FirstID() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, seg := range l.segments {
+		if len(seg.index) > 0 {
+			return seg.firstID()
+		}
+	}
+	return 0
+}
+
+// LastID returns the ID of the newest event in the log, or 0 when it is empty.
+func (l *Log) LastID() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.lastID()
+}
+
+// lastID is LastID for callers holding mu.
+func (l *Log) lastID() int64 {
+	for i := len(l.segments) - 1; i >= 0; i-- {
+		if len(l.segments[i].index) > 0 {
+			return l.segments[i].lastID()
+		}
+	}
+	return 0
+}
+
+// Size returns the total size of all segments in bytes.
+func (l *Log) Size() int64 {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.size
+}
+
+// Close closes the active segment. Every Append is already fsynced.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active == nil {
+		return nil
+	}
+	err := l.active.Close()
+	l.active = nil
+	return err
+}
@@ -0,0 +1,244 @@
+package wal
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents(from, to int64) []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	var list []events.Event
+	for id := from; id <= to; id++ {
+		list = append(list, events.Event{ID: id, Timestamp: ts, Activity: 51, ActivityCode: "dashboard.login", AccountID: "acc1", Meta: `{"ip":"10.0.0.1"}`})
+	}
+	return list
+}
+
+func openTestLog(t *testing.T, cfg Config) *Log {
+	t.Helper()
+	l, err := Open(&cfg, testLogger())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l
+}
+
+func ids(list []events.Event) []int64 {
+	var out []int64
+	for _, evt := range list {
+		out = append(out, evt.ID)
+	}
+	return out
+}
+
+func TestOpen_Validation(t *testing.T) {
+	if _, err := Open(&Config{}, testLogger()); err == nil {
+		t.Error("Expected error for missing dir")
+	}
+}
+
+func TestAppendRead_AcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	// Tiny segments: every batch after the first starts a new one
+	l := openTestLog(t, Config{Dir: dir, SegmentSize: 1})
+
+	for _, batch := range [][]events.Event{testEvents(1, 3), testEvents(4, 6), testEvents(10, 12)} {
+		if err := l.Append(batch); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if len(segments) != 3 {
+		t.Errorf("Expected 3 segments, got %d", len(segments))
+	}
+	if l.FirstID() != 1 || l.LastID() != 12 {
+		t.Errorf("Expected IDs 1..12, got %d..%d", l.FirstID(), l.LastID())
+	}
+
+	got, err := l.Read(2, 5)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if want := []int64{3, 4, 5, 6, 10}; !slices.Equal(ids(got), want) {
+		t.Errorf("Expected %v, got %v", want, ids(got))
+	}
+	if got[0].Meta != `{"ip":"10.0.0.1"}` || !got[0].Timestamp.Equal(testEvents(1, 1)[0].Timestamp) {
+		t.Errorf("Event did not round-trip: %+v", got[0])
+	}
+
+	// Reading from between two segments skips the gap
+	got, err = l.Read(7, 100)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if want := []int64{10, 11, 12}; !slices.Equal(ids(got), want) {
+		t.Errorf("Expected %v, got %v", want, ids(got))
+	}
+
+	if got, _ := l.Read(12, 100); len(got) != 0 {
+		t.Errorf("Expected nothing after the last event, got %v", ids(got))
+	}
+}
+
+func TestAppend_RejectsOutOfOrder(t *testing.T) {
+	l := openTestLog(t, Config{Dir: t.TempDir()})
+	if err := l.Append(testEvents(5, 6)); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	if err := l.Append(testEvents(6, 7)); err == nil {
+		t.Error("Expected error for an event at or before the last ID")
+	}
+	if err := l.Append([]events.Event{{ID: 9}, {ID: 8}}); err == nil {
+		t.Error("Expected error for a batch out of ID order")
+	}
+	if l.LastID() != 6 {
+		t.Errorf("Expected rejected batches to leave the log unchanged, last ID %d", l.LastID())
+	}
+}
+
+func TestOpen_RecoversAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(&Config{Dir: dir, SegmentSize: 1}, testLogger())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	_ = l.Append(testEvents(1, 2))
+	_ = l.Append(testEvents(3, 4))
+	_ = l.Close()
+
+	l = openTestLog(t, Config{Dir: dir, SegmentSize: 1})
+	if l.LastID() != 4 {
+		t.Fatalf("Expected last ID 4 after reopen, got %d", l.LastID())
+	}
+	if err := l.Append(testEvents(5, 5)); err != nil {
+		t.Fatalf("Append after reopen failed: %v", err)
+	}
+	got, _ := l.Read(0, 100)
+	if want := []int64{1, 2, 3, 4, 5}; !slices.Equal(ids(got), want) {
+		t.Errorf("Expected %v, got %v", want, ids(got))
+	}
+}
+
+func TestOpen_TruncatesTornTail(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(&Config{Dir: dir}, testLogger())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	_ = l.Append(testEvents(1, 3))
+	path := l.segments[0].path
+	goodSize := l.Size()
+	_ = l.Close()
+
+	// Simulate a crash halfway through the next record
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	_, _ = f.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	_ = f.Close()
+
+	l = openTestLog(t, Config{Dir: dir})
+	if l.LastID() != 3 {
+		t.Errorf("Expected last ID 3, got %d", l.LastID())
+	}
+	if info, _ := os.Stat(path); info.Size() != goodSize {
+		t.Errorf("Expected segment truncated to %d bytes, got %d", goodSize, info.Size())
+	}
+	if err := l.Append(testEvents(4, 4)); err != nil {
+		t.Fatalf("Append after recovery failed: %v", err)
+	}
+	if got, _ := l.Read(3, 10); !slices.Equal(ids(got), []int64{4}) {
+		t.Errorf("Expected event 4 after recovery, got %v", ids(got))
+	}
+}
+
+func TestOpen_DetectsChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(&Config{Dir: dir}, testLogger())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	_ = l.Append(testEvents(1, 3))
+	path := l.segments[0].path
+	secondRecord := l.segments[0].index[1].offset
+	_ = l.Close()
+
+	// Flip a payload byte of the second record
+	data, _ := os.ReadFile(path)
+	data[secondRecord+headerSize+2] ^= 0xff
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	l = openTestLog(t, Config{Dir: dir})
+	if l.LastID() != 1 {
+		t.Errorf("Expected the log to end before the corrupt record, last ID %d", l.LastID())
+	}
+}
+
+func TestTruncate_RemovesDeliveredSegments(t *testing.T) {
+	dir := t.TempDir()
+	l := openTestLog(t, Config{Dir: dir, SegmentSize: 1})
+	_ = l.Append(testEvents(1, 3))
+	_ = l.Append(testEvents(4, 6))
+	_ = l.Append(testEvents(7, 9))
+	before := l.Size()
+
+	// Event 5 is still needed, so only the first segment can go
+	if err := l.Truncate(5); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if l.FirstID() != 4 {
+		t.Errorf("Expected first ID 4, got %d", l.FirstID())
+	}
+	if l.Size() >= before {
+		t.Errorf("Expected size to shrink from %d, got %d", before, l.Size())
+	}
+
+	// A fully delivered log is removed entirely and appending starts over
+	if err := l.Truncate(9); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "*.wal")); len(segments) != 0 || l.Size() != 0 {
+		t.Errorf("Expected no segments left, got %v", segments)
+	}
+	if err := l.Append(testEvents(10, 11)); err != nil {
+		t.Fatalf("Append after truncation failed: %v", err)
+	}
+	if got, _ := l.Read(9, 10); !slices.Equal(ids(got), []int64{10, 11}) {
+		t.Errorf("Expected events 10 and 11, got %v", ids(got))
+	}
+}
+
+func TestAppend_Full(t *testing.T) {
+	l := openTestLog(t, Config{Dir: t.TempDir(), SegmentSize: 1, MaxSize: 1})
+	if err := l.Append(testEvents(1, 2)); err != nil {
+		t.Fatalf("First append failed: %v", err)
+	}
+	if err := l.Append(testEvents(3, 4)); !errors.Is(err, ErrFull) {
+		t.Fatalf("Expected ErrFull, got %v", err)
+	}
+
+	// Once writers have drained the log there is room again
+	if err := l.Truncate(2); err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+	if err := l.Append(testEvents(3, 4)); err != nil {
+		t.Errorf("Expected append to succeed after draining, got %v", err)
+	}
+}
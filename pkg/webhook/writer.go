@@ -0,0 +1,362 @@
+// Package webhook POSTs events to arbitrary HTTP endpoints (ticketing,
+// ChatOps bots, internal services) with a body rendered from a Go template
+// and an HMAC-SHA256 signature receivers can verify.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/format"
+	"github.com/xh63/netbird-events/pkg/tlsutil"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+// Supported values for Config.Mode.
+const (
+	ModeEvent = "event" // one request per event
+	ModeBatch = "batch" // one request per SendEvents batch (default)
+)
+
+// Default signature headers.
+const (
+	DefaultSignatureHeader = "X-Signature-256"
+	DefaultTimestampHeader = "X-Signature-Timestamp"
+)
+
+// Default body templates: the flattened event JSON the stdout writer emits,
+// or a JSON array of them in batch mode.
+const (
+	defaultEventTemplate = `{{eventJSON .}}`
+	defaultBatchTemplate = `[{{range $i, $e := .Events}}{{if $i}},{{end}}{{eventJSON $e}}{{end}}]`
+)
+
+// Config holds configuration for one webhook destination.
+type Config struct {
+	// Name identifies the destination in logs and errors (default: the URL host).
+	Name string
+
+	// URL is the endpoint events are sent to.
+	URL string
+
+	// Method is the HTTP method (default: POST).
+	Method string
+
+	// Headers are sent with every request. Content-Type defaults to application/json.
+	Headers map[string]string
+
+	// Mode is "batch" (default, one request per batch) or "event" (one request per event).
+	Mode string
+
+	// Template is a text/template for the request body. In event mode it is
+	// executed with an events.Event, in batch mode with {Events, Count}.
+	// Available functions: json, eventJSON, severity, meta.
+	// Default: the flattened event JSON (a JSON array in batch mode).
+	Template string
+
+	// Secret enables request signing. The signature header carries
+	// "sha256=" + hex(HMAC-SHA256(secret, timestamp + "." + body)) and the
+	// timestamp header the Unix time in seconds, so receivers can reject replays.
+	Secret string
+
+	// SignatureHeader and TimestampHeader name the signing headers
+	// (defaults: X-Signature-256 and X-Signature-Timestamp).
+	SignatureHeader string
+	TimestampHeader string
+
+	// Timeout bounds each request to this destination. Default: 10s.
+	Timeout time.Duration
+
+	// TLS configures server verification and mutual TLS.
+	TLS tlsutil.Config
+}
+
+// Writer sends events to one webhook destination. Any non-2xx response fails
+// the batch, so the processor does not advance the checkpoint past events the
+// receiver did not accept.
+type Writer struct {
+	client          *http.Client
+	name            string
+	url             string
+	method          string
+	headers         map[string]string
+	mode            string
+	tmpl            *template.Template
+	secret          []byte
+	signatureHeader string
+	timestampHeader string
+	logger          *slog.Logger
+
+	// now is the signing clock (replaced in tests).
+	now func() time.Time
+}
+
+// batchData is the template input in batch mode.
+type batchData struct {
+	Events []events.Event
+	Count  int
+}
+
+// templateFuncs are available to body templates.
+var templateFuncs = template.FuncMap{
+	// json encodes any value as JSON, e.g. {{json .ActivityName}} for a quoted string
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// eventJSON renders an event as the flattened JSON line the stdout writer emits
+	"eventJSON": format.EventToJSON,
+	// severity returns "info", "notice" or "warning" for an event
+	"severity": func(evt events.Event) string {
+		return format.ActivitySeverity(evt.ActivityCode).String()
+	},
+	// meta returns the event meta as a map, e.g. {{index (meta .) "name"}}
+	"meta": func(evt events.Event) map[string]any {
+		m := map[string]any{}
+		for _, f := range format.FlattenMeta(evt.Meta) {
+			m[f.Key] = f.Value
+		}
+		return m
+	},
+}
+
+// NewWriter creates a webhook writer. The template is parsed here so syntax
+// errors fail at startup; the destination is not contacted.
+func NewWriter(cfg *Config, logger *slog.Logger) (*Writer, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook url is required")
+	}
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, nil)
+	if err != nil || req.URL.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", cfg.URL)
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name = req.URL.Host
+	}
+
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	mode := cfg.Mode
+	if mode == "" {
+		mode = ModeBatch
+	}
+	body := cfg.Template
+	switch mode {
+	case ModeEvent:
+		if body == "" {
+			body = defaultEventTemplate
+		}
+	case ModeBatch:
+		if body == "" {
+			body = defaultBatchTemplate
+		}
+	default:
+		return nil, fmt.Errorf("unsupported webhook mode %q (use event or batch)", mode)
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template for %s: %w", name, err)
+	}
+
+	signatureHeader := cfg.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = DefaultSignatureHeader
+	}
+	timestampHeader := cfg.TimestampHeader
+	if timestampHeader == "" {
+		timestampHeader = DefaultTimestampHeader
+	}
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	tlsCfg, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook tls config: %w", err)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsCfg
+
+	var secret []byte
+	if cfg.Secret != "" {
+		secret = []byte(cfg.Secret)
+	}
+
+	return &Writer{
+		client:          &http.Client{Timeout: timeout, Transport: transport},
+		name:            name,
+		url:             cfg.URL,
+		method:          method,
+		headers:         cfg.Headers,
+		mode:            mode,
+		tmpl:            tmpl,
+		secret:          secret,
+		signatureHeader: signatureHeader,
+		timestampHeader: timestampHeader,
+		logger:          logger,
+		now:             time.Now,
+	}, nil
+}
+
+// SendEvents delivers a batch: one request in batch mode, or one request per
+// event in event mode. Event mode stops at the first failure; the whole batch
+// is then retried, so receivers should deduplicate on the event ID. Events
+// the template cannot render are skipped and returned in a
+// writer.RejectedError once the rest was delivered.
+func (w *Writer) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	if len(eventsList) == 0 {
+		return nil
+	}
+
+	var rejected []writer.Rejection
+	if w.mode == ModeBatch {
+		var body []byte
+		var err error
+		body, rejected, err = w.renderBatch(eventsList)
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			if err := w.send(ctx, body); err != nil {
+				return err
+			}
+		}
+	} else {
+		for _, evt := range eventsList {
+			body, err := w.render(evt)
+			if err != nil {
+				rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+				continue
+			}
+			if err := w.send(ctx, body); err != nil {
+				return fmt.Errorf("event %d: %w", evt.ID, err)
+			}
+		}
+	}
+
+	w.logger.Debug("Sent events to webhook", "webhook", w.name, "count", len(eventsList)-len(rejected))
+	return writer.RejectUnformatted(rejected, len(eventsList))
+}
+
+// SendEvent sends a single event to the webhook
+func (w *Writer) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Name returns the destination name (Config.Name, or the URL host).
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Close releases idle HTTP connections. Nothing is buffered, so there is nothing to flush.
+func (w *Writer) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+// renderBatch renders the batch mode body. When the template fails on the
+// batch, every event is rendered alone to find the ones it fails on, and the
+// body is rendered from the others; it is nil when none is left.
+func (w *Writer) renderBatch(eventsList []events.Event) ([]byte, []writer.Rejection, error) {
+	body, err := w.render(batchData{Events: eventsList, Count: len(eventsList)})
+	if err == nil {
+		return body, nil, nil
+	}
+
+	var rendered []events.Event
+	var rejected []writer.Rejection
+	for _, evt := range eventsList {
+		if _, err := w.render(batchData{Events: []events.Event{evt}, Count: 1}); err != nil {
+			rejected = append(rejected, writer.Rejection{Event: evt, Reason: err.Error()})
+			continue
+		}
+		rendered = append(rendered, evt)
+	}
+	if len(rejected) == 0 {
+		// The template fails on the batch as a whole, not on any event
+		return nil, nil, err
+	}
+	if len(rendered) == 0 {
+		return nil, rejected, nil
+	}
+	body, err = w.render(batchData{Events: rendered, Count: len(rendered)})
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, rejected, nil
+}
+
+// render executes the template with data.
+func (w *Writer) render(data any) ([]byte, error) {
+	var body bytes.Buffer
+	if err := w.tmpl.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("failed to render webhook %s body: %w", w.name, err)
+	}
+	return body.Bytes(), nil
+}
+
+// send delivers one request with the rendered body.
+func (w *Writer) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook %s request: %w", w.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eventsproc")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if w.secret != nil {
+		ts := strconv.FormatInt(w.now().Unix(), 10)
+		req.Header.Set(w.timestampHeader, ts)
+		req.Header.Set(w.signatureHeader, "sha256="+Sign(w.secret, ts, body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook %s request failed: %w", w.name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("webhook %s returned %s: %s", w.name, resp.Status, strings.TrimSpace(string(msg)))
+		if writer.IsPermanentStatus(resp.StatusCode) {
+			return writer.Permanent(err)
+		}
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of timestamp + "." + body. Receivers
+// recompute it with the shared secret, compare with hmac.Equal and reject
+// timestamps outside their tolerance window.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
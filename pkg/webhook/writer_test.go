@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/writer"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvents() []events.Event {
+	ts := time.Date(2026, 1, 28, 10, 30, 0, 0, time.UTC)
+	return []events.Event{
+		{ID: 1, Timestamp: ts, Activity: 51, ActivityName: "Dashboard login", ActivityCode: "dashboard.login", AccountID: "acc1", InitiatorEmail: "alice@example.com"},
+		{ID: 2, Timestamp: ts, Activity: 11, ActivityName: "User role updated", ActivityCode: "user.role.update", AccountID: "acc1", Meta: `{"role":"admin"}`},
+	}
+}
+
+type request struct {
+	method string
+	header http.Header
+	body   string
+}
+
+// receiver records webhook requests and answers with status.
+type receiver struct {
+	mu       sync.Mutex
+	requests []request
+	status   int
+}
+
+func (rc *receiver) serve(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		rc.requests = append(rc.requests, request{method: r.Method, header: r.Header.Clone(), body: string(body)})
+		if rc.status != 0 {
+			http.Error(w, "nope", rc.status)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewWriter_Validation(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing url", Config{}},
+		{"bad mode", Config{URL: "http://hooks.example.com", Mode: "stream"}},
+		{"bad template", Config{URL: "http://hooks.example.com", Template: "{{.Nope"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWriter(&tt.cfg, testLogger()); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestSendEvents_DefaultBatchBody(t *testing.T) {
+	rc := &receiver{}
+	srv := rc.serve(t)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Headers: map[string]string{"X-Team": "secops"}}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if len(rc.requests) != 1 {
+		t.Fatalf("Expected 1 request in batch mode, got %d", len(rc.requests))
+	}
+	req := rc.requests[0]
+	if req.method != http.MethodPost || req.header.Get("X-Team") != "secops" || req.header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected request %s %v", req.method, req.header)
+	}
+	if req.header.Get(DefaultSignatureHeader) != "" {
+		t.Error("Expected no signature without a secret")
+	}
+	var docs []map[string]any
+	if err := json.Unmarshal([]byte(req.body), &docs); err != nil {
+		t.Fatalf("Expected JSON array body, got %q: %v", req.body, err)
+	}
+	if len(docs) != 2 || docs[1]["meta_role"] != "admin" {
+		t.Errorf("Unexpected body %v", docs)
+	}
+}
+
+func TestSendEvents_EventModeTemplate(t *testing.T) {
+	rc := &receiver{}
+	srv := rc.serve(t)
+
+	w, err := NewWriter(&Config{
+		URL:      srv.URL,
+		Method:   "put",
+		Mode:     ModeEvent,
+		Template: `{"text": {{json (printf "%s by %s" .ActivityName .InitiatorEmail)}}, "severity": "{{severity .}}", "role": {{json (index (meta .) "role")}}}`,
+	}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	if len(rc.requests) != 2 {
+		t.Fatalf("Expected one request per event, got %d", len(rc.requests))
+	}
+	if rc.requests[0].method != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", rc.requests[0].method)
+	}
+	want := `{"text": "Dashboard login by alice@example.com", "severity": "info", "role": null}`
+	if rc.requests[0].body != want {
+		t.Errorf("body = %s, want %s", rc.requests[0].body, want)
+	}
+	if !strings.Contains(rc.requests[1].body, `"severity": "warning", "role": "admin"`) {
+		t.Errorf("Unexpected body %s", rc.requests[1].body)
+	}
+}
+
+// TestSendEvents_RejectsUnrenderableEvents verifies that an event the
+// template fails on is rejected and the others are still delivered.
+func TestSendEvents_RejectsUnrenderableEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		template string
+		want     string
+	}{
+		{"event", ModeEvent, `{{(meta .).role}}`, "admin"},
+		{"batch", ModeBatch, `{{range .Events}}{{(meta .).role}};{{end}}{{.Count}}`, "admin;1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rc := &receiver{}
+			srv := rc.serve(t)
+
+			w, err := NewWriter(&Config{URL: srv.URL, Mode: tt.mode, Template: tt.template}, testLogger())
+			if err != nil {
+				t.Fatalf("NewWriter failed: %v", err)
+			}
+			err = w.SendEvents(context.Background(), testEvents())
+			var rejected *writer.RejectedError
+			if !errors.As(err, &rejected) || len(rejected.Rejected) != 1 || rejected.Rejected[0].Event.ID != 1 {
+				t.Fatalf("Expected event 1 without meta to be rejected, got %v", err)
+			}
+			if len(rc.requests) != 1 || rc.requests[0].body != tt.want {
+				t.Errorf("Expected one request with %q, got %+v", tt.want, rc.requests)
+			}
+		})
+	}
+}
+
+func TestSendEvents_Signature(t *testing.T) {
+	rc := &receiver{}
+	srv := rc.serve(t)
+
+	w, err := NewWriter(&Config{URL: srv.URL, Secret: "s3cret", SignatureHeader: "X-Hub-Signature-256"}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	w.now = func() time.Time { return time.Unix(1769596200, 0) }
+	if err := w.SendEvents(context.Background(), testEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+
+	req := rc.requests[0]
+	ts := req.header.Get(DefaultTimestampHeader)
+	if ts != "1769596200" {
+		t.Errorf("Expected timestamp header, got %q", ts)
+	}
+	want := "sha256=" + Sign([]byte("s3cret"), ts, []byte(req.body))
+	if got := req.header.Get("X-Hub-Signature-256"); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	// A replayed body with a different timestamp must not verify
+	if Sign([]byte("s3cret"), "1769596201", []byte(req.body)) == Sign([]byte("s3cret"), ts, []byte(req.body)) {
+		t.Error("Expected signature to depend on the timestamp")
+	}
+}
+
+func TestSendEvents_Non2xxIsError(t *testing.T) {
+	rc := &receiver{status: http.StatusBadGateway}
+	srv := rc.serve(t)
+
+	w, err := NewWriter(&Config{Name: "tickets", URL: srv.URL, Mode: ModeEvent}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	err = w.SendEvents(context.Background(), testEvents())
+	if err == nil {
+		t.Fatal("Expected error for 502 response")
+	}
+	if !strings.Contains(err.Error(), "tickets") || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Expected error naming the destination and status, got %v", err)
+	}
+	if len(rc.requests) != 1 {
+		t.Errorf("Expected event mode to stop at the first failure, got %d requests", len(rc.requests))
+	}
+	if writer.IsPermanent(err) {
+		t.Error("Expected a 502 to be retryable")
+	}
+
+	rc.mu.Lock()
+	rc.status = http.StatusBadRequest
+	rc.mu.Unlock()
+	if err := w.SendEvents(context.Background(), testEvents()); !writer.IsPermanent(err) {
+		t.Errorf("Expected a 400 to be permanent, got %v", err)
+	}
+}
+
+func TestSendEvents_Timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(&Config{URL: srv.URL, Timeout: 20 * time.Millisecond}, testLogger())
+	if err != nil {
+		t.Fatalf("NewWriter failed: %v", err)
+	}
+	if err := w.SendEvents(context.Background(), testEvents()); err == nil {
+		t.Error("Expected timeout error")
+	}
+}
@@ -0,0 +1,362 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// ErrCircuitOpen is returned without calling the sink while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState is the state of a RetryWriter's circuit breaker. The values
+// are exported as the eventsproc_writer_circuit_state gauge.
+type CircuitState int
+
+const (
+	// CircuitClosed passes every batch to the sink.
+	CircuitClosed CircuitState = 0
+	// CircuitHalfOpen lets a single trial batch through after OpenTimeout.
+	CircuitHalfOpen CircuitState = 1
+	// CircuitOpen fails every batch at once until OpenTimeout has passed.
+	CircuitOpen CircuitState = 2
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// permanentError marks an error retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as fatal: a RetryWriter returns it at once instead of
+// retrying, e.g. for a request the sink rejected as invalid.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err, or the error it wraps, was marked with
+// Permanent or is a RejectedError. A joined error, e.g. from a MultiWriter,
+// is permanent only when every error in it is, as retrying fixes the others.
+func IsPermanent(err error) bool {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *permanentError, *RejectedError:
+		return true
+	case interface{ Unwrap() []error }:
+		errs := e.Unwrap()
+		for _, err := range errs {
+			if !IsPermanent(err) {
+				return false
+			}
+		}
+		return len(errs) > 0
+	case interface{ Unwrap() error }:
+		return IsPermanent(e.Unwrap())
+	}
+	return false
+}
+
+// IsPermanentStatus reports whether an HTTP status means the request content
+// was rejected: any 4xx except timeouts, rate limiting and the statuses that
+// point at eventsproc's configuration (authentication, permissions, a wrong
+// URL), which no event can be blamed for.
+func IsPermanentStatus(code int) bool {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusProxyAuthRequired,
+		http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return false
+	}
+	return code >= 400 && code < 500
+}
+
+// Rejection is one event a sink refused for good, and why.
+type Rejection struct {
+	Event  events.Event
+	Reason string
+}
+
+// RejectedError reports events a writer permanently rejected while it
+// delivered the rest of the batch. The processor dead-letters the rejected
+// events and moves on; a RetryWriter does not retry it.
+type RejectedError struct {
+	Err      error
+	Rejected []Rejection
+}
+
+func (e *RejectedError) Error() string { return e.Err.Error() }
+func (e *RejectedError) Unwrap() error { return e.Err }
+
+// Reject returns a RejectedError for the given events, described by err.
+func Reject(err error, rejected ...Rejection) error {
+	return &RejectedError{Err: err, Rejected: rejected}
+}
+
+// RejectUnformatted returns a RejectedError for the events of a batch of
+// total that could not be formatted, or nil when there are none. Writers skip
+// those events, send the rest and return this once that succeeded.
+func RejectUnformatted(rejected []Rejection, total int) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+	return Reject(fmt.Errorf("failed to format %d of %d events", len(rejected), total), rejected...)
+}
+
+// RetryConfig holds configuration for a RetryWriter.
+type RetryConfig struct {
+	// MaxAttempts is how many times a batch is sent, including the first
+	// attempt, before SendEvents gives up. Default: 3.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry, doubled on every
+	// further retry up to MaxBackoff. Each delay is jittered between half and
+	// the full value. Defaults: 1s and 30s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// FailureThreshold opens the circuit breaker after this many consecutive
+	// SendEvents calls failed with retryable errors. Default: 5. Negative
+	// disables the breaker.
+	FailureThreshold int
+
+	// OpenTimeout is how long the breaker stays open before one trial batch
+	// is let through. Default: 60s.
+	OpenTimeout time.Duration
+}
+
+// RetryWriter wraps an EventWriter with retries and a circuit breaker.
+// Errors marked with Permanent are returned without retrying and do not count
+// against the breaker, as the sink did answer. While the breaker is open
+// SendEvents fails with ErrCircuitOpen without touching the sink, so a dead
+// sink costs one cheap error per poll instead of a timeout per attempt.
+type RetryWriter struct {
+	name             string
+	next             EventWriter
+	maxAttempts      int
+	initialBackoff   time.Duration
+	maxBackoff       time.Duration
+	failureThreshold int
+	openTimeout      time.Duration
+	logger           *slog.Logger
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int       // consecutive failed calls while closed
+	openedAt time.Time // when the breaker last opened
+
+	// now and sleep are replaced in tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetryWriter wraps next. name labels the log lines and metrics, normally
+// the checkpoint writer_type of the sink.
+func NewRetryWriter(name string, next EventWriter, cfg *RetryConfig, logger *slog.Logger) *RetryWriter {
+	maxAttempts := cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	initialBackoff := cfg.InitialBackoff
+	if initialBackoff <= 0 {
+		initialBackoff = time.Second
+	}
+	maxBackoff := cfg.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	failureThreshold := cfg.FailureThreshold
+	if failureThreshold == 0 {
+		failureThreshold = 5
+	}
+	openTimeout := cfg.OpenTimeout
+	if openTimeout <= 0 {
+		openTimeout = time.Minute
+	}
+
+	metrics.WriterCircuitState.WithLabelValues(name).Set(float64(CircuitClosed))
+	return &RetryWriter{
+		name:             name,
+		next:             next,
+		maxAttempts:      maxAttempts,
+		initialBackoff:   initialBackoff,
+		maxBackoff:       max(maxBackoff, initialBackoff),
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		logger:           logger,
+		now:              time.Now,
+		sleep:            sleepContext,
+	}
+}
+
+// SendEvents sends the batch, retrying retryable failures with exponential
+// backoff. A trial batch in the half-open state is sent once.
+func (w *RetryWriter) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	trial, err := w.acquire()
+	if err != nil {
+		return err
+	}
+	attempts := w.maxAttempts
+	if trial {
+		attempts = 1
+	}
+
+	backoff := w.initialBackoff
+	attempt := 1
+	for {
+		err = w.next.SendEvents(ctx, eventsList)
+		if err == nil || IsPermanent(err) || ctx.Err() != nil || attempt >= attempts {
+			break
+		}
+
+		delay := jitter(backoff)
+		w.logger.Warn("Writer failed, retrying",
+			"writer", w.name,
+			"attempt", attempt,
+			"delay", delay,
+			"error", err,
+		)
+		metrics.WriterRetries.WithLabelValues(w.name).Inc()
+		if w.sleep(ctx, delay) != nil {
+			break
+		}
+		backoff = min(backoff*2, w.maxBackoff)
+		attempt++
+	}
+
+	w.record(ctx, err)
+	if err != nil && attempt > 1 {
+		return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+	}
+	return err
+}
+
+// SendEvent sends a single event
+func (w *RetryWriter) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the wrapped writer.
+func (w *RetryWriter) Close() error {
+	return w.next.Close()
+}
+
+// State returns the current circuit breaker state.
+func (w *RetryWriter) State() CircuitState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.state
+}
+
+// acquire checks the breaker before a call. It reports whether the call is
+// the half-open trial, or returns ErrCircuitOpen when the call must not reach
+// the sink.
+func (w *RetryWriter) acquire() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch w.state {
+	case CircuitOpen:
+		if wait := w.openTimeout - w.now().Sub(w.openedAt); wait > 0 {
+			return false, fmt.Errorf("writer %s: %w (retrying in %s)", w.name, ErrCircuitOpen, wait.Round(time.Second))
+		}
+		w.setState(CircuitHalfOpen)
+		return true, nil
+	case CircuitHalfOpen:
+		// Another call is running the trial
+		return false, fmt.Errorf("writer %s: %w", w.name, ErrCircuitOpen)
+	}
+	return false, nil
+}
+
+// record updates the breaker with the outcome of a call.
+func (w *RetryWriter) record(ctx context.Context, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	switch {
+	case err == nil || IsPermanent(err):
+		w.failures = 0
+		if w.state != CircuitClosed {
+			w.logger.Info("Circuit breaker closed", "writer", w.name)
+			w.setState(CircuitClosed)
+		}
+	case ctx.Err() != nil:
+		// Shutting down says nothing about the sink; an interrupted trial
+		// is simply run again by the next call
+		if w.state == CircuitHalfOpen {
+			w.setState(CircuitOpen)
+		}
+	case w.failureThreshold < 0:
+	case w.state == CircuitHalfOpen:
+		w.open(err)
+	default:
+		w.failures++
+		if w.failures >= w.failureThreshold {
+			w.open(err)
+		}
+	}
+}
+
+// open trips the breaker. Callers hold mu.
+func (w *RetryWriter) open(err error) {
+	w.logger.Warn("Circuit breaker opened",
+		"writer", w.name,
+		"consecutive_failures", w.failures,
+		"open_timeout", w.openTimeout,
+		"error", err,
+	)
+	w.failures = 0
+	w.openedAt = w.now()
+	w.setState(CircuitOpen)
+}
+
+// setState changes the breaker state and its gauge. Callers hold mu.
+func (w *RetryWriter) setState(s CircuitState) {
+	w.state = s
+	metrics.WriterCircuitState.WithLabelValues(w.name).Set(float64(s))
+}
+
+// jitter returns a random delay between half of d and d, so writers that
+// failed together do not retry in lockstep.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// sleepContext waits for d or until ctx is cancelled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
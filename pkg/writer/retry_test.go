@@ -0,0 +1,186 @@
+package writer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// flakyWriter returns the queued errors in order, then succeeds.
+type flakyWriter struct {
+	MockWriter
+	errs  []error
+	calls int
+}
+
+func (f *flakyWriter) SendEvents(_ context.Context, _ []events.Event) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+// newTestRetryWriter returns a RetryWriter with a fake clock whose sleeps
+// advance the clock and are recorded instead of waiting.
+func newTestRetryWriter(name string, next EventWriter, cfg RetryConfig) (*RetryWriter, *time.Time, *[]time.Duration) {
+	w := NewRetryWriter(name, next, &cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	clock := time.Date(2026, 1, 28, 10, 0, 0, 0, time.UTC)
+	var delays []time.Duration
+	w.now = func() time.Time { return clock }
+	w.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		clock = clock.Add(d)
+		return ctx.Err()
+	}
+	return w, &clock, &delays
+}
+
+func TestRetryWriter_RetriesTransientErrors(t *testing.T) {
+	next := &flakyWriter{errs: []error{errors.New("503"), errors.New("503")}}
+	w, _, delays := newTestRetryWriter("loki", next, RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second})
+
+	if err := w.SendEvents(context.Background(), []events.Event{{ID: 1}}); err != nil {
+		t.Fatalf("Expected success on the third attempt, got %v", err)
+	}
+	if next.calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", next.calls)
+	}
+	if len(*delays) != 2 {
+		t.Fatalf("Expected 2 backoff delays, got %v", *delays)
+	}
+	// Exponential backoff with jitter between half and the full delay
+	for i, want := range []time.Duration{time.Second, 2 * time.Second} {
+		if d := (*delays)[i]; d < want/2 || d > want {
+			t.Errorf("Delay %d: expected between %s and %s, got %s", i, want/2, want, d)
+		}
+	}
+}
+
+func TestRetryWriter_GivesUp(t *testing.T) {
+	next := &flakyWriter{errs: []error{errors.New("503"), errors.New("503"), errors.New("503 again")}}
+	w, _, _ := newTestRetryWriter("splunk", next, RetryConfig{MaxAttempts: 3})
+
+	err := w.SendEvents(context.Background(), []events.Event{{ID: 1}})
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") || !strings.Contains(err.Error(), "503 again") {
+		t.Errorf("Expected error after 3 attempts with the last cause, got %v", err)
+	}
+}
+
+func TestRetryWriter_PermanentErrorNotRetried(t *testing.T) {
+	next := &flakyWriter{errs: []error{Permanent(errors.New("400 Bad Request"))}}
+	w, _, _ := newTestRetryWriter("webhook:siem", next, RetryConfig{MaxAttempts: 5, FailureThreshold: 1})
+
+	err := w.SendEvents(context.Background(), []events.Event{{ID: 1}})
+	if !IsPermanent(err) {
+		t.Errorf("Expected the permanent error, got %v", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("Expected a single call, got %d", next.calls)
+	}
+	if w.State() != CircuitClosed {
+		t.Errorf("Expected permanent errors not to open the breaker, got %s", w.State())
+	}
+}
+
+func TestRetryWriter_RejectedEventsNotRetried(t *testing.T) {
+	next := &flakyWriter{errs: []error{Reject(errors.New("1 event rejected"), Rejection{Event: events.Event{ID: 2}, Reason: "bad"})}}
+	w, _, _ := newTestRetryWriter("elasticsearch", next, RetryConfig{MaxAttempts: 3})
+
+	err := w.SendEvents(context.Background(), []events.Event{{ID: 1}, {ID: 2}})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || len(rejected.Rejected) != 1 || rejected.Rejected[0].Event.ID != 2 {
+		t.Errorf("Expected the rejected event to be passed through, got %v", err)
+	}
+	if next.calls != 1 {
+		t.Errorf("Expected a single call, got %d", next.calls)
+	}
+}
+
+func TestRetryWriter_CircuitBreaker(t *testing.T) {
+	next := &flakyWriter{errs: []error{errors.New("down"), errors.New("down"), errors.New("still down")}}
+	w, clock, _ := newTestRetryWriter("elasticsearch", next, RetryConfig{MaxAttempts: 1, FailureThreshold: 2, OpenTimeout: time.Minute})
+	ctx := context.Background()
+	batch := []events.Event{{ID: 1}}
+	gauge := metrics.WriterCircuitState.WithLabelValues("elasticsearch")
+
+	_ = w.SendEvents(ctx, batch)
+	if w.State() != CircuitClosed {
+		t.Fatalf("Expected closed after one failure, got %s", w.State())
+	}
+	_ = w.SendEvents(ctx, batch)
+	if w.State() != CircuitOpen || testutil.ToFloat64(gauge) != 2 {
+		t.Fatalf("Expected open after two failures, got %s", w.State())
+	}
+
+	// Open: the sink is not called
+	if err := w.SendEvents(ctx, batch); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if next.calls != 2 {
+		t.Errorf("Expected no call while open, got %d calls", next.calls)
+	}
+
+	// After the timeout a failing trial opens the breaker again
+	*clock = clock.Add(time.Minute)
+	_ = w.SendEvents(ctx, batch)
+	if next.calls != 3 || w.State() != CircuitOpen {
+		t.Errorf("Expected one trial call and the breaker open again, got %d calls, %s", next.calls, w.State())
+	}
+
+	// A successful trial closes it
+	*clock = clock.Add(time.Minute)
+	if err := w.SendEvents(ctx, batch); err != nil {
+		t.Fatalf("Expected the trial to succeed, got %v", err)
+	}
+	if w.State() != CircuitClosed || testutil.ToFloat64(gauge) != 0 {
+		t.Errorf("Expected closed after a successful trial, got %s", w.State())
+	}
+}
+
+func TestRetryWriter_BreakerDisabled(t *testing.T) {
+	next := &flakyWriter{errs: []error{errors.New("down"), errors.New("down"), errors.New("down")}}
+	w, _, _ := newTestRetryWriter("kafka", next, RetryConfig{MaxAttempts: 1, FailureThreshold: -1})
+
+	for range 3 {
+		_ = w.SendEvents(context.Background(), []events.Event{{ID: 1}})
+	}
+	if w.State() != CircuitClosed || next.calls != 3 {
+		t.Errorf("Expected every call to reach the sink, got %d calls, %s", next.calls, w.State())
+	}
+}
+
+func TestRetryWriter_StopsOnCancel(t *testing.T) {
+	next := &flakyWriter{errs: []error{errors.New("503"), errors.New("503")}}
+	w, _, _ := newTestRetryWriter("otlp", next, RetryConfig{MaxAttempts: 5, FailureThreshold: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := w.SendEvents(ctx, []events.Event{{ID: 1}}); err == nil {
+		t.Error("Expected error")
+	}
+	if next.calls != 1 {
+		t.Errorf("Expected no retries after cancel, got %d calls", next.calls)
+	}
+	if w.State() != CircuitClosed {
+		t.Errorf("Expected cancellation not to open the breaker, got %s", w.State())
+	}
+}
+
+func TestIsPermanentStatus(t *testing.T) {
+	for code, want := range map[int]bool{400: true, 413: true, 422: true, 401: false, 403: false, 404: false, 408: false, 429: false, 500: false, 503: false, 200: false} {
+		if got := IsPermanentStatus(code); got != want {
+			t.Errorf("IsPermanentStatus(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
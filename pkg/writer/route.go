@@ -0,0 +1,202 @@
+package writer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+// Rule matches events. Every non-empty field must match; within a field any
+// entry may match.
+type Rule struct {
+	// ActivityCodes are globs on the activity code, e.g. "user.*" or
+	// "setupkey.*" (see ValidateGlob).
+	ActivityCodes []string
+
+	// AccountIDs are exact account IDs.
+	AccountIDs []string
+
+	// Meta maps a top-level meta key to a glob on its value, e.g.
+	// {"os": "linux*"} or {"ip": "10.*"}. Non-string values are compared in
+	// their JSON form.
+	Meta map[string]string
+}
+
+// Routes selects the events a writer receives.
+type Routes struct {
+	// Include passes events matching any rule; empty passes every event.
+	Include []Rule
+
+	// Exclude drops events matching any rule, even when included.
+	Exclude []Rule
+}
+
+// Match reports whether evt is routed to the writer.
+func (r *Routes) Match(evt events.Event) bool {
+	var meta map[string]any
+	parsed := false
+	metaValue := func(key string) (string, bool) {
+		if !parsed {
+			parsed = true
+			_ = json.Unmarshal([]byte(evt.Meta), &meta)
+		}
+		v, ok := meta[key]
+		if !ok {
+			return "", false
+		}
+		if s, ok := v.(string); ok {
+			return s, true
+		}
+		b, _ := json.Marshal(v)
+		return string(b), true
+	}
+	matchesAny := func(rules []Rule) bool {
+		return slices.ContainsFunc(rules, func(rule Rule) bool { return rule.match(evt, metaValue) })
+	}
+
+	if len(r.Include) > 0 && !matchesAny(r.Include) {
+		return false
+	}
+	return !matchesAny(r.Exclude)
+}
+
+// match reports whether evt satisfies every field of the rule.
+func (rule *Rule) match(evt events.Event, metaValue func(key string) (string, bool)) bool {
+	if len(rule.ActivityCodes) > 0 && !slices.ContainsFunc(rule.ActivityCodes, func(p string) bool {
+		return globMatch(p, evt.ActivityCode)
+	}) {
+		return false
+	}
+	if len(rule.AccountIDs) > 0 && !slices.Contains(rule.AccountIDs, evt.AccountID) {
+		return false
+	}
+	for key, pattern := range rule.Meta {
+		value, ok := metaValue(key)
+		if !ok {
+			return false
+		}
+		if !globMatch(pattern, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// globs caches the compiled route patterns by pattern.
+var globs sync.Map
+
+// ValidateGlob checks a route pattern. Patterns have the path.Match syntax,
+// but '*' and '?' also match '/': activity codes and meta values are not
+// paths, and "10.*" must match "10.0.0.0/8" as "/api/*" must match
+// "/api/v1/peers". The error wraps path.ErrBadPattern.
+func ValidateGlob(pattern string) error {
+	_, err := compileGlob(pattern)
+	return err
+}
+
+// globMatch reports whether value matches pattern. An invalid pattern, which
+// config validation rejects, matches nothing.
+func globMatch(pattern, value string) bool {
+	if re, ok := globs.Load(pattern); ok {
+		return re.(*regexp.Regexp).MatchString(value)
+	}
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return false
+	}
+	globs.Store(pattern, re)
+	return re.MatchString(value)
+}
+
+// compileGlob translates a pattern to an anchored regular expression.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	// path.Match checks the syntax of the whole pattern
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	inClass := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			if pattern[i] == '-' {
+				b.WriteString(`\-`)
+			} else {
+				b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+			}
+		case inClass:
+			// Ranges and ^ negation mean the same in both syntaxes
+			inClass = c != ']'
+			if c == '[' {
+				b.WriteString(`\[`)
+			} else {
+				b.WriteByte(c)
+			}
+		case c == '[':
+			inClass = true
+			b.WriteByte(c)
+		case c == '*':
+			b.WriteString(`.*`)
+		case c == '?':
+			b.WriteString(`.`)
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString(`)$`)
+	return regexp.Compile(b.String())
+}
+
+// RouteWriter passes only the events matching its routes to the wrapped
+// writer. A batch without matching events is not sent at all, and the
+// writer's checkpoint still moves past it.
+type RouteWriter struct {
+	name   string
+	next   EventWriter
+	routes *Routes
+}
+
+// NewRouteWriter wraps next. name labels the eventsproc_writer_events_filtered_total
+// metric, normally the checkpoint writer_type of the sink.
+func NewRouteWriter(name string, next EventWriter, routes *Routes) *RouteWriter {
+	return &RouteWriter{name: name, next: next, routes: routes}
+}
+
+// SendEvents sends the matching events of the batch.
+func (w *RouteWriter) SendEvents(ctx context.Context, eventsList []events.Event) error {
+	matched := make([]events.Event, 0, len(eventsList))
+	for _, evt := range eventsList {
+		if w.routes.Match(evt) {
+			matched = append(matched, evt)
+		}
+	}
+	if filtered := len(eventsList) - len(matched); filtered > 0 {
+		metrics.WriterEventsFiltered.WithLabelValues(w.name).Add(float64(filtered))
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return w.next.SendEvents(ctx, matched)
+}
+
+// SendEvent sends a single event if it matches.
+func (w *RouteWriter) SendEvent(ctx context.Context, evt events.Event) error {
+	return w.SendEvents(ctx, []events.Event{evt})
+}
+
+// Close closes the wrapped writer.
+func (w *RouteWriter) Close() error {
+	return w.next.Close()
+}
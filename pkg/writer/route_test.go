@@ -0,0 +1,82 @@
+package writer
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
+)
+
+func routeTestEvents() []events.Event {
+	return []events.Event{
+		{ID: 1, ActivityCode: "user.join", AccountID: "acc1", Meta: `{"email":"a@example.com"}`},
+		{ID: 2, ActivityCode: "setupkey.add", AccountID: "acc2", Meta: `{"name":"ci","reusable":true}`},
+		{ID: 3, ActivityCode: "peer.login", AccountID: "acc1", Meta: `{"os":"linux 6.8","fqdn":"db1.example.com"}`},
+		{ID: 4, ActivityCode: "dashboard.login", AccountID: "acc2", Meta: `not json`},
+		{ID: 5, ActivityCode: "route.add", AccountID: "acc3", Meta: `{"range":"10.0.0.0/8","path":"/api/v1/routes"}`},
+	}
+}
+
+func TestRoutes_Match(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes Routes
+		want   []int64
+	}{
+		{"no rules", Routes{}, []int64{1, 2, 3, 4, 5}},
+		{"activity globs", Routes{Include: []Rule{{ActivityCodes: []string{"user.*", "setupkey.*"}}}}, []int64{1, 2}},
+		{"account", Routes{Include: []Rule{{AccountIDs: []string{"acc2"}}}}, []int64{2, 4}},
+		{"meta glob", Routes{Include: []Rule{{Meta: map[string]string{"os": "linux*"}}}}, []int64{3}},
+		{"meta non-string", Routes{Include: []Rule{{Meta: map[string]string{"reusable": "true"}}}}, []int64{2}},
+		{"globs match slashes", Routes{Include: []Rule{{Meta: map[string]string{"range": "10.*"}}}}, []int64{5}},
+		{"globs match nested paths", Routes{Include: []Rule{{Meta: map[string]string{"path": "/api/*"}}}}, []int64{5}},
+		{"invalid glob matches nothing", Routes{Include: []Rule{{ActivityCodes: []string{"user.["}}}}, nil},
+		{"fields are combined", Routes{Include: []Rule{{ActivityCodes: []string{"*.login"}, AccountIDs: []string{"acc1"}}}}, []int64{3}},
+		{"rules are alternatives", Routes{Include: []Rule{{ActivityCodes: []string{"user.*"}}, {AccountIDs: []string{"acc2"}}}}, []int64{1, 2, 4}},
+		{"exclude only", Routes{Exclude: []Rule{{ActivityCodes: []string{"dashboard.*"}}}}, []int64{1, 2, 3, 5}},
+		{"exclude wins", Routes{
+			Include: []Rule{{AccountIDs: []string{"acc1"}}},
+			Exclude: []Rule{{Meta: map[string]string{"fqdn": "*.example.com"}}},
+		}, []int64{1}},
+	}
+	for _, tt := range tests {
+		var got []int64
+		for _, evt := range routeTestEvents() {
+			if tt.routes.Match(evt) {
+				got = append(got, evt.ID)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRouteWriter_SendEvents(t *testing.T) {
+	next := &MockWriter{}
+	w := NewRouteWriter("splunk", next, &Routes{Include: []Rule{{ActivityCodes: []string{"user.*", "setupkey.*"}}}})
+	filtered := metrics.WriterEventsFiltered.WithLabelValues("splunk")
+	before := testutil.ToFloat64(filtered)
+
+	if err := w.SendEvents(context.Background(), routeTestEvents()); err != nil {
+		t.Fatalf("SendEvents failed: %v", err)
+	}
+	if len(next.receivedEvents) != 2 || next.receivedEvents[0].ID != 1 || next.receivedEvents[1].ID != 2 {
+		t.Errorf("Expected events 1 and 2, got %+v", next.receivedEvents)
+	}
+	if got := testutil.ToFloat64(filtered) - before; got != 3 {
+		t.Errorf("Expected 3 filtered events, got %v", got)
+	}
+
+	// A batch without matches does not reach the sink
+	next.sendEventsCalled = false
+	if err := w.SendEvent(context.Background(), routeTestEvents()[3]); err != nil {
+		t.Fatalf("SendEvent failed: %v", err)
+	}
+	if next.sendEventsCalled {
+		t.Error("Expected the sink not to be called")
+	}
+}
@@ -2,8 +2,14 @@ package writer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
 
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
 )
 
 // EventWriter defines the common interface for all event writers
@@ -18,33 +24,189 @@ type EventWriter interface {
 	Close() error
 }
 
-// MultiWriter sends events to multiple writers simultaneously
+// Policy decides when a MultiWriter batch counts as delivered.
+type Policy string
+
+const (
+	// PolicyAll requires every writer to succeed (default).
+	PolicyAll Policy = "all"
+	// PolicyAny requires at least one writer to succeed.
+	PolicyAny Policy = "any"
+	// PolicyQuorum requires MultiConfig.Quorum writers to succeed.
+	PolicyQuorum Policy = "quorum"
+	// PolicyBestEffort never fails; failures are only logged and counted
+	// in eventsproc_multi_writer_failures_total.
+	PolicyBestEffort Policy = "best_effort"
+)
+
+// ParsePolicy returns the policy for name. An empty name selects PolicyAll.
+func ParsePolicy(name string) (Policy, error) {
+	switch p := Policy(name); p {
+	case "":
+		return PolicyAll, nil
+	case PolicyAll, PolicyAny, PolicyQuorum, PolicyBestEffort:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown delivery policy %q (supported: all, any, quorum, best_effort)", name)
+}
+
+// Target is one writer of a MultiWriter.
+type Target struct {
+	// Name identifies the writer in errors, logs and metrics, e.g. "loki".
+	Name   string
+	Writer EventWriter
+
+	// Timeout bounds each SendEvents call of this writer; 0 uses
+	// MultiConfig.Timeout.
+	Timeout time.Duration
+}
+
+// MultiConfig holds configuration for a MultiWriter.
+type MultiConfig struct {
+	// Name labels the log lines and metrics of the group.
+	Name string
+
+	// Policy decides when a batch counts as delivered. Default: PolicyAll.
+	Policy Policy
+
+	// Quorum is how many writers must succeed with PolicyQuorum.
+	// Default: a majority of the writers.
+	Quorum int
+
+	// Timeout bounds each writer's SendEvents call; 0 means no timeout
+	// beyond the caller's context.
+	Timeout time.Duration
+}
+
+// WriterError is the failure of one writer of a MultiWriter.
+type WriterError struct {
+	Writer string
+	Err    error
+}
+
+func (e *WriterError) Error() string { return e.Writer + ": " + e.Err.Error() }
+func (e *WriterError) Unwrap() error { return e.Err }
+
+// MultiWriter sends events to multiple writers concurrently and applies a
+// delivery policy to the results.
 type MultiWriter struct {
-	writers []EventWriter
+	name    string
+	targets []Target
+	policy  Policy
+	quorum  int
+	logger  *slog.Logger
 }
 
-// NewMultiWriter creates a new MultiWriter that writes to all provided writers
+// NewMultiWriter creates a MultiWriter that requires every writer to
+// succeed. The writers are named writer-1, writer-2, ... in errors.
 func NewMultiWriter(writers ...EventWriter) *MultiWriter {
-	return &MultiWriter{
-		writers: writers,
+	targets := make([]Target, len(writers))
+	for i, w := range writers {
+		targets[i] = Target{Name: fmt.Sprintf("writer-%d", i+1), Writer: w}
 	}
+	return &MultiWriter{name: "multi", targets: targets, policy: PolicyAll, logger: slog.Default()}
 }
 
-// SendEvents sends events to all writers
-// Returns the first error encountered, but continues sending to remaining writers
+// NewMultiWriterWithConfig creates a MultiWriter for the named targets.
+func NewMultiWriterWithConfig(cfg *MultiConfig, logger *slog.Logger, targets ...Target) (*MultiWriter, error) {
+	policy, err := ParsePolicy(string(cfg.Policy))
+	if err != nil {
+		return nil, err
+	}
+	quorum := cfg.Quorum
+	if quorum <= 0 {
+		quorum = len(targets)/2 + 1
+	}
+	if policy == PolicyQuorum && quorum > len(targets) {
+		return nil, fmt.Errorf("quorum %d exceeds the %d writers", quorum, len(targets))
+	}
+
+	named := make([]Target, len(targets))
+	for i, t := range targets {
+		if t.Timeout <= 0 {
+			t.Timeout = cfg.Timeout
+		}
+		named[i] = t
+	}
+	name := cfg.Name
+	if name == "" {
+		name = "multi"
+	}
+	return &MultiWriter{name: name, targets: named, policy: policy, quorum: quorum, logger: logger}, nil
+}
+
+// SendEvents sends the batch to every writer concurrently, each bounded by
+// its own timeout, and waits for all of them. It fails when the policy is
+// not met; the error joins one WriterError per failing writer. Events a
+// writer rejected while delivering the rest (a RejectedError) count as a
+// success of that writer and are returned in a RejectedError once the policy
+// is met.
 func (mw *MultiWriter) SendEvents(ctx context.Context, eventsList []events.Event) error {
-	var firstErr error
+	errs := make([]error, len(mw.targets))
+	var wg sync.WaitGroup
+	for i, t := range mw.targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sendCtx := ctx
+			if t.Timeout > 0 {
+				var cancel context.CancelFunc
+				sendCtx, cancel = context.WithTimeout(ctx, t.Timeout)
+				defer cancel()
+			}
+			errs[i] = t.Writer.SendEvents(sendCtx, eventsList)
+		}()
+	}
+	wg.Wait()
 
-	for _, writer := range mw.writers {
-		if err := writer.SendEvents(ctx, eventsList); err != nil {
-			if firstErr == nil {
-				firstErr = err
+	var failures []error
+	var rejected []Rejection
+	seen := make(map[int64]bool)
+	for i, err := range errs {
+		var rejectedErr *RejectedError
+		switch {
+		case err == nil:
+		case errors.As(err, &rejectedErr):
+			for _, r := range rejectedErr.Rejected {
+				if !seen[r.Event.ID] {
+					seen[r.Event.ID] = true
+					rejected = append(rejected, Rejection{Event: r.Event, Reason: mw.targets[i].Name + ": " + r.Reason})
+				}
 			}
-			// Continue sending to other writers even if one fails
+		default:
+			failures = append(failures, &WriterError{Writer: mw.targets[i].Name, Err: err})
+			metrics.MultiWriterFailures.WithLabelValues(mw.name, mw.targets[i].Name).Inc()
 		}
 	}
 
-	return firstErr
+	if len(failures) > 0 {
+		succeeded := len(mw.targets) - len(failures)
+		var met bool
+		switch mw.policy {
+		case PolicyAny:
+			met = succeeded > 0
+		case PolicyQuorum:
+			met = succeeded >= mw.quorum
+		case PolicyBestEffort:
+			met = true
+		}
+		joined := errors.Join(failures...)
+		if !met {
+			return fmt.Errorf("%d of %d writers failed (policy %s): %w", len(failures), len(mw.targets), mw.policy, joined)
+		}
+		mw.logger.Warn("Writers failed, delivery policy still met",
+			"group", mw.name,
+			"policy", mw.policy,
+			"failed", len(failures),
+			"writers", len(mw.targets),
+			"error", joined,
+		)
+	}
+
+	if len(rejected) > 0 {
+		return Reject(fmt.Errorf("writers rejected %d events", len(rejected)), rejected...)
+	}
+	return nil
 }
 
 // SendEvent sends a single event to all writers
@@ -56,8 +218,8 @@ func (mw *MultiWriter) SendEvent(ctx context.Context, evt events.Event) error {
 func (mw *MultiWriter) Close() error {
 	var firstErr error
 
-	for _, writer := range mw.writers {
-		if err := writer.Close(); err != nil {
+	for _, t := range mw.targets {
+		if err := t.Writer.Close(); err != nil {
 			if firstErr == nil {
 				firstErr = err
 			}
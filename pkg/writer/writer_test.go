@@ -3,10 +3,15 @@ package writer
 import (
 	"context"
 	"errors"
+	"io"
+	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/xh63/netbird-events/pkg/events"
+	"github.com/xh63/netbird-events/pkg/metrics"
 )
 
 // MockWriter is a mock implementation of EventWriter for testing
@@ -47,8 +52,8 @@ func TestNewMultiWriter(t *testing.T) {
 		t.Fatal("Expected non-nil MultiWriter")
 	}
 
-	if len(mw.writers) != 2 {
-		t.Errorf("Expected 2 writers, got %d", len(mw.writers))
+	if len(mw.targets) != 2 {
+		t.Errorf("Expected 2 writers, got %d", len(mw.targets))
 	}
 }
 
@@ -119,12 +124,12 @@ func TestMultiWriter_SendEvents_OneWriterFails(t *testing.T) {
 
 	err := mw.SendEvents(context.Background(), testEvents)
 
-	// Should return the first error
+	// Should name the failing writer
 	if err == nil {
-		t.Error("Expected error from failing writer")
+		t.Fatal("Expected error from failing writer")
 	}
-	if err.Error() != "mock2 error" {
-		t.Errorf("Expected 'mock2 error', got: %v", err)
+	if err.Error() != "1 of 3 writers failed (policy all): writer-2: mock2 error" {
+		t.Errorf("Expected error naming writer-2, got: %v", err)
 	}
 
 	// But all writers should still have been called
@@ -161,12 +166,16 @@ func TestMultiWriter_SendEvents_MultipleWritersFail(t *testing.T) {
 
 	err := mw.SendEvents(context.Background(), testEvents)
 
-	// Should return the first error encountered
+	// Should join the errors of every writer, in writer order
 	if err == nil {
-		t.Error("Expected error from failing writers")
+		t.Fatal("Expected error from failing writers")
 	}
-	if err.Error() != "mock1 error" {
-		t.Errorf("Expected first error 'mock1 error', got: %v", err)
+	if !strings.Contains(err.Error(), "writer-1: mock1 error\nwriter-2: mock2 error\nwriter-3: mock3 error") {
+		t.Errorf("Expected every writer's error, got: %v", err)
+	}
+	var we *WriterError
+	if !errors.As(err, &we) || we.Writer != "writer-1" {
+		t.Errorf("Expected a WriterError for writer-1, got: %v", err)
 	}
 
 	// All writers should have been called
@@ -352,3 +361,138 @@ func TestMultiWriter_ContextCancellation(t *testing.T) {
 		t.Error("Expected mock2.SendEvents to be called")
 	}
 }
+
+// slowWriter blocks until its context is done.
+type slowWriter struct {
+	MockWriter
+}
+
+func (sw *slowWriter) SendEvents(ctx context.Context, _ []events.Event) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func newTestMultiWriter(t *testing.T, cfg MultiConfig, writers map[string]EventWriter, order ...string) *MultiWriter {
+	t.Helper()
+	targets := make([]Target, len(order))
+	for i, name := range order {
+		targets[i] = Target{Name: name, Writer: writers[name]}
+	}
+	mw, err := NewMultiWriterWithConfig(&cfg, slog.New(slog.NewTextHandler(io.Discard, nil)), targets...)
+	if err != nil {
+		t.Fatalf("NewMultiWriterWithConfig failed: %v", err)
+	}
+	return mw
+}
+
+func TestMultiWriter_Policies(t *testing.T) {
+	batch := []events.Event{{ID: 1}}
+	down := errors.New("down")
+	writers := func() map[string]EventWriter {
+		return map[string]EventWriter{
+			"loki":   &MockWriter{},
+			"splunk": &MockWriter{sendEventsErr: down},
+			"kafka":  &MockWriter{sendEventsErr: down},
+		}
+	}
+
+	tests := []struct {
+		cfg     MultiConfig
+		wantErr bool
+	}{
+		{MultiConfig{Policy: PolicyAll}, true},
+		{MultiConfig{Policy: PolicyAny}, false},
+		{MultiConfig{Policy: PolicyQuorum}, true}, // majority of 3 is 2
+		{MultiConfig{Policy: PolicyQuorum, Quorum: 1}, false},
+		{MultiConfig{Policy: PolicyBestEffort}, false},
+	}
+	for _, tt := range tests {
+		mw := newTestMultiWriter(t, tt.cfg, writers(), "loki", "splunk", "kafka")
+		err := mw.SendEvents(context.Background(), batch)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s quorum %d: expected error %v, got %v", tt.cfg.Policy, tt.cfg.Quorum, tt.wantErr, err)
+		}
+		if err != nil && (!strings.Contains(err.Error(), "splunk: down") || !strings.Contains(err.Error(), "kafka: down")) {
+			t.Errorf("%s: expected the failing writers to be named, got %v", tt.cfg.Policy, err)
+		}
+	}
+
+	// Best effort reports failures only to metrics, even when every writer fails
+	failures := metrics.MultiWriterFailures.WithLabelValues("siem", "splunk")
+	before := testutil.ToFloat64(failures)
+	mw := newTestMultiWriter(t, MultiConfig{Name: "siem", Policy: PolicyBestEffort}, map[string]EventWriter{
+		"splunk": &MockWriter{sendEventsErr: down},
+	}, "splunk")
+	if err := mw.SendEvents(context.Background(), batch); err != nil {
+		t.Errorf("Expected best effort to succeed, got %v", err)
+	}
+	if got := testutil.ToFloat64(failures); got != before+1 {
+		t.Errorf("Expected the failure to be counted, got %v", got-before)
+	}
+}
+
+func TestMultiWriter_PerWriterTimeout(t *testing.T) {
+	fast := &MockWriter{}
+	mw, err := NewMultiWriterWithConfig(&MultiConfig{Policy: PolicyAny, Timeout: time.Hour}, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Target{Name: "webhook:slow", Writer: &slowWriter{}, Timeout: 20 * time.Millisecond},
+		Target{Name: "loki", Writer: fast},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiWriterWithConfig failed: %v", err)
+	}
+
+	start := time.Now()
+	if err := mw.SendEvents(context.Background(), []events.Event{{ID: 1}}); err != nil {
+		t.Errorf("Expected success from the fast writer, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the slow writer to time out, took %s", elapsed)
+	}
+	if !fast.sendEventsCalled {
+		t.Error("Expected the fast writer to be called")
+	}
+
+	mw.policy = PolicyAll
+	err = mw.SendEvents(context.Background(), []events.Event{{ID: 1}})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "webhook:slow") {
+		t.Errorf("Expected a timeout naming webhook:slow, got %v", err)
+	}
+}
+
+func TestMultiWriter_Rejections(t *testing.T) {
+	rejecting := &MockWriter{sendEventsErr: Reject(errors.New("rejected"), Rejection{Event: events.Event{ID: 2}, Reason: "mapping"})}
+	mw := newTestMultiWriter(t, MultiConfig{}, map[string]EventWriter{
+		"elasticsearch": rejecting,
+		"loki":          &MockWriter{},
+	}, "elasticsearch", "loki")
+
+	err := mw.SendEvents(context.Background(), []events.Event{{ID: 1}, {ID: 2}})
+	var rejected *RejectedError
+	if !errors.As(err, &rejected) || len(rejected.Rejected) != 1 || rejected.Rejected[0].Reason != "elasticsearch: mapping" {
+		t.Errorf("Expected event 2 rejected by elasticsearch, got %v", err)
+	}
+}
+
+func TestMultiWriter_InvalidConfig(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewMultiWriterWithConfig(&MultiConfig{Policy: "most"}, logger); err == nil {
+		t.Error("Expected error for an unknown policy")
+	}
+	if _, err := NewMultiWriterWithConfig(&MultiConfig{Policy: PolicyQuorum, Quorum: 3}, logger,
+		Target{Name: "a", Writer: &MockWriter{}}, Target{Name: "b", Writer: &MockWriter{}}); err == nil {
+		t.Error("Expected error for a quorum above the number of writers")
+	}
+}
+
+func TestIsPermanent_JoinedErrors(t *testing.T) {
+	perm := Permanent(errors.New("400"))
+	if !IsPermanent(errors.Join(perm, Permanent(errors.New("422")))) {
+		t.Error("Expected a join of permanent errors to be permanent")
+	}
+	if IsPermanent(errors.Join(perm, errors.New("503"))) {
+		t.Error("Expected a join with a transient error not to be permanent")
+	}
+	if !IsPermanent(&WriterError{Writer: "loki", Err: perm}) {
+		t.Error("Expected a wrapped permanent error to be permanent")
+	}
+}